@@ -0,0 +1,342 @@
+// Package adaptiverw implements a reader-writer lock that switches between a
+// read-optimized and a write-optimized implementation as its workload shifts.
+//
+// No static reader-writer design wins under every mix of operations. Distributed read
+// counters in the style of percpurw make readers nearly free but cost every writer a
+// sweep over all the counters, while a centralized lock such as rwticket makes writers
+// cheap and has readers fight over one cache line. An RWLock runs in one of two modes:
+//   - Distributed: readers count themselves on per-P counters, as in percpurw
+//   - Central: readers and writers go through a single rwticket.Lock
+//
+// and moves between them based on the read/write ratio it observes. Writers tally their
+// acquisitions, and every few writes one of them compares the reads and writes since the
+// last evaluation: fewer reads per write than the low threshold moves the lock to
+// central mode, more than the high threshold back to distributed mode. The gap between
+// the thresholds keeps a lock near the boundary from flapping. In central mode, where a
+// read-only phase would otherwise never be evaluated, every so often a reader triggers
+// the evaluation itself by briefly taking the write lock after it releases its read lock.
+//
+// A switch happens while the deciding goroutine holds the lock for writing in the old
+// mode: it takes the write lock of the new mode as well before publishing the new mode,
+// so no reader or writer of either mode is ever inside alongside it. Goroutines that
+// picked the old mode just before the switch notice once they hold its lock, release it,
+// and start over in the new mode.
+//
+// Example usage:
+//
+//	rw := adaptiverw.New(adaptiverw.WithThresholds(8, 64))
+//
+//	rw.RLock()
+//	// ... read shared state ...
+//	rw.RUnlock()
+//
+//	rw.Lock()
+//	// ... modify shared state ...
+//	rw.Unlock()
+package adaptiverw
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/rwticket"
+	"github.com/ahrav/go-locks/spin"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Mode is the implementation an RWLock is currently running.
+type Mode uint8
+
+const (
+	Distributed Mode = iota // Per-P read counters; cheap reads, expensive writes
+	Central                 // A single rwticket.Lock; cheap writes, contended reads
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Distributed:
+		return "Distributed"
+	case Central:
+		return "Central"
+	}
+	return "Mode(?)"
+}
+
+// Bits of RWLock.state.
+const (
+	blocked = 1 << iota // A distributed-mode writer holds or is acquiring the lock
+	central             // The lock is in central mode
+)
+
+const (
+	// Default thresholds, in reads per write.
+	DefaultLow  = 8
+	DefaultHigh = 64
+
+	evalWrites = 16   // A writer evaluates the mode every evalWrites writes
+	evalReads  = 1024 // A central-mode reader evaluates it every evalReads reads per counter
+	minWindow  = 256  // Fewest operations an evaluation decides on
+)
+
+type counter struct {
+	in  atomic.Uint64 // Read acquisitions counted here
+	out atomic.Uint64 // Read releases counted here
+
+	_ pad.CacheLinePad
+}
+
+// Option configures an RWLock.
+type Option func(*RWLock)
+
+// WithThresholds sets the read/write ratios at which the lock switches modes: it moves
+// to central mode when it sees fewer than low reads per write, and to distributed mode
+// when it sees more than high. low must not exceed high.
+func WithThresholds(low, high int) Option {
+	if low > high {
+		panic("adaptiverw: low threshold greater than high threshold")
+	}
+	return func(rw *RWLock) { rw.low, rw.high = uint64(low), uint64(high) }
+}
+
+// WithMode sets the mode the lock starts in. The default is Distributed.
+func WithMode(m Mode) Option {
+	return func(rw *RWLock) {
+		if m == Central {
+			rw.state.Store(central)
+		}
+	}
+}
+
+// RWLock is a reader-writer lock that adapts its implementation to its read/write mix.
+type RWLock struct {
+	state atomic.Uint32 // blocked and central bits
+
+	// Distributed mode
+	readers []counter
+	writers *ticket.Lock // Serializes distributed-mode writers
+
+	// Central mode
+	single *rwticket.Lock
+
+	low, high uint64
+
+	// Guarded by the write lock of the current mode
+	writes     uint64
+	lastReads  uint64
+	lastWrites uint64
+
+	switches atomic.Uint64
+}
+
+// New creates an RWLock with one read counter per P.
+func New(opts ...Option) *RWLock {
+	rw := &RWLock{
+		readers: make([]counter, runtime.GOMAXPROCS(0)),
+		writers: ticket.NewLock(),
+		single:  rwticket.NewLock(),
+		low:     DefaultLow,
+		high:    DefaultHigh,
+	}
+	for _, opt := range opts {
+		opt(rw)
+	}
+	return rw
+}
+
+// counter picks a read counter at random; see percpurw.
+func (rw *RWLock) counter() *counter {
+	return &rw.readers[rand.Uint32()%uint32(len(rw.readers))]
+}
+
+// RLock acquires the lock for reading.
+func (rw *RWLock) RLock() {
+	for {
+		if rw.state.Load()&central == 0 {
+			c := rw.counter()
+			c.in.Add(1)
+			chaos.Point()
+			if rw.state.Load() == 0 {
+				return // Fast path: distributed, no writer around
+			}
+			// A writer is active or the mode changed: back out, and wait for the writer.
+			c.out.Add(1)
+			rw.wait(func() bool { return rw.state.Load() != blocked })
+			continue
+		}
+
+		rw.single.RLock()
+		if rw.state.Load()&central != 0 {
+			rw.counter().in.Add(1)
+			return
+		}
+		rw.single.RUnlock() // Switched to distributed mode while we waited
+	}
+}
+
+// RUnlock releases a read lock.
+func (rw *RWLock) RUnlock() {
+	chaos.Point()
+	if rw.state.Load()&central == 0 { // The mode can't change while we read
+		rw.counter().out.Add(1)
+		return
+	}
+	n := rw.counter().out.Add(1)
+	rw.single.RUnlock()
+	if n%evalReads == 0 {
+		rw.lock()
+		rw.evaluate()
+		rw.Unlock()
+	}
+}
+
+// Lock acquires the lock for writing.
+func (rw *RWLock) Lock() {
+	rw.lock()
+	rw.writes++
+	if rw.writes%evalWrites == 0 {
+		rw.evaluate()
+	}
+}
+
+// lock acquires the write lock of the current mode.
+func (rw *RWLock) lock() {
+	for {
+		if rw.state.Load()&central == 0 {
+			rw.writers.Lock() //lockcheck:ignore Unlock releases it
+			if rw.state.Load()&central == 0 {
+				rw.state.Store(blocked) // New readers now take the slow path
+				chaos.Point()
+				rw.wait(func() bool { return rw.activeReaders() == 0 })
+				return
+			}
+			rw.writers.Unlock() // Switched to central mode while we waited
+			continue
+		}
+
+		rw.single.Lock() //lockcheck:ignore Unlock releases it
+		if rw.state.Load()&central != 0 {
+			return
+		}
+		rw.single.Unlock()
+	}
+}
+
+// Unlock releases a write lock.
+func (rw *RWLock) Unlock() {
+	if rw.state.Load()&central != 0 {
+		rw.single.Unlock()
+		return
+	}
+	if invariant.Enabled {
+		// Readers arriving meanwhile count themselves before backing out, so the
+		// counters needn't have drained
+		invariant.Check(rw.state.Load() == blocked, "adaptiverw: Unlock of a lock not held for writing")
+	}
+	rw.state.Store(0)
+	rw.writers.Unlock()
+}
+
+// Mode returns the mode the lock is running in.
+func (rw *RWLock) Mode() Mode {
+	if rw.state.Load()&central != 0 {
+		return Central
+	}
+	return Distributed
+}
+
+// evaluate switches modes if the reads and writes since the last evaluation call for
+// it. The caller holds the write lock.
+func (rw *RWLock) evaluate() {
+	reads := rw.totalReads()
+	dr, dw := reads-rw.lastReads, rw.writes-rw.lastWrites
+	if dr+dw < minWindow {
+		return
+	}
+	rw.lastReads, rw.lastWrites = reads, rw.writes
+
+	if rw.state.Load()&central == 0 {
+		if dr < dw*rw.low {
+			rw.toCentral()
+		}
+	} else if dr > dw*rw.high {
+		rw.toDistributed()
+	}
+}
+
+// toCentral moves a lock held for writing in distributed mode to central mode, leaving
+// it held for writing in central mode.
+func (rw *RWLock) toCentral() {
+	//lockcheck:ignore Held for writing in its new mode
+	rw.single.Lock() // Only goroutines about to find out they are stale can be inside
+	rw.state.Store(central)
+	rw.writers.Unlock()
+	rw.switches.Add(1)
+}
+
+// toDistributed moves a lock held for writing in central mode to distributed mode,
+// leaving it held for writing in distributed mode.
+func (rw *RWLock) toDistributed() {
+	rw.writers.Lock() //lockcheck:ignore Held for writing in its new mode
+	rw.state.Store(blocked)
+	rw.single.Unlock()
+	rw.wait(func() bool { return rw.activeReaders() == 0 }) // Readers backing out of a stale attempt
+	rw.switches.Add(1)
+}
+
+// Switches returns how many times the lock has changed modes.
+func (rw *RWLock) Switches() uint64 { return rw.switches.Load() }
+
+// activeReaders returns the number of readers inside in distributed mode. A reader may
+// leave from a different counter than it arrived on, so only the totals are meaningful.
+func (rw *RWLock) activeReaders() int64 {
+	var in, out uint64
+	for i := range rw.readers {
+		in += rw.readers[i].in.Load()
+		out += rw.readers[i].out.Load()
+	}
+	return int64(in - out)
+}
+
+// totalReads returns the number of read acquisitions counted so far.
+func (rw *RWLock) totalReads() uint64 {
+	var n uint64
+	for i := range rw.readers {
+		n += rw.readers[i].in.Load()
+	}
+	return n
+}
+
+// wait spins, then yields, until cond reports true.
+func (rw *RWLock) wait(cond func() bool) {
+	if cond() {
+		return
+	}
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	for i := 0; !cond(); i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+	lockprof.Record(start, 1)
+}
@@ -0,0 +1,129 @@
+package adaptiverw
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	locks "github.com/ahrav/go-locks"
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+var _ locks.RWLocker = (*RWLock)(nil)
+
+func TestSwitchesWithWorkload(t *testing.T) {
+	rw := New()
+	assert.Equal(t, Distributed, rw.Mode())
+
+	for range 2 * minWindow {
+		rw.Lock()
+		rw.Unlock()
+	}
+	assert.Equal(t, Central, rw.Mode(), "A write-only phase should move the lock to central mode")
+
+	for range 4 * evalReads * len(rw.readers) {
+		rw.RLock()
+		rw.RUnlock()
+	}
+	assert.Equal(t, Distributed, rw.Mode(), "A read-only phase should move the lock back")
+	assert.Equal(t, uint64(2), rw.Switches())
+}
+
+func TestThresholdsHysteresis(t *testing.T) {
+	rw := New(WithThresholds(2, 100))
+	for range 8 * minWindow { // 10 reads per write: between the thresholds
+		rw.Lock()
+		rw.Unlock()
+		for range 10 {
+			rw.RLock()
+			rw.RUnlock()
+		}
+	}
+	assert.Equal(t, Distributed, rw.Mode())
+	assert.Zero(t, rw.Switches())
+
+	assert.Panics(t, func() { WithThresholds(10, 1) })
+}
+
+func TestConcurrentAccessAcrossSwitches(t *testing.T) {
+	rw := New(WithThresholds(4, 8))
+	const numReaders = 6
+	const numWriters = 2
+	const iterations = 3000
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numReaders + numWriters)
+	for range numWriters {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				rw.Lock()
+				counter++
+				rw.Unlock()
+			}
+		}()
+	}
+	for i := range numReaders {
+		go func() {
+			defer wg.Done()
+			for j := range iterations {
+				if (j/500)%2 == i%2 { // Readers come and go in phases
+					continue
+				}
+				rw.RLock()
+				_ = counter
+				rw.RUnlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numWriters*iterations, counter)
+	assert.Zero(t, rw.activeReaders())
+}
+
+func TestWithModeCentral(t *testing.T) {
+	rw := New(WithMode(Central))
+	assert.Equal(t, Central, rw.Mode())
+
+	rw.RLock()
+	locked := make(chan struct{})
+	go func() {
+		rw.Lock() //lockcheck:ignore The test unlocks it
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("Writer acquired the lock while a reader was inside")
+	default:
+	}
+	rw.RUnlock()
+	<-locked
+	rw.Unlock()
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	rw := New()
+	allocs.Zero(t, func() {
+		rw.Lock()
+		rw.Unlock()
+		rw.RLock()
+		rw.RUnlock()
+	}, "Uncontended acquisitions and releases should not allocate")
+}
+
+func BenchmarkRLock(b *testing.B) {
+	for _, m := range []Mode{Distributed, Central} {
+		b.Run(m.String(), func(b *testing.B) {
+			rw := New(WithMode(m), WithThresholds(0, 1<<30)) // Never switch
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					rw.RLock()
+					rw.RUnlock()
+				}
+			})
+		})
+	}
+}
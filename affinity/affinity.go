@@ -0,0 +1,116 @@
+// Package affinity pins goroutines to CPUs and NUMA nodes and describes the machine's
+// NUMA topology.
+//
+// Go schedules goroutines over OS threads freely, so a goroutine has no fixed CPU and
+// no fixed NUMA node. Pinning wires the calling goroutine to its current OS thread with
+// runtime.LockOSThread and restricts that thread to a set of CPUs. The NUMA-aware locks
+// in this module, such as cohort.Lock, take a node index from the caller; a worker
+// pinned with PinNode knows its node and can pass it. Benchmarks pin their workers the
+// same way to get results that don't depend on where the scheduler happened to run
+// them.
+//
+// Example usage:
+//
+//	for node := range affinity.NumNodes() {
+//	    go func() {
+//	        unpin, err := affinity.PinNode(node)
+//	        defer unpin() // A no-op if pinning failed
+//	        if err != nil {
+//	            // Not supported on this platform; run unpinned
+//	        }
+//
+//	        lock.Lock(node)
+//	        // ...
+//	        lock.Unlock(node)
+//	    }()
+//	}
+//
+// Nodes are indexed from 0 to NumNodes()-1 in the order of the kernel's node IDs, which
+// can have gaps; node 1 is the second node even on a machine whose nodes are 0 and 2.
+//
+// Pinning is only implemented on Linux. Elsewhere the pin functions return
+// ErrUnsupported and the topology functions report a single node holding every CPU.
+package affinity
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned by the pin functions on platforms without thread affinity.
+var ErrUnsupported = errors.New("affinity: CPU pinning is not supported on this platform")
+
+// Pin restricts the calling goroutine to the given CPUs. It locks the goroutine to its
+// OS thread for as long as the pin lasts; the returned function restores the thread's
+// previous CPU set and unlocks it, and must be called from the same goroutine. If Pin
+// fails the goroutine is left unpinned and unpin does nothing, so it can be deferred
+// before the error is checked.
+func Pin(cpus ...int) (unpin func(), err error) {
+	if len(cpus) == 0 {
+		return func() {}, errors.New("affinity: no CPUs to pin to")
+	}
+	runtime.LockOSThread()
+	prev, err := pin(cpus)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return func() {}, err
+	}
+	return func() {
+		restore(prev)
+		runtime.UnlockOSThread()
+	}, nil
+}
+
+// PinNode restricts the calling goroutine to the CPUs of a NUMA node, as Pin does.
+func PinNode(node int) (unpin func(), err error) {
+	cpus, err := NodeCPUs(node)
+	if err != nil {
+		return func() {}, err
+	}
+	return Pin(cpus...)
+}
+
+// NodeCPUs returns the CPUs of a NUMA node in ascending order.
+func NodeCPUs(node int) ([]int, error) {
+	if node < 0 || node >= NumNodes() {
+		return nil, fmt.Errorf("affinity: no NUMA node %d", node)
+	}
+	return nodeCPUs(node)
+}
+
+// allCPUs lists every CPU, for platforms that can't describe the topology.
+func allCPUs() []int {
+	cpus := make([]int, runtime.NumCPU())
+	for i := range cpus {
+		cpus[i] = i
+	}
+	return cpus
+}
+
+// parseCPUList parses a kernel CPU list such as "0-3,8,10-11".
+func parseCPUList(s string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("affinity: bad CPU list %q", s)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("affinity: bad CPU list %q", s)
+			}
+		}
+		for c := first; c <= last; c++ {
+			cpus = append(cpus, c)
+		}
+	}
+	return cpus, nil
+}
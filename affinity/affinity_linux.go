@@ -0,0 +1,100 @@
+package affinity
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// cpuMask is a kernel cpu_set_t covering 1024 CPUs.
+type cpuMask [16]uint64
+
+const sysfsNodes = "/sys/devices/system/node"
+
+func getMask() (cpuMask, error) {
+	var m cpuMask
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(m), uintptr(unsafe.Pointer(&m)))
+	if errno != 0 {
+		return m, errno
+	}
+	return m, nil
+}
+
+func setMask(m *cpuMask) error {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(*m), uintptr(unsafe.Pointer(m)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// pin sets the calling thread's CPU set, returning the previous one.
+func pin(cpus []int) (cpuMask, error) {
+	prev, err := getMask()
+	if err != nil {
+		return prev, fmt.Errorf("affinity: sched_getaffinity: %w", err)
+	}
+	var m cpuMask
+	for _, c := range cpus {
+		if c < 0 || c >= len(m)*64 {
+			return prev, fmt.Errorf("affinity: CPU %d out of range", c)
+		}
+		m[c/64] |= 1 << (c % 64)
+	}
+	if err := setMask(&m); err != nil {
+		return prev, fmt.Errorf("affinity: sched_setaffinity %v: %w", cpus, err)
+	}
+	return prev, nil
+}
+
+func restore(prev cpuMask) { setMask(&prev) }
+
+// CurrentCPUs returns the CPUs the calling thread may run on.
+func CurrentCPUs() ([]int, error) {
+	m, err := getMask()
+	if err != nil {
+		return nil, fmt.Errorf("affinity: sched_getaffinity: %w", err)
+	}
+	var cpus []int
+	for c := range len(m) * 64 {
+		if m[c/64]&(1<<(c%64)) != 0 {
+			cpus = append(cpus, c)
+		}
+	}
+	return cpus, nil
+}
+
+// nodeIDs lists the kernel IDs of the NUMA nodes; kernels without NUMA support have none.
+var nodeIDs = sync.OnceValue(func() []int { return readNodeIDs(sysfsNodes) })
+
+// readNodeIDs reads the IDs of the nodes that have CPUs from a sysfs node directory.
+// Memory-only nodes are left out, since nothing can be pinned to them.
+func readNodeIDs(dir string) []int {
+	b, err := os.ReadFile(filepath.Join(dir, "has_cpu"))
+	if err != nil {
+		return nil
+	}
+	ids, err := parseCPUList(string(b)) // Node lists share the CPU list format
+	if err != nil {
+		return nil
+	}
+	return ids
+}
+
+// NumNodes returns the number of NUMA nodes, which is 1 on machines without NUMA.
+func NumNodes() int { return max(len(nodeIDs()), 1) }
+
+func nodeCPUs(node int) ([]int, error) {
+	ids := nodeIDs()
+	if len(ids) == 0 {
+		return allCPUs(), nil // No NUMA support: one node holding every CPU
+	}
+	b, err := os.ReadFile(filepath.Join(sysfsNodes, fmt.Sprintf("node%d", ids[node]), "cpulist"))
+	if err != nil {
+		return nil, fmt.Errorf("affinity: %w", err)
+	}
+	return parseCPUList(string(b))
+}
@@ -0,0 +1,18 @@
+package affinity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadNodeIDs(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, readNodeIDs(dir), "No NUMA support")
+
+	// Node 1 is offline and node 3 holds only memory
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "has_cpu"), []byte("0,2,4-5\n"), 0o644))
+	assert.Equal(t, []int{0, 2, 4, 5}, readNodeIDs(dir))
+}
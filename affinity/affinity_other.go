@@ -0,0 +1,17 @@
+//go:build !linux
+
+package affinity
+
+type cpuMask struct{}
+
+func pin([]int) (cpuMask, error) { return cpuMask{}, ErrUnsupported }
+
+func restore(cpuMask) {}
+
+// CurrentCPUs returns the CPUs the calling thread may run on.
+func CurrentCPUs() ([]int, error) { return allCPUs(), nil }
+
+// NumNodes returns the number of NUMA nodes, which is 1 on machines without NUMA.
+func NumNodes() int { return 1 }
+
+func nodeCPUs(int) ([]int, error) { return allCPUs(), nil }
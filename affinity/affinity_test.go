@@ -0,0 +1,60 @@
+package affinity
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("0-3,8,10-11\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 8, 10, 11}, cpus)
+
+	for _, bad := range []string{"a", "3-1", "1-x"} {
+		_, err := parseCPUList(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestTopology(t *testing.T) {
+	assert.GreaterOrEqual(t, NumNodes(), 1)
+	cpus, err := NodeCPUs(0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cpus)
+
+	_, err = NodeCPUs(NumNodes())
+	assert.Error(t, err)
+}
+
+func TestPinRestores(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		_, err := Pin(0)
+		assert.ErrorIs(t, err, ErrUnsupported)
+		return
+	}
+	before, err := CurrentCPUs()
+	assert.NoError(t, err)
+
+	unpin, err := Pin(before[0])
+	assert.NoError(t, err)
+	during, err := CurrentCPUs()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{before[0]}, during)
+	unpin()
+
+	after, err := CurrentCPUs()
+	assert.NoError(t, err)
+	assert.Equal(t, before, after, "Unpin should restore the previous CPU set")
+}
+
+func TestUnpinAfterFailure(t *testing.T) {
+	unpin, err := Pin()
+	assert.Error(t, err)
+	assert.NotPanics(t, unpin)
+
+	unpin, err = PinNode(-1)
+	assert.Error(t, err)
+	assert.NotPanics(t, unpin)
+}
@@ -28,18 +28,39 @@
 package alock
 
 import (
-	"runtime"
 	"sync/atomic"
+	"unsafe"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/sema"
+	"github.com/ahrav/go-locks/spin"
 )
 
+// flag is one waiter's slot. Each sits on its own cache line so that a waiter spinning
+// on its flag isn't disturbed by writes to its neighbours'.
+type flag struct {
+	v uint32
+	_ pad.CacheLinePad
+}
+
 // Share manages a shared lock among multiple goroutines.
 type Share struct {
-	flags []uint32 // Array of flags to indicate whether a goroutine can acquire the lock
-	tail  uint32   // Atomic index to assign slots to incoming goroutines
-	size  uint32   // Size of the flags array (number of goroutines)
+	flags []flag // Array of flags to indicate whether a goroutine can acquire the lock
+	tail  uint32 // Atomic index to assign slots to incoming goroutines
+	size  uint32 // Size of the flags array (number of goroutines)
+
+	spin spin.Adaptive // Self-tuning spin limit for waiters
 }
 
 // ArrayLock manages a local lock for each goroutine.
+//
+// myIndex holds the slot of the current holder. It is only written once the lock has
+// been acquired, so a single ArrayLock may be shared by all contending goroutines as
+// long as no more than numGoroutines of them contend at once.
 type ArrayLock struct {
 	share   *Share
 	myIndex uint32
@@ -50,9 +71,9 @@ func NewArrayLock(numGoroutines uint32) *ArrayLock {
 	share := &Share{
 		size:  numGoroutines,
 		tail:  0,
-		flags: make([]uint32, numGoroutines),
+		flags: make([]flag, numGoroutines),
 	}
-	share.flags[0] = 1 // Set the first flag to 1 to allow the first goroutine to acquire the lock
+	share.flags[0].v = 1 // Set the first flag to 1 to allow the first goroutine to acquire the lock
 
 	return &ArrayLock{share: share}
 }
@@ -61,38 +82,106 @@ func NewArrayLock(numGoroutines uint32) *ArrayLock {
 func (al *ArrayLock) Lock() {
 	lock := al.share
 	// Atomically increment the tail and determine the slot for the current goroutine.
-	slot := atomic.AddUint32(&lock.tail, 1) % lock.size
+	// AddUint32 returns the new value, so step back one to get the slot we claimed.
+	slot := (atomic.AddUint32(&lock.tail, 1) - 1) % lock.size
+	chaos.Point()
+
+	if atomic.LoadUint32(&lock.flags[slot].v) == 0 {
+		lock.wait(slot)
+	}
+
+	// Only record our slot once we own the lock; the holder still needs its own index
+	// to unlock, and the ArrayLock may be shared by every contending goroutine.
 	al.myIndex = slot
+	if invariant.Enabled {
+		lock.checkOneFlag(slot)
+	}
+}
 
-	// Spin until the flag for this slot is set to 1.
-	for atomic.LoadUint32(&lock.flags[slot]) == 0 {
+// wait spins until the flag for slot is set to 1.
+func (s *Share) wait(slot uint32) {
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = s.spin.Limit()
+	}
+	i := 0
+	for ; atomic.LoadUint32(&s.flags[slot].v) == 0; i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		if sema.Enabled { // Park until Unlock wakes us
+			flag := &s.flags[slot].v
+			sema.Wait(uintptr(unsafe.Pointer(flag)), func() bool { return atomic.LoadUint32(flag) != 0 })
+			continue
+		}
 		// Yield to allow other goroutines to run, not sure if this is the best approach.
-		runtime.Gosched()
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
 	}
+
+	if spinLimit > 0 && i > 0 { // Uncontended acquisitions say nothing about handoff latency
+		s.spin.Update(min(i, spinLimit), i < spinLimit)
+	}
+	lockprof.Record(start, 1)
 }
 
 // Unlock releases the lock, allowing the next goroutine in the queue to acquire it.
 func (al *ArrayLock) Unlock() {
 	lock := al.share
 	slot := al.myIndex
+	if invariant.Enabled {
+		lock.checkOneFlag(slot)
+	}
 
 	// Set the current slot's flag to 0 to indicate release.
-	atomic.StoreUint32(&lock.flags[slot], 0)
+	atomic.StoreUint32(&lock.flags[slot].v, 0)
+	chaos.Point()
 
 	// Set the next slot's flag to 1 to allow the next goroutine to acquire the lock.
 	nextSlot := (slot + 1) % lock.size
-	atomic.StoreUint32(&lock.flags[nextSlot], 1)
+	atomic.StoreUint32(&lock.flags[nextSlot].v, 1)
+	if sema.Enabled {
+		sema.Wake(uintptr(unsafe.Pointer(&lock.flags[nextSlot].v)))
+	}
 }
 
 // TryLock attempts to acquire the lock without blocking. Returns true if successful.
 func (al *ArrayLock) TryLock() bool {
 	lock := al.share
 	tail := atomic.LoadUint32(&lock.tail)
-	if atomic.LoadUint32(&lock.flags[tail%lock.size]) == 1 {
+	chaos.Point()
+	if atomic.LoadUint32(&lock.flags[tail%lock.size].v) == 1 {
 		if atomic.CompareAndSwapUint32(&lock.tail, tail, tail+1) {
 			al.myIndex = tail % lock.size
+			if invariant.Enabled {
+				lock.checkOneFlag(al.myIndex)
+			}
 			return true
 		}
 	}
 	return false
 }
+
+// checkOneFlag verifies that holder's flag is the only one set. It must only be called
+// by the holder, when no release is in flight.
+func (s *Share) checkOneFlag(holder uint32) {
+	set := 0
+	vals := make([]uint32, len(s.flags))
+	for i := range s.flags {
+		if vals[i] = atomic.LoadUint32(&s.flags[i].v); vals[i] != 0 {
+			set++
+		}
+	}
+	invariant.Check(set == 1 && vals[holder] == 1,
+		"alock: holder slot %d, want exactly that flag set, found %d flags set: %v", holder, set, vals)
+}
@@ -0,0 +1,71 @@
+package alock
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestArrayLockConcurrentAccess(t *testing.T) {
+	// Multiple Ps let waiters take the relax path before falling back to Gosched on
+	// machines with more than one CPU.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	const numGoroutines = 8
+	const iterations = 1000
+	lock := NewArrayLock(numGoroutines)
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				lock.Lock()
+				counter++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+}
+
+func TestArrayLockFirstAcquisition(t *testing.T) {
+	lock := NewArrayLock(4)
+
+	for range 10 { // Wrap around the slot array a few times
+		lock.Lock()
+		lock.Unlock()
+	}
+	assert.True(t, lock.TryLock(), "Lock should be free after balanced Lock/Unlock calls")
+	lock.Unlock()
+}
+
+func TestArrayLockTryLock(t *testing.T) {
+	lock := NewArrayLock(2)
+
+	assert.True(t, lock.TryLock())
+	assert.False(t, lock.TryLock(), "TryLock should fail while the lock is held")
+	lock.Unlock()
+
+	lock.Lock()
+	assert.False(t, lock.TryLock())
+	lock.Unlock()
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	lock := NewArrayLock(4)
+	allocs.Zero(t, func() {
+		lock.Lock()
+		lock.Unlock()
+		assert.True(t, lock.TryLock())
+		lock.Unlock()
+	}, "Uncontended Lock, TryLock and Unlock should not allocate")
+}
@@ -0,0 +1,15 @@
+// Package archspin provides CPU relax hints for use inside busy-wait loops.
+//
+// On amd64 Relax executes PAUSE and on arm64 it executes YIELD. Both instructions tell
+// the processor that the current hardware thread is spinning, which reduces power usage,
+// frees execution resources for a sibling hyperthread, and avoids the memory-order
+// mis-speculation penalty when the awaited cache line finally changes. On every other
+// architecture Relax is a no-op.
+//
+// The functions are implemented in assembly so the compiler can neither inline them
+// away nor eliminate the loops that call them:
+//
+//	for atomic.LoadUint32(&flag) == 0 {
+//	    archspin.Relax()
+//	}
+package archspin
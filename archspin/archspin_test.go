@@ -0,0 +1,22 @@
+package archspin
+
+import "testing"
+
+func TestRelaxReturns(t *testing.T) {
+	Relax()
+	RelaxN(0)
+	RelaxN(1)
+	RelaxN(1000)
+}
+
+func BenchmarkRelax(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Relax()
+	}
+}
+
+func BenchmarkRelaxN100(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		RelaxN(100)
+	}
+}
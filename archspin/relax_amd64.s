@@ -0,0 +1,20 @@
+#include "textflag.h"
+
+// func Relax()
+TEXT ·Relax(SB), NOSPLIT, $0-0
+	PAUSE
+	RET
+
+// func RelaxN(n uint32)
+TEXT ·RelaxN(SB), NOSPLIT, $0-4
+	MOVL n+0(FP), CX
+	TESTL CX, CX
+	JZ   done
+
+loop:
+	PAUSE
+	DECL CX
+	JNZ  loop
+
+done:
+	RET
@@ -0,0 +1,19 @@
+#include "textflag.h"
+
+// func Relax()
+TEXT ·Relax(SB), NOSPLIT, $0-0
+	YIELD
+	RET
+
+// func RelaxN(n uint32)
+TEXT ·RelaxN(SB), NOSPLIT, $0-4
+	MOVWU n+0(FP), R0
+	CBZ   R0, done
+
+loop:
+	YIELD
+	SUBS  $1, R0, R0
+	BNE   loop
+
+done:
+	RET
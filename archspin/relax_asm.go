@@ -0,0 +1,9 @@
+//go:build amd64 || arm64
+
+package archspin
+
+// Relax executes a single CPU relax instruction.
+func Relax()
+
+// RelaxN executes n CPU relax instructions back to back.
+func RelaxN(n uint32)
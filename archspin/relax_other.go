@@ -0,0 +1,9 @@
+//go:build !amd64 && !arm64
+
+package archspin
+
+// Relax is a no-op on architectures without a dedicated relax instruction.
+func Relax() {}
+
+// RelaxN is a no-op on architectures without a dedicated relax instruction.
+func RelaxN(n uint32) {}
@@ -0,0 +1,300 @@
+// Package banker allocates several types of resources among clients without ever
+// entering a state that can deadlock, using Dijkstra's banker's algorithm.
+//
+// Each client declares up front the most of each resource it may hold at once, its
+// claim. A request is granted only if, after granting it, the state is safe: there is
+// some order in which every client could be given the rest of its claim, finish, and
+// return everything. A request that would leave the state unsafe waits, even if the
+// resources are free, until releases make it safe. Since some client can always run to
+// completion, clients that eventually release what they hold can never deadlock on the
+// pool, which needs far less capacity than provisioning every client's claim at once.
+//
+// Waiting requests are granted in FIFO order among those that are safe. A request that
+// is not safe yet does not hold up the requests behind it: one of them may be what lets
+// a holder finish and release what the earlier request needs, so blocking them could
+// deadlock. A large request can therefore be passed by smaller ones while it waits.
+//
+// Example usage:
+//
+//	b := banker.New([]int{8, 4}) // 8 connections, 4 GPUs
+//
+//	c, err := b.Register([]int{3, 2}) // At most 3 connections and 2 GPUs at once
+//	if err != nil {
+//	    return err
+//	}
+//	defer c.Close() // Releases whatever c still holds
+//
+//	if err := c.Acquire(ctx, []int{2, 1}); err != nil {
+//	    return err
+//	}
+//	// ... use them ...
+//	c.Release([]int{2, 1})
+package banker
+
+import (
+	"container/list"
+	"context"
+	"errors"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+var (
+	// ErrClaim is returned when a claim exceeds the pool, or a request exceeds what is
+	// left of the client's claim.
+	ErrClaim = errors.New("banker: request exceeds claim")
+
+	// ErrClosed is returned when acquiring through a closed Client.
+	ErrClosed = errors.New("banker: client closed")
+)
+
+// Banker is a pool of several resource types. Create it with New.
+type Banker struct {
+	mu        *ticket.Lock
+	available []int
+	clients   map[*Client]struct{}
+	queue     list.List // Waiting *request values
+}
+
+// New creates a pool holding total[i] units of resource i.
+func New(total []int) *Banker {
+	return &Banker{
+		mu:        ticket.NewLock(),
+		available: append([]int(nil), total...),
+		clients:   make(map[*Client]struct{}),
+	}
+}
+
+// Client is a registered user of a Banker. Its methods must not be called
+// concurrently.
+type Client struct {
+	b     *Banker
+	claim []int
+	alloc []int // Guarded by b.mu
+	open  bool  // Guarded by b.mu
+}
+
+type request struct {
+	c     *Client
+	n     []int
+	ready chan struct{} // Closed when the request is granted
+}
+
+// Register adds a client that may hold up to claim[i] units of resource i at once.
+func (b *Banker) Register(claim []int) (*Client, error) {
+	if len(claim) != len(b.available) {
+		return nil, ErrClaim
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	total := b.total()
+	for i, n := range claim {
+		if n < 0 || n > total[i] {
+			return nil, ErrClaim
+		}
+	}
+	c := &Client{b: b, claim: append([]int(nil), claim...), alloc: make([]int, len(claim)), open: true}
+	b.clients[c] = struct{}{}
+	return c, nil
+}
+
+// Acquire takes n[i] units of each resource i, waiting until granting them leaves the
+// pool in a safe state. It returns
+// ErrClaim if the client's holdings would exceed its claim, and ctx.Err() without
+// taking anything if ctx is done first.
+func (c *Client) Acquire(ctx context.Context, n []int) error {
+	b := c.b
+	b.mu.Lock()
+	if err := c.check(n); err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	if b.safe(c, n) { // Every queued request is unsafe, so this overtakes nothing grantable
+		b.grant(c, n)
+		b.mu.Unlock()
+		return nil
+	}
+	req := &request{c: c, n: n, ready: make(chan struct{})}
+	elem := b.queue.PushBack(req)
+	b.mu.Unlock()
+
+	select {
+	case <-req.ready:
+		return nil
+	case <-ctx.Done():
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-req.ready:
+		return nil // Granted as we gave up; keep it
+	default:
+	}
+	b.queue.Remove(elem)
+	return ctx.Err()
+}
+
+// TryAcquire takes n[i] units of each resource i if that can be done without waiting,
+// and reports whether it did.
+func (c *Client) TryAcquire(n []int) bool {
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c.check(n) != nil || !b.safe(c, n) {
+		return false
+	}
+	b.grant(c, n)
+	return true
+}
+
+// Release returns n[i] units of each resource i. It panics if the client holds less.
+func (c *Client) Release(n []int) {
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range n {
+		if n[i] < 0 || n[i] > c.alloc[i] {
+			panic("banker: Release of more than held")
+		}
+	}
+	for i := range n {
+		c.alloc[i] -= n[i]
+		b.available[i] += n[i]
+	}
+	b.grantWaiters()
+}
+
+// Close releases everything the client holds and unregisters it. Its claim no longer
+// constrains other clients.
+func (c *Client) Close() {
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !c.open {
+		return
+	}
+	c.open = false
+	for i, n := range c.alloc {
+		b.available[i] += n
+		c.alloc[i] = 0
+	}
+	delete(b.clients, c)
+	b.grantWaiters()
+}
+
+// Held returns the units of each resource the client holds.
+func (c *Client) Held() []int {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	return append([]int(nil), c.alloc...)
+}
+
+// check validates a request of n by c against its claim. b.mu must be held.
+func (c *Client) check(n []int) error {
+	if !c.open {
+		return ErrClosed
+	}
+	if len(n) != len(c.claim) {
+		return ErrClaim
+	}
+	for i := range n {
+		if n[i] < 0 || c.alloc[i]+n[i] > c.claim[i] {
+			return ErrClaim
+		}
+	}
+	return nil
+}
+
+// total returns the pool's capacity. b.mu must be held.
+func (b *Banker) total() []int {
+	total := append([]int(nil), b.available...)
+	for c := range b.clients {
+		for i, n := range c.alloc {
+			total[i] += n
+		}
+	}
+	return total
+}
+
+// safe reports whether granting n to c would leave the pool in a safe state: one in
+// which the clients can finish one after another, each being given the rest of its
+// claim from what is free plus what the clients before it returned. b.mu must be held.
+func (b *Banker) safe(c *Client, n []int) bool {
+	work := append([]int(nil), b.available...)
+	for i := range n {
+		work[i] -= n[i]
+		if work[i] < 0 {
+			return false
+		}
+	}
+	alloc := func(x *Client, i int) int {
+		if x == c {
+			return x.alloc[i] + n[i]
+		}
+		return x.alloc[i]
+	}
+	pending := make([]*Client, 0, len(b.clients))
+	for x := range b.clients {
+		pending = append(pending, x)
+	}
+	for progress := true; progress && len(pending) > 0; {
+		progress = false
+		for j := 0; j < len(pending); j++ {
+			x := pending[j]
+			if !canFinish(x, work, alloc) {
+				continue
+			}
+			for i := range work {
+				work[i] += alloc(x, i)
+			}
+			pending[j] = pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			j--
+			progress = true
+		}
+	}
+	return len(pending) == 0
+}
+
+// canFinish reports whether x's remaining claim fits in work.
+func canFinish(x *Client, work []int, alloc func(*Client, int) int) bool {
+	for i := range work {
+		if x.claim[i]-alloc(x, i) > work[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// grant gives n to c. b.mu must be held.
+func (b *Banker) grant(c *Client, n []int) {
+	for i := range n {
+		c.alloc[i] += n[i]
+		b.available[i] -= n[i]
+	}
+}
+
+// grantWaiters grants, in FIFO order, every waiting request that is safe. b.mu must be
+// held.
+func (b *Banker) grantWaiters() {
+	for e := b.queue.Front(); e != nil; {
+		req, next := e.Value.(*request), e.Next()
+		if b.safe(req.c, req.n) {
+			b.grant(req.c, req.n)
+			b.queue.Remove(e)
+			close(req.ready)
+		}
+		e = next
+	}
+}
+
+// waiting returns the number of queued requests.
+func (b *Banker) waiting() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.queue.Len()
+}
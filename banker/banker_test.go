@@ -0,0 +1,127 @@
+package banker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func register(t *testing.T, b *Banker, claim ...int) *Client {
+	t.Helper()
+	c, err := b.Register(claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestUnsafeRequestWaits(t *testing.T) {
+	b := New([]int{10})
+	x, y := register(t, b, 8), register(t, b, 8)
+	assert.NoError(t, x.Acquire(context.Background(), []int{4}))
+
+	// Free, but granting it would leave neither client able to finish
+	assert.False(t, y.TryAcquire([]int{4}))
+	done := make(chan error)
+	go func() { done <- y.Acquire(context.Background(), []int{4}) }()
+	select {
+	case <-done:
+		t.Fatal("unsafe request was granted")
+	case <-time.After(20 * time.Millisecond):
+	}
+	z := register(t, b, 1)
+	assert.True(t, z.TryAcquire([]int{1}), "A safe request waited behind an unsafe one")
+
+	x.Close()
+	assert.NoError(t, <-done)
+	assert.Equal(t, []int{4}, y.Held())
+}
+
+func TestSafeRequestsAreGrantedInOrder(t *testing.T) {
+	b := New([]int{2})
+	holder := register(t, b, 2)
+	assert.True(t, holder.TryAcquire([]int{2}))
+
+	first, second := register(t, b, 1), register(t, b, 1)
+	firstDone, secondDone := make(chan error), make(chan error)
+	go func() { firstDone <- first.Acquire(context.Background(), []int{1}) }()
+	for b.waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() { secondDone <- second.Acquire(context.Background(), []int{1}) }()
+	for b.waiting() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	holder.Release([]int{1})
+	assert.NoError(t, <-firstDone)
+	assert.Equal(t, 1, b.waiting())
+	holder.Release([]int{1})
+	assert.NoError(t, <-secondDone)
+}
+
+func TestAcquireCancelled(t *testing.T) {
+	b := New([]int{2})
+	x, y, z := register(t, b, 2), register(t, b, 2), register(t, b, 1)
+	assert.True(t, x.TryAcquire([]int{2}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, y.Acquire(ctx, []int{1}), context.DeadlineExceeded)
+	assert.Equal(t, []int{0}, y.Held())
+	assert.Equal(t, 0, b.waiting())
+
+	x.Release([]int{1})
+	assert.True(t, z.TryAcquire([]int{1}))
+	assert.Equal(t, []int{0}, y.Held(), "The cancelled request was granted")
+}
+
+func TestClaims(t *testing.T) {
+	b := New([]int{4, 4})
+	_, err := b.Register([]int{5, 1})
+	assert.ErrorIs(t, err, ErrClaim)
+	_, err = b.Register([]int{1})
+	assert.ErrorIs(t, err, ErrClaim)
+
+	c := register(t, b, 2, 2)
+	assert.ErrorIs(t, c.Acquire(context.Background(), []int{3, 0}), ErrClaim)
+	assert.NoError(t, c.Acquire(context.Background(), []int{2, 0}))
+	assert.ErrorIs(t, c.Acquire(context.Background(), []int{1, 0}), ErrClaim)
+	assert.Panics(t, func() { c.Release([]int{0, 1}) })
+
+	c.Close()
+	assert.ErrorIs(t, c.Acquire(context.Background(), []int{1, 0}), ErrClosed)
+	assert.True(t, register(t, b, 4, 4).TryAcquire([]int{4, 4}), "Close returned the holdings")
+}
+
+func TestIncrementalAcquisitionDoesNotDeadlock(t *testing.T) {
+	const numClients = 6
+	const iterations = 100
+	b := New([]int{5, 3})
+	var wg sync.WaitGroup
+
+	wg.Add(numClients)
+	for g := range numClients {
+		go func() {
+			defer wg.Done()
+			c := register(t, b, 3, 2)
+			defer c.Close()
+			for i := range iterations {
+				// Take the claim one unit at a time, in an order that varies per client
+				steps := [][]int{{1, 0}, {0, 1}, {1, 0}, {0, 1}, {1, 0}}
+				if (g+i)%2 == 1 {
+					steps = [][]int{{0, 1}, {0, 1}, {1, 0}, {1, 0}, {1, 0}}
+				}
+				for _, s := range steps {
+					assert.NoError(t, c.Acquire(context.Background(), s))
+				}
+				c.Release([]int{3, 2})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []int{5, 3}, b.available)
+}
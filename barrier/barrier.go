@@ -0,0 +1,158 @@
+// Package barrier provides a reusable cyclic barrier.
+//
+// A Cyclic barrier makes a fixed number of parties wait for each other: each calls
+// Await, and once the last one arrives they are all released together and the barrier
+// resets for the next generation. An optional action runs exactly once per generation,
+// on the last arriver, before anyone is released, which is the place to advance shared
+// state between phases.
+//
+// If a waiter gives up, because its context is done, or the action panics, the
+// barrier is broken: every waiter of that generation and every later Await fails with
+// ErrBroken until Reset is called. This keeps a phase from running with some parties
+// missing.
+//
+// Example usage:
+//
+//	b := barrier.NewCyclic(workers, world.Advance)
+//
+//	// In each worker:
+//	for step := range steps {
+//	    simulate(step)
+//	    if _, err := b.Await(ctx); err != nil {
+//	        return err
+//	    }
+//	}
+package barrier
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// ErrBroken is returned by Await when the barrier has been broken.
+var ErrBroken = errors.New("barrier: broken")
+
+// generation is one cycle of the barrier.
+type generation struct {
+	done   chan struct{} // Closed when the generation trips or breaks
+	broken bool          // Set before done is closed
+}
+
+// Cyclic is a reusable barrier for a fixed number of parties.
+type Cyclic struct {
+	mu      *ticket.Lock
+	parties int
+	action  func()
+	waiting int         // Parties arrived in the current generation; guarded by mu
+	gen     *generation // Guarded by mu
+}
+
+// NewCyclic creates a barrier for the given number of parties (at least 1). action, if
+// not nil, runs on the last party to arrive in each generation; it must not call
+// methods of the barrier.
+func NewCyclic(parties int, action func()) *Cyclic {
+	return &Cyclic{
+		mu:      ticket.NewLock(),
+		parties: max(parties, 1),
+		action:  action,
+		gen:     &generation{done: make(chan struct{})},
+	}
+}
+
+// Parties returns the number of parties the barrier waits for.
+func (b *Cyclic) Parties() int { return b.parties }
+
+// Await waits until all parties have called Await in this generation. It returns the
+// arrival index of the caller, from Parties()-1 for the first to arrive down to 0 for
+// the last, which runs the action.
+//
+// If ctx is done first, Await breaks the barrier and returns ctx.Err(). If the barrier
+// is or becomes broken while waiting, Await returns ErrBroken.
+func (b *Cyclic) Await(ctx context.Context) (int, error) {
+	b.mu.Lock() //lockcheck:ignore trip unlocks it on the last arrival
+	gen := b.gen
+	if gen.broken {
+		b.mu.Unlock()
+		return 0, ErrBroken
+	}
+	if err := ctx.Err(); err != nil {
+		b.breakLocked()
+		b.mu.Unlock()
+		return 0, err
+	}
+
+	b.waiting++
+	index := b.parties - b.waiting
+	if index == 0 {
+		b.trip() // Unlocks mu
+		if gen.broken {
+			return 0, ErrBroken
+		}
+		return 0, nil
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-gen.done:
+	case <-ctx.Done():
+		b.mu.Lock()
+		if b.gen == gen && !gen.broken {
+			b.breakLocked()
+			b.mu.Unlock()
+			return index, ctx.Err()
+		}
+		b.mu.Unlock() // The generation ended meanwhile; report how
+	}
+	if gen.broken {
+		return index, ErrBroken
+	}
+	return index, nil
+}
+
+// trip runs the action and releases the current generation. mu must be held; trip
+// releases it. The action runs under mu, so a waiter giving up meanwhile cannot break
+// a generation that is about to trip.
+func (b *Cyclic) trip() {
+	defer b.mu.Unlock()
+	if b.action != nil {
+		ok := false
+		defer func() {
+			if !ok {
+				b.breakLocked() // The action panicked
+			}
+		}()
+		b.action()
+		ok = true
+	}
+	gen := b.gen
+	b.waiting = 0
+	b.gen = &generation{done: make(chan struct{})}
+	close(gen.done)
+}
+
+// Reset breaks the current generation, failing its waiters with ErrBroken, and starts
+// a fresh one.
+func (b *Cyclic) Reset() {
+	b.mu.Lock()
+	b.breakLocked()
+	b.waiting = 0
+	b.gen = &generation{done: make(chan struct{})}
+	b.mu.Unlock()
+}
+
+// IsBroken reports whether the current generation is broken.
+func (b *Cyclic) IsBroken() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.gen.broken
+}
+
+// breakLocked breaks the current generation. mu must be held.
+func (b *Cyclic) breakLocked() {
+	if !b.gen.broken {
+		b.gen.broken = true
+		close(b.gen.done)
+	}
+}
@@ -0,0 +1,85 @@
+package barrier
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCyclicRunsActionOncePerGeneration(t *testing.T) {
+	const parties = 4
+	const generations = 5
+	phase := 0
+	b := NewCyclic(parties, func() { phase++ })
+	var wg sync.WaitGroup
+
+	wg.Add(parties)
+	for range parties {
+		go func() {
+			defer wg.Done()
+			for gen := range generations {
+				assert.Equal(t, gen, phase, "Every party should see the same phase")
+				_, err := b.Await(context.Background())
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, generations, phase)
+	assert.False(t, b.IsBroken())
+}
+
+func TestCyclicArrivalIndex(t *testing.T) {
+	b := NewCyclic(2, nil)
+	first := make(chan int)
+	go func() {
+		i, _ := b.Await(context.Background())
+		first <- i
+	}()
+	for {
+		b.mu.Lock()
+		waiting := b.waiting
+		b.mu.Unlock()
+		if waiting == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	last, err := b.Await(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, last)
+	assert.Equal(t, 1, <-first)
+}
+
+func TestCyclicBreaksOnTimeout(t *testing.T) {
+	b := NewCyclic(3, nil)
+	other := make(chan error)
+	go func() {
+		_, err := b.Await(context.Background())
+		other <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := b.Await(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, <-other, ErrBroken, "Other waiters of the generation should fail")
+	assert.True(t, b.IsBroken())
+
+	_, err = b.Await(context.Background())
+	assert.ErrorIs(t, err, ErrBroken, "A broken barrier stays broken until Reset")
+
+	b.Reset()
+	assert.False(t, b.IsBroken())
+}
+
+func TestCyclicBreaksOnActionPanic(t *testing.T) {
+	b := NewCyclic(1, func() { panic("boom") })
+	assert.Panics(t, func() { _, _ = b.Await(context.Background()) })
+	assert.True(t, b.IsBroken())
+}
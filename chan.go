@@ -0,0 +1,97 @@
+package locks
+
+import (
+	"context"
+	"sync"
+)
+
+// Releaser releases a lock that was acquired on the caller's behalf.
+type Releaser interface {
+	// Release unlocks the lock. Calls after the first are no-ops.
+	Release()
+}
+
+// releaser is the Releaser for a sync.Locker.
+type releaser struct {
+	once sync.Once
+	l    sync.Locker
+}
+
+func (r *releaser) Release() { r.once.Do(r.l.Unlock) }
+
+// ChanLocker exposes acquisition of a sync.Locker as a channel, so that acquiring a lock
+// can be one case of a select alongside context cancellation, timers, and other
+// channel operations:
+//
+//	acquired := cl.AcquireCh(ctx)
+//	select {
+//	case r := <-acquired:
+//	    defer r.Release()
+//	    // ... critical section ...
+//	case <-timer.C:
+//	    cancel() // Abandon the acquisition
+//	}
+type ChanLocker struct {
+	l sync.Locker
+}
+
+// NewChanLocker creates a ChanLocker that acquires l.
+func NewChanLocker(l sync.Locker) *ChanLocker { return &ChanLocker{l: l} }
+
+// AcquireCh starts acquiring the lock on a background goroutine and returns a channel
+// that delivers a Releaser once the lock is held.
+//
+// The acquisition is tied to ctx: if ctx is done before the Releaser is received, the
+// lock is released again (or never acquired) and the channel is closed, so a receive
+// yields nil. A caller that selects on something other than ctx and abandons the
+// acquisition must cancel ctx, otherwise the lock stays held by the background
+// goroutine waiting to deliver it.
+func (c *ChanLocker) AcquireCh(ctx context.Context) <-chan Releaser {
+	ch := make(chan Releaser)
+	go func() {
+		if ctx.Err() != nil {
+			close(ch)
+			return
+		}
+
+		c.l.Lock()
+		if ctx.Err() != nil { // Cancelled while we were queued
+			c.l.Unlock()
+			close(ch)
+			return
+		}
+		select {
+		case ch <- &releaser{l: c.l}:
+		case <-ctx.Done():
+			c.l.Unlock()
+			close(ch)
+		}
+	}()
+	return ch
+}
+
+// LockContext acquires the lock, or returns ctx.Err() without holding it if ctx is
+// done first. Together with Unlock it makes a ChanLocker a ContextLocker.
+//
+// If the wrapped lock has a TryLock method and is free, LockContext takes it directly
+// without starting a goroutine.
+func (c *ChanLocker) LockContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if tl, ok := c.l.(interface{ TryLock() bool }); ok && tl.TryLock() {
+		return nil
+	}
+	select {
+	case r := <-c.AcquireCh(ctx):
+		if r == nil {
+			return ctx.Err()
+		}
+		return nil
+	case <-ctx.Done(): // The background goroutine releases the lock if it gets it
+		return ctx.Err()
+	}
+}
+
+// Unlock releases a lock acquired with LockContext.
+func (c *ChanLocker) Unlock() { c.l.Unlock() }
@@ -0,0 +1,72 @@
+package locks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestAcquireChDelivers(t *testing.T) {
+	lock := ticket.NewLock()
+	cl := NewChanLocker(lock)
+
+	r := <-cl.AcquireCh(context.Background())
+	assert.NotNil(t, r)
+	assert.False(t, lock.TryLock(), "Lock should be held once the Releaser is delivered")
+
+	r.Release()
+	r.Release() // Second release is a no-op
+	assert.True(t, lock.TryLock())
+	lock.Unlock()
+}
+
+func TestAcquireChCancelled(t *testing.T) {
+	lock := ticket.NewLock()
+	cl := NewChanLocker(lock)
+	lock.Lock() // Hold the lock so the acquisition cannot complete
+
+	ctx, cancel := context.WithCancel(context.Background())
+	acquired := cl.AcquireCh(ctx)
+
+	select {
+	case <-acquired:
+		t.Fatal("Lock should not be acquired while held")
+	case <-time.After(10 * time.Millisecond):
+		cancel()
+	}
+	lock.Unlock()
+
+	// The background goroutine acquires, notices the cancellation, and releases.
+	_, ok := <-acquired
+	assert.False(t, ok, "Channel should be closed after cancellation")
+	assert.True(t, lock.TryLock(), "Abandoned acquisition must release the lock")
+	lock.Unlock()
+}
+
+func TestChanLockerLockContextReturnsWhileHeld(t *testing.T) {
+	lock := ticket.NewLock()
+	cl := NewChanLocker(lock)
+	lock.Lock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, cl.LockContext(ctx), context.DeadlineExceeded, "Must not wait for the holder")
+	lock.Unlock()
+
+	assert.NoError(t, cl.LockContext(context.Background()), "The abandoned acquisition is passed on")
+	cl.Unlock()
+}
+
+func TestChanLockerLockContextDoesNotAllocate(t *testing.T) {
+	cl := NewChanLocker(ticket.NewLock())
+	ctx := context.Background()
+	allocs.Zero(t, func() {
+		_ = cl.LockContext(ctx)
+		cl.Unlock()
+	}, "LockContext on a free TryLocker should not start a goroutine")
+}
@@ -0,0 +1,31 @@
+// Package chaos injects scheduling noise into the locks in this module to shake out
+// ordering bugs in both the lock algorithms and the code that uses them.
+//
+// The locks call Point at their internal linearization points: between claiming a
+// ticket or queue slot and publishing it, between the stores of a multi-step release,
+// and on every iteration of a wait loop (where an injected yield acts like a spurious
+// wakeup). Without the lockschaos build tag, Point is an empty function the compiler
+// inlines away. With the tag, every Point randomly yields the processor or sleeps,
+// according to the active Config:
+//
+//	go test -tags lockschaos ./...
+//
+// Chaos mode is meant for tests and stress runs only; it makes every lock slower.
+package chaos
+
+import "time"
+
+// Config controls how much noise Point injects when chaos mode is compiled in.
+type Config struct {
+	YieldProbability float64       // Probability that a Point calls runtime.Gosched
+	DelayProbability float64       // Probability that a Point sleeps
+	MaxDelay         time.Duration // Upper bound on an injected sleep
+	Seed             uint64        // Seed for the noise; 0 selects a random seed
+}
+
+// DefaultConfig is the Config in effect until Configure is called.
+var DefaultConfig = Config{
+	YieldProbability: 0.1,
+	DelayProbability: 0.01,
+	MaxDelay:         100 * time.Microsecond,
+}
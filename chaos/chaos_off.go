@@ -0,0 +1,18 @@
+//go:build !lockschaos
+
+package chaos
+
+// Enabled reports whether chaos mode is compiled in.
+const Enabled = false
+
+// Point marks an internal linearization point. It is a no-op without the lockschaos
+// build tag.
+func Point() {}
+
+// Configure replaces the active Config. It has no effect without the lockschaos build
+// tag.
+func Configure(Config) {}
+
+// Injected returns the number of times noise was injected. It is always 0 without the
+// lockschaos build tag.
+func Injected() uint64 { return 0 }
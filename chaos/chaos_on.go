@@ -0,0 +1,61 @@
+//go:build lockschaos
+
+package chaos
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Enabled reports whether chaos mode is compiled in.
+const Enabled = true
+
+var (
+	active   atomic.Pointer[state]
+	injected atomic.Uint64
+)
+
+type state struct {
+	cfg Config
+	mu  sync.Mutex // Guards rng, which is not safe for concurrent use
+	rng *rand.Rand
+}
+
+func init() { Configure(DefaultConfig) }
+
+// Configure replaces the active Config.
+func Configure(cfg Config) {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	active.Store(&state{cfg: cfg, rng: rand.New(rand.NewPCG(seed, seed))})
+}
+
+// Injected returns the number of times noise was injected.
+func Injected() uint64 { return injected.Load() }
+
+// Point marks an internal linearization point and randomly yields or sleeps there.
+func Point() {
+	s := active.Load()
+
+	s.mu.Lock()
+	roll := s.rng.Float64()
+	delay := time.Duration(0)
+	if s.cfg.MaxDelay > 0 {
+		delay = time.Duration(s.rng.Int64N(int64(s.cfg.MaxDelay)) + 1)
+	}
+	s.mu.Unlock()
+
+	switch {
+	case roll < s.cfg.DelayProbability:
+		injected.Add(1)
+		time.Sleep(delay)
+	case roll < s.cfg.DelayProbability+s.cfg.YieldProbability:
+		injected.Add(1)
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,31 @@
+//go:build lockschaos
+
+package chaos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointInjectsNoise(t *testing.T) {
+	defer Configure(DefaultConfig)
+	Configure(Config{YieldProbability: 1, Seed: 1})
+
+	before := Injected()
+	for range 10 {
+		Point()
+	}
+	assert.Equal(t, before+10, Injected(), "Every point should yield with probability 1")
+}
+
+func TestPointQuiet(t *testing.T) {
+	defer Configure(DefaultConfig)
+	Configure(Config{Seed: 1})
+
+	before := Injected()
+	for range 10 {
+		Point()
+	}
+	assert.Equal(t, before, Injected(), "No noise should be injected with zero probabilities")
+}
@@ -0,0 +1,86 @@
+// Package clock abstracts the passage of time for the locks in this module so that
+// their time-dependent paths (sleeping waiters, backoff, hold-time tracking) can be
+// exercised deterministically in tests.
+//
+// Locks default to Real. Tests construct a Fake and install it through the owning
+// package's SetClock function:
+//
+//	fake := clock.NewFake(time.Time{})
+//	fake.OnSleep(func(time.Duration) { /* release the holder */ })
+//	defer ticket.SetClock(ticket.SetClock(fake))
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source used by the locks.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Sleep pauses the calling goroutine for at least d.
+	Sleep(d time.Duration)
+}
+
+// Real is the Clock backed by the time package.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Fake is a Clock whose time only moves when told to. Sleep never blocks: it advances
+// the fake time by the requested duration, counts the call, and invokes the OnSleep
+// hook, which tests use to make progress happen "during" the sleep.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	sleeps  int
+	onSleep func(time.Duration)
+}
+
+// NewFake creates a Fake clock whose current time is start.
+func NewFake(start time.Time) *Fake { return &Fake{now: start} }
+
+// Now returns the fake current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Sleep advances the fake time by d and runs the OnSleep hook, if any, before returning.
+func (f *Fake) Sleep(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.sleeps++
+	hook := f.onSleep
+	f.mu.Unlock()
+
+	if hook != nil {
+		hook(d)
+	}
+}
+
+// Advance moves the fake time forward by d without counting a sleep.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
+// Sleeps returns the number of times Sleep has been called.
+func (f *Fake) Sleeps() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sleeps
+}
+
+// OnSleep registers fn to be called, outside the clock's internal lock, on every Sleep.
+func (f *Fake) OnSleep(fn func(time.Duration)) {
+	f.mu.Lock()
+	f.onSleep = fn
+	f.mu.Unlock()
+}
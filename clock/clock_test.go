@@ -0,0 +1,51 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeSleepAdvancesTime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+
+	fake.Sleep(time.Second)
+	fake.Sleep(2 * time.Second)
+
+	assert.Equal(t, start.Add(3*time.Second), fake.Now())
+	assert.Equal(t, 2, fake.Sleeps())
+}
+
+func TestFakeAdvanceDoesNotCountSleeps(t *testing.T) {
+	fake := NewFake(time.Time{})
+
+	fake.Advance(time.Minute)
+
+	assert.Equal(t, time.Time{}.Add(time.Minute), fake.Now())
+	assert.Zero(t, fake.Sleeps())
+}
+
+func TestFakeOnSleep(t *testing.T) {
+	fake := NewFake(time.Time{})
+	var got []time.Duration
+	fake.OnSleep(func(d time.Duration) {
+		// The hook runs outside the clock's lock, so it may use the clock itself.
+		assert.Equal(t, time.Time{}.Add(d), fake.Now())
+		got = append(got, d)
+	})
+
+	fake.Sleep(time.Millisecond)
+	assert.Equal(t, []time.Duration{time.Millisecond}, got)
+
+	fake.OnSleep(nil)
+	fake.Sleep(time.Millisecond)
+	assert.Len(t, got, 1, "A cleared hook should no longer be called")
+}
+
+func TestRealSleeps(t *testing.T) {
+	start := Real.Now()
+	Real.Sleep(time.Millisecond)
+	assert.GreaterOrEqual(t, Real.Now().Sub(start), time.Millisecond)
+}
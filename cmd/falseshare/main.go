@@ -0,0 +1,98 @@
+// Command falseshare runs a benchmark under the Linux perf subsystem and reports the
+// cache lines that suffer false sharing.
+//
+// It builds the test binary of a package, runs the selected benchmarks while sampling
+// loads that hit a line modified in another core's cache (a HITM event) together with
+// the data address they touched, and groups the samples by cache line. A line whose
+// hits land on several different words is one that cores keep stealing from each other
+// to write unrelated fields: the signature of false sharing. Each line is annotated
+// with the functions that touched it and the lock types those functions belong to, so
+// an unpadded field like the flags of an array lock shows up by name.
+//
+// Usage:
+//
+//	falseshare [flags] package [benchmark flags]
+//
+// For example:
+//
+//	falseshare -bench BenchmarkShardedMapParallel ./shardedmap
+//
+// HITM events are model-specific. The default raw event code is Intel's
+// MEM_LOAD_L3_HIT_RETIRED.XSNP_HITM (Skylake and later); other processors need the
+// matching code passed with -event, as listed by `perf list`. Sampling data addresses
+// requires perf_event_paranoid of 1 or lower, or CAP_PERFMON.
+//
+// falseshare only runs on Linux.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+func main() {
+	var (
+		bench  = flag.String("bench", ".", "run only the benchmarks matching `regexp`")
+		event  = flag.String("event", "0x04d2", "raw perf event `code` counting HITM loads")
+		period = flag.Uint64("period", 97, "sample one in every `n` events")
+		line   = flag.Uint64("line", 64, "cache line size in `bytes`")
+		top    = flag.Int("top", 10, "report at most `n` cache lines")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: falseshare [flags] package [benchmark flags]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	config, err := strconv.ParseUint(*event, 0, 64)
+	if err != nil {
+		fatalf("invalid -event: %v", err)
+	}
+	if err := run(config, *period, *bench, *line, *top); err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// run builds the package named by the first argument, samples its benchmarks and
+// writes the report. It returns errors rather than exiting so that the build directory
+// is always removed.
+func run(config, period uint64, bench string, line uint64, top int) error {
+	dir, err := os.MkdirTemp("", "falseshare")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	bin := filepath.Join(dir, "bench.test")
+	build := exec.Command("go", "test", "-c", "-o", bin, flag.Arg(0))
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("building %s: %v", flag.Arg(0), err)
+	}
+
+	args := append([]string{"-test.run=^$", "-test.bench=" + bench}, flag.Args()[1:]...)
+	samples, err := record(bin, args, config, period)
+	if err != nil {
+		return err
+	}
+
+	syms, err := loadSymbols(bin)
+	if err != nil {
+		return fmt.Errorf("reading symbols: %v", err)
+	}
+	lines := analyze(samples, line, syms.lookup)
+	writeReport(os.Stdout, lines, len(samples), top)
+	return nil
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "falseshare: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// perfEventAttr mirrors struct perf_event_attr up to PERF_ATTR_SIZE_VER5.
+type perfEventAttr struct {
+	Type             uint32
+	Size             uint32
+	Config           uint64
+	SamplePeriod     uint64
+	SampleType       uint64
+	ReadFormat       uint64
+	Bits             uint64
+	WakeupEvents     uint32
+	BpType           uint32
+	Config1          uint64
+	Config2          uint64
+	BranchSampleType uint64
+	SampleRegsUser   uint64
+	SampleStackUser  uint32
+	ClockID          int32
+	SampleRegsIntr   uint64
+	AuxWatermark     uint32
+	SampleMaxStack   uint16
+	_                uint16
+}
+
+const (
+	perfTypeRaw = 4
+
+	perfSampleIP   = 1 << 0
+	perfSampleTID  = 1 << 1
+	perfSampleAddr = 1 << 3
+
+	perfBitInherit       = 1 << 1
+	perfBitExcludeKernel = 1 << 5
+	perfBitExcludeHV     = 1 << 6
+	perfBitPreciseIP2    = 2 << 15 // Request zero skid, needed for exact data addresses
+
+	perfFlagFDCloexec = 1 << 3
+	perfRecordSample  = 9
+
+	// Offsets of data_head and data_tail in struct perf_event_mmap_page.
+	dataHeadOffset = 1024
+	dataTailOffset = 1032
+
+	ringPages = 64 // Data pages in the sample ring buffer; must be a power of two
+)
+
+// record runs bin with args and samples the raw event config every period occurrences
+// in it and all its threads.
+func record(bin string, args []string, config, period uint64) ([]sample, error) {
+	// The child stops at its first instruction under ptrace, which gives us the moment
+	// to attach the events before it starts any threads. Ptrace requests must come from
+	// the thread that started the child.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Ptrace: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	pid := cmd.Process.Pid
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &ws, 0, nil); err != nil || !ws.Stopped() {
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("waiting for benchmark to start: %v (%v)", err, ws)
+	}
+
+	rings, err := openRings(pid, config, period)
+	defer func() {
+		for _, r := range rings {
+			r.close()
+		}
+	}()
+	if err == nil {
+		err = syscall.PtraceDetach(pid)
+		if err != nil {
+			err = fmt.Errorf("resuming benchmark: %v", err)
+		}
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	var samples []sample
+	for {
+		select {
+		case err := <-done:
+			for _, r := range rings {
+				samples = r.drain(samples)
+			}
+			return samples, err
+		case <-time.After(10 * time.Millisecond):
+			for _, r := range rings {
+				samples = r.drain(samples)
+			}
+		}
+	}
+}
+
+// openRings opens the sampling event for pid and the threads it starts on every online
+// CPU, and maps the sample ring of each. The kernel can't map an inherited event that
+// follows a task across CPUs, so, like perf record, this opens one per CPU. It returns
+// the rings opened so far along with any error, for the caller to close.
+func openRings(pid int, config, period uint64) ([]*ring, error) {
+	cpus, err := onlineCPUs()
+	if err != nil {
+		return nil, fmt.Errorf("listing CPUs: %v", err)
+	}
+	attr := perfEventAttr{
+		Type:         perfTypeRaw,
+		Config:       config,
+		SamplePeriod: period,
+		SampleType:   perfSampleIP | perfSampleTID | perfSampleAddr,
+		Bits:         perfBitInherit | perfBitExcludeKernel | perfBitExcludeHV | perfBitPreciseIP2,
+	}
+	attr.Size = uint32(unsafe.Sizeof(attr))
+	pageSize := os.Getpagesize()
+
+	var rings []*ring
+	for _, cpu := range cpus {
+		fd, _, errno := syscall.Syscall6(syscall.SYS_PERF_EVENT_OPEN,
+			uintptr(unsafe.Pointer(&attr)), uintptr(pid), uintptr(cpu), ^uintptr(0), perfFlagFDCloexec, 0)
+		if errno != 0 {
+			return rings, fmt.Errorf("perf_event_open on CPU %d: %v (check -event and perf_event_paranoid)", cpu, errno)
+		}
+		mem, err := syscall.Mmap(int(fd), 0, (1+ringPages)*pageSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+		if err != nil {
+			syscall.Close(int(fd))
+			return rings, fmt.Errorf("mapping sample buffer of CPU %d: %v", cpu, err)
+		}
+		rings = append(rings, &ring{fd: int(fd), mem: mem, meta: mem[:pageSize], data: mem[pageSize:]})
+	}
+	return rings, nil
+}
+
+// onlineCPUs returns the IDs of the online CPUs, from a list such as "0-3,8-11".
+func onlineCPUs() ([]int, error) {
+	b, err := os.ReadFile("/sys/devices/system/cpu/online")
+	if err != nil {
+		return nil, err
+	}
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(string(b)), ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil {
+				return nil, err
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// ring reads records from the perf sample ring buffer of one CPU.
+type ring struct {
+	fd   int
+	mem  []byte // The whole mapping
+	meta []byte // Control page
+	data []byte // Power-of-two sized record area
+}
+
+func (r *ring) close() {
+	syscall.Munmap(r.mem)
+	syscall.Close(r.fd)
+}
+
+func (r *ring) head() uint64 {
+	return atomic.LoadUint64((*uint64)(unsafe.Pointer(&r.meta[dataHeadOffset])))
+}
+
+func (r *ring) setTail(t uint64) {
+	atomic.StoreUint64((*uint64)(unsafe.Pointer(&r.meta[dataTailOffset])), t)
+}
+
+func (r *ring) tail() uint64 {
+	return atomic.LoadUint64((*uint64)(unsafe.Pointer(&r.meta[dataTailOffset])))
+}
+
+// read copies n bytes starting at position pos, unwrapping the ring.
+func (r *ring) read(pos uint64, n int) []byte {
+	b := make([]byte, n)
+	size := uint64(len(r.data))
+	for i := range b {
+		b[i] = r.data[(pos+uint64(i))%size]
+	}
+	return b
+}
+
+// drain appends every complete sample in the buffer to samples and frees the space.
+func (r *ring) drain(samples []sample) []sample {
+	head, tail := r.head(), r.tail()
+	for tail < head {
+		hdr := r.read(tail, 8)
+		typ := binary.LittleEndian.Uint32(hdr)
+		size := binary.LittleEndian.Uint16(hdr[6:])
+		if typ == perfRecordSample && size >= 8+24 {
+			body := r.read(tail+8, 24) // ip, pid/tid, addr
+			samples = append(samples, sample{
+				ip:   binary.LittleEndian.Uint64(body),
+				addr: binary.LittleEndian.Uint64(body[16:]),
+			})
+		}
+		tail += uint64(size)
+	}
+	r.setTail(tail)
+	return samples
+}
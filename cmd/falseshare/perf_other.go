@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+func record(bin string, args []string, config, period uint64) ([]sample, error) {
+	return nil, errors.New("sampling requires Linux perf events")
+}
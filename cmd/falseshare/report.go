@@ -0,0 +1,171 @@
+package main
+
+import (
+	"debug/elf"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// modulePrefix identifies functions belonging to this module's locks.
+const modulePrefix = "github.com/ahrav/go-locks/"
+
+// sample is one HITM load: the instruction that performed it and the address it read.
+type sample struct {
+	ip, addr uint64
+}
+
+// lineReport aggregates the samples that fell on one cache line.
+type lineReport struct {
+	addr    uint64         // Address of the start of the line
+	samples int            // Samples on the line
+	words   map[uint64]int // Samples per 8-byte word offset within the line
+	funcs   map[string]int // Samples per accessing function
+}
+
+// falselyShared reports whether the line's hits land on more than one word, meaning
+// cores fight over the line for the sake of different variables.
+func (l *lineReport) falselyShared() bool { return len(l.words) > 1 }
+
+// analyze groups samples by cache line, busiest first.
+func analyze(samples []sample, lineSize uint64, symbolize func(ip uint64) string) []*lineReport {
+	lines := make(map[uint64]*lineReport)
+	for _, s := range samples {
+		if s.addr == 0 {
+			continue // The processor couldn't attribute the load to an address
+		}
+		base := s.addr &^ (lineSize - 1)
+		l := lines[base]
+		if l == nil {
+			l = &lineReport{addr: base, words: make(map[uint64]int), funcs: make(map[string]int)}
+			lines[base] = l
+		}
+		l.samples++
+		l.words[(s.addr-base)&^7]++
+		l.funcs[symbolize(s.ip)]++
+	}
+
+	out := make([]*lineReport, 0, len(lines))
+	for _, l := range lines {
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].samples != out[j].samples {
+			return out[i].samples > out[j].samples
+		}
+		return out[i].addr < out[j].addr
+	})
+	return out
+}
+
+// lockType extracts the package-qualified receiver type from a method symbol of this
+// module, such as "alock.ArrayLock" from "github.com/ahrav/go-locks/alock.(*ArrayLock).Lock".
+func lockType(fn string) (string, bool) {
+	name, ok := strings.CutPrefix(fn, modulePrefix)
+	if !ok {
+		return "", false
+	}
+	pkg, rest, ok := strings.Cut(name, ".")
+	if !ok {
+		return "", false
+	}
+	recv, method, ok := strings.Cut(rest, ".")
+	if !ok || isClosure(method) {
+		return "", false // A plain function, or a closure inside one, has no receiver
+	}
+	recv = strings.TrimSuffix(strings.TrimPrefix(recv, "(*"), ")")
+	if i := strings.IndexByte(recv, '['); i >= 0 {
+		recv = recv[:i]
+	}
+	return pkg + "." + recv, true
+}
+
+// isClosure reports whether name is a compiler-generated closure name such as "func1".
+func isClosure(name string) bool {
+	rest, ok := strings.CutPrefix(name, "func")
+	return ok && rest != "" && rest[0] >= '0' && rest[0] <= '9'
+}
+
+// writeReport prints the top lines, marking those with signs of false sharing.
+func writeReport(w io.Writer, lines []*lineReport, total, top int) {
+	fmt.Fprintf(w, "%d HITM samples on %d cache lines\n", total, len(lines))
+	for _, l := range lines[:min(top, len(lines))] {
+		verdict := "true sharing"
+		if l.falselyShared() {
+			verdict = "FALSE SHARING"
+		}
+		fmt.Fprintf(w, "\nline %#x: %d samples on %d words, %s\n", l.addr, l.samples, len(l.words), verdict)
+
+		offsets := make([]uint64, 0, len(l.words))
+		for off := range l.words {
+			offsets = append(offsets, off)
+		}
+		sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+		for _, off := range offsets {
+			fmt.Fprintf(w, "  +%-3d %d\n", off, l.words[off])
+		}
+
+		types := make(map[string]bool)
+		for _, fn := range sortedKeys(l.funcs) {
+			fmt.Fprintf(w, "  %6d  %s\n", l.funcs[fn], fn)
+			if t, ok := lockType(fn); ok {
+				types[t] = true
+			}
+		}
+		if len(types) > 0 {
+			names := make([]string, 0, len(types))
+			for t := range types {
+				names = append(names, t)
+			}
+			sort.Strings(names)
+			fmt.Fprintf(w, "  lock types: %s\n", strings.Join(names, ", "))
+		}
+	}
+}
+
+// sortedKeys returns the keys of m by descending count.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// symbols maps instruction addresses to function names.
+type symbols []elf.Symbol
+
+func loadSymbols(bin string) (symbols, error) {
+	f, err := elf.Open(bin)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	all, err := f.Symbols()
+	if err != nil {
+		return nil, err
+	}
+	var funcs symbols
+	for _, s := range all {
+		if elf.ST_TYPE(s.Info) == elf.STT_FUNC && s.Size > 0 {
+			funcs = append(funcs, s)
+		}
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Value < funcs[j].Value })
+	return funcs, nil
+}
+
+func (s symbols) lookup(ip uint64) string {
+	i := sort.Search(len(s), func(i int) bool { return s[i].Value > ip }) - 1
+	if i >= 0 && ip < s[i].Value+s[i].Size {
+		return s[i].Name
+	}
+	return fmt.Sprintf("%#x", ip)
+}
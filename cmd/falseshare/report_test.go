@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"debug/elf"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeGroupsByLine(t *testing.T) {
+	syms := symbols{
+		{Name: modulePrefix + "alock.(*ArrayLock).Lock", Value: 0x1000, Size: 0x100},
+		{Name: modulePrefix + "alock.(*ArrayLock).Unlock", Value: 0x1100, Size: 0x100},
+	}
+	samples := []sample{
+		{ip: 0x1010, addr: 0x2000}, // Two words of one line: false sharing
+		{ip: 0x1110, addr: 0x2004},
+		{ip: 0x1010, addr: 0x2008},
+		{ip: 0x1010, addr: 0x3000}, // One word of another line: true sharing
+		{ip: 0x1010, addr: 0x3000},
+		{ip: 0x1010, addr: 0}, // Unattributed
+	}
+
+	lines := analyze(samples, 64, syms.lookup)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, uint64(0x2000), lines[0].addr)
+	assert.Equal(t, 3, lines[0].samples)
+	assert.True(t, lines[0].falselyShared())
+	assert.Equal(t, 2, lines[0].funcs[modulePrefix+"alock.(*ArrayLock).Lock"])
+	assert.False(t, lines[1].falselyShared())
+
+	var buf bytes.Buffer
+	writeReport(&buf, lines, len(samples), 1)
+	assert.Contains(t, buf.String(), "line 0x2000: 3 samples on 2 words, FALSE SHARING")
+	assert.Contains(t, buf.String(), "lock types: alock.ArrayLock")
+	assert.NotContains(t, buf.String(), "line 0x3000", "Only the top line should be reported")
+}
+
+func TestLockType(t *testing.T) {
+	for fn, want := range map[string]string{
+		modulePrefix + "mcs.(*Lock).Lock":                    "mcs.Lock",
+		modulePrefix + "lockfree/mpmc.(*Queue[...]).TryPush": "lockfree/mpmc.Queue",
+		modulePrefix + "shardedmap.(*shard[...]).acquire":    "shardedmap.shard",
+		modulePrefix + "ticket.Handle.Release":               "ticket.Handle",
+	} {
+		got, ok := lockType(fn)
+		assert.True(t, ok, fn)
+		assert.Equal(t, want, got, fn)
+	}
+	for _, fn := range []string{"runtime.mallocgc", modulePrefix + "spin.CanSpin", modulePrefix + "gls.Go.func1"} {
+		_, ok := lockType(fn)
+		assert.False(t, ok, fn)
+	}
+}
+
+func TestSymbolLookup(t *testing.T) {
+	syms := symbols{{Name: "f", Value: 0x100, Size: 0x10, Info: byte(elf.STT_FUNC)}}
+	assert.Equal(t, "f", syms.lookup(0x108))
+	assert.Equal(t, "0x110", syms.lookup(0x110))
+	assert.Equal(t, "0x80", syms.lookup(0x80))
+}
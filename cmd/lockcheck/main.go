@@ -0,0 +1,15 @@
+// Command lockcheck reports misuse of the locks in github.com/ahrav/go-locks.
+//
+// It can be run standalone or as a go vet tool:
+//
+//	lockcheck ./...
+//	go vet -vettool=$(which lockcheck) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/ahrav/go-locks/lockcheck"
+)
+
+func main() { singlechecker.Main(lockcheck.Analyzer) }
@@ -0,0 +1,104 @@
+// Command lockdoctor samples the lock statistics of a running process and prints the
+// most contended locks, with what to do about each.
+//
+// The process must serve its registry, either by importing debuglocks, which answers
+// at /debug/locks, or through expvar at /debug/vars, where debuglocks publishes the
+// same data. lockdoctor takes two samples an interval apart and ranks the locks by the
+// time goroutines spent waiting for them in between, so that the report is about what
+// is happening now rather than since the process started.
+//
+// Usage:
+//
+//	lockdoctor [flags] url
+//
+// For example:
+//
+//	lockdoctor -interval 30s http://localhost:6060/debug/locks
+//
+// Each lock is diagnosed from its acquisition rate, the share of acquisitions that
+// waited, its average hold time, its queue depth and its recent wait percentiles. The
+// suggestions are rules of thumb: striping a lock that is held briefly but very often,
+// shrinking a critical section that is held long, switching to a FIFO or queue lock
+// when waits have a long tail or queues run deep.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ahrav/go-locks/debuglocks"
+)
+
+func main() {
+	var (
+		interval = flag.Duration("interval", 10*time.Second, "sample over `duration`")
+		top      = flag.Int("top", 10, "report at most `n` locks")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: lockdoctor [flags] url\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	url := flag.Arg(0)
+
+	before, err := fetch(url)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	time.Sleep(*interval)
+	after, err := fetch(url)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	writeReport(os.Stdout, diagnose(before, after), after.Time.Sub(before.Time), *top)
+}
+
+// fetch reads a report from a debuglocks handler or an expvar page.
+func fetch(url string) (debuglocks.Report, error) {
+	var rep debuglocks.Report
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return rep, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return rep, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return rep, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	var page map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return rep, fmt.Errorf("%s: %v", url, err)
+	}
+	raw, ok := page["debuglocks"] // An expvar page
+	if !ok {
+		raw, err = json.Marshal(page)
+		if err != nil {
+			return rep, err
+		}
+	}
+	if err := json.Unmarshal(raw, &rep); err != nil {
+		return rep, fmt.Errorf("%s: %v", url, err)
+	}
+	if rep.Locks == nil {
+		return rep, fmt.Errorf("%s: no lock report; does the process import debuglocks?", url)
+	}
+	return rep, nil
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "lockdoctor: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ahrav/go-locks/debuglocks"
+)
+
+// Thresholds of the rules of thumb in diagnose.
+const (
+	contendedShare = 0.1                    // Share of waiting acquisitions worth acting on
+	shortHold      = 10 * time.Microsecond  // Below this, contention comes from frequency
+	longHold       = 100 * time.Microsecond // Above this, from the critical section
+	tailRatio      = 10                     // p99 wait over p50 that marks a long tail
+	deepQueue      = 8                      // Queue depth where queue locks pay off
+)
+
+// finding is the diagnosis of one lock over the sampled interval.
+type finding struct {
+	lock      debuglocks.Lock // As of the second sample
+	acquired  uint64          // Acquisitions in the interval
+	contended uint64          // Of which waited
+	wait      time.Duration   // Time spent waiting in the interval
+	hold      time.Duration   // Average hold time in the interval
+	advice    []string
+}
+
+// diagnose compares two reports and returns a finding for every lock acquired in
+// between, the most waited for first.
+func diagnose(before, after debuglocks.Report) []finding {
+	prev := make(map[string]debuglocks.Lock, len(before.Locks))
+	for _, l := range before.Locks {
+		prev[l.Name] = l
+	}
+
+	var out []finding
+	for _, l := range after.Locks {
+		p := prev[l.Name] // Zero for a lock registered in between
+		if l.Acquired < p.Acquired {
+			p = debuglocks.Lock{} // Re-registered; count from zero
+		}
+		f := finding{
+			lock:      l,
+			acquired:  l.Acquired - p.Acquired,
+			contended: l.Contended - p.Contended,
+			wait:      l.TotalWait - p.TotalWait,
+		}
+		if f.acquired == 0 {
+			continue
+		}
+		f.hold = (l.TotalHold - p.TotalHold) / time.Duration(f.acquired)
+		f.advice = advise(f)
+		out = append(out, f)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].wait > out[j].wait })
+	return out
+}
+
+// advise applies the rules of thumb to f.
+func advise(f finding) []string {
+	if float64(f.contended) < contendedShare*float64(f.acquired) {
+		return nil
+	}
+	var advice []string
+	switch {
+	case f.hold < shortHold:
+		advice = append(advice, "held briefly but acquired too often: stripe it (see shardedmap) or batch the work done under it")
+	case f.hold > longHold:
+		advice = append(advice, "held long: shrink the critical section, moving I/O and allocation out of it and deferring frees (see reclaim.Locker)")
+	}
+	if p50 := f.lock.WaitP50; f.lock.WaitP99 > tailRatio*max(p50, time.Microsecond) {
+		advice = append(advice, "waits have a long tail: switch to a FIFO lock such as ticket.Lock, or rtlock.Lock to bound it")
+	}
+	if f.lock.QueueDepth >= deepQueue {
+		advice = append(advice, fmt.Sprintf("%d goroutines queued: switch to a queue lock such as mcs.Lock, whose waiters spin on lines of their own", f.lock.QueueDepth))
+	}
+	return advice
+}
+
+// writeReport prints the top findings.
+func writeReport(w io.Writer, findings []finding, interval time.Duration, top int) {
+	fmt.Fprintf(w, "%d locks acquired over %v\n", len(findings), interval.Round(time.Millisecond))
+	for i, f := range findings[:min(top, len(findings))] {
+		fmt.Fprintf(w, "\n%d. %s: waited %v in total, %.0f%% of %d acquisitions contended\n",
+			i+1, f.lock.Name, f.wait, 100*float64(f.contended)/float64(f.acquired), f.acquired)
+		fmt.Fprintf(w, "   hold avg %v, wait p50 %v p90 %v p99 %v, queue depth %d\n",
+			f.hold, f.lock.WaitP50, f.lock.WaitP90, f.lock.WaitP99, f.lock.QueueDepth)
+		if len(f.advice) == 0 {
+			fmt.Fprintf(w, "   no action needed\n")
+		}
+		for _, a := range f.advice {
+			fmt.Fprintf(w, "   - %s\n", a)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/debuglocks"
+	"github.com/ahrav/go-locks/registry"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestDiagnoseRanksAndAdvises(t *testing.T) {
+	before := debuglocks.Report{Locks: []debuglocks.Lock{
+		{Name: "config", Acquired: 100, TotalWait: time.Second},
+		{Name: "counters", Acquired: 1000, Contended: 100},
+	}}
+	after := debuglocks.Report{Locks: []debuglocks.Lock{
+		{Name: "config", Acquired: 200, TotalWait: time.Second}, // Uncontended
+		{ // Short holds, many acquisitions
+			Name: "counters", Acquired: 101000, Contended: 50100,
+			TotalWait: 2 * time.Second, TotalHold: 100 * time.Millisecond,
+			WaitP50: time.Microsecond, WaitP99: time.Millisecond, QueueDepth: 12,
+		},
+		{ // Long holds, registered in between
+			Name: "journal", Acquired: 10, Contended: 5,
+			TotalWait: 3 * time.Second, TotalHold: 10 * time.Millisecond,
+			WaitP50: 100 * time.Millisecond, WaitP99: 200 * time.Millisecond,
+		},
+		{Name: "idle"},
+	}}
+
+	findings := diagnose(before, after)
+	if !assert.Len(t, findings, 3, "Locks not acquired are left out") {
+		return
+	}
+	assert.Equal(t, "journal", findings[0].lock.Name)
+	assert.Equal(t, time.Millisecond, findings[0].hold)
+	assert.Len(t, findings[0].advice, 1)
+	assert.Contains(t, findings[0].advice[0], "shrink the critical section")
+
+	assert.Equal(t, "counters", findings[1].lock.Name)
+	assert.Equal(t, uint64(100000), findings[1].acquired)
+	assert.Equal(t, time.Microsecond, findings[1].hold)
+	assert.Len(t, findings[1].advice, 3)
+	assert.Contains(t, findings[1].advice[0], "stripe it")
+	assert.Contains(t, findings[1].advice[1], "long tail")
+	assert.Contains(t, findings[1].advice[2], "12 goroutines queued")
+
+	assert.Equal(t, "config", findings[2].lock.Name)
+	assert.Empty(t, findings[2].advice)
+
+	var buf bytes.Buffer
+	writeReport(&buf, findings, 10*time.Second, 2)
+	assert.Contains(t, buf.String(), "3 locks acquired over 10s")
+	assert.Contains(t, buf.String(), "1. journal: waited 3s in total, 50% of 10 acquisitions contended")
+	assert.NotContains(t, buf.String(), "config", "Only the top 2 should be reported")
+}
+
+func TestFetch(t *testing.T) {
+	l := registry.Register("lockdoctor-test", ticket.NewLock(), nil)
+	defer registry.Unregister("lockdoctor-test")
+	l.Lock()
+	l.Unlock()
+
+	srv := httptest.NewServer(debuglocks.Handler(registry.Default))
+	defer srv.Close()
+	vars := httptest.NewServer(expvar.Handler())
+	defer vars.Close()
+
+	for _, url := range []string{srv.URL, vars.URL} {
+		rep, err := fetch(url)
+		assert.NoError(t, err, url)
+		if assert.Len(t, rep.Locks, 1, url) {
+			assert.Equal(t, uint64(1), rep.Locks[0].Acquired, url)
+		}
+	}
+
+	closed := httptest.NewServer(expvar.Handler())
+	closed.Close()
+	_, err := fetch(closed.URL)
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package cohort
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/affinity"
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestLockConcurrentAccess(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	lock := NewLock(2)
+	const numGoroutines = 8
+	const iterations = 500
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			node := g % lock.Nodes()
+			for range iterations {
+				lock.Lock(node)
+				counter++
+				lock.Unlock(node)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+	assert.False(t, lock.held(), "The global lock should be released once everyone is done")
+}
+
+func TestLockPassesWithinNode(t *testing.T) {
+	lock := NewLock(2)
+	lock.Lock(0)
+
+	acquired := make(chan struct{})
+	go func() {
+		lock.Lock(0) //lockcheck:ignore The test unlocks it
+		close(acquired)
+	}()
+	for lock.local(0).lock.QueueDepth() < 2 {
+		runtime.Gosched() // Wait for the node-mate to queue
+	}
+
+	lock.Unlock(0)
+	<-acquired
+	assert.True(t, lock.local(0).passed, "The global lock should be handed to the waiting node-mate")
+	assert.Equal(t, 1, lock.global.QueueDepth(), "The global lock should never have been released")
+	lock.Unlock(0)
+	assert.False(t, lock.held())
+}
+
+func TestRWLockConcurrentAccess(t *testing.T) {
+	rw := NewRWLock(2)
+	const numReaders = 6
+	const numWriters = 2
+	const iterations = 300
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numReaders + numWriters)
+	for g := range numWriters {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				rw.Lock(g)
+				counter++
+				rw.Unlock(g)
+			}
+		}()
+	}
+	for g := range numReaders {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				rw.RLock(g)
+				_ = counter
+				rw.RUnlock(g)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numWriters*iterations, counter)
+	assert.True(t, rw.noReaders())
+}
+
+func TestRWLockExcludesReadersFromWriter(t *testing.T) {
+	rw := NewRWLock(2)
+	rw.RLock(0)
+	rw.RLock(1)
+
+	locked := make(chan struct{})
+	go func() {
+		rw.Lock(0) //lockcheck:ignore The test unlocks it
+		close(locked)
+	}()
+
+	rw.RUnlock(0)
+	select {
+	case <-locked:
+		t.Fatal("Writer acquired the lock while a reader on another node was inside")
+	default:
+	}
+	rw.RUnlock(1)
+	<-locked
+	rw.Unlock(0)
+}
+
+func BenchmarkLockPinned(b *testing.B) {
+	lock := NewLock(affinity.NumNodes())
+	var next atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		// Spread workers over the nodes and keep each on its node's CPUs, so the node
+		// index passed to the lock is the one the worker actually runs on.
+		node := int(next.Add(1)-1) % lock.Nodes()
+		if unpin, err := affinity.PinNode(node); err == nil {
+			defer unpin()
+		}
+		for pb.Next() {
+			lock.Lock(node)
+			lock.Unlock(node)
+		}
+	})
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	lock := NewLock(2)
+	allocs.Zero(t, func() {
+		lock.Lock(1)
+		lock.Unlock(1)
+	}, "Uncontended Lock and Unlock should not allocate")
+
+	rw := NewRWLock(2)
+	allocs.Zero(t, func() {
+		rw.Lock(1)
+		rw.Unlock(1)
+		rw.RLock(0)
+		rw.RUnlock(0)
+	}, "Uncontended RWLock acquisitions should not allocate")
+}
@@ -0,0 +1,104 @@
+// Package cohort implements lock cohorting (Dice, Marathe and Shavit) and the C-RW-WP
+// reader-writer lock built on it (Calciu et al.), for machines where goroutines are
+// grouped into NUMA nodes.
+//
+// A cohort Lock pairs a global ticket lock with a local ticket lock per node. A
+// goroutine first takes its node's local lock; the global lock is then either inherited
+// from a node-mate that just released it or acquired outright. On release, the holder
+// passes the global lock to a waiting node-mate, up to a fixed number of times in a row,
+// so that ownership and the data it protects stay on one socket for a batch of critical
+// sections before moving on.
+//
+// RWLock adds a per-node reader indicator to a cohort Lock used by writers. Readers only
+// touch their own node's indicator, so the read path never bounces a shared counter
+// between sockets. Writers take preference: readers stay out while a writer holds or
+// waits for the cohort lock, and a writer waits for every node's indicator to drain.
+//
+// Example usage:
+//
+//	rw := cohort.NewRWLock(2) // 2 NUMA nodes
+//
+//	rw.RLock(node)
+//	// ... read ...
+//	rw.RUnlock(node)
+//
+//	rw.Lock(node)
+//	// ... write ...
+//	rw.Unlock(node)
+//
+// Go exposes no way to ask which NUMA node a goroutine runs on, so callers pass the node
+// index themselves, typically from how they pinned (see affinity.PinNode) or partitioned
+// their workers. Indices are taken modulo the number of nodes. The same node index must
+// be passed to the matching unlock.
+package cohort
+
+import (
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// DefaultMaxPasses bounds how many times in a row the global lock stays within one node
+// before it is released to the other nodes.
+const DefaultMaxPasses = 64
+
+type local struct {
+	lock   *ticket.Lock
+	passed bool // Global lock was inherited from a node-mate; guarded by lock
+	passes int  // Consecutive handoffs within the node; guarded by lock
+
+	_ pad.CacheLinePad
+}
+
+// Lock is a NUMA-aware cohort lock built from ticket locks.
+type Lock struct {
+	global    *ticket.Lock
+	locals    []local
+	maxPasses int
+}
+
+// NewLock creates a cohort lock for the given number of nodes (at least 1).
+func NewLock(nodes int) *Lock {
+	l := &Lock{
+		global:    ticket.NewLock(),
+		locals:    make([]local, max(nodes, 1)),
+		maxPasses: DefaultMaxPasses,
+	}
+	for i := range l.locals {
+		l.locals[i].lock = ticket.NewLock()
+	}
+	return l
+}
+
+// Nodes returns the number of nodes the lock was created for.
+func (l *Lock) Nodes() int { return len(l.locals) }
+
+func (l *Lock) local(node int) *local { return &l.locals[uint(node)%uint(len(l.locals))] }
+
+// Lock acquires the lock on behalf of a goroutine running on the given node.
+func (l *Lock) Lock(node int) {
+	loc := l.local(node)
+	loc.lock.Lock()
+	if loc.passed {
+		return // A node-mate handed us the global lock
+	}
+	l.global.Lock()
+}
+
+// Unlock releases the lock acquired with the same node.
+func (l *Lock) Unlock(node int) {
+	loc := l.local(node)
+	if loc.lock.QueueDepth() > 1 && loc.passes < l.maxPasses {
+		// A node-mate is waiting: keep the global lock within the node.
+		loc.passes++
+		loc.passed = true
+		loc.lock.Unlock()
+		return
+	}
+	loc.passes = 0
+	loc.passed = false
+	l.global.Unlock()
+	loc.lock.Unlock()
+}
+
+// held reports whether any node holds, or is about to hold, the global lock.
+func (l *Lock) held() bool { return l.global.QueueDepth() > 0 }
@@ -0,0 +1,108 @@
+package cohort
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/spin"
+)
+
+type indicator struct {
+	readers atomic.Int64
+
+	_ pad.CacheLinePad
+}
+
+// RWLock is the C-RW-WP reader-writer lock: per-node reader indicators combined with a
+// cohort lock for writers, giving preference to writers.
+type RWLock struct {
+	writers    *Lock
+	indicators []indicator
+}
+
+// NewRWLock creates a C-RW-WP lock for the given number of nodes (at least 1).
+func NewRWLock(nodes int) *RWLock {
+	w := NewLock(nodes)
+	return &RWLock{writers: w, indicators: make([]indicator, w.Nodes())}
+}
+
+func (rw *RWLock) indicator(node int) *indicator {
+	return &rw.indicators[uint(node)%uint(len(rw.indicators))]
+}
+
+// RLock acquires the lock for reading on behalf of a goroutine on the given node.
+func (rw *RWLock) RLock(node int) {
+	ind := rw.indicator(node)
+	for {
+		waitUntil(func() bool { return !rw.writers.held() })
+		ind.readers.Add(1)
+		chaos.Point()
+		if !rw.writers.held() {
+			return
+		}
+		// A writer arrived between the check and our arrival; let it go first.
+		ind.readers.Add(-1)
+	}
+}
+
+// RUnlock releases a read lock acquired with the same node.
+func (rw *RWLock) RUnlock(node int) {
+	n := rw.indicator(node).readers.Add(-1)
+	if invariant.Enabled {
+		invariant.Check(n >= 0, "cohort: RUnlock of node %d without a reader", node)
+	}
+}
+
+// Lock acquires the lock for writing on behalf of a goroutine on the given node.
+func (rw *RWLock) Lock(node int) {
+	rw.writers.Lock(node)
+	chaos.Point()
+	waitUntil(rw.noReaders)
+}
+
+// Unlock releases a write lock acquired with the same node.
+func (rw *RWLock) Unlock(node int) {
+	rw.writers.Unlock(node) // Arriving readers may still be counted, on their way back out
+}
+
+func (rw *RWLock) noReaders() bool {
+	for i := range rw.indicators {
+		if rw.indicators[i].readers.Load() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// waitUntil spins, then yields, until cond reports true.
+func waitUntil(cond func() bool) {
+	if cond() {
+		return
+	}
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	for i := 0; !cond(); i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+	lockprof.Record(start, 1)
+}
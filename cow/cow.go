@@ -0,0 +1,126 @@
+// Package cow provides copy-on-write containers for read-mostly data.
+//
+// Readers take no lock at all: Load returns the current snapshot with a single atomic
+// load, and a snapshot never changes once published, so it can be used for as long as
+// the reader likes. Writers serialize on a ticket.Lock, copy the current snapshot, change
+// the copy and publish it, so every write costs a full copy, and writers are admitted in
+// arrival order however many of them contend.
+//
+// This sits between a mutex, which makes readers wait for writers, and RCU, which avoids
+// the copies but needs readers to announce themselves: old snapshots are simply left to
+// the garbage collector once no reader holds them.
+//
+// Example usage:
+//
+//	routes := cow.NewMap[string, http.Handler]()
+//	routes.Store("/health", healthHandler)
+//
+//	if h, ok := routes.Get(r.URL.Path); ok { // No locking
+//	    h.ServeHTTP(w, r)
+//	}
+//
+//	for path := range routes.Load() { // A consistent snapshot
+//	    fmt.Println(path)
+//	}
+//
+// Snapshots returned by Load are shared by every reader and must not be modified.
+package cow
+
+import (
+	"maps"
+	"slices"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Slice is a copy-on-write slice.
+type Slice[T any] struct {
+	mu *ticket.Lock
+	p  atomic.Pointer[[]T]
+}
+
+// NewSlice creates a Slice holding a copy of items.
+func NewSlice[T any](items ...T) *Slice[T] {
+	s := &Slice[T]{mu: ticket.NewLock()}
+	c := slices.Clone(items)
+	s.p.Store(&c)
+	return s
+}
+
+// Load returns the current snapshot. It must not be modified.
+func (s *Slice[T]) Load() []T { return *s.p.Load() }
+
+// Len returns the length of the current snapshot.
+func (s *Slice[T]) Len() int { return len(*s.p.Load()) }
+
+// Update replaces the slice with the result of fn, which is given a private copy of the
+// current snapshot that it may modify and return. fn runs under the writer lock, so it
+// must not call Update or Append on s.
+func (s *Slice[T]) Update(fn func(items []T) []T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := fn(slices.Clone(*s.p.Load()))
+	s.p.Store(&next)
+}
+
+// Append appends items to the slice.
+func (s *Slice[T]) Append(items ...T) {
+	s.Update(func(cur []T) []T { return append(cur, items...) })
+}
+
+// Map is a copy-on-write map.
+type Map[K comparable, V any] struct {
+	mu *ticket.Lock
+	p  atomic.Pointer[map[K]V]
+}
+
+// NewMap creates an empty Map.
+func NewMap[K comparable, V any]() *Map[K, V] {
+	m := &Map[K, V]{mu: ticket.NewLock()}
+	empty := map[K]V{}
+	m.p.Store(&empty)
+	return m
+}
+
+// Load returns the current snapshot. It must not be modified.
+func (m *Map[K, V]) Load() map[K]V { return *m.p.Load() }
+
+// Get returns the value stored under k in the current snapshot.
+func (m *Map[K, V]) Get(k K) (V, bool) {
+	v, ok := (*m.p.Load())[k]
+	return v, ok
+}
+
+// Len returns the number of entries in the current snapshot.
+func (m *Map[K, V]) Len() int { return len(*m.p.Load()) }
+
+// Update changes the map with fn, which is given a private copy of the current snapshot
+// to modify in place. fn runs under the writer lock, so it must not call Update, Store
+// or Delete on m.
+func (m *Map[K, V]) Update(fn func(entries map[K]V)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	next := maps.Clone(*m.p.Load())
+	fn(next)
+	m.p.Store(&next)
+}
+
+// Store sets the value for k.
+func (m *Map[K, V]) Store(k K, v V) {
+	m.Update(func(entries map[K]V) { entries[k] = v })
+}
+
+// Delete removes the entry for k, if there is one. Deleting an absent key publishes
+// nothing.
+func (m *Map[K, V]) Delete(k K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur := *m.p.Load()
+	if _, ok := cur[k]; !ok {
+		return
+	}
+	next := maps.Clone(cur)
+	delete(next, k)
+	m.p.Store(&next)
+}
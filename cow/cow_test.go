@@ -0,0 +1,74 @@
+package cow
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSliceSnapshotsAreStable(t *testing.T) {
+	s := NewSlice(1, 2)
+	before := s.Load()
+
+	s.Append(3)
+	s.Update(func(items []int) []int {
+		items[0] = 10 // A private copy
+		return items
+	})
+
+	assert.Equal(t, []int{1, 2}, before, "An old snapshot is never changed")
+	assert.Equal(t, []int{10, 2, 3}, s.Load())
+	assert.Equal(t, 3, s.Len())
+}
+
+func TestMapSnapshotsAreStable(t *testing.T) {
+	m := NewMap[string, int]()
+	m.Store("a", 1)
+	before := m.Load()
+
+	m.Store("b", 2)
+	m.Delete("a")
+	m.Delete("missing")
+
+	assert.Equal(t, map[string]int{"a": 1}, before)
+	_, ok := m.Get("a")
+	assert.False(t, ok)
+	v, ok := m.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, 1, m.Len())
+}
+
+func TestConcurrentWritersAndReaders(t *testing.T) {
+	m := NewMap[int, int]()
+	s := NewSlice[int]()
+	const numGoroutines = 4
+	const iterations = 100
+	var wg sync.WaitGroup
+
+	wg.Add(2 * numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for i := range iterations {
+				m.Store(g*iterations+i, i)
+				s.Append(i)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				snap := s.Load()
+				assert.LessOrEqual(t, len(snap), numGoroutines*iterations)
+				for k, v := range m.Load() { // Reading alongside writers is safe
+					assert.Equal(t, k%iterations, v)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, m.Len())
+	assert.Equal(t, numGoroutines*iterations, s.Len())
+}
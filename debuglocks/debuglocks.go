@@ -0,0 +1,148 @@
+// Package debuglocks serves the state of the locks in a registry over HTTP, as
+// net/http/pprof does for profiles. Importing it registers a handler for /debug/locks
+// on http.DefaultServeMux that renders registry.Default:
+//
+//	import _ "github.com/ahrav/go-locks/debuglocks"
+//
+// The page lists every registered lock with whether it is held, by which goroutine when
+// holders are tracked (see registry.SetTrackHolders), its queue depth, its counters, and
+// percentiles of its recent waits. Locks are ranked by total wait, the most contended
+// first, and followed by the locks registry.Advise suggests splitting or coarsening.
+// Requesting /debug/locks?format=json, or sending Accept: application/json, returns the
+// same data as JSON, for tools such as cmd/lockdoctor. The JSON is also published as
+// the expvar "debuglocks", for processes that already serve /debug/vars.
+//
+// To serve a registry of your own, or on another mux, use Handler:
+//
+//	mux.Handle("/debug/locks", debuglocks.Handler(reg))
+package debuglocks
+
+import (
+	"cmp"
+	"encoding/json"
+	"expvar"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ahrav/go-locks/registry"
+)
+
+func init() {
+	http.Handle("/debug/locks", Handler(registry.Default))
+	expvar.Publish("debuglocks", expvar.Func(func() any { return report(registry.Default) }))
+}
+
+// Report is the JSON form of a page.
+type Report struct {
+	Time   time.Time `json:"time"`
+	Locks  []Lock    `json:"locks"`
+	Advice []Advice  `json:"advice,omitempty"`
+}
+
+// Lock is the JSON form of one lock's registry.Stats. Durations are in nanoseconds.
+type Lock struct {
+	Name       string            `json:"name"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Held       bool              `json:"held"`
+	Holder     int64             `json:"holder,omitempty"`
+	Waiters    int               `json:"waiters"`
+	QueueDepth int               `json:"queue_depth"`
+	Acquired   uint64            `json:"acquired"`
+	Contended  uint64            `json:"contended"`
+	TotalWait  time.Duration     `json:"total_wait_ns"`
+	TotalHold  time.Duration     `json:"total_hold_ns"`
+	MaxWait    time.Duration     `json:"max_wait_ns"`
+	WaitP50    time.Duration     `json:"wait_p50_ns"`
+	WaitP90    time.Duration     `json:"wait_p90_ns"`
+	WaitP99    time.Duration     `json:"wait_p99_ns"`
+	HeldWith   map[string]uint64 `json:"held_with,omitempty"`
+}
+
+// Advice is the JSON form of a registry.Advice.
+type Advice struct {
+	Action string   `json:"action"` // "split" or "coarsen"
+	Locks  []string `json:"locks"`
+	Reason string   `json:"reason"`
+}
+
+// Handler returns a handler rendering r's locks as HTML, or as a JSON Report.
+func Handler(r *registry.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rep := report(r)
+		if req.URL.Query().Get("format") == "json" || strings.Contains(req.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(rep)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = page.Execute(w, rep)
+	})
+}
+
+// report snapshots r, most contended lock first.
+func report(r *registry.Registry) Report {
+	snap := r.Snapshot()
+	rep := Report{Time: time.Now(), Locks: make([]Lock, len(snap))}
+	for i, s := range snap {
+		rep.Locks[i] = Lock{
+			Name:       s.Name,
+			Labels:     s.Labels,
+			Held:       s.Held,
+			Holder:     s.Holder,
+			Waiters:    s.Waiters,
+			QueueDepth: s.QueueDepth,
+			Acquired:   s.Acquired,
+			Contended:  s.Contended,
+			TotalWait:  s.TotalWait,
+			TotalHold:  s.TotalHold,
+			MaxWait:    s.MaxWait,
+			WaitP50:    s.WaitP50,
+			WaitP90:    s.WaitP90,
+			WaitP99:    s.WaitP99,
+			HeldWith:   s.HeldWith,
+		}
+	}
+	for _, a := range registry.Advise(snap) {
+		rep.Advice = append(rep.Advice, Advice{Action: a.Action.String(), Locks: a.Locks, Reason: a.Reason})
+	}
+	slices.SortStableFunc(rep.Locks, func(a, b Lock) int { return cmp.Compare(b.TotalWait, a.TotalWait) })
+	return rep
+}
+
+var page = template.Must(template.New("locks").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>/debug/locks</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { padding: 2px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+tr:nth-child(even) { background: #f0f0f0; }
+.held { font-weight: bold; }
+</style>
+</head>
+<body>
+<p>/debug/locks at {{.Time.Format "2006-01-02 15:04:05.000 MST"}}: {{len .Locks}} locks, most contended first. <a href="?format=json">JSON</a></p>
+<table>
+<tr><th>Lock</th><th>Held</th><th>Holder</th><th>Queue</th><th>Waiters</th><th>Acquired</th><th>Contended</th><th>Total wait</th><th>Total hold</th><th>p50 wait</th><th>p90 wait</th><th>p99 wait</th><th>Max wait</th></tr>
+{{range .Locks}}<tr{{if .Held}} class="held"{{end}}>
+<td>{{.Name}}{{range $k, $v := .Labels}} <small>{{$k}}={{$v}}</small>{{end}}</td>
+<td>{{if .Held}}yes{{else}}no{{end}}</td>
+<td>{{if .Holder}}goroutine {{.Holder}}{{end}}</td>
+<td>{{.QueueDepth}}</td><td>{{.Waiters}}</td><td>{{.Acquired}}</td><td>{{.Contended}}</td>
+<td>{{.TotalWait}}</td><td>{{.TotalHold}}</td><td>{{.WaitP50}}</td><td>{{.WaitP90}}</td><td>{{.WaitP99}}</td><td>{{.MaxWait}}</td>
+</tr>
+{{end}}</table>
+{{if .Advice}}<p>Advice:</p>
+<ul>
+{{range .Advice}}<li>{{.Action}} {{range $i, $l := .Locks}}{{if $i}} and {{end}}{{$l}}{{end}}: {{.Reason}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,89 @@
+package debuglocks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/registry"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestJSONRanksByTotalWait(t *testing.T) {
+	r := registry.New()
+	r.Register("idle", ticket.NewLock(), nil)
+	hot := r.Register("hot", ticket.NewLock(), map[string]string{"tier": "db"})
+	hot.Lock()
+	done := make(chan struct{})
+	go func() {
+		hot.Lock()
+		hot.Unlock()
+		close(done)
+	}()
+	for r.Snapshot()[0].Waiters == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	hot.Unlock()
+	<-done
+
+	rec := httptest.NewRecorder()
+	Handler(r).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/locks?format=json", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var rep Report
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rep))
+	if assert.Len(t, rep.Locks, 2) {
+		assert.Equal(t, "hot", rep.Locks[0].Name)
+		assert.Equal(t, "db", rep.Locks[0].Labels["tier"])
+		assert.Equal(t, uint64(2), rep.Locks[0].Acquired)
+		assert.Positive(t, rep.Locks[0].TotalWait)
+		assert.Equal(t, "idle", rep.Locks[1].Name)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	r := registry.New()
+	l := r.Register("<accounts>", ticket.NewLock(), nil)
+	l.Lock()
+	defer l.Unlock()
+
+	rec := httptest.NewRecorder()
+	Handler(r).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/locks", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rec.Body.String(), "&lt;accounts&gt;")
+	assert.Contains(t, rec.Body.String(), `class="held"`)
+}
+
+func TestRegisteredOnDefaultMux(t *testing.T) {
+	req := httptest.NewRequest("GET", "/debug/locks", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(rec, req)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
+
+func TestAdvice(t *testing.T) {
+	defer registry.SetTrackHolders(registry.SetTrackHolders(true))
+	r := registry.New()
+	outer := r.Register("outer", ticket.NewLock(), nil)
+	inner := r.Register("inner", ticket.NewLock(), nil)
+	outer.Lock()
+	inner.Lock()
+	inner.Unlock()
+	outer.Unlock()
+
+	rep := report(r)
+	assert.Equal(t, []Advice{{
+		Action: "coarsen",
+		Locks:  []string{"outer", "inner"},
+		Reason: "1 of 1 acquisitions of inner were made holding outer, which was acquired 1 times",
+	}}, rep.Advice)
+
+	rec := httptest.NewRecorder()
+	Handler(r).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/locks", nil))
+	assert.Contains(t, rec.Body.String(), "<li>coarsen outer and inner: ")
+}
@@ -0,0 +1,69 @@
+package event
+
+import (
+	"container/list"
+	"context"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// AutoReset is an event that releases exactly one waiter per Set, in FIFO order, and
+// resets itself as it does so. A Set with nobody waiting leaves the event set until the
+// next Wait consumes it; setting an event that is already set has no further effect.
+type AutoReset struct {
+	mu      *ticket.Lock
+	set     bool
+	waiters list.List // Queued chan struct{} values, closed to release the waiter
+}
+
+// NewAutoReset creates an event, initially set if set is true.
+func NewAutoReset(set bool) *AutoReset {
+	return &AutoReset{mu: ticket.NewLock(), set: set}
+}
+
+// Set releases the longest-waiting waiter, or sets the event if nobody is waiting.
+func (e *AutoReset) Set() {
+	e.mu.Lock()
+	if front := e.waiters.Front(); front != nil {
+		e.waiters.Remove(front)
+		close(front.Value.(chan struct{}))
+	} else {
+		e.set = true
+	}
+	e.mu.Unlock()
+}
+
+// Wait blocks until a Set releases the caller or ctx is done, returning ctx.Err() in
+// the latter case. A Wait that returns an error consumes no Set.
+func (e *AutoReset) Wait(ctx context.Context) error {
+	e.mu.Lock()
+	if e.set {
+		e.set = false
+		e.mu.Unlock()
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		e.mu.Unlock()
+		return err
+	}
+	ready := make(chan struct{})
+	elem := e.waiters.PushBack(ready)
+	e.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		e.mu.Lock()
+		select {
+		case <-ready:
+			// Released after we gave up; pass the Set on.
+			e.mu.Unlock()
+			e.Set()
+		default:
+			e.waiters.Remove(elem)
+			e.mu.Unlock()
+		}
+		return ctx.Err()
+	}
+}
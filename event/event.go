@@ -0,0 +1,91 @@
+// Package event provides signalling primitives whose waits honor contexts, for
+// coordinating goroutines around conditions rather than around data.
+//
+// ManualReset is an event that, once Set, releases every current and future waiter
+// until it is Reset:
+//
+//	ready := event.NewManualReset(false)
+//
+//	go func() {
+//	    warmUp()
+//	    ready.Set() // Everyone waiting, now or later, proceeds
+//	}()
+//
+//	if err := ready.Wait(ctx); err != nil {
+//	    return err // ctx was done first
+//	}
+//
+// AutoReset releases exactly one waiter per Set, in the order they started waiting,
+// which makes it a fair handoff between producers and consumers. Notifier broadcasts
+// numbered notifications, a sync.Cond whose waits can time out or be cancelled. Gate
+// pauses and resumes admission of entrants.
+package event
+
+import (
+	"context"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// ManualReset is an event that stays set, releasing all waiters, until it is reset.
+type ManualReset struct {
+	mu  *ticket.Lock
+	set bool
+	ch  chan struct{} // Closed while set; replaced on Reset
+}
+
+// NewManualReset creates an event, initially set if set is true.
+func NewManualReset(set bool) *ManualReset {
+	e := &ManualReset{mu: ticket.NewLock(), ch: make(chan struct{})}
+	if set {
+		e.Set()
+	}
+	return e
+}
+
+// Set sets the event, releasing all current waiters and every waiter until Reset.
+func (e *ManualReset) Set() {
+	e.mu.Lock()
+	if !e.set {
+		e.set = true
+		close(e.ch)
+	}
+	e.mu.Unlock()
+}
+
+// Reset clears the event, so that later waiters block until the next Set.
+func (e *ManualReset) Reset() {
+	e.mu.Lock()
+	if e.set {
+		e.set = false
+		e.ch = make(chan struct{})
+	}
+	e.mu.Unlock()
+}
+
+// IsSet reports whether the event is set.
+func (e *ManualReset) IsSet() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.set
+}
+
+// Done returns a channel that is closed when the event is next set, or already closed
+// if it is set now, for use in a select. A Reset after the channel is closed does not
+// reopen it.
+func (e *ManualReset) Done() <-chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ch
+}
+
+// Wait blocks until the event is set or ctx is done, returning ctx.Err() in the latter
+// case.
+func (e *ManualReset) Wait(ctx context.Context) error {
+	select {
+	case <-e.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,142 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualResetReleasesAllWaiters(t *testing.T) {
+	e := NewManualReset(false)
+	const numWaiters = 5
+	var wg sync.WaitGroup
+
+	wg.Add(numWaiters)
+	for range numWaiters {
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, e.Wait(context.Background()))
+		}()
+	}
+	e.Set()
+	wg.Wait()
+
+	assert.True(t, e.IsSet())
+	assert.NoError(t, e.Wait(context.Background()), "Later waiters pass while set")
+}
+
+func TestManualResetReset(t *testing.T) {
+	e := NewManualReset(true)
+	e.Reset()
+	assert.False(t, e.IsSet())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, e.Wait(ctx), context.DeadlineExceeded)
+
+	done := e.Done()
+	e.Set()
+	<-done
+}
+
+func TestAutoResetReleasesOneWaiterInOrder(t *testing.T) {
+	e := NewAutoReset(false)
+	const numWaiters = 3
+	order := make(chan int, numWaiters)
+
+	for i := range numWaiters {
+		go func() {
+			assert.NoError(t, e.Wait(context.Background()))
+			order <- i
+		}()
+		for e.queued() != i+1 {
+			time.Sleep(time.Millisecond) // Queue the waiters in a known order
+		}
+	}
+
+	for i := range numWaiters {
+		e.Set()
+		assert.Equal(t, i, <-order)
+	}
+	assert.Zero(t, e.queued())
+}
+
+func TestAutoResetStaysSetUntilConsumed(t *testing.T) {
+	e := NewAutoReset(false)
+	e.Set()
+	e.Set() // Does not accumulate
+
+	assert.NoError(t, e.Wait(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, e.Wait(ctx), context.DeadlineExceeded, "The second Set should have been absorbed")
+	assert.Zero(t, e.queued(), "A cancelled waiter should leave the queue")
+}
+
+// queued returns the number of waiters.
+func (e *AutoReset) queued() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.waiters.Len()
+}
+
+func TestNotifierNoLostWakeup(t *testing.T) {
+	n := NewNotifier()
+	gen := n.Generation()
+	n.Notify() // Sent between reading the generation and waiting
+
+	next, err := n.Wait(context.Background(), gen)
+	assert.NoError(t, err)
+	assert.Equal(t, gen+1, next)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	_, err = n.Wait(ctx, next)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "No notification has been sent since next")
+}
+
+func TestNotifierBroadcasts(t *testing.T) {
+	n := NewNotifier()
+	gen := n.Generation()
+	const numWaiters = 5
+	var wg sync.WaitGroup
+
+	wg.Add(numWaiters)
+	for range numWaiters {
+		go func() {
+			defer wg.Done()
+			next, err := n.Wait(context.Background(), gen)
+			assert.NoError(t, err)
+			assert.Equal(t, gen+1, next)
+		}()
+	}
+	n.Notify()
+	wg.Wait()
+}
+
+func TestGateAdmitsQueuedEntrantsOnOpen(t *testing.T) {
+	g := NewGate(false)
+	const numEntrants = 4
+	var wg sync.WaitGroup
+
+	wg.Add(numEntrants)
+	for range numEntrants {
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, g.Enter(context.Background()))
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond) // Let the entrants queue
+	g.Open()
+	g.Close() // Entrants already queued still pass
+	wg.Wait()
+
+	assert.False(t, g.IsOpen())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, g.Enter(ctx), context.DeadlineExceeded)
+}
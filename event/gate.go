@@ -0,0 +1,37 @@
+package event
+
+import "context"
+
+// Gate controls admission: while it is open Enter returns immediately, and while it is
+// closed Enter blocks. Opening the gate admits everyone queued at that moment, even if
+// it closes again right away, so that pausing intake never strands an entrant that was
+// already waiting:
+//
+//	g := event.NewGate(true)
+//
+//	g.Close() // Pause intake during rebalancing
+//	rebalance()
+//	g.Open()
+//
+//	// In each worker:
+//	if err := g.Enter(ctx); err != nil {
+//	    return err
+//	}
+type Gate struct {
+	open *ManualReset
+}
+
+// NewGate creates a gate, initially open if open is true.
+func NewGate(open bool) *Gate { return &Gate{open: NewManualReset(open)} }
+
+// Open opens the gate, admitting all queued and subsequent entrants.
+func (g *Gate) Open() { g.open.Set() }
+
+// Close closes the gate; subsequent entrants block until it is opened.
+func (g *Gate) Close() { g.open.Reset() }
+
+// IsOpen reports whether the gate is open.
+func (g *Gate) IsOpen() bool { return g.open.IsSet() }
+
+// Enter returns once the gate is open, or returns ctx.Err() if ctx is done first.
+func (g *Gate) Enter(ctx context.Context) error { return g.open.Wait(ctx) }
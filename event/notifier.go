@@ -0,0 +1,76 @@
+package event
+
+import (
+	"context"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Notifier broadcasts notifications to every goroutine waiting for the next one. Unlike
+// sync.Cond, waits honor contexts, and notifications are numbered by generation so that
+// none is lost between checking a condition and starting to wait:
+//
+//	for {
+//	    gen := n.Generation()
+//	    if conditionHolds() {
+//	        break
+//	    }
+//	    // A Notify after Generation makes Wait return immediately.
+//	    if _, err := n.Wait(ctx, gen); err != nil {
+//	        return err
+//	    }
+//	}
+type Notifier struct {
+	mu  *ticket.Lock
+	gen uint64
+	ch  chan struct{} // Closed by the next Notify
+}
+
+// NewNotifier creates a Notifier at generation 0.
+func NewNotifier() *Notifier { return &Notifier{mu: ticket.NewLock(), ch: make(chan struct{})} }
+
+// Generation returns the number of notifications sent so far.
+func (n *Notifier) Generation() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.gen
+}
+
+// Notify wakes every goroutine waiting for a generation past the current one, and
+// advances the generation.
+func (n *Notifier) Notify() {
+	n.mu.Lock()
+	n.gen++
+	close(n.ch)
+	n.ch = make(chan struct{})
+	n.mu.Unlock()
+}
+
+// After returns a channel that is closed once a notification after generation gen has
+// been sent, for use in a select.
+func (n *Notifier) After(gen uint64) <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.gen != gen {
+		return closed
+	}
+	return n.ch
+}
+
+// Wait blocks until a notification after generation gen has been sent, and returns the
+// generation at that point. If ctx is done first it returns gen and ctx.Err().
+func (n *Notifier) Wait(ctx context.Context, gen uint64) (uint64, error) {
+	select {
+	case <-n.After(gen):
+		return n.Generation(), nil
+	case <-ctx.Done():
+		return gen, ctx.Err()
+	}
+}
+
+// closed is a channel that is always closed.
+var closed = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
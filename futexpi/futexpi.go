@@ -0,0 +1,56 @@
+// Package futexpi provides a Linux lock built on priority-inheriting futexes
+// (FUTEX_LOCK_PI and FUTEX_UNLOCK_PI), for sharing a lock between processes that need
+// the kernel to resolve priority inversion, such as a real-time thread and a
+// lower-priority control process.
+//
+// The lock is a single 32-bit word holding the kernel thread ID of its owner, or 0 when
+// free, which the kernel understands. Uncontended acquisitions and releases are a single
+// compare-and-swap in user space; a contended Lock blocks in the kernel, which boosts
+// the owning thread to the highest priority among the threads blocked on the word, in
+// whichever process they live, until it releases the lock. If the owner exits while
+// holding the lock, the kernel hands it to the next waiter if there is one, and
+// otherwise the next Lock finds the owner gone and takes the lock over; either way
+// OwnerDied reports it. A dead owner is only detected as long as its thread ID hasn't
+// been reused by a new thread.
+//
+// The word is placed by the caller, typically in a file or shared anonymous mapping
+// that both processes map, and passed to New. Every process must only access it through
+// this package or an equivalent implementation of the futex PI protocol, such as a
+// pthread mutex with PTHREAD_PRIO_INHERIT.
+//
+// Ownership belongs to an OS thread, not a goroutine, so Lock wires the calling
+// goroutine to its current thread with runtime.LockOSThread, and Unlock, which must be
+// called by the same goroutine, releases it. Priority inheritance acts on that thread;
+// give it a real-time priority with sched_setscheduler to benefit. Acquisitions and
+// releases also ask the kernel for the thread ID, which costs a system call each.
+//
+// On other platforms New and NewLock return ErrUnsupported.
+//
+// Example usage:
+//
+//	mem, err := syscall.Mmap(fd, 0, 4096, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+//	if err != nil {
+//	    return err
+//	}
+//	l, err := futexpi.New((*uint32)(unsafe.Pointer(&mem[0])))
+//	if err != nil {
+//	    return err
+//	}
+//
+//	l.Lock()
+//	// ... touch state shared with the other process ...
+//	l.Unlock()
+package futexpi
+
+import "errors"
+
+// ErrUnsupported is returned on platforms without priority-inheriting futexes.
+var ErrUnsupported = errors.New("futexpi: not supported on this platform")
+
+// Lock is a priority-inheriting lock on a futex word.
+type Lock struct {
+	word *uint32
+}
+
+// NewLock creates a lock on a word of its own, for use within a single process.
+func NewLock() (*Lock, error) { return New(new(uint32)) }
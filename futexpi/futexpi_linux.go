@@ -0,0 +1,108 @@
+package futexpi
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// Futex operations and word bits from linux/futex.h. The operations are deliberately
+// not FUTEX_PRIVATE_FLAG, so that the word may be shared between processes.
+const (
+	futexLockPI   = 6
+	futexUnlockPI = 7
+
+	futexWaiters   = 0x80000000 // Set by the kernel while threads are blocked on the word
+	futexOwnerDied = 0x40000000 // Set when the owner exited holding the lock
+	futexTIDMask   = 0x3fffffff
+)
+
+// New creates a lock on word, which must be 4-byte aligned and, for a fresh lock,
+// zero. Every process sharing the lock passes the address of the same word in its
+// mapping.
+func New(word *uint32) (*Lock, error) {
+	if uintptr(unsafe.Pointer(word))%4 != 0 {
+		return nil, fmt.Errorf("futexpi: word at %p is not 4-byte aligned", word)
+	}
+	return &Lock{word: word}, nil
+}
+
+// Lock acquires the lock, wiring the calling goroutine to its thread until Unlock.
+func (l *Lock) Lock() {
+	runtime.LockOSThread()
+	if atomic.CompareAndSwapUint32(l.word, 0, uint32(syscall.Gettid())) {
+		return
+	}
+	for {
+		switch errno := l.futex(futexLockPI); errno {
+		case 0:
+			atomic.LoadUint32(l.word) // Pairs with the Or in Unlock for the race detector
+			return
+		case syscall.EINTR, syscall.EAGAIN: // EAGAIN: the owner is exiting
+			continue
+		case syscall.ESRCH:
+			// The owner exited with nobody blocked on the lock, so the kernel kept no
+			// state to hand it over with and the word still names the dead thread.
+			if l.takeOver() {
+				return
+			}
+		case syscall.EDEADLK:
+			runtime.UnlockOSThread()
+			panic("futexpi: Lock of a lock already held by the calling thread")
+		default:
+			runtime.UnlockOSThread()
+			panic(fmt.Sprintf("futexpi: FUTEX_LOCK_PI: %v", errno))
+		}
+	}
+}
+
+// takeOver replaces the dead owner recorded in the word with the calling thread,
+// flagging the owner's death, and reports whether it did. It fails if the word changed
+// meanwhile, for the caller to try again.
+func (l *Lock) takeOver() bool {
+	w := atomic.LoadUint32(l.word)
+	if w&futexTIDMask == 0 {
+		return false // Released or taken over since
+	}
+	return atomic.CompareAndSwapUint32(l.word, w, uint32(syscall.Gettid())|futexOwnerDied|w&futexWaiters)
+}
+
+// TryLock acquires the lock if it is free, without blocking. On success the calling
+// goroutine is wired to its thread until Unlock.
+func (l *Lock) TryLock() bool {
+	runtime.LockOSThread()
+	if atomic.CompareAndSwapUint32(l.word, 0, uint32(syscall.Gettid())) {
+		return true
+	}
+	runtime.UnlockOSThread()
+	return false
+}
+
+// Unlock releases the lock, handing it to the highest-priority waiter if there is one.
+// It must be called by the goroutine that acquired the lock.
+func (l *Lock) Unlock() {
+	if !atomic.CompareAndSwapUint32(l.word, uint32(syscall.Gettid()), 0) {
+		// The kernel set the waiters or owner-died bit: it must hand the lock over. The
+		// hand-off orders memory, but only atomics on the word tell the race detector so.
+		atomic.OrUint32(l.word, 0)
+		if errno := l.futex(futexUnlockPI); errno != 0 {
+			panic(fmt.Sprintf("futexpi: Unlock by a thread that doesn't hold the lock: %v", errno))
+		}
+	}
+	runtime.UnlockOSThread()
+}
+
+// Owner returns the kernel thread ID of the lock's owner, or 0 if the lock is free.
+func (l *Lock) Owner() int { return int(atomic.LoadUint32(l.word) & futexTIDMask) }
+
+// OwnerDied reports whether the lock was handed over by the kernel because its previous
+// owner exited while holding it, leaving the state it guards possibly inconsistent. The
+// flag stays set until the lock is next released.
+func (l *Lock) OwnerDied() bool { return atomic.LoadUint32(l.word)&futexOwnerDied != 0 }
+
+func (l *Lock) futex(op uintptr) syscall.Errno {
+	_, _, errno := syscall.Syscall6(syscall.SYS_FUTEX, uintptr(unsafe.Pointer(l.word)), op, 0, 0, 0, 0)
+	return errno
+}
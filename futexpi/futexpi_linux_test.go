@@ -0,0 +1,90 @@
+package futexpi
+
+import (
+	"os/exec"
+	"sync"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockUnlock(t *testing.T) {
+	l, err := NewLock()
+	assert.NoError(t, err)
+
+	l.Lock()
+	assert.Equal(t, syscall.Gettid(), l.Owner())
+	assert.False(t, l.OwnerDied())
+	assert.Panics(t, l.Lock, "The kernel refuses recursive acquisition")
+	l.Unlock()
+	assert.Zero(t, l.Owner())
+
+	assert.True(t, l.TryLock())
+	done := make(chan bool)
+	go func() { done <- l.TryLock() }()
+	assert.False(t, <-done)
+	l.Unlock()
+}
+
+func TestMutualExclusionThroughKernel(t *testing.T) {
+	l, err := NewLock()
+	assert.NoError(t, err)
+	const numGoroutines = 4
+	const iterations = 500
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				l.Lock()
+				counter++
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+	assert.Zero(t, *l.word, "No owner or waiters bit should be left behind")
+}
+
+func TestSharedMapping(t *testing.T) {
+	mem, err := syscall.Mmap(-1, 0, 4096, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_ANON)
+	if err != nil {
+		t.Skipf("mmap: %v", err)
+	}
+	defer syscall.Munmap(mem)
+
+	l, err := New((*uint32)(unsafe.Pointer(&mem[0])))
+	assert.NoError(t, err)
+	l.Lock()
+	assert.NotZero(t, mem[0]|mem[1]|mem[2]|mem[3], "The owner's TID lives in the shared word")
+	l.Unlock()
+
+	_, err = New((*uint32)(unsafe.Pointer(&mem[1])))
+	assert.Error(t, err)
+}
+
+func TestLockTakesOverFromDeadOwner(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("starting a process: %v", err)
+	}
+	l, err := NewLock()
+	assert.NoError(t, err)
+	*l.word = uint32(cmd.Process.Pid) // The main thread of a process that has exited
+
+	l.Lock()
+	assert.Equal(t, syscall.Gettid(), l.Owner())
+	assert.True(t, l.OwnerDied())
+	l.Unlock()
+	assert.Zero(t, *l.word)
+	assert.True(t, l.TryLock(), "The lock should be usable again")
+	assert.False(t, l.OwnerDied())
+	l.Unlock()
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package futexpi
+
+// New creates a lock on word. It returns ErrUnsupported outside Linux.
+func New(word *uint32) (*Lock, error) { return nil, ErrUnsupported }
+
+// Lock acquires the lock.
+func (l *Lock) Lock() { panic(ErrUnsupported) }
+
+// TryLock acquires the lock if it is free.
+func (l *Lock) TryLock() bool { panic(ErrUnsupported) }
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() { panic(ErrUnsupported) }
+
+// Owner returns the kernel thread ID of the lock's owner.
+func (l *Lock) Owner() int { panic(ErrUnsupported) }
+
+// OwnerDied reports whether the lock's previous owner exited while holding it.
+func (l *Lock) OwnerDied() bool { panic(ErrUnsupported) }
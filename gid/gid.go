@@ -0,0 +1,54 @@
+// Package gid identifies the calling goroutine. It is the one place in this module that
+// recovers goroutine IDs, shared by the reentrant locks, owner tracking and deadlock
+// detection, and is exported so that code built on these locks can key its own
+// per-goroutine state the same way.
+//
+// The runtime deliberately doesn't expose goroutine IDs. Get recovers the ID from the
+// header line of the goroutine's own stack trace. That is portable across Go versions,
+// unlike reading the ID out of the runtime's g struct in assembly, but costs on the
+// order of a few microseconds, so callers should only ask where re-entrance or ownership
+// actually has to be decided.
+//
+// Example usage:
+//
+//	owner := gid.Get()
+//	// ...
+//	if gid.Get() != owner {
+//	    panic("unlock by non-owner")
+//	}
+package gid
+
+import (
+	"runtime"
+	"sync"
+)
+
+// bufs recycles stack trace buffers. runtime.Stack makes its argument escape, so a
+// buffer on Get's own stack would cost an allocation per call.
+var bufs = sync.Pool{New: func() any { return new([64]byte) }}
+
+// Get returns the ID of the calling goroutine. IDs are positive and are not reused
+// while the goroutine is alive.
+func Get() int64 {
+	buf := bufs.Get().(*[64]byte)
+	n := runtime.Stack(buf[:], false)
+	id := parse(buf[:n])
+	bufs.Put(buf)
+	return id
+}
+
+// parse extracts the ID from a stack trace beginning "goroutine 123 [running]:".
+func parse(b []byte) int64 {
+	const prefix = "goroutine "
+	if len(b) < len(prefix) || string(b[:len(prefix)]) != prefix {
+		panic("gid: unexpected stack trace header: " + string(b))
+	}
+	var id int64
+	for _, c := range b[len(prefix):] {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + int64(c-'0')
+	}
+	return id
+}
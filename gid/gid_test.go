@@ -0,0 +1,34 @@
+package gid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestGetDistinguishesGoroutines(t *testing.T) {
+	self := Get()
+	assert.Positive(t, self)
+	assert.Equal(t, self, Get(), "The ID should be stable within a goroutine")
+
+	other := make(chan int64)
+	go func() { other <- Get() }()
+	assert.NotEqual(t, self, <-other)
+}
+
+func TestParse(t *testing.T) {
+	assert.Equal(t, int64(42), parse([]byte("goroutine 42 [running]:\nmain.main()")))
+	assert.Panics(t, func() { parse([]byte("garbage")) })
+}
+
+func BenchmarkGet(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Get()
+	}
+}
+
+func TestGetDoesNotAllocate(t *testing.T) {
+	allocs.Zero(t, func() { Get() })
+}
@@ -0,0 +1,107 @@
+// Package gls provides goroutine-local storage keyed by goroutine ID.
+//
+// Several locks in this module keep per-goroutine state: reentrancy counts, implicit
+// queue nodes, the set of lock levels a goroutine holds. A Key gives each goroutine its
+// own value of type T, stored in a sharded map keyed by gid.Get so goroutines on
+// different shards don't contend.
+//
+// Go has no hook that runs when a goroutine exits, so values are not freed
+// automatically. A goroutine releases its values with Delete or Clear, which run each
+// key's cleanup function, and Go starts a goroutine that does so when it returns.
+// Values left behind by a goroutine that exits without clearing stay in the key's map.
+//
+// Example usage:
+//
+//	var depth = gls.NewKey[int](nil)
+//
+//	gls.Go(func() {
+//	    d, _ := depth.Get()
+//	    depth.Set(d + 1)
+//	})
+package gls
+
+import (
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/shardedmap"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// numShards spreads goroutines over enough shards that unrelated goroutines rarely
+// share a lock.
+const numShards = 64
+
+var (
+	keysMu = ticket.NewLock()
+	keys   []clearer // Every key ever created, for Clear
+)
+
+// hashID spreads goroutines over shards. IDs are handed out sequentially, so their low
+// bits are already as even as any hash would make them.
+func hashID(g int64) uint64 { return uint64(g) }
+
+type clearer interface{ clear(g int64) }
+
+// Key holds one value of type T per goroutine. Keys are meant to be created once, as
+// package-level variables; every key ever created is visited by Clear.
+type Key[T any] struct {
+	m       *shardedmap.Map[int64, T]
+	cleanup func(T)
+}
+
+// NewKey creates a key. If cleanup is non-nil it is called with a goroutine's value
+// when that value is deleted.
+func NewKey[T any](cleanup func(T)) *Key[T] {
+	k := &Key[T]{
+		m:       shardedmap.New[int64, T](numShards, hashID),
+		cleanup: cleanup,
+	}
+	keysMu.Lock()
+	keys = append(keys, k)
+	keysMu.Unlock()
+	return k
+}
+
+// Get returns the calling goroutine's value and whether it has one.
+func (k *Key[T]) Get() (T, bool) { return k.m.Get(gid.Get()) }
+
+// Set stores v as the calling goroutine's value, replacing any previous value without
+// cleaning it up.
+func (k *Key[T]) Set(v T) { k.m.Set(gid.Get(), v) }
+
+// Delete removes the calling goroutine's value, running the key's cleanup on it.
+func (k *Key[T]) Delete() { k.clear(gid.Get()) }
+
+// Len returns the number of goroutines holding a value for k, which helps spot
+// goroutines that exited without clearing.
+func (k *Key[T]) Len() int { return k.m.Len() }
+
+func (k *Key[T]) clear(g int64) {
+	v, ok := k.m.Get(g)
+	if !ok {
+		return
+	}
+	k.m.Delete(g)
+	if k.cleanup != nil {
+		k.cleanup(v)
+	}
+}
+
+// Clear deletes the calling goroutine's values for every key, running their cleanups.
+func Clear() {
+	g := gid.Get()
+	keysMu.Lock()
+	all := keys[:len(keys):len(keys)]
+	keysMu.Unlock()
+	for _, k := range all {
+		k.clear(g)
+	}
+}
+
+// Go runs fn in a new goroutine that clears its goroutine-local values when fn
+// returns, including by panicking.
+func Go(fn func()) {
+	go func() {
+		defer Clear()
+		fn()
+	}()
+}
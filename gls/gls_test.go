@@ -0,0 +1,66 @@
+package gls
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValuesArePerGoroutine(t *testing.T) {
+	k := NewKey[int](nil)
+	k.Set(1)
+	defer k.Delete()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, ok := k.Get()
+		assert.False(t, ok, "A new goroutine should not see another's value")
+		k.Set(2)
+		v, _ := k.Get()
+		assert.Equal(t, 2, v)
+		k.Delete()
+	}()
+	<-done
+
+	v, ok := k.Get()
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+}
+
+func TestDeleteRunsCleanup(t *testing.T) {
+	var cleaned []string
+	k := NewKey(func(s string) { cleaned = append(cleaned, s) })
+
+	k.Delete() // No value, no cleanup
+	k.Set("a")
+	k.Delete()
+	_, ok := k.Get()
+	assert.False(t, ok)
+	assert.Equal(t, []string{"a"}, cleaned)
+}
+
+func TestGoClearsOnExit(t *testing.T) {
+	var mu sync.Mutex
+	var cleaned int
+	k1 := NewKey(func(int) { mu.Lock(); cleaned++; mu.Unlock() })
+	k2 := NewKey(func(int) { mu.Lock(); cleaned++; mu.Unlock() })
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		Go(func() {
+			defer wg.Done()
+			k1.Set(i)
+			k2.Set(i)
+		})
+	}
+	wg.Wait()
+
+	assert.Eventually(t, func() bool { return k1.Len() == 0 && k2.Len() == 0 }, time.Second, time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 8, cleaned, "Every value should be cleaned up")
+	mu.Unlock()
+}
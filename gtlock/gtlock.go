@@ -0,0 +1,134 @@
+// Package gtlock implements the Graunke–Thakkar array lock, a FIFO queue lock in which
+// each contender spins on the flag of the goroutine that queued just before it.
+//
+// Every contender owns a Node holding a single flag bit. To acquire the lock it swaps
+// a reference to its flag, together with the flag's current value, into the lock's
+// tail. The reference it gets back names the predecessor's flag and the value that
+// means "still held"; the contender spins until the flag flips. Releasing the lock is
+// a single toggle of the holder's own flag.
+//
+// Compared with the Anderson-style alock:
+//   - There is no shared flag array, so no modulo indexing and no slot wraparound
+//   - The number of contenders does not have to be known in advance
+//   - Memory is one Node per contender instead of one slot per possible contender
+//
+// Example usage:
+//
+//	lock := gtlock.NewLock()
+//	node := &gtlock.Node{}
+//
+//	// Blocking acquisition
+//	lock.Lock(node)
+//	// ... critical section ...
+//	lock.Unlock(node)
+//
+//	// Non-blocking try-lock
+//	if lock.TryLock(node) {
+//	    // ... critical section ...
+//	    lock.Unlock(node)
+//	}
+//
+// Each goroutine must maintain its own Node for each lock it holds at the same time;
+// releasing one lock flips the Node's flag for every lock queued behind it. A single
+// Node should not be used concurrently by multiple goroutines.
+package gtlock
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// ref names a Node's flag and the value it holds while the lock is held through it.
+type ref struct {
+	node *Node
+	held uint32
+}
+
+// released reports whether the holder named by r has released the lock.
+func (r *ref) released() bool { return r == nil || r.node.flag.Load() != r.held }
+
+// Node represents a contender in the Graunke–Thakkar lock.
+type Node struct {
+	flag atomic.Uint32
+	refs [2]ref // One per flag value, so acquiring never allocates
+}
+
+// ref returns the reference to publish for the node's current flag value.
+func (n *Node) ref() *ref {
+	v := n.flag.Load()
+	r := &n.refs[v]
+	r.node, r.held = n, v
+	return r
+}
+
+// Lock represents the Graunke–Thakkar lock.
+type Lock struct {
+	tail atomic.Pointer[ref] // nil until the first acquisition
+	spin spin.Adaptive       // Self-tuning spin limit for waiters
+}
+
+// NewLock creates a new Graunke–Thakkar lock.
+func NewLock() *Lock { return new(Lock) }
+
+// TryLock attempts to acquire the lock without blocking.
+// Returns true if lock was acquired, false otherwise.
+func (l *Lock) TryLock(node *Node) bool {
+	pred := l.tail.Load()
+	return pred.released() && l.tail.CompareAndSwap(pred, node.ref())
+}
+
+// Lock acquires the lock.
+func (l *Lock) Lock(node *Node) {
+	pred := l.tail.Swap(node.ref()) // Atomically put ourselves at the tail
+	chaos.Point()
+	if !pred.released() { // Predecessor still holds or awaits the lock
+		l.lockSlow(pred)
+	}
+}
+
+// lockSlow spins until the predecessor flips its flag, yielding once the spin budget is
+// exhausted.
+func (l *Lock) lockSlow(pred *ref) {
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = l.spin.Limit()
+	}
+	i := 0
+	for ; !pred.released(); i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+		l.spin.Update(min(i, spinLimit), i < spinLimit)
+	}
+	lockprof.Record(start, 1)
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock(node *Node) {
+	if invariant.Enabled {
+		invariant.Check(!l.IsFree(), "gtlock: Unlock of an unlocked lock")
+	}
+
+	chaos.Point()
+	node.flag.Store(node.flag.Load() ^ 1) // Releases whoever queued behind us
+}
+
+// IsFree returns true if the lock is currently free.
+func (l *Lock) IsFree() bool { return l.tail.Load().released() }
@@ -0,0 +1,77 @@
+package gtlock
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestLockConcurrentAccess(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	lock := NewLock()
+	const numGoroutines = 10
+	const iterations = 500
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			node := &Node{} // Each goroutine owns its node
+			for range iterations {
+				lock.Lock(node)
+				counter++
+				lock.Unlock(node)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+	assert.True(t, lock.IsFree())
+}
+
+func TestLockTryLock(t *testing.T) {
+	lock := NewLock()
+	a, b := &Node{}, &Node{}
+
+	assert.True(t, lock.IsFree())
+	assert.True(t, lock.TryLock(a))
+	assert.False(t, lock.TryLock(b), "TryLock should fail while the lock is held")
+	lock.Unlock(a)
+	assert.True(t, lock.IsFree())
+
+	// The node's flag has flipped; reacquiring must publish the other value.
+	assert.True(t, lock.TryLock(a))
+	assert.False(t, lock.IsFree())
+	lock.Unlock(a)
+	assert.True(t, lock.IsFree())
+}
+
+func BenchmarkLock(b *testing.B) {
+	lock := NewLock()
+	b.RunParallel(func(pb *testing.PB) {
+		node := &Node{}
+		for pb.Next() {
+			lock.Lock(node)
+			lock.Unlock(node)
+		}
+	})
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	lock := NewLock()
+	node := new(Node)
+	allocs.Zero(t, func() {
+		lock.Lock(node)
+		lock.Unlock(node)
+		assert.True(t, lock.TryLock(node))
+		lock.Unlock(node)
+	}, "Uncontended Lock, TryLock and Unlock should not allocate")
+}
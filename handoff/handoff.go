@@ -0,0 +1,236 @@
+// Package handoff provides a queue lock whose holder can hand the lock directly to a
+// waiter of its choosing, and whose choice of the next waiter is otherwise pluggable.
+//
+// Every acquisition goes through a Waiter handle obtained at enqueue time. Unlock grants
+// the lock to the longest-waiting Waiter as usual, while UnlockTo grants it to a
+// particular one, for example the goroutine whose condition has just become true, so
+// that only that goroutine wakes instead of a thundering broadcast. If the chosen Waiter
+// has given up or already been granted the lock, UnlockTo falls back to FIFO order.
+//
+// Example usage:
+//
+//	l := handoff.NewLock()
+//
+//	w := l.Enqueue() // Join the queue, keeping the handle
+//	register(w)      // Let the holder find us
+//	w.Wait()
+//	// ... critical section ...
+//	l.UnlockTo(next) // Grant the lock to a chosen waiter
+//
+// Which waiter Unlock picks is decided by the Policy given to NewLock: strict FIFO by
+// default, or by priority, NUMA node, at random, or any of those with a bound on how
+// often a waiter may be bypassed. Waiters describe themselves to the policy with the
+// Attrs passed to EnqueueWith or LockWith:
+//
+//	l := handoff.NewLock(handoff.WithPolicy(handoff.Bounded(handoff.NUMAGrouped, 16)))
+//	l.LockWith(handoff.Attrs{Node: node})
+//
+// A lock ordered by priority still suffers priority inversion when a high-priority
+// waiter queues behind a low-priority holder. WithInheritance makes the lock report the
+// holder's effective priority, the highest among the holder and its waiters, so that
+// the application can boost the holder's work until it releases the lock; see
+// Inheritance.
+//
+// The queue is guarded by a ticket lock, and waiters block on a channel rather than
+// spinning, since a directed handoff may wait an arbitrarily long time.
+package handoff
+
+import (
+	"container/list"
+	"context"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Waiter is a handle on one queued acquisition of a Lock.
+type Waiter struct {
+	l        *Lock
+	attrs    Attrs
+	elem     *list.Element // nil once granted or cancelled; guarded by l.mu
+	bypassed int           // Guarded by l.mu
+	ready    chan struct{} // Closed when the lock is granted to this Waiter
+}
+
+// Wait blocks until the lock has been granted to w.
+func (w *Waiter) Wait() { <-w.ready }
+
+// Ready returns a channel that is closed once the lock has been granted to w, for use
+// in a select.
+func (w *Waiter) Ready() <-chan struct{} { return w.ready }
+
+// Cancel withdraws w from the queue. If the lock was already granted to w, Cancel
+// releases it to the next waiter instead. It returns whether w was still waiting.
+func (w *Waiter) Cancel() bool {
+	l := w.l
+	l.mu.Lock()
+	if w.elem == nil {
+		l.mu.Unlock()
+		select {
+		case <-w.ready:
+			l.Unlock() // Granted before we gave up; pass it on
+		default: // Already cancelled
+		}
+		return false
+	}
+	l.waiters.Remove(w.elem)
+	w.elem = nil
+	l.reprioritize() // w may have been the reason for a boost
+	l.mu.Unlock()
+	return true
+}
+
+// Lock is a queue lock supporting directed handoff and pluggable waiter selection.
+type Lock struct {
+	mu        *ticket.Lock
+	policy    Policy
+	inherit   Inheritance
+	held      bool
+	holder    Attrs       // Attrs of the current holder; guarded by mu
+	effective int         // Holder's priority including inheritance; guarded by mu
+	waiters   list.List   // Queued *Waiter values
+	cands     []Candidate // Scratch space for the policy; guarded by mu
+}
+
+// Option configures a Lock.
+type Option func(*Lock)
+
+// WithPolicy selects the policy Unlock uses to choose the next holder.
+func WithPolicy(p Policy) Option { return func(l *Lock) { l.policy = p } }
+
+// NewLock creates a new handoff lock, granting the lock in FIFO order unless another
+// Policy is selected.
+func NewLock(opts ...Option) *Lock {
+	l := &Lock{mu: ticket.NewLock(), policy: FIFO}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Enqueue joins the queue for the lock and returns the handle for this acquisition.
+// If the lock is free and nobody is queued, it is granted immediately.
+func (l *Lock) Enqueue() *Waiter { return l.EnqueueWith(Attrs{}) }
+
+// EnqueueWith is like Enqueue, describing the waiter to the lock's Policy with a.
+func (l *Lock) EnqueueWith(a Attrs) *Waiter {
+	w := &Waiter{l: l, attrs: a, ready: make(chan struct{})}
+	l.mu.Lock()
+	if !l.held && l.waiters.Len() == 0 {
+		l.held = true
+		l.holder, l.effective = a, a.Priority
+		close(w.ready)
+	} else {
+		w.elem = l.waiters.PushBack(w)
+		if a.Priority > l.effective {
+			l.reprioritize()
+		}
+	}
+	l.mu.Unlock()
+	return w
+}
+
+// Lock acquires the lock.
+func (l *Lock) Lock() { l.LockWith(Attrs{}) }
+
+// LockContext acquires the lock, or withdraws from the queue and returns ctx.Err() if
+// ctx is done first.
+func (l *Lock) LockContext(ctx context.Context) error {
+	if l.tryLock(Attrs{}) {
+		return nil
+	}
+	w := l.Enqueue()
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		w.Cancel() // Passes the lock on if it was granted meanwhile
+		return ctx.Err()
+	}
+}
+
+// LockWith acquires the lock, describing the caller to the lock's Policy with a.
+func (l *Lock) LockWith(a Attrs) {
+	if !l.tryLock(a) { // Only queueing needs a Waiter
+		l.EnqueueWith(a).Wait()
+	}
+}
+
+// TryLock acquires the lock if it is free and nobody is queued, without blocking.
+func (l *Lock) TryLock() bool { return l.tryLock(Attrs{}) }
+
+// tryLock is TryLock on behalf of a caller described by a.
+func (l *Lock) tryLock(a Attrs) bool {
+	l.mu.Lock()
+	ok := !l.held && l.waiters.Len() == 0
+	if ok {
+		l.held = true
+		l.holder, l.effective = a, a.Priority
+	}
+	l.mu.Unlock()
+	return ok
+}
+
+// Unlock releases the lock to the waiter chosen by the lock's Policy, if any.
+func (l *Lock) Unlock() {
+	l.mu.Lock()
+	l.checkHeld()
+	if l.waiters.Len() == 0 {
+		l.held = false
+		l.mu.Unlock()
+		return
+	}
+
+	l.cands = l.cands[:0]
+	for e := l.waiters.Front(); e != nil; e = e.Next() {
+		w := e.Value.(*Waiter)
+		l.cands = append(l.cands, Candidate{Attrs: w.attrs, Bypassed: w.bypassed})
+	}
+	next := l.waiters.Front()
+	for range l.policy.Next(l.holder, l.cands) {
+		next = next.Next()
+	}
+	l.grant(next.Value.(*Waiter))
+	l.mu.Unlock()
+}
+
+// UnlockTo releases the lock to w if w is still queued, and as Unlock would otherwise.
+// It reports whether w received the lock.
+func (l *Lock) UnlockTo(w *Waiter) bool {
+	if w.l != l {
+		panic("handoff: UnlockTo with a Waiter of another Lock")
+	}
+	l.mu.Lock()
+	l.checkHeld()
+	if w.elem != nil {
+		l.grant(w)
+		l.mu.Unlock()
+		return true
+	}
+	l.mu.Unlock()
+	l.Unlock()
+	return false
+}
+
+// grant hands the held lock to w, which bypasses everyone queued ahead of it. l.mu
+// must be held.
+func (l *Lock) grant(w *Waiter) {
+	for e := l.waiters.Front(); e != w.elem; e = e.Next() {
+		e.Value.(*Waiter).bypassed++
+	}
+	if l.effective != l.holder.Priority && l.inherit != nil {
+		l.inherit(l.holder, l.holder.Priority) // The old holder loses its boost
+	}
+	l.holder, l.effective = w.attrs, w.attrs.Priority
+	l.waiters.Remove(w.elem)
+	w.elem = nil
+	l.reprioritize()
+	close(w.ready)
+}
+
+// checkHeld panics on unlocking a free lock. l.mu must be held.
+func (l *Lock) checkHeld() {
+	if !l.held {
+		l.mu.Unlock()
+		panic("handoff: Unlock of an unlocked lock")
+	}
+}
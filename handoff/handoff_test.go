@@ -0,0 +1,100 @@
+package handoff
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestUnlockToChosenWaiter(t *testing.T) {
+	l := NewLock()
+	l.Lock()
+	a, b, c := l.Enqueue(), l.Enqueue(), l.Enqueue()
+
+	assert.True(t, l.UnlockTo(c), "A queued waiter should receive the lock")
+	<-c.Ready()
+	assertWaiting(t, a, b)
+
+	l.Unlock() // Back to FIFO
+	<-a.Ready()
+	assertWaiting(t, b)
+	l.Unlock()
+	b.Wait()
+	l.Unlock()
+	assert.True(t, l.TryLock())
+}
+
+func TestUnlockToFallsBackToFIFO(t *testing.T) {
+	l := NewLock()
+	l.Lock()
+	a, b := l.Enqueue(), l.Enqueue()
+
+	assert.True(t, b.Cancel())
+	assert.False(t, l.UnlockTo(b), "A cancelled waiter cannot receive the lock")
+	<-a.Ready()
+	assert.False(t, l.TryLock())
+	l.Unlock()
+	assert.True(t, l.TryLock())
+}
+
+func TestCancelAfterGrantPassesLockOn(t *testing.T) {
+	l := NewLock()
+	l.Lock()
+	a, b := l.Enqueue(), l.Enqueue()
+
+	l.Unlock()
+	<-a.Ready()
+	assert.False(t, a.Cancel(), "a was no longer waiting")
+	<-b.Ready()
+	l.Unlock()
+	assert.Panics(t, l.Unlock)
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	l := NewLock()
+	const numGoroutines = 8
+	const iterations = 200
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				l.Lock()
+				counter++
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+}
+
+func assertWaiting(t *testing.T, ws ...*Waiter) {
+	t.Helper()
+	for _, w := range ws {
+		select {
+		case <-w.Ready():
+			t.Errorf("waiter %p was granted the lock out of turn", w)
+		default:
+		}
+	}
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	l := NewLock()
+	allocs.Zero(t, func() {
+		l.Lock()
+		l.Unlock()
+		assert.True(t, l.TryLock())
+		l.Unlock()
+		l.LockWith(Attrs{Priority: 1})
+		l.Unlock()
+	}, "Only queueing behind a holder should allocate a Waiter")
+}
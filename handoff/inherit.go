@@ -0,0 +1,53 @@
+package handoff
+
+import "github.com/ahrav/go-locks/internal/invariant"
+
+// Inheritance is notified when the effective priority of a Lock's holder changes. The
+// effective priority is the highest Priority among the holder and the goroutines
+// queued for the lock: it rises when a more urgent waiter queues, and falls back when
+// that waiter gives up or the holder releases the lock, in which case the callback is
+// called with the old holder's own Priority.
+//
+// The callback identifies the holder by the Attrs it acquired the lock with, typically
+// through their Tag, and should raise or restore the urgency of whatever the holder is
+// doing, such as the priority of the worker pool it runs on. The lock itself can't make
+// a goroutine run sooner. If the holder is in turn waiting for another lock, passing the
+// boost on to that lock's holder is up to the callback.
+//
+// The callback runs with the lock's internal queue locked, in the order the changes
+// happen, and must not call back into the Lock.
+type Inheritance func(holder Attrs, priority int)
+
+// WithInheritance enables priority inheritance, reporting changes of the holder's
+// effective priority to fn.
+func WithInheritance(fn Inheritance) Option { return func(l *Lock) { l.inherit = fn } }
+
+// EffectivePriority returns the holder's effective priority: the highest Priority among
+// the holder and its waiters. It returns 0 if the lock is free.
+func (l *Lock) EffectivePriority() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.held {
+		return 0
+	}
+	return l.effective
+}
+
+// reprioritize recomputes the holder's effective priority and reports a change to the
+// Inheritance callback. l.mu must be held.
+func (l *Lock) reprioritize() {
+	if invariant.Enabled {
+		invariant.Check(l.mu.QueueDepth() > 0, "handoff: reprioritize without the queue lock")
+	}
+	p := l.holder.Priority
+	for e := l.waiters.Front(); e != nil; e = e.Next() {
+		p = max(p, e.Value.(*Waiter).attrs.Priority)
+	}
+	if p == l.effective {
+		return
+	}
+	l.effective = p
+	if l.inherit != nil {
+		l.inherit(l.holder, p)
+	}
+}
@@ -0,0 +1,55 @@
+package handoff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInheritanceBoostsHolder(t *testing.T) {
+	var events []string
+	l := NewLock(WithPolicy(Priority), WithInheritance(func(holder Attrs, priority int) {
+		events = append(events, fmt.Sprintf("%v=%d", holder.Tag, priority))
+	}))
+
+	l.LockWith(Attrs{Priority: 1, Tag: "low"})
+	assert.Equal(t, 1, l.EffectivePriority())
+
+	mid := l.EnqueueWith(Attrs{Priority: 5, Tag: "mid"})
+	high := l.EnqueueWith(Attrs{Priority: 9, Tag: "high"})
+	l.EnqueueWith(Attrs{Priority: 3, Tag: "lower"}) // No boost: below the effective priority
+	assert.Equal(t, 9, l.EffectivePriority())
+
+	high.Cancel()
+	assert.Equal(t, 5, l.EffectivePriority(), "The boost falls back when its cause gives up")
+
+	l.Unlock() // To mid, which inherits from the remaining waiter only if more urgent
+	mid.Wait()
+	assert.Equal(t, 5, l.EffectivePriority())
+
+	l.Unlock()
+	l.Unlock()
+	assert.Zero(t, l.EffectivePriority())
+
+	assert.Equal(t, []string{"low=5", "low=9", "low=5", "low=1"}, events)
+}
+
+func TestInheritanceOnHandoffToLowerPriority(t *testing.T) {
+	var events []string
+	l := NewLock(WithInheritance(func(holder Attrs, priority int) { // FIFO policy
+		events = append(events, fmt.Sprintf("%v=%d", holder.Tag, priority))
+	}))
+
+	l.LockWith(Attrs{Priority: 1, Tag: "a"})
+	b := l.EnqueueWith(Attrs{Priority: 2, Tag: "b"})
+	l.EnqueueWith(Attrs{Priority: 7, Tag: "c"})
+
+	l.Unlock() // FIFO grants b, which is now the holder blocking c
+	b.Wait()
+	assert.Equal(t, 7, l.EffectivePriority())
+	l.Unlock()
+	l.Unlock()
+
+	assert.Equal(t, []string{"a=2", "a=7", "a=1", "b=7", "b=2"}, events)
+}
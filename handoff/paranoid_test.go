@@ -0,0 +1,23 @@
+//go:build locksparanoid
+
+package handoff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/invariant"
+)
+
+func TestReprioritizeWithoutQueueLockPanics(t *testing.T) {
+	l := NewLock()
+	l.Lock()
+	defer l.Unlock()
+
+	defer func() {
+		_, ok := recover().(*invariant.Violation)
+		assert.True(t, ok, "Reprioritizing outside the queue lock should report a violation")
+	}()
+	l.reprioritize()
+}
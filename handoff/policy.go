@@ -0,0 +1,74 @@
+package handoff
+
+import "math/rand/v2"
+
+// Attrs describes a waiter to a Policy. Policies that don't use a field ignore it.
+type Attrs struct {
+	Priority int // Higher is more urgent
+	Node     int // NUMA node, or any other locality group, of the waiting goroutine
+	Tag      any // Identifies the waiter to an Inheritance callback; never inspected
+}
+
+// Candidate is a queued waiter as seen by a Policy.
+type Candidate struct {
+	Attrs
+	Bypassed int // Number of younger waiters granted the lock ahead of this one
+}
+
+// Policy selects which waiter Unlock grants the lock to.
+type Policy interface {
+	// Next returns the index of the waiter to grant the lock to. waiters is never empty
+	// and is ordered oldest first; holder describes the goroutine releasing the lock.
+	Next(holder Attrs, waiters []Candidate) int
+}
+
+// PolicyFunc adapts a function to a Policy.
+type PolicyFunc func(holder Attrs, waiters []Candidate) int
+
+// Next calls f.
+func (f PolicyFunc) Next(holder Attrs, waiters []Candidate) int { return f(holder, waiters) }
+
+var (
+	// FIFO grants the lock to the longest-waiting waiter. It is the default.
+	FIFO Policy = PolicyFunc(func(Attrs, []Candidate) int { return 0 })
+
+	// Priority grants the lock to the waiter with the highest Priority, oldest first
+	// among equals. Low-priority waiters can starve; wrap it in Bounded to prevent that.
+	Priority Policy = PolicyFunc(func(_ Attrs, waiters []Candidate) int {
+		best := 0
+		for i, w := range waiters {
+			if w.Priority > waiters[best].Priority {
+				best = i
+			}
+		}
+		return best
+	})
+
+	// NUMAGrouped keeps the lock on the holder's Node while anyone there is waiting,
+	// oldest first, so the protected data stays in that node's caches. Waiters on other
+	// nodes can starve; wrap it in Bounded to prevent that.
+	NUMAGrouped Policy = PolicyFunc(func(holder Attrs, waiters []Candidate) int {
+		for i, w := range waiters {
+			if w.Node == holder.Node {
+				return i
+			}
+		}
+		return 0
+	})
+
+	// Random grants the lock to a uniformly chosen waiter.
+	Random Policy = PolicyFunc(func(_ Attrs, waiters []Candidate) int {
+		return rand.IntN(len(waiters))
+	})
+)
+
+// Bounded wraps p so that no waiter is bypassed more than k times: once the oldest
+// waiter has been bypassed k times it is granted the lock regardless of p.
+func Bounded(p Policy, k int) Policy {
+	return PolicyFunc(func(holder Attrs, waiters []Candidate) int {
+		if waiters[0].Bypassed >= k {
+			return 0 // The oldest waiter is always the most bypassed
+		}
+		return p.Next(holder, waiters)
+	})
+}
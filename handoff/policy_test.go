@@ -0,0 +1,66 @@
+package handoff
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// grantOrder queues a waiter per Attrs behind the held lock l, then unlocks until
+// everyone has had the lock, and returns the indices of the waiters in grant order.
+func grantOrder(l *Lock, attrs ...Attrs) []int {
+	ws := make([]*Waiter, len(attrs))
+	for i, a := range attrs {
+		ws[i] = l.EnqueueWith(a)
+	}
+	var order []int
+	for range ws {
+		l.Unlock()
+		for i, w := range ws {
+			select {
+			case <-w.Ready():
+				if !slices.Contains(order, i) {
+					order = append(order, i)
+				}
+			default:
+			}
+		}
+	}
+	l.Unlock()
+	return order
+}
+
+func TestFIFOPolicy(t *testing.T) {
+	l := NewLock()
+	l.Lock() //lockcheck:ignore grantOrder unlocks it
+	assert.Equal(t, []int{0, 1, 2}, grantOrder(l, Attrs{Priority: 1}, Attrs{Priority: 3}, Attrs{Priority: 2}))
+}
+
+func TestPriorityPolicy(t *testing.T) {
+	l := NewLock(WithPolicy(Priority))
+	l.Lock() //lockcheck:ignore grantOrder unlocks it
+	assert.Equal(t, []int{1, 3, 2, 0}, grantOrder(l,
+		Attrs{Priority: 1}, Attrs{Priority: 3}, Attrs{Priority: 2}, Attrs{Priority: 3}))
+}
+
+func TestNUMAGroupedPolicy(t *testing.T) {
+	l := NewLock(WithPolicy(NUMAGrouped))
+	l.LockWith(Attrs{Node: 1})
+	assert.Equal(t, []int{1, 3, 0, 2}, grantOrder(l,
+		Attrs{Node: 0}, Attrs{Node: 1}, Attrs{Node: 0}, Attrs{Node: 1}))
+}
+
+func TestBoundedPolicy(t *testing.T) {
+	// Node 1 keeps the lock until the node 0 waiter has been bypassed twice.
+	l := NewLock(WithPolicy(Bounded(NUMAGrouped, 2)))
+	l.LockWith(Attrs{Node: 1})
+	assert.Equal(t, []int{1, 2, 0, 3}, grantOrder(l,
+		Attrs{Node: 0}, Attrs{Node: 1}, Attrs{Node: 1}, Attrs{Node: 1}))
+}
+
+func TestRandomPolicy(t *testing.T) {
+	l := NewLock(WithPolicy(Random))
+	l.Lock() //lockcheck:ignore grantOrder unlocks it
+	assert.ElementsMatch(t, []int{0, 1, 2, 3}, grantOrder(l, Attrs{}, Attrs{}, Attrs{}, Attrs{}))
+}
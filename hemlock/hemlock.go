@@ -0,0 +1,117 @@
+// Package hemlock implements Hemlock, a compact FIFO queue lock by Dice and Kogan.
+//
+// Like MCS, waiters form a queue and each one spins on a word owned by its predecessor
+// rather than on the lock itself. Unlike MCS, the queue element is not per lock: every
+// goroutine owns a single Thread, holding one word, that it uses for every lock it
+// acquires, and it may hold any number of locks at once with it. A Lock itself is a
+// single pointer. That makes Hemlock a low-memory alternative to MCS for lock-per-object
+// designs with huge numbers of lock instances, where a queue node per lock per
+// goroutine is not affordable.
+//
+// The price is that Unlock waits for its successor to acknowledge the handoff before
+// returning, so that the Thread's word is free for its next handoff.
+//
+// Example usage:
+//
+//	self := hemlock.NewThread() // One per goroutine
+//
+//	a, b := hemlock.NewLock(), hemlock.NewLock()
+//	a.Lock(self)
+//	b.Lock(self) // The same Thread may hold several locks
+//	// ... critical section ...
+//	b.Unlock(self)
+//	a.Unlock(self)
+//
+// A Thread must not be used concurrently by multiple goroutines.
+package hemlock
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// Thread is the per-goroutine context shared by every Hemlock the goroutine acquires.
+type Thread struct {
+	grant atomic.Pointer[Lock] // Lock being handed to our successor, nil once acknowledged
+}
+
+// NewThread creates a new Thread context.
+func NewThread() *Thread { return new(Thread) }
+
+// Lock represents a Hemlock.
+type Lock struct {
+	tail atomic.Pointer[Thread]
+}
+
+// NewLock creates a new Hemlock.
+func NewLock() *Lock { return new(Lock) }
+
+// TryLock attempts to acquire the lock without blocking.
+// Returns true if lock was acquired, false otherwise.
+func (l *Lock) TryLock(self *Thread) bool { return l.tail.CompareAndSwap(nil, self) }
+
+// Lock acquires the lock.
+func (l *Lock) Lock(self *Thread) {
+	pred := l.tail.Swap(self) // Atomically put ourselves at the tail
+	chaos.Point()
+	if pred != nil { // Someone else is holding the lock
+		l.lockSlow(pred)
+	}
+}
+
+// lockSlow waits for the predecessor to grant us this lock. Its word may carry grants
+// for other locks it holds, so we only proceed once it names ours.
+func (l *Lock) lockSlow(pred *Thread) {
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	for i := 0; pred.grant.Load() != l; i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+	pred.grant.Store(nil) // Acknowledge, freeing the predecessor's word
+	lockprof.Record(start, 1)
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock(self *Thread) {
+	if invariant.Enabled {
+		invariant.Check(l.tail.Load() != nil, "hemlock: Unlock of an unlocked lock")
+		invariant.Check(self.grant.Load() == nil, "hemlock: thread %p has an unacknowledged grant", self)
+	}
+
+	chaos.Point()
+	if l.tail.CompareAndSwap(self, nil) { // No one waiting
+		return
+	}
+
+	// Hand the lock to our successor through our own word, then wait for it to
+	// acknowledge so the word can carry the next grant.
+	self.grant.Store(l)
+	for self.grant.Load() != nil {
+		chaos.Point()
+		spin.Yield()
+	}
+}
+
+// IsFree returns true if the lock is currently free.
+func (l *Lock) IsFree() bool { return l.tail.Load() == nil }
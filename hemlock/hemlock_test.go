@@ -0,0 +1,100 @@
+package hemlock
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestLockConcurrentAccess(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	lock := NewLock()
+	const numGoroutines = 10
+	const iterations = 500
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			self := NewThread()
+			for range iterations {
+				lock.Lock(self)
+				counter++
+				lock.Unlock(self)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+	assert.True(t, lock.IsFree())
+}
+
+func TestThreadHoldsManyLocks(t *testing.T) {
+	// Every goroutine nests the same pair of locks with a single Thread, so grants for
+	// both locks travel through the same word.
+	a, b := NewLock(), NewLock()
+	const numGoroutines = 8
+	const iterations = 300
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			self := NewThread()
+			for range iterations {
+				a.Lock(self)
+				b.Lock(self)
+				counter++
+				b.Unlock(self)
+				a.Unlock(self)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+	assert.True(t, a.IsFree())
+	assert.True(t, b.IsFree())
+}
+
+func TestLockTryLock(t *testing.T) {
+	lock := NewLock()
+	a, b := NewThread(), NewThread()
+
+	assert.True(t, lock.TryLock(a))
+	assert.False(t, lock.TryLock(b), "TryLock should fail while the lock is held")
+	lock.Unlock(a)
+	assert.True(t, lock.IsFree())
+}
+
+func BenchmarkLock(b *testing.B) {
+	lock := NewLock()
+	b.RunParallel(func(pb *testing.PB) {
+		self := NewThread()
+		for pb.Next() {
+			lock.Lock(self)
+			lock.Unlock(self)
+		}
+	})
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	lock := NewLock()
+	self := NewThread()
+	allocs.Zero(t, func() {
+		lock.Lock(self)
+		lock.Unlock(self)
+		assert.True(t, lock.TryLock(self))
+		lock.Unlock(self)
+	}, "Uncontended Lock, TryLock and Unlock should not allocate")
+}
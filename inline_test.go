@@ -0,0 +1,58 @@
+package locks
+
+import (
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestIntendedInlining checks that the uncontended lock and unlock paths stay within
+// the compiler's inlining budget, so they cost no more than sync.Mutex's. Each entry
+// keeps its slow path in a separate function; an edit that pulls work back into the
+// fast path fails here rather than showing up as a slower benchmark.
+//
+// The pointer-based queue locks (mcs, hemlock, gtlock) are absent on purpose: atomic
+// pointer operations carry GC write barriers whose cost alone exceeds the budget. The
+// budget only holds on 64-bit platforms with single-instruction atomics; elsewhere the
+// 64-bit atomics the ticket locks use expand into calls.
+func TestIntendedInlining(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds packages with the go command")
+	}
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skipf("inlining budget not checked on %s", runtime.GOARCH)
+	}
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	want := map[string][]string{
+		"ticket":   {"(*Lock).Lock", "(*Lock).Unlock", "(*Lock).TryLock"},
+		"rwticket": {"(*Lock).Lock", "(*Lock).Unlock", "(*Lock).RUnlock", "(*Lock).TryLock", "(*Lock).TryRLock"},
+		"alock":    {"(*ArrayLock).Unlock", "(*ArrayLock).TryLock"},
+	}
+	args := []string{"build", "-gcflags=-m"}
+	for pkg := range want {
+		args = append(args, "./"+pkg)
+	}
+	out, err := exec.Command(gocmd, args...).CombinedOutput()
+	if !assert.NoError(t, err, "%s", out) {
+		return
+	}
+
+	inlinable := make(map[string]bool)
+	re := regexp.MustCompile(`(?m)^(\S+?):\d+:\d+: can inline (\S+)`)
+	for _, m := range re.FindAllStringSubmatch(string(out), -1) {
+		inlinable[filepath.Dir(m[1])+"."+m[2]] = true
+	}
+	for pkg, funcs := range want {
+		for _, fn := range funcs {
+			assert.True(t, inlinable[pkg+"."+fn], "%s.%s should be inlinable", pkg, fn)
+		}
+	}
+}
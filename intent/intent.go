@@ -0,0 +1,303 @@
+// Package intent implements a database-style hierarchical lock manager with intention
+// locks.
+//
+// Resources form a tree, such as table → page → row, and are named by their path from
+// the root. Locking a resource in shared (S) or exclusive (X) mode covers its whole
+// subtree, and requires every ancestor to be locked in the matching intention mode
+// first: intent-shared (IS) above S, intent-exclusive (IX) above X. Two owners conflict
+// on a resource according to the usual compatibility matrix:
+//
+//	     IS  IX  S   X
+//	IS   ✓   ✓   ✓
+//	IX   ✓   ✓
+//	S    ✓       ✓
+//	X
+//
+// so a table-level S lock excludes anyone writing one of its rows, while writers of
+// different rows of the same table only share compatible IX locks on the table.
+//
+// Each resource queues its requests in FIFO order: a request is granted only when it is
+// compatible with every granted mode and nobody is queued ahead of it, so a stream of
+// readers cannot starve a writer. The exception is an Owner that already holds the
+// resource and asks for another mode, such as IX on a table it holds IS on: that request
+// goes ahead of the queue, because the requests queued behind its existing hold would
+// otherwise wait for it while it waits for them.
+//
+// Example usage:
+//
+//	m := intent.NewManager()
+//
+//	h, err := m.Lock(ctx, intent.X, "orders", "page7", "row42") // IX, IX, X
+//	if err != nil {
+//	    return err // ctx was done first
+//	}
+//	defer h.Unlock()
+//
+// Acquire and Release operate on a single resource without the hierarchy, for callers
+// such as transaction managers that track their own holds. Their AcquireAs and
+// ReleaseAs variants act on behalf of an Owner, which lets a manager created
+// WithPolicy avoid deadlocks between owners by aborting one of them, see Policy.
+package intent
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Key returns the resource key for a path in the resource tree.
+func Key(path ...string) string { return strings.Join(path, "\x00") }
+
+type request struct {
+	owner  *Owner
+	mode   Mode
+	holder bool          // owner already held the resource when it asked
+	ready  chan struct{} // Closed when the request is granted
+}
+
+type grant struct {
+	owner *Owner
+	mode  Mode
+}
+
+type resource struct {
+	granted [numModes]int
+	holders []grant   // One entry per granted hold
+	queue   list.List // Waiting *request values
+}
+
+// admits reports whether a request in mode can be granted without waiting.
+func (r *resource) admits(mode Mode) bool {
+	for held, n := range r.granted {
+		if n > 0 && !mode.Compatible(Mode(held)) {
+			return false
+		}
+	}
+	return true
+}
+
+// grant records a hold of mode by owner.
+func (r *resource) grant(owner *Owner, mode Mode) {
+	r.granted[mode]++
+	r.holders = append(r.holders, grant{owner: owner, mode: mode})
+}
+
+// release forgets a hold of mode by owner, reporting whether there was one.
+func (r *resource) release(owner *Owner, mode Mode) bool {
+	i := slices.Index(r.holders, grant{owner: owner, mode: mode})
+	if i < 0 {
+		return false
+	}
+	r.holders = slices.Delete(r.holders, i, i+1)
+	r.granted[mode]--
+	return true
+}
+
+// heldBy reports whether owner holds r in any mode. A nil owner never does.
+func (r *resource) heldBy(owner *Owner) bool {
+	return owner != nil && slices.ContainsFunc(r.holders, func(g grant) bool { return g.owner == owner })
+}
+
+// enqueue queues req, behind the other requests of holders if req is one and behind
+// everyone otherwise.
+func (r *resource) enqueue(req *request) *list.Element {
+	if req.holder {
+		for e := r.queue.Front(); e != nil; e = e.Next() {
+			if !e.Value.(*request).holder {
+				return r.queue.InsertBefore(req, e)
+			}
+		}
+	}
+	return r.queue.PushBack(req)
+}
+
+// blockers returns the owners req would wait for: the holders of conflicting modes and
+// everyone queued ahead of it.
+func (r *resource) blockers(req *request) []*Owner {
+	var owners []*Owner
+	for _, g := range r.holders {
+		if g.owner != req.owner && !req.mode.Compatible(g.mode) {
+			owners = append(owners, g.owner)
+		}
+	}
+	for e := r.queue.Front(); e != nil; e = e.Next() {
+		q := e.Value.(*request)
+		if req.holder && !q.holder {
+			break
+		}
+		if q.owner != req.owner {
+			owners = append(owners, q.owner)
+		}
+	}
+	return owners
+}
+
+// Manager grants locks on the resources of a tree.
+type Manager struct {
+	mu        *ticket.Lock
+	policy    Policy
+	resources map[string]*resource // Resources with holders or waiters; guarded by mu
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithPolicy selects the deadlock-avoidance policy applied to acquisitions made on
+// behalf of an Owner. The default is NoPolicy.
+func WithPolicy(p Policy) Option { return func(m *Manager) { m.policy = p } }
+
+// NewManager creates a lock manager with no resources locked.
+func NewManager(opts ...Option) *Manager {
+	m := &Manager{mu: ticket.NewLock(), resources: make(map[string]*resource)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Policy returns the manager's deadlock-avoidance policy.
+func (m *Manager) Policy() Policy { return m.policy }
+
+// Acquire locks the single resource key in mode, blocking until it is granted or ctx is
+// done. On failure it returns ctx.Err() and leaves the resource unchanged.
+func (m *Manager) Acquire(ctx context.Context, key string, mode Mode) error {
+	return m.AcquireAs(ctx, nil, key, mode)
+}
+
+// AcquireAs is like Acquire, on behalf of owner. If owner already holds key, the request
+// is granted ahead of the queue when it is compatible with every granted mode, and
+// otherwise waits ahead of the requests of owners that don't hold key. Under WaitDie it returns ErrDie instead
+// of waiting for an older owner; under WoundWait it returns ErrWounded once an older
+// owner has wounded owner. In both cases owner should release everything it holds and
+// retry with its original timestamp. A nil owner is never aborted.
+func (m *Manager) AcquireAs(ctx context.Context, owner *Owner, key string, mode Mode) error {
+	done := ctx.Done()
+
+	m.mu.Lock()
+	if err := ctx.Err(); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	if owner.isWounded() {
+		m.mu.Unlock()
+		return ErrWounded
+	}
+	r := m.resources[key]
+	if r == nil {
+		r = new(resource)
+		m.resources[key] = r
+	}
+	req := &request{owner: owner, mode: mode, holder: r.heldBy(owner)}
+	if (r.queue.Len() == 0 || req.holder) && r.admits(mode) {
+		r.grant(owner, mode)
+		m.mu.Unlock()
+		return nil
+	}
+	if owner != nil && m.policy != NoPolicy {
+		if err := m.policy.resolve(owner, r.blockers(req)); err != nil {
+			m.grantWaiters(key, r) // r may have been created for us
+			m.mu.Unlock()
+			return err
+		}
+	}
+	req.ready = make(chan struct{})
+	elem := r.enqueue(req)
+	m.mu.Unlock()
+
+	var err error
+	select {
+	case <-req.ready:
+		return nil
+	case <-done:
+		err = ctx.Err()
+	case <-owner.woundedCh():
+		err = ErrWounded
+	}
+
+	m.mu.Lock()
+	select {
+	case <-req.ready:
+		// Granted after we gave up; give it back.
+		r.release(owner, mode)
+	default:
+		r.queue.Remove(elem)
+	}
+	m.grantWaiters(key, r)
+	m.mu.Unlock()
+	return err
+}
+
+// Release unlocks one hold of the resource key in mode.
+func (m *Manager) Release(key string, mode Mode) { m.ReleaseAs(nil, key, mode) }
+
+// ReleaseAs unlocks one hold of the resource key in mode made by owner.
+func (m *Manager) ReleaseAs(owner *Owner, key string, mode Mode) {
+	m.mu.Lock()
+	r := m.resources[key]
+	if r == nil || !r.release(owner, mode) {
+		m.mu.Unlock()
+		panic("intent: Release of " + mode.String() + " lock not held on " + strings.ReplaceAll(key, "\x00", "/"))
+	}
+	m.grantWaiters(key, r)
+	m.mu.Unlock()
+}
+
+// grantWaiters grants queued requests on r in FIFO order, stopping at the first one
+// that conflicts, and forgets r once it is unused. m.mu must be held.
+func (m *Manager) grantWaiters(key string, r *resource) {
+	for front := r.queue.Front(); front != nil; front = r.queue.Front() {
+		req := front.Value.(*request)
+		if !r.admits(req.mode) {
+			break
+		}
+		r.grant(req.owner, req.mode)
+		r.queue.Remove(front)
+		close(req.ready)
+	}
+	if r.queue.Len() == 0 && len(r.holders) == 0 {
+		delete(m.resources, key)
+	}
+}
+
+// Hold is a lock on a resource together with the intention locks on its ancestors.
+type Hold struct {
+	m     *Manager
+	keys  []string // Locked so far, root first
+	depth int      // Length of the full path
+	mode  Mode
+}
+
+// Lock locks the resource at path in mode, after locking each of its ancestors in the
+// matching intention mode, root first. It blocks until everything is granted or ctx is
+// done; on failure it returns ctx.Err() with nothing left locked.
+func (m *Manager) Lock(ctx context.Context, mode Mode, path ...string) (*Hold, error) {
+	h := &Hold{m: m, mode: mode, depth: len(path), keys: make([]string, 0, len(path))}
+	for i := range path {
+		key := Key(path[:i+1]...)
+		if err := m.Acquire(ctx, key, h.modeAt(i)); err != nil {
+			h.Unlock()
+			return nil, err
+		}
+		h.keys = append(h.keys, key)
+	}
+	return h, nil
+}
+
+// modeAt returns the mode held at depth i of the path.
+func (h *Hold) modeAt(i int) Mode {
+	if i == h.depth-1 {
+		return h.mode
+	}
+	return h.mode.Intent()
+}
+
+// Unlock releases the resource and then its ancestors, leaf first.
+func (h *Hold) Unlock() {
+	for i := len(h.keys) - 1; i >= 0; i-- {
+		h.m.Release(h.keys[i], h.modeAt(i))
+	}
+	h.keys = h.keys[:0]
+}
@@ -0,0 +1,150 @@
+package intent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompatibility(t *testing.T) {
+	assert.True(t, IS.Compatible(IX))
+	assert.True(t, IX.Compatible(IX))
+	assert.True(t, S.Compatible(IS))
+	assert.False(t, S.Compatible(IX))
+	assert.False(t, X.Compatible(IS))
+	for a := IS; a < numModes; a++ {
+		for b := IS; b < numModes; b++ {
+			assert.Equal(t, a.Compatible(b), b.Compatible(a), "%v/%v should be symmetric", a, b)
+		}
+	}
+
+	assert.True(t, X.Covers(S))
+	assert.True(t, S.Covers(IS))
+	assert.False(t, S.Covers(IX))
+	assert.Equal(t, IX, X.Intent())
+	assert.Equal(t, IS, S.Intent())
+}
+
+func TestTableLockExcludesRowWriters(t *testing.T) {
+	m := NewManager()
+	ctx := context.Background()
+	table, err := m.Lock(ctx, S, "orders")
+	assert.NoError(t, err)
+
+	// Reading a row is compatible with reading the table.
+	row, err := m.Lock(ctx, S, "orders", "p1", "r1")
+	assert.NoError(t, err)
+	row.Unlock()
+
+	// Writing a row needs IX on the table, which conflicts with S.
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = m.Lock(short, X, "orders", "p1", "r1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	table.Unlock()
+	row, err = m.Lock(ctx, X, "orders", "p1", "r1")
+	assert.NoError(t, err)
+	row.Unlock()
+	assert.Empty(t, m.resources, "Unused resources should be forgotten")
+}
+
+func TestRowWritersShareTable(t *testing.T) {
+	m := NewManager()
+	ctx := context.Background()
+
+	a, err := m.Lock(ctx, X, "orders", "p1", "r1")
+	assert.NoError(t, err)
+	b, err := m.Lock(ctx, X, "orders", "p1", "r2")
+	assert.NoError(t, err, "Writers of different rows only share IX locks")
+	a.Unlock()
+	b.Unlock()
+}
+
+func TestFIFOPreventsWriterStarvation(t *testing.T) {
+	m := NewManager()
+	ctx := context.Background()
+	assert.NoError(t, m.Acquire(ctx, "k", S))
+
+	writer := make(chan error)
+	go func() { writer <- m.Acquire(ctx, "k", X) }()
+	for {
+		m.mu.Lock()
+		queued := m.resources["k"].queue.Len()
+		m.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A new reader is compatible with the holder but must queue behind the writer.
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Acquire(short, "k", S), context.DeadlineExceeded)
+
+	m.Release("k", S)
+	assert.NoError(t, <-writer)
+	m.Release("k", X)
+	assert.Panics(t, func() { m.Release("k", X) })
+}
+
+func TestConcurrentRowWriters(t *testing.T) {
+	m := NewManager()
+	const numGoroutines = 8
+	const iterations = 100
+	counters := make([]int, 2)
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			row := []string{"r0", "r1"}[g%2]
+			for range iterations {
+				h, err := m.Lock(context.Background(), X, "t", "p", row)
+				assert.NoError(t, err)
+				counters[g%2]++
+				h.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, []int{numGoroutines / 2 * iterations, numGoroutines / 2 * iterations}, counters)
+	assert.Empty(t, m.resources)
+}
+
+func TestHolderGoesAheadOfQueue(t *testing.T) {
+	m := NewManager()
+	ctx := context.Background()
+	reader, writer := NewOwner(), NewOwner()
+	assert.NoError(t, m.AcquireAs(ctx, reader, "k", IS))
+
+	queued := make(chan error)
+	go func() { queued <- m.AcquireAs(ctx, writer, "k", X) }()
+	for {
+		m.mu.Lock()
+		n := m.resources["k"].queue.Len()
+		m.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The writer waits for reader, so reader's IX must not wait for the writer.
+	short, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	assert.NoError(t, m.AcquireAs(short, reader, "k", IX))
+	assert.ErrorIs(t, m.AcquireAs(short, NewOwner(), "k", IS), context.DeadlineExceeded,
+		"Owners not holding k still queue behind the writer")
+
+	m.ReleaseAs(reader, "k", IX)
+	m.ReleaseAs(reader, "k", IS)
+	assert.NoError(t, <-queued)
+	m.ReleaseAs(writer, "k", X)
+}
@@ -0,0 +1,61 @@
+package intent
+
+// Mode is a lock mode of the hierarchical lock manager.
+type Mode uint8
+
+// Lock modes, from weakest to strongest.
+const (
+	IS Mode = iota // Intent shared: will lock descendants in S
+	IX             // Intent exclusive: will lock descendants in X
+	S              // Shared: read the resource and all of its descendants
+	X              // Exclusive: write the resource and all of its descendants
+
+	numModes
+)
+
+// compatible[a][b] reports whether a and b may be granted on the same resource at once.
+var compatible = [numModes][numModes]bool{
+	//    IS     IX     S      X
+	IS: {true, true, true, false},
+	IX: {true, true, false, false},
+	S:  {true, false, true, false},
+	X:  {false, false, false, false},
+}
+
+// Compatible reports whether m and o may be held on the same resource at the same time
+// by different owners.
+func (m Mode) Compatible(o Mode) bool { return compatible[m][o] }
+
+// Covers reports whether holding m already grants everything o would.
+func (m Mode) Covers(o Mode) bool {
+	switch m {
+	case X:
+		return true
+	case S, IX:
+		return o == m || o == IS
+	default:
+		return o == IS
+	}
+}
+
+// Intent returns the mode that must be held on every ancestor of a resource locked in m.
+func (m Mode) Intent() Mode {
+	if m == S || m == IS {
+		return IS
+	}
+	return IX
+}
+
+func (m Mode) String() string {
+	switch m {
+	case IS:
+		return "IS"
+	case IX:
+		return "IX"
+	case S:
+		return "S"
+	case X:
+		return "X"
+	}
+	return "Mode(?)"
+}
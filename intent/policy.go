@@ -0,0 +1,118 @@
+package intent
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrDie is returned under WaitDie when an owner would have to wait for an older one.
+	ErrDie = errors.New("intent: wait-die: conflicting lock held by an older owner")
+
+	// ErrWounded is returned under WoundWait once an older owner has wounded the caller.
+	ErrWounded = errors.New("intent: wound-wait: wounded by an older owner")
+)
+
+// Policy decides what happens when an owner's request conflicts with locks held or
+// requested by other owners. The timestamp-based policies guarantee that owners never
+// deadlock, by aborting a deterministic victim instead: of two conflicting owners, the
+// one with the larger timestamp (the younger one) is always the one to abort. An owner
+// that retries with its original timestamp eventually becomes the oldest and cannot be
+// aborted again, so no owner starves.
+type Policy uint8
+
+const (
+	// NoPolicy waits for every conflict; owners can deadlock.
+	NoPolicy Policy = iota
+
+	// WaitDie lets an owner wait only for younger owners. A younger owner that conflicts
+	// with an older one dies: its request fails with ErrDie.
+	WaitDie
+
+	// WoundWait lets an owner wait only for older owners. An older owner that conflicts
+	// with a younger one wounds it: the younger owner's pending and future requests fail
+	// with ErrWounded, and the older owner waits for it to release its locks.
+	WoundWait
+)
+
+func (p Policy) String() string {
+	switch p {
+	case NoPolicy:
+		return "NoPolicy"
+	case WaitDie:
+		return "WaitDie"
+	case WoundWait:
+		return "WoundWait"
+	}
+	return "Policy(?)"
+}
+
+// resolve applies the policy to owner's request, which conflicts with blockers. It
+// returns an error if owner must abort instead of waiting. m.mu must be held.
+func (p Policy) resolve(owner *Owner, blockers []*Owner) error {
+	for _, b := range blockers {
+		if b == nil || b == owner {
+			continue // Unowned locks are outside the policy
+		}
+		switch {
+		case p == WaitDie && !owner.older(b):
+			return ErrDie
+		case p == WoundWait && owner.older(b):
+			b.wound()
+		}
+	}
+	return nil
+}
+
+// lastTimestamp hands out owner timestamps.
+var lastTimestamp atomic.Uint64
+
+// Owner identifies one transaction, or other unit of work, to a Manager's Policy.
+// Owners are ordered by timestamp; a smaller timestamp is older.
+type Owner struct {
+	ts      uint64
+	once    sync.Once
+	wounded chan struct{} // Closed when wounded
+}
+
+// NewOwner returns an Owner younger than every Owner created before it.
+func NewOwner() *Owner { return NewOwnerAt(lastTimestamp.Add(1)) }
+
+// NewOwnerAt returns an Owner with the given timestamp, typically that of an aborted
+// owner being retried, so that it keeps its seniority. Live owners must not share a
+// timestamp.
+func NewOwnerAt(ts uint64) *Owner { return &Owner{ts: ts, wounded: make(chan struct{})} }
+
+// Timestamp returns the owner's timestamp.
+func (o *Owner) Timestamp() uint64 { return o.ts }
+
+// Wounded reports whether an older owner has wounded o under WoundWait. A wounded owner
+// should abort and release its locks as soon as possible.
+func (o *Owner) Wounded() bool { return o.isWounded() }
+
+// older reports whether o is older than b.
+func (o *Owner) older(b *Owner) bool { return o.ts < b.ts }
+
+func (o *Owner) wound() { o.once.Do(func() { close(o.wounded) }) }
+
+func (o *Owner) isWounded() bool {
+	if o == nil {
+		return false
+	}
+	select {
+	case <-o.wounded:
+		return true
+	default:
+		return false
+	}
+}
+
+// woundedCh returns a channel closed when o is wounded; nil, which blocks forever, for
+// a nil owner.
+func (o *Owner) woundedCh() <-chan struct{} {
+	if o == nil {
+		return nil
+	}
+	return o.wounded
+}
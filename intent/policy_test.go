@@ -0,0 +1,71 @@
+package intent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitDie(t *testing.T) {
+	m := NewManager(WithPolicy(WaitDie))
+	ctx := context.Background()
+	older, younger := NewOwner(), NewOwner()
+
+	assert.NoError(t, m.AcquireAs(ctx, older, "k", X))
+	assert.ErrorIs(t, m.AcquireAs(ctx, younger, "k", S), ErrDie, "The younger owner should die")
+
+	m.ReleaseAs(older, "k", X)
+	assert.NoError(t, m.AcquireAs(ctx, younger, "k", X))
+
+	// The older owner waits for the younger one instead of dying.
+	acquired := make(chan error)
+	go func() { acquired <- m.AcquireAs(ctx, older, "k", X) }()
+	select {
+	case err := <-acquired:
+		t.Fatalf("Older owner should wait, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	m.ReleaseAs(younger, "k", X)
+	assert.NoError(t, <-acquired)
+	m.ReleaseAs(older, "k", X)
+	assert.Empty(t, m.resources)
+}
+
+func TestWoundWait(t *testing.T) {
+	m := NewManager(WithPolicy(WoundWait))
+	ctx := context.Background()
+	older, younger := NewOwner(), NewOwner()
+
+	assert.NoError(t, m.AcquireAs(ctx, younger, "a", X))
+	assert.NoError(t, m.AcquireAs(ctx, older, "b", X))
+
+	// The younger owner waits for b, then the older one wounds it by requesting a.
+	wounded := make(chan error)
+	go func() { wounded <- m.AcquireAs(ctx, younger, "b", X) }()
+	for !m.queued("b") {
+		time.Sleep(time.Millisecond)
+	}
+	acquired := make(chan error)
+	go func() { acquired <- m.AcquireAs(ctx, older, "a", X) }()
+
+	assert.ErrorIs(t, <-wounded, ErrWounded, "The younger owner's pending request should fail")
+	assert.True(t, younger.Wounded())
+	assert.ErrorIs(t, m.AcquireAs(ctx, younger, "c", S), ErrWounded, "A wounded owner cannot lock anything new")
+
+	m.ReleaseAs(younger, "a", X) // The victim aborts
+	assert.NoError(t, <-acquired)
+	assert.False(t, older.Wounded())
+	m.ReleaseAs(older, "a", X)
+	m.ReleaseAs(older, "b", X)
+	assert.Empty(t, m.resources)
+}
+
+// queued reports whether anyone is waiting for key.
+func (m *Manager) queued(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.resources[key]
+	return r != nil && r.queue.Len() > 0
+}
@@ -0,0 +1,33 @@
+// Package allocs lets tests assert that a lock operation does not allocate.
+//
+// Allocation counts are only meaningful in normal builds: the race detector and the
+// locksparanoid invariant checks allocate on paths that are otherwise allocation-free,
+// so Zero skips the test under either.
+//
+// Example usage:
+//
+//	func TestLockDoesNotAllocate(t *testing.T) {
+//	    l := ticket.NewLock()
+//	    allocs.Zero(t, func() {
+//	        l.Lock()
+//	        l.Unlock()
+//	    })
+//	}
+package allocs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/invariant"
+)
+
+// Zero asserts that f does not allocate, averaged over 100 runs.
+func Zero(t *testing.T, f func(), msgAndArgs ...any) {
+	t.Helper()
+	if raceEnabled || invariant.Enabled {
+		t.Skip("allocation counts are not meaningful under the race detector or locksparanoid")
+	}
+	assert.Zero(t, testing.AllocsPerRun(100, f), msgAndArgs...)
+}
@@ -0,0 +1,6 @@
+//go:build locksparanoid
+
+package invariant
+
+// Enabled reports whether invariant checking is compiled in.
+const Enabled = true
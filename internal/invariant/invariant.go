@@ -0,0 +1,31 @@
+// Package invariant supports the locksparanoid build tag, under which every lock in
+// this module verifies its internal invariants on each operation and panics with a
+// diagnostic when one is violated.
+//
+// Call sites guard their checks with the Enabled constant so that the checks, and the
+// work needed to compute them, are compiled out of normal builds:
+//
+//	if invariant.Enabled {
+//	    invariant.Check(head <= tail, "ticket: head %d ahead of tail %d", head, tail)
+//	}
+//
+// Run a test suite with the checks enabled using:
+//
+//	go test -tags locksparanoid ./...
+package invariant
+
+import "fmt"
+
+// Violation is the value passed to panic when an invariant does not hold.
+type Violation struct {
+	Msg string
+}
+
+func (v *Violation) Error() string { return "invariant violated: " + v.Msg }
+
+// Check panics with a *Violation built from format and args if cond is false.
+func Check(cond bool, format string, args ...any) {
+	if !cond {
+		panic(&Violation{Msg: fmt.Sprintf(format, args...)})
+	}
+}
@@ -0,0 +1,18 @@
+package invariant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	assert.NotPanics(t, func() { Check(true, "unused") })
+
+	defer func() {
+		v, ok := recover().(*Violation)
+		assert.True(t, ok, "Check should panic with a *Violation")
+		assert.Equal(t, "invariant violated: head 3 > tail 1", v.Error())
+	}()
+	Check(false, "head %d > tail %d", 3, 1)
+}
@@ -0,0 +1,59 @@
+// Package offheap lets tests assert that a lock type can live in memory the Go garbage
+// collector doesn't manage, such as an mmap'd region or a manually managed arena.
+//
+// Such memory is never scanned by the collector, so a Go pointer stored in it doesn't
+// keep its target alive, and the write barriers that accompany pointer stores assume
+// heap or stack memory. A type is only safe there if it contains no pointers at all:
+// no pointers, slices, strings, maps, channels, functions or interfaces, at any depth.
+//
+// Example usage:
+//
+//	func TestLockIsPointerFree(t *testing.T) {
+//	    offheap.NoPointers(t, ticket.Lock{})
+//	}
+package offheap
+
+import "reflect"
+
+// TB is the part of testing.TB that NoPointers uses. Taking it rather than *testing.T
+// keeps package testing out of the non-test packages that call Pointer.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// NoPointers asserts that the type of v contains no pointers, naming the field path of
+// the first one found.
+func NoPointers(t TB, v any) {
+	t.Helper()
+	if path, ok := Pointer(reflect.TypeOf(v)); ok {
+		t.Errorf("%s contains a pointer at %s", reflect.TypeOf(v), path)
+	}
+}
+
+// Pointer returns the field path of the first pointer within typ, and false if typ
+// contains none.
+func Pointer(typ reflect.Type) (string, bool) { return findPointer(typ, typ.String()) }
+
+// findPointer returns the path to the first pointer within typ, which is reached by
+// path.
+func findPointer(typ reflect.Type, path string) (string, bool) {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Slice, reflect.String,
+		reflect.Map, reflect.Chan, reflect.Func, reflect.Interface:
+		return path, true
+	case reflect.Array:
+		if typ.Len() == 0 {
+			return "", false // Zero-length arrays, like alignment markers, hold nothing
+		}
+		return findPointer(typ.Elem(), path+"[0]")
+	case reflect.Struct:
+		for i := range typ.NumField() {
+			f := typ.Field(i)
+			if p, ok := findPointer(f.Type, path+"."+f.Name); ok {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
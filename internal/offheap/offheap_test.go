@@ -0,0 +1,38 @@
+package offheap
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPointer(t *testing.T) {
+	type free struct {
+		_ [0]atomic.Uint64
+		a atomic.Uint32
+		b [4]int64
+	}
+	type nested struct {
+		x  int
+		in [2]struct{ p *int }
+	}
+	type hasString struct{ s string }
+
+	for _, tt := range []struct {
+		v    any
+		path string
+	}{
+		{free{}, ""},
+		{[0]*int{}, ""},
+		{nested{}, "offheap.nested.in[0].p"},
+		{hasString{}, "offheap.hasString.s"},
+		{atomic.Pointer[int]{}, "atomic.Pointer[int].v"},
+	} {
+		typ := reflect.TypeOf(tt.v)
+		path, found := findPointer(typ, typ.String())
+		assert.Equal(t, tt.path != "", found, "%v", typ)
+		assert.Equal(t, tt.path, path)
+	}
+}
@@ -0,0 +1,65 @@
+// Package keyserial runs work one at a time per key, such as one mutation at a time per
+// account, while work for different keys runs in parallel.
+//
+// Keys are locked exclusively in an intent.Manager, so work for a key runs in the order
+// it arrived, and a caller whose context is done while it waits leaves the queue
+// without running. Middleware applies the same to HTTP handlers, keyed by a function of
+// the request.
+//
+// Example usage:
+//
+//	s := keyserial.New()
+//
+//	err := s.Do(ctx, accountID, func() error {
+//	    return debit(accountID, amount) // No other Do for accountID runs meanwhile
+//	})
+//
+//	mux.Handle("/accounts/{id}/", s.Middleware(func(r *http.Request) string {
+//	    return r.PathValue("id")
+//	}, accountHandler))
+package keyserial
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ahrav/go-locks/intent"
+)
+
+// Serializer serializes work by key. Create it with New.
+type Serializer struct {
+	m *intent.Manager
+}
+
+// New creates a Serializer with no work in progress.
+func New() *Serializer { return &Serializer{m: intent.NewManager()} }
+
+// Do runs fn once no earlier work for key is running or waiting, and returns its error.
+// If ctx is done first, Do returns ctx.Err() without running fn.
+func (s *Serializer) Do(ctx context.Context, key string, fn func() error) error {
+	if err := s.m.Acquire(ctx, key, intent.X); err != nil {
+		return err
+	}
+	defer s.m.Release(key, intent.X)
+	return fn()
+}
+
+// Middleware serializes next for requests with the same key, as returned by key.
+// Requests for which key returns "" are not serialized. A request whose context is done
+// before its turn gets a 503 Service Unavailable response without reaching next.
+func (s *Serializer) Middleware(key func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := key(r)
+		if k == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		err := s.Do(r.Context(), k, func() error {
+			next.ServeHTTP(w, r)
+			return nil
+		})
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package keyserial
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoSerializesPerKey(t *testing.T) {
+	s := New()
+	const numGoroutines = 6
+	const iterations = 50
+	running := make([]atomic.Int32, 2)
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			key := []string{"a", "b"}[g%2]
+			for range iterations {
+				assert.NoError(t, s.Do(context.Background(), key, func() error {
+					assert.Equal(t, int32(1), running[g%2].Add(1), "Two calls for %q at once", key)
+					running[g%2].Add(-1)
+					return nil
+				}))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDoSkipsFnWhenContextDone(t *testing.T) {
+	s := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := false
+	err := s.Do(ctx, "k", func() error {
+		ran = true
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, ran)
+}
+
+func TestMiddleware(t *testing.T) {
+	s := New()
+	var running atomic.Int32
+	h := s.Middleware(func(r *http.Request) string { return r.URL.Query().Get("account") },
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("account") != "" {
+				assert.Equal(t, int32(1), running.Add(1))
+				defer running.Add(-1)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	var wg sync.WaitGroup
+
+	wg.Add(8)
+	for i := range 8 {
+		go func() {
+			defer wg.Done()
+			target := "/?account=1"
+			if i%4 == 0 {
+				target = "/" // Unkeyed requests pass straight through
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+		}()
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?account=1", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
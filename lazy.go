@@ -0,0 +1,58 @@
+package locks
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Lazy holds a value of type T that is initialized on first use. Unlike sync.Once, a
+// failed initialization is not remembered: if the init function returns an error or
+// panics, the next Get calls it again.
+//
+// Get uses double-checked locking. Once the value is set, Get is a single atomic load.
+// Before that, callers queue on a ticket.Lock: the first one runs init, and the others
+// find its value when their turn comes, or retry in arrival order if it failed.
+type Lazy[T any] struct {
+	v  atomic.Pointer[T]
+	mu *ticket.Lock
+}
+
+// NewLazy creates an uninitialized Lazy.
+func NewLazy[T any]() *Lazy[T] { return &Lazy[T]{mu: ticket.NewLock()} }
+
+// Get returns the value, calling init to create it if no earlier call has succeeded.
+// The error of a failed init is returned to its caller only. init must not call Get on
+// the same Lazy.
+func (z *Lazy[T]) Get(init func() (T, error)) (T, error) {
+	if p := z.v.Load(); p != nil {
+		return *p, nil
+	}
+	return z.getSlow(init)
+}
+
+// getSlow initializes the value under the lock, unless a caller queued ahead did.
+func (z *Lazy[T]) getSlow(init func() (T, error)) (T, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if p := z.v.Load(); p != nil {
+		return *p, nil
+	}
+	v, err := init()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	z.v.Store(&v)
+	return v, nil
+}
+
+// Loaded returns the value and true if it has been initialized, without initializing
+// it.
+func (z *Lazy[T]) Loaded() (T, bool) {
+	if p := z.v.Load(); p != nil {
+		return *p, true
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,62 @@
+package locks
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLazyInitializesOnce(t *testing.T) {
+	z := NewLazy[*int]()
+	const numGoroutines = 8
+	var calls atomic.Int32
+	results := make([]*int, numGoroutines)
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for i := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			p, err := z.Get(func() (*int, error) {
+				calls.Add(1)
+				return new(int), nil
+			})
+			assert.NoError(t, err)
+			results[i] = p
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, p := range results {
+		assert.Same(t, results[0], p)
+	}
+}
+
+func TestLazyRetriesAfterError(t *testing.T) {
+	z := NewLazy[string]()
+	errDown := errors.New("backend down")
+	_, ok := z.Loaded()
+	assert.False(t, ok)
+
+	v, err := z.Get(func() (string, error) { return "partial", errDown })
+	assert.ErrorIs(t, err, errDown)
+	assert.Empty(t, v, "A failed init yields the zero value")
+
+	assert.Panics(t, func() {
+		_, _ = z.Get(func() (string, error) { panic("boom") })
+	})
+
+	v, err = z.Get(func() (string, error) { return "ready", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "ready", v)
+	v, err = z.Get(func() (string, error) { return "", errDown }) // Not called again
+	assert.NoError(t, err)
+	assert.Equal(t, "ready", v)
+	v, ok = z.Loaded()
+	assert.True(t, ok)
+	assert.Equal(t, "ready", v)
+}
@@ -0,0 +1,142 @@
+// Package levels enforces a lock hierarchy at run time, the cheapest effective
+// protection against deadlock.
+//
+// Every lock wrapped with Wrap is given an integer level, and a goroutine may only
+// acquire locks in strictly decreasing order of level: acquiring a lock whose level is
+// greater than or equal to that of any lock it already holds panics, naming both locks.
+// If every goroutine follows the order, no cycle of goroutines waiting for each other's
+// locks can form, so code that passes its tests with checking enabled cannot deadlock
+// on these locks in production either, whatever the interleaving.
+//
+// Checking keeps each goroutine's held locks in goroutine-local storage (package gls),
+// which costs a goroutine ID lookup per operation, so it is enabled by default only in
+// builds with the locksparanoid tag; SetEnabled turns it on or off at run time, for
+// example in tests. With checking off, a wrapped lock only adds a call.
+//
+// Example usage:
+//
+//	accounts := levels.Wrap(ticket.NewLock(), 20, "accounts")
+//	ledger := levels.Wrap(ticket.NewLock(), 10, "ledger")
+//
+//	accounts.Lock()
+//	ledger.Lock() // Fine: 10 < 20
+//	ledger.Unlock()
+//	accounts.Unlock()
+//
+//	ledger.Lock()
+//	accounts.Lock() // panic: levels: goroutine 7 acquiring "accounts" (level 20) while holding "ledger" (level 10)
+package levels
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/gls"
+	"github.com/ahrav/go-locks/internal/invariant"
+)
+
+var enabled atomic.Bool
+
+func init() { enabled.Store(invariant.Enabled) }
+
+// SetEnabled turns checking on or off and returns the previous setting. Locks acquired
+// while checking was off are not known to be held, so turn it on before any wrapped
+// lock is held.
+func SetEnabled(on bool) bool { return enabled.Swap(on) }
+
+// held is the calling goroutine's wrapped locks, in acquisition order.
+var held = gls.NewKey[[]*Locker](nil)
+
+// Locker is a sync.Locker with a level in the lock hierarchy.
+type Locker struct {
+	l     sync.Locker
+	level int
+	name  string
+}
+
+// Wrap returns l with the given level. name identifies the lock in panic messages.
+func Wrap(l sync.Locker, level int, name string) *Locker {
+	return &Locker{l: l, level: level, name: name}
+}
+
+// Level returns the lock's level.
+func (c *Locker) Level() int { return c.level }
+
+// Lock acquires the lock. With checking enabled, it panics if the calling goroutine
+// holds a wrapped lock whose level is not greater than c's.
+func (c *Locker) Lock() {
+	if enabled.Load() {
+		c.check()
+		c.l.Lock()
+		c.push()
+		return
+	}
+	c.l.Lock()
+}
+
+// TryLock acquires the lock if the wrapped lock has a TryLock method and succeeds. It is
+// not checked against the hierarchy, since a TryLock can't wait and so can't deadlock.
+func (c *Locker) TryLock() bool {
+	tl, ok := c.l.(interface{ TryLock() bool })
+	if !ok || !tl.TryLock() {
+		return false
+	}
+	if enabled.Load() {
+		c.push()
+	}
+	return true
+}
+
+// Unlock releases the lock. Locks may be released in any order.
+func (c *Locker) Unlock() {
+	if enabled.Load() {
+		c.pop()
+	}
+	c.l.Unlock()
+}
+
+// check panics if acquiring c would violate the hierarchy.
+func (c *Locker) check() {
+	locks, _ := held.Get()
+	for _, h := range locks {
+		if c.level >= h.level {
+			panic(fmt.Sprintf("levels: goroutine %d acquiring %q (level %d) while holding %q (level %d)",
+				gid.Get(), c.name, c.level, h.name, h.level))
+		}
+	}
+}
+
+// push records c as held by the calling goroutine.
+func (c *Locker) push() {
+	locks, _ := held.Get()
+	held.Set(append(locks, c))
+}
+
+// pop forgets the calling goroutine's most recent hold of c.
+func (c *Locker) pop() {
+	locks, _ := held.Get()
+	i := slices.Index(locks, c)
+	if i < 0 {
+		return // Acquired while checking was off, or by another goroutine
+	}
+	locks = slices.Delete(locks, i, i+1)
+	if len(locks) == 0 {
+		held.Delete() // Don't leave an entry behind for every goroutine that locked
+		return
+	}
+	held.Set(locks)
+}
+
+// Held returns the names of the wrapped locks the calling goroutine holds, in the order
+// it acquired them. It is empty unless checking is enabled.
+func Held() []string {
+	locks, _ := held.Get()
+	names := make([]string, len(locks))
+	for i, h := range locks {
+		names[i] = h.name
+	}
+	return names
+}
@@ -0,0 +1,82 @@
+package levels
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestDescendingOrderIsAllowed(t *testing.T) {
+	defer SetEnabled(SetEnabled(true))
+	high := Wrap(ticket.NewLock(), 20, "high")
+	low := Wrap(ticket.NewLock(), 10, "low")
+
+	high.Lock()
+	low.Lock()
+	assert.Equal(t, []string{"high", "low"}, Held())
+	high.Unlock() // Out of order release is fine
+	low.Unlock()
+	assert.Empty(t, Held())
+}
+
+func TestAscendingOrderPanics(t *testing.T) {
+	defer SetEnabled(SetEnabled(true))
+	high := Wrap(ticket.NewLock(), 20, "high")
+	low := Wrap(ticket.NewLock(), 10, "low")
+	same := Wrap(ticket.NewLock(), 10, "same")
+
+	low.Lock()
+	assert.PanicsWithValue(t, fmt.Sprintf(`levels: goroutine %d acquiring "high" (level 20) while holding "low" (level 10)`, gid.Get()), high.Lock)
+	assert.Panics(t, same.Lock, "Equal levels are not ordered")
+	assert.True(t, high.TryLock(), "TryLock can't deadlock")
+	high.Unlock()
+	low.Unlock()
+
+	high.Lock() // The failed attempts left nothing held
+	high.Unlock()
+}
+
+func TestDisabledChecksNothing(t *testing.T) {
+	defer SetEnabled(SetEnabled(false))
+	high := Wrap(ticket.NewLock(), 20, "high")
+	low := Wrap(ticket.NewLock(), 10, "low")
+
+	low.Lock()
+	high.Lock()
+	assert.Empty(t, Held())
+	high.Unlock()
+	low.Unlock()
+}
+
+func TestHeldSetsArePerGoroutine(t *testing.T) {
+	defer SetEnabled(SetEnabled(true))
+	high := Wrap(&sync.Mutex{}, 20, "high")
+	low := Wrap(&sync.Mutex{}, 10, "low")
+	const numGoroutines = 4
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if g%2 == 0 {
+					high.Lock()
+					low.Lock()
+					low.Unlock()
+					high.Unlock()
+				} else {
+					low.Lock() // Holding only low on this goroutine doesn't restrict others
+					low.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Zero(t, held.Len(), "Goroutines holding nothing leave no entries")
+}
@@ -0,0 +1,133 @@
+// Package litmus runs memory-model litmus tests: small concurrent programs with an
+// outcome the memory model forbids, run over and over to catch the outcome if an
+// implementation allows it.
+//
+// A counting test (n goroutines increment a counter under a lock, check the total)
+// exercises mutual exclusion but says little about ordering: a lock whose release
+// doesn't publish the holder's writes can pass it for hours on amd64 and then fail on
+// arm64, whose weaker hardware model lets the missing ordering show. Litmus tests
+// target the ordering directly. The classic shapes are:
+//
+//   - Message passing (MP): a writer stores data and then a flag; a reader that sees
+//     the flag must see the data.
+//   - Store buffering (SB): two threads each store to one variable and load the other;
+//     at least one of them must see the other's store.
+//   - Independent reads of independent writes (IRIW): two readers of two independent
+//     writes must agree on the order in which the writes happened.
+//
+// The tests in this package apply these shapes to sync/atomic, which Go specifies as
+// sequentially consistent, and to the hand-off paths of each lock in this module.
+//
+// Run drives the threads of a Test with persistent goroutines released together each
+// iteration, which makes the racy windows far more likely to overlap than starting
+// fresh goroutines would. The package's own tests run a modest number of iterations by
+// default; CI can soak them for longer, on each architecture:
+//
+//	go test ./litmus -litmus.duration=10m
+//	GOARCH=arm64 go test ./litmus -litmus.duration=10m
+//
+// Example usage:
+//
+//	var x, y atomic.Int32
+//	var r1, r2 int32
+//	res := litmus.Run(litmus.Test{
+//	    Init: func() { x.Store(0); y.Store(0) },
+//	    Threads: []func(){
+//	        func() { x.Store(1); r1 = y.Load() },
+//	        func() { y.Store(1); r2 = x.Load() },
+//	    },
+//	    Forbidden: func() bool { return r1 == 0 && r2 == 0 },
+//	}, litmus.Iterations(100_000))
+//	if res.Violations > 0 {
+//	    // The forbidden outcome was observed
+//	}
+package litmus
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Test is a litmus test. Init resets the shared state, the Threads run concurrently,
+// and Forbidden inspects the outcome once they have all finished. Init and Forbidden
+// run on the coordinating goroutine, ordered before and after the threads, so they may
+// use plain accesses to the state and to the threads' results.
+type Test struct {
+	Init      func()
+	Threads   []func()
+	Forbidden func() bool
+}
+
+// Result counts the runs of a test and the forbidden outcomes observed.
+type Result struct {
+	Runs       int
+	Violations int
+}
+
+// Limit bounds how long Run repeats a test.
+type Limit struct {
+	iters    int
+	duration time.Duration
+}
+
+// Iterations runs a test n times.
+func Iterations(n int) Limit { return Limit{iters: n} }
+
+// For repeats a test until d has passed.
+func For(d time.Duration) Limit { return Limit{duration: d} }
+
+func (l Limit) done(runs int, start time.Time) bool {
+	if l.duration > 0 {
+		return time.Since(start) >= l.duration
+	}
+	return runs >= l.iters
+}
+
+// Run repeats t within the limit and reports how often the forbidden outcome appeared.
+func Run(t Test, limit Limit) Result {
+	n := len(t.Threads)
+	var (
+		gen      atomic.Int64 // Iteration the threads may start; -1 tells them to exit
+		finished atomic.Int64 // Threads done with the current iteration
+		wg       sync.WaitGroup
+	)
+	wg.Add(n)
+	for _, thread := range t.Threads {
+		go func() {
+			defer wg.Done()
+			for seen := int64(0); ; {
+				g := gen.Load()
+				for ; g == seen; g = gen.Load() {
+					runtime.Gosched()
+				}
+				if g < 0 {
+					return
+				}
+				seen = g
+				thread()
+				finished.Add(1)
+			}
+		}()
+	}
+
+	var res Result
+	for start := time.Now(); !limit.done(res.Runs, start); {
+		if t.Init != nil {
+			t.Init()
+		}
+		finished.Store(0)
+		gen.Add(1)
+		for finished.Load() < int64(n) {
+			runtime.Gosched()
+		}
+		res.Runs++
+		if t.Forbidden() {
+			res.Violations++
+		}
+	}
+	gen.Store(-1)
+	wg.Wait()
+	return res
+}
@@ -0,0 +1,172 @@
+package litmus
+
+import (
+	"flag"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/alock"
+	"github.com/ahrav/go-locks/gtlock"
+	"github.com/ahrav/go-locks/hemlock"
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/rwticket"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+var (
+	iters    = flag.Int("litmus.iters", 2000, "iterations per litmus test")
+	duration = flag.Duration("litmus.duration", 0, "run each litmus test for this long instead of -litmus.iters times")
+)
+
+func limit() Limit {
+	if *duration > 0 {
+		return For(*duration)
+	}
+	return Iterations(*iters)
+}
+
+func check(t *testing.T, test Test) {
+	t.Helper()
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(max(runtime.NumCPU(), len(test.Threads))))
+	res := Run(test, limit())
+	assert.Zero(t, res.Violations, "Forbidden outcome observed in %d of %d runs", res.Violations, res.Runs)
+}
+
+func TestRunCountsViolations(t *testing.T) {
+	calls := 0
+	res := Run(Test{
+		Threads:   []func(){func() {}, func() {}},
+		Forbidden: func() bool { calls++; return calls%2 == 0 },
+	}, Iterations(10))
+	assert.Equal(t, Result{Runs: 10, Violations: 5}, res)
+
+	start := time.Now()
+	res = Run(Test{Threads: []func(){func() {}}, Forbidden: func() bool { return false }}, For(10*time.Millisecond))
+	assert.Positive(t, res.Runs)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
+
+func TestAtomicMessagePassing(t *testing.T) {
+	var data, ready atomic.Int32
+	var r1, r2 int32
+	check(t, Test{
+		Init: func() { data.Store(0); ready.Store(0) },
+		Threads: []func(){
+			func() { data.Store(1); ready.Store(1) },
+			func() { r1 = ready.Load(); r2 = data.Load() },
+		},
+		Forbidden: func() bool { return r1 == 1 && r2 == 0 },
+	})
+}
+
+func TestAtomicStoreBuffering(t *testing.T) {
+	var x, y atomic.Int32
+	var r1, r2 int32
+	check(t, Test{
+		Init: func() { x.Store(0); y.Store(0) },
+		Threads: []func(){
+			func() { x.Store(1); r1 = y.Load() },
+			func() { y.Store(1); r2 = x.Load() },
+		},
+		Forbidden: func() bool { return r1 == 0 && r2 == 0 },
+	})
+}
+
+func TestAtomicIRIW(t *testing.T) {
+	var x, y atomic.Int32
+	var a1, a2, b1, b2 int32
+	check(t, Test{
+		Init: func() { x.Store(0); y.Store(0) },
+		Threads: []func(){
+			func() { x.Store(1) },
+			func() { y.Store(1) },
+			func() { a1 = x.Load(); a2 = y.Load() },
+			func() { b1 = y.Load(); b2 = x.Load() },
+		},
+		// The readers saw the writes in opposite orders.
+		Forbidden: func() bool { return a1 == 1 && a2 == 0 && b1 == 1 && b2 == 0 },
+	})
+}
+
+// mutex abstracts the per-thread lock and unlock calls of a lock under test, for locks
+// that need a per-goroutine node.
+type mutex struct {
+	name   string
+	thread func() (lock, unlock func()) // Returns one thread's lock and unlock
+}
+
+func mutexes() []mutex {
+	plain := func(name string, l sync.Locker) mutex {
+		return mutex{name, func() (func(), func()) { return l.Lock, l.Unlock }}
+	}
+	mcsLock := mcs.NewLock()
+	hemLock := hemlock.NewLock()
+	gtLock := gtlock.NewLock()
+	return []mutex{
+		plain("ticket", ticket.NewLock()),
+		plain("alock", alock.NewArrayLock(4)),
+		plain("rwticket", rwticket.NewLock()),
+		plain("mcs.Locker", mcs.NewLocker()),
+		{"mcs", func() (func(), func()) {
+			node := &mcs.QNode{}
+			//lockcheck:ignore The second closure unlocks it
+			return func() { mcsLock.Lock(node) }, func() { mcsLock.Unlock(node) }
+		}},
+		{"hemlock", func() (func(), func()) {
+			self := hemlock.NewThread()
+			//lockcheck:ignore The second closure unlocks it
+			return func() { hemLock.Lock(self) }, func() { hemLock.Unlock(self) }
+		}},
+		{"gtlock", func() (func(), func()) {
+			node := &gtlock.Node{}
+			//lockcheck:ignore The second closure unlocks it
+			return func() { gtLock.Lock(node) }, func() { gtLock.Unlock(node) }
+		}},
+	}
+}
+
+// TestLockMessagePassing checks that a holder's writes, made with plain stores, are
+// visible to the next holder: the release must publish them and the acquire must
+// observe the release.
+func TestLockMessagePassing(t *testing.T) {
+	for _, m := range mutexes() {
+		t.Run(m.name, func(t *testing.T) {
+			lock0, unlock0 := m.thread()
+			lock1, unlock1 := m.thread()
+			var data, ready, r1, r2 int
+			check(t, Test{
+				Init: func() { data, ready = 0, 0 },
+				Threads: []func(){
+					func() { lock0(); data = 1; ready = 1; unlock0() },
+					func() { lock1(); r1 = ready; r2 = data; unlock1() },
+				},
+				Forbidden: func() bool { return r1 == 1 && r2 == 0 },
+			})
+		})
+	}
+}
+
+// TestLockStoreBuffering checks that critical sections are ordered: whichever holder
+// comes second must see the first one's store.
+func TestLockStoreBuffering(t *testing.T) {
+	for _, m := range mutexes() {
+		t.Run(m.name, func(t *testing.T) {
+			lock0, unlock0 := m.thread()
+			lock1, unlock1 := m.thread()
+			var x, y, r1, r2 int
+			check(t, Test{
+				Init: func() { x, y = 0, 0 },
+				Threads: []func(){
+					func() { lock0(); x = 1; r1 = y; unlock0() },
+					func() { lock1(); y = 1; r2 = x; unlock1() },
+				},
+				Forbidden: func() bool { return r1 == 0 && r2 == 0 },
+			})
+		})
+	}
+}
@@ -0,0 +1,472 @@
+// Package lockcheck defines an analyzer that reports misuse of the locks in this module.
+//
+// The analyzer detects:
+//   - lock values (or structs containing them) being copied, which silently forks the
+//     lock's state
+//   - an mcs.QNode captured by, or passed to, a new goroutine, which lets two goroutines
+//     enqueue the same node
+//   - a Lock or RLock call that some path out of the function leaves without the
+//     matching Unlock or RUnlock
+//   - TryLock, TryRLock and TryAcquire results that are ignored
+//
+// The unlock check follows the function's control flow graph from each acquisition to
+// its returns. A path is released by a call to the matching method, by a closure on the
+// path that makes one, or by a deferred release anywhere in the function; paths that
+// end in a panic or another call that never returns are exempt. Functions named Lock,
+// RLock, TryLock or TryRLock are skipped, since wrappers legitimately acquire without
+// releasing.
+//
+// A finding that is intended, such as a lock a function returns holding or a result a
+// test ignores on purpose, is silenced by a //lockcheck:ignore comment on the reported
+// line or the line above it, followed by the reason:
+//
+//	s.lock.Lock() //lockcheck:ignore Unlock releases it
+//
+// Run it with go vet:
+//
+//	go install github.com/ahrav/go-locks/cmd/lockcheck@latest
+//	go vet -vettool=$(which lockcheck) ./...
+package lockcheck
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/cfg"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const modulePath = "github.com/ahrav/go-locks"
+
+const doc = `check for misuse of github.com/ahrav/go-locks locks
+
+Reports copied lock values, mcs.QNode values shared with new goroutines,
+Lock calls that a path out of the function leaves without a matching Unlock,
+and ignored TryLock results. A //lockcheck:ignore comment on the reported
+line or the line above silences a finding.`
+
+// Analyzer reports misuse of the locks in this module.
+var Analyzer = &analysis.Analyzer{
+	Name:     "lockcheck",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+	pass = withIgnores(pass)
+
+	nodeFilter := []ast.Node{
+		(*ast.AssignStmt)(nil),
+		(*ast.CallExpr)(nil),
+		(*ast.ReturnStmt)(nil),
+		(*ast.RangeStmt)(nil),
+		(*ast.FuncDecl)(nil),
+		(*ast.FuncLit)(nil),
+		(*ast.GoStmt)(nil),
+		(*ast.ExprStmt)(nil),
+	}
+	insp.Preorder(nodeFilter, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			checkCopyAssign(pass, n)
+			checkIgnoredTryAssign(pass, n)
+		case *ast.CallExpr:
+			checkCopyCall(pass, n)
+		case *ast.ReturnStmt:
+			for _, r := range n.Results {
+				checkCopyExpr(pass, r, "return copies lock value")
+			}
+		case *ast.RangeStmt:
+			if n.Value != nil {
+				if t := pass.TypesInfo.TypeOf(n.Value); t != nil && containsLock(t) {
+					pass.Reportf(n.Value.Pos(), "range var %s copies lock value", types.ExprString(n.Value))
+				}
+			}
+		case *ast.FuncDecl:
+			checkCopyParams(pass, n.Recv)
+			checkCopyParams(pass, n.Type.Params)
+			if n.Body != nil && !isAcquireWrapper(n.Name.Name) {
+				checkUnlock(pass, cfgs.FuncDecl(n), n.Body)
+			}
+		case *ast.FuncLit:
+			checkCopyParams(pass, n.Type.Params)
+			checkUnlock(pass, cfgs.FuncLit(n), n.Body)
+		case *ast.GoStmt:
+			checkSharedQNode(pass, n)
+		case *ast.ExprStmt:
+			if call, ok := n.X.(*ast.CallExpr); ok {
+				checkIgnoredTry(pass, call)
+			}
+		}
+	})
+	return nil, nil
+}
+
+// isModuleType reports whether t is a named type declared in this module.
+func isModuleType(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	path := named.Obj().Pkg().Path()
+	return path == modulePath || strings.HasPrefix(path, modulePath+"/")
+}
+
+// isLock reports whether t is one of this module's lock types: a named type with a
+// Lock or Unlock method on its pointer, or an mcs.QNode.
+func isLock(t types.Type) bool {
+	if !isModuleType(t) {
+		return false
+	}
+	if isQNode(t) {
+		return true
+	}
+	mset := types.NewMethodSet(types.NewPointer(t))
+	return hasMethod(mset, "Lock") || hasMethod(mset, "Unlock")
+}
+
+func hasMethod(mset *types.MethodSet, name string) bool {
+	for i := range mset.Len() {
+		if mset.At(i).Obj().Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// containsLock reports whether a value of type t holds a lock by value.
+func containsLock(t types.Type) bool {
+	return containsLockSeen(t, make(map[types.Type]bool))
+}
+
+func containsLockSeen(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	if isLock(t) {
+		return true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := range u.NumFields() {
+			if containsLockSeen(u.Field(i).Type(), seen) {
+				return true
+			}
+		}
+	case *types.Array:
+		return containsLockSeen(u.Elem(), seen)
+	}
+	return false
+}
+
+// isQNode reports whether t is mcs.QNode.
+func isQNode(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	return ok && named.Obj().Pkg() != nil &&
+		named.Obj().Pkg().Path() == modulePath+"/mcs" && named.Obj().Name() == "QNode"
+}
+
+// isFreshValue reports whether e creates a new value rather than copying an existing
+// one, which is always safe.
+func isFreshValue(e ast.Expr) bool {
+	switch e := ast.Unparen(e).(type) {
+	case *ast.CompositeLit, *ast.CallExpr:
+		return true
+	case *ast.UnaryExpr:
+		return e.Op == token.AND
+	}
+	return false
+}
+
+func checkCopyExpr(pass *analysis.Pass, e ast.Expr, msg string) {
+	if isFreshValue(e) {
+		return
+	}
+	if t := pass.TypesInfo.TypeOf(e); t != nil && containsLock(t) {
+		pass.Reportf(e.Pos(), "%s: %s", msg, types.ExprString(e))
+	}
+}
+
+func checkCopyAssign(pass *analysis.Pass, as *ast.AssignStmt) {
+	for i, rhs := range as.Rhs {
+		if len(as.Lhs) == len(as.Rhs) {
+			if id, ok := as.Lhs[i].(*ast.Ident); ok && id.Name == "_" {
+				continue // Discarding a value doesn't create a second lock
+			}
+		}
+		checkCopyExpr(pass, rhs, "assignment copies lock value")
+	}
+}
+
+func checkCopyCall(pass *analysis.Pass, call *ast.CallExpr) {
+	// Conversions and builtins like len() don't copy in a meaningful way.
+	if tv, ok := pass.TypesInfo.Types[call.Fun]; ok && (tv.IsType() || tv.IsBuiltin()) {
+		return
+	}
+	for _, arg := range call.Args {
+		checkCopyExpr(pass, arg, "call passes lock by value")
+	}
+}
+
+func checkCopyParams(pass *analysis.Pass, fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	for _, f := range fields.List {
+		if t := pass.TypesInfo.TypeOf(f.Type); t != nil && containsLock(t) {
+			pass.Reportf(f.Type.Pos(), "parameter passes lock by value: %s", types.ExprString(f.Type))
+		}
+	}
+}
+
+// moduleMethod returns the name and receiver expression of call if it invokes a method
+// declared in this module.
+func moduleMethod(pass *analysis.Pass, call *ast.CallExpr) (string, ast.Expr, bool) {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return "", nil, false
+	}
+	path := fn.Pkg().Path()
+	if path != modulePath && !strings.HasPrefix(path, modulePath+"/") {
+		return "", nil, false
+	}
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return "", nil, false
+	}
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return "", nil, false
+	}
+	return fn.Name(), sel.X, true
+}
+
+func isAcquireWrapper(name string) bool {
+	switch name {
+	case "Lock", "RLock", "TryLock", "TryRLock":
+		return true
+	}
+	return false
+}
+
+// checkUnlock reports Lock and RLock calls in body, the body of a function with
+// control flow graph g, that some path to a return leaves without the matching release.
+// Calls in nested closures are checked when the closure itself is visited.
+func checkUnlock(pass *analysis.Pass, g *cfg.CFG, body *ast.BlockStmt) {
+	if g == nil {
+		return
+	}
+	for _, b := range g.Blocks {
+		if !b.Live {
+			continue
+		}
+		for i, n := range b.Nodes {
+			ast.Inspect(n, func(m ast.Node) bool {
+				if _, ok := m.(*ast.FuncLit); ok {
+					return false
+				}
+				call, ok := m.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				name, recv, ok := moduleMethod(pass, call)
+				if !ok || (name != "Lock" && name != "RLock") {
+					return true
+				}
+				rel := release{"Unlock", types.ExprString(recv)}
+				if name == "RLock" {
+					rel.method = "RUnlock"
+				}
+				if !hasRelease(pass.TypesInfo.TypeOf(recv), rel.method) {
+					return true // Released through something else, such as a handle it returns
+				}
+				if !rel.deferredIn(pass, body) && rel.escapes(pass, b, i+1) {
+					pass.Reportf(call.Pos(), "%s is acquired without a matching %s.%s() on some path out of this function", rel.expr, rel.expr, rel.method)
+				}
+				return true
+			})
+		}
+	}
+}
+
+// hasRelease reports whether a value of type t, or a pointer to one, has the release
+// method.
+func hasRelease(t types.Type, method string) bool {
+	if t == nil {
+		return false
+	}
+	if _, ok := t.Underlying().(*types.Pointer); !ok && !types.IsInterface(t) {
+		t = types.NewPointer(t)
+	}
+	return hasMethod(types.NewMethodSet(t), method)
+}
+
+// release is the call that releases an acquisition: method called on expr.
+type release struct {
+	method string // Unlock or RUnlock
+	expr   string
+}
+
+// in reports whether n makes the release, itself or in a closure.
+func (r release) in(pass *analysis.Pass, n ast.Node) bool {
+	found := false
+	ast.Inspect(n, func(m ast.Node) bool {
+		if call, ok := m.(*ast.CallExpr); ok && !found {
+			name, recv, ok := moduleMethod(pass, call)
+			found = ok && name == r.method && types.ExprString(recv) == r.expr
+		}
+		return !found
+	})
+	return found
+}
+
+// deferredIn reports whether a defer statement of body, outside nested closures, makes
+// the release.
+func (r release) deferredIn(pass *analysis.Pass, body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			found = found || r.in(pass, n)
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// escapes reports whether some path from node i of block b reaches a return without
+// making the release.
+func (r release) escapes(pass *analysis.Pass, b *cfg.Block, i int) bool {
+	seen := make(map[*cfg.Block]bool)
+	var walk func(b *cfg.Block, i int) bool
+	walk = func(b *cfg.Block, i int) bool {
+		for _, n := range b.Nodes[i:] {
+			if r.in(pass, n) {
+				return false
+			}
+		}
+		if b.Return() != nil {
+			return true
+		}
+		for _, succ := range b.Succs { // None after a call that never returns
+			if !seen[succ] {
+				seen[succ] = true
+				if walk(succ, 0) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return walk(b, i)
+}
+
+// withIgnores returns pass with the findings on lines marked by a //lockcheck:ignore
+// comment, or on the line below one, left out.
+func withIgnores(pass *analysis.Pass) *analysis.Pass {
+	type line struct {
+		file string
+		n    int
+	}
+	ignored := make(map[line]bool)
+	for _, f := range pass.Files {
+		for _, group := range f.Comments {
+			for _, c := range group.List {
+				if strings.HasPrefix(c.Text, "//lockcheck:ignore") {
+					pos := pass.Fset.Position(c.Slash)
+					ignored[line{pos.Filename, pos.Line}] = true
+					ignored[line{pos.Filename, pos.Line + 1}] = true
+				}
+			}
+		}
+	}
+	if len(ignored) == 0 {
+		return pass
+	}
+
+	p := *pass
+	p.Report = func(d analysis.Diagnostic) {
+		if pos := pass.Fset.Position(d.Pos); !ignored[line{pos.Filename, pos.Line}] {
+			pass.Report(d)
+		}
+	}
+	return &p
+}
+
+// checkSharedQNode reports mcs.QNode values that a go statement shares with the new
+// goroutine, either as arguments or by capturing variables from the enclosing scope.
+func checkSharedQNode(pass *analysis.Pass, g *ast.GoStmt) {
+	for _, arg := range g.Call.Args {
+		if t := pass.TypesInfo.TypeOf(arg); t != nil && isQNodeOrPtr(t) {
+			pass.Reportf(arg.Pos(), "mcs.QNode %s is shared with a new goroutine; each goroutine needs its own node", types.ExprString(arg))
+		}
+	}
+
+	lit, ok := g.Call.Fun.(*ast.FuncLit)
+	if !ok {
+		return
+	}
+	reported := make(map[types.Object]bool)
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		obj, ok := pass.TypesInfo.Uses[id].(*types.Var)
+		if !ok || reported[obj] || !isQNodeOrPtr(obj.Type()) {
+			return true
+		}
+		if obj.Pos() < lit.Pos() || obj.Pos() >= lit.End() { // Declared outside the goroutine
+			reported[obj] = true
+			pass.Reportf(id.Pos(), "mcs.QNode %s is captured by a new goroutine; each goroutine needs its own node", id.Name)
+		}
+		return true
+	})
+}
+
+func isQNodeOrPtr(t types.Type) bool {
+	if p, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	return isQNode(t)
+}
+
+func isTry(name string) bool {
+	switch name {
+	case "TryLock", "TryRLock", "TryAcquire":
+		return true
+	}
+	return false
+}
+
+func checkIgnoredTry(pass *analysis.Pass, call *ast.CallExpr) {
+	if name, recv, ok := moduleMethod(pass, call); ok && isTry(name) {
+		pass.Reportf(call.Pos(), "result of %s.%s() is ignored", types.ExprString(recv), name)
+	}
+}
+
+func checkIgnoredTryAssign(pass *analysis.Pass, as *ast.AssignStmt) {
+	for i, lhs := range as.Lhs {
+		id, ok := lhs.(*ast.Ident)
+		if !ok || id.Name != "_" || i >= len(as.Rhs) {
+			continue
+		}
+		if call, ok := as.Rhs[i].(*ast.CallExpr); ok {
+			checkIgnoredTry(pass, call)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package a
+
+import (
+	"github.com/ahrav/go-locks/intent"
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/rwticket"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+type guarded struct {
+	mu ticket.Lock
+	n  int
+}
+
+func copies(l *ticket.Lock, g *guarded) {
+	c := *l // want `assignment copies lock value: \*l`
+	_ = c
+	h := *g // want `assignment copies lock value: \*g`
+	_ = h
+	byValue(*l) // want `call passes lock by value: \*l`
+
+	fresh := ticket.Lock{} // Composite literals are fine
+	_ = &fresh
+}
+
+func byValue(l ticket.Lock) {} // want `parameter passes lock by value: ticket.Lock`
+
+func sharedNode(l *mcs.Lock) {
+	node := &mcs.QNode{}
+	go func() {
+		l.Lock(node) // want `mcs.QNode node is captured by a new goroutine`
+		l.Unlock(node)
+	}()
+	go worker(l, node) // want `mcs.QNode node is shared with a new goroutine`
+}
+
+func worker(l *mcs.Lock, node *mcs.QNode) {
+	l.Lock(node)
+	defer l.Unlock(node)
+}
+
+func ownNode(l *mcs.Lock) {
+	go func() {
+		node := &mcs.QNode{} // Declared inside the goroutine: fine
+		l.Lock(node)
+		l.Unlock(node)
+	}()
+}
+
+func missingUnlock(l *ticket.Lock) {
+	l.Lock() // want `l is acquired without a matching l.Unlock\(\) on some path out of this function`
+}
+
+func earlyReturn(l *ticket.Lock, fail bool) bool {
+	l.Lock() // want `l is acquired without a matching l.Unlock\(\) on some path out of this function`
+	if fail {
+		return false
+	}
+	l.Unlock()
+	return true
+}
+
+func unlockOnEveryPath(l *ticket.Lock, fail bool) bool {
+	l.Lock()
+	if fail {
+		l.Unlock()
+		return false
+	}
+	l.Unlock()
+	return true
+}
+
+func unlockOrPanic(l *ticket.Lock, fail bool) {
+	l.Lock()
+	if fail {
+		panic("failed") // A path that never returns needs no release
+	}
+	l.Unlock()
+}
+
+func unlockInLoop(l *ticket.Lock) {
+	for range 3 {
+		l.Lock()
+		l.Unlock()
+	}
+}
+
+func readLocked(l *rwticket.Lock, fail bool) {
+	l.RLock()
+	if fail {
+		l.RUnlock()
+		return
+	}
+	defer l.RUnlock()
+}
+
+func missingRUnlock(l *rwticket.Lock) {
+	l.RLock() // want `l is acquired without a matching l.RUnlock\(\) on some path out of this function`
+	l.Unlock()
+}
+
+func viaInterface(l rwticket.RWLocker) {
+	l.RLock() // want `l is acquired without a matching l.RUnlock\(\) on some path out of this function`
+}
+
+func releasedByHandle(m *intent.Manager) *intent.Hold {
+	h, _ := m.Lock("table") // Manager has no Unlock; the Hold releases it
+	return h
+}
+
+func returnsHolding(l *ticket.Lock) {
+	l.Lock() //lockcheck:ignore The caller unlocks
+}
+
+func deferredUnlock(l *ticket.Lock) {
+	l.Lock()
+	defer func() { l.Unlock() }()
+}
+
+func ignoredTry(l *ticket.Lock) {
+	l.TryLock()     // want `result of l.TryLock\(\) is ignored`
+	_ = l.TryLock() // want `result of l.TryLock\(\) is ignored`
+	if l.TryLock() {
+		l.Unlock()
+	}
+
+	//lockcheck:ignore Only whether it blocks matters
+	l.TryLock()
+}
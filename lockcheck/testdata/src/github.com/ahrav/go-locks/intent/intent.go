@@ -0,0 +1,8 @@
+package intent
+
+type Manager struct{}
+
+type Hold struct{}
+
+func (m *Manager) Lock(path ...string) (*Hold, error) { return &Hold{}, nil }
+func (h *Hold) Unlock()                               {}
@@ -0,0 +1,10 @@
+package mcs
+
+type QNode struct{ waiting uint32 }
+
+type Lock struct{ tail *QNode }
+
+func NewLock() *Lock                     { return new(Lock) }
+func (l *Lock) Lock(node *QNode)         {}
+func (l *Lock) Unlock(node *QNode)       {}
+func (l *Lock) TryLock(node *QNode) bool { return true }
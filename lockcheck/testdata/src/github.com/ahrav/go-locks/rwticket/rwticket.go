@@ -0,0 +1,13 @@
+package rwticket
+
+type Lock struct{ state uint64 }
+
+func (l *Lock) Lock()    {}
+func (l *Lock) Unlock()  {}
+func (l *Lock) RLock()   {}
+func (l *Lock) RUnlock() {}
+
+type RWLocker interface {
+	RLock()
+	RUnlock()
+}
@@ -0,0 +1,8 @@
+package ticket
+
+type Lock struct{ head, tail uint32 }
+
+func NewLock() *Lock          { return &Lock{head: 1} }
+func (t *Lock) Lock()         {}
+func (t *Lock) Unlock()       {}
+func (t *Lock) TryLock() bool { return true }
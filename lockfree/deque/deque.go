@@ -0,0 +1,141 @@
+// Package deque provides the Chase–Lev work-stealing deque.
+//
+// One owner goroutine pushes and pops tasks at the bottom of the deque, LIFO, which
+// keeps its most recent and cache-warm work local. Any number of thief goroutines steal
+// from the top, FIFO, taking the oldest work. The owner's operations are plain loads
+// and stores except when the deque is down to its last element, where the owner and the
+// thieves settle the race with a CAS on top; thieves always use that CAS. The buffer is
+// circular and grows when full.
+//
+// Example usage:
+//
+//	d := deque.New[Task](64)
+//
+//	// Owner (worker) goroutine:
+//	d.Push(t)
+//	if t, ok := d.Pop(); ok {
+//	    run(t)
+//	}
+//
+//	// Other workers:
+//	if t, ok := d.Steal(); ok {
+//	    run(t)
+//	}
+//
+// Push and Pop must only be called by the owner.
+package deque
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/pad"
+)
+
+// ring is a circular buffer of element pointers; slots are atomic because a thief may
+// read a slot the owner is about to reuse, and then discard it when its CAS fails.
+type ring[T any] struct {
+	mask  int64
+	slots []atomic.Pointer[T]
+}
+
+func newRing[T any](size int64) *ring[T] {
+	return &ring[T]{mask: size - 1, slots: make([]atomic.Pointer[T], size)}
+}
+
+func (r *ring[T]) get(i int64) *T    { return r.slots[i&r.mask].Load() }
+func (r *ring[T]) put(i int64, v *T) { r.slots[i&r.mask].Store(v) }
+func (r *ring[T]) size() int64       { return r.mask + 1 }
+
+// grow returns a ring of twice the size holding the elements in [top, bottom).
+func (r *ring[T]) grow(top, bottom int64) *ring[T] {
+	g := newRing[T](2 * r.size())
+	for i := top; i < bottom; i++ {
+		g.put(i, r.get(i))
+	}
+	return g
+}
+
+// Deque is a Chase–Lev work-stealing deque. It must be created with New.
+type Deque[T any] struct {
+	top atomic.Int64 // Next position to steal from
+	_   pad.CacheLinePad
+
+	bottom atomic.Int64 // Next position to push to
+	buf    atomic.Pointer[ring[T]]
+	_      pad.CacheLinePad
+}
+
+// New creates a deque with room for at least capacity elements before it first grows.
+func New[T any](capacity int) *Deque[T] {
+	n := int64(1)
+	for n < int64(max(capacity, 1)) {
+		n <<= 1
+	}
+	d := new(Deque[T])
+	d.buf.Store(newRing[T](n))
+	return d
+}
+
+// Len returns the number of elements in the deque. The value is a snapshot and may be
+// stale by the time it is used.
+func (d *Deque[T]) Len() int {
+	b := d.bottom.Load()
+	t := d.top.Load()
+	return int(max(b-t, 0))
+}
+
+// Push adds v at the bottom of the deque. Owner only.
+func (d *Deque[T]) Push(v T) {
+	b := d.bottom.Load()
+	t := d.top.Load()
+	buf := d.buf.Load()
+	if b-t >= buf.size() {
+		buf = buf.grow(t, b)
+		d.buf.Store(buf)
+	}
+	buf.put(b, &v)
+	d.bottom.Store(b + 1) // Publishes the element to thieves
+}
+
+// Pop removes and returns the element at the bottom of the deque, the most recently
+// pushed one. Owner only.
+func (d *Deque[T]) Pop() (T, bool) {
+	var zero T
+	b := d.bottom.Load() - 1
+	buf := d.buf.Load()
+	d.bottom.Store(b) // Reserve the bottom element before looking at top
+	t := d.top.Load()
+
+	if t > b { // Empty
+		d.bottom.Store(b + 1)
+		return zero, false
+	}
+	v := buf.get(b)
+	if t == b {
+		// Last element: race the thieves for it.
+		won := d.top.CompareAndSwap(t, t+1)
+		d.bottom.Store(b + 1)
+		if !won {
+			return zero, false
+		}
+	}
+	return *v, true
+}
+
+// Steal removes and returns the element at the top of the deque, the least recently
+// pushed one. It may be called from any goroutine.
+func (d *Deque[T]) Steal() (T, bool) {
+	for {
+		t := d.top.Load()
+		b := d.bottom.Load()
+		if t >= b {
+			var zero T
+			return zero, false
+		}
+		v := d.buf.Load().get(t)
+		if d.top.CompareAndSwap(t, t+1) {
+			return *v, true
+		}
+		// Lost to another thief or the owner's last-element Pop; retry.
+	}
+}
@@ -0,0 +1,79 @@
+package deque
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnerLIFOThiefFIFO(t *testing.T) {
+	d := New[int](2)
+	for i := range 5 { // Grows past the initial capacity
+		d.Push(i)
+	}
+	assert.Equal(t, 5, d.Len())
+
+	v, ok := d.Steal()
+	assert.True(t, ok)
+	assert.Equal(t, 0, v, "Thieves take the oldest element")
+	v, ok = d.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, 4, v, "The owner takes the newest element")
+
+	for range 3 {
+		_, ok = d.Pop()
+		assert.True(t, ok)
+	}
+	_, ok = d.Pop()
+	assert.False(t, ok)
+	_, ok = d.Steal()
+	assert.False(t, ok)
+}
+
+func TestConcurrentSteal(t *testing.T) {
+	d := New[int](8)
+	const n = 5000
+	const numThieves = 4
+	var taken atomic.Int64
+	var sum atomic.Int64
+	var wg sync.WaitGroup
+
+	wg.Add(numThieves)
+	for range numThieves {
+		go func() {
+			defer wg.Done()
+			for taken.Load() < n {
+				v, ok := d.Steal()
+				if !ok {
+					runtime.Gosched() // Nothing to steal yet
+					continue
+				}
+				sum.Add(int64(v))
+				taken.Add(1)
+			}
+		}()
+	}
+
+	for i := 1; i <= n; i++ {
+		d.Push(i)
+		if i%3 == 0 { // The owner works through some of its own tasks
+			if v, ok := d.Pop(); ok {
+				sum.Add(int64(v))
+				taken.Add(1)
+			}
+		}
+	}
+	for taken.Load() < n {
+		if v, ok := d.Pop(); ok {
+			sum.Add(int64(v))
+			taken.Add(1)
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(n), taken.Load(), "Every element should be taken exactly once")
+	assert.Equal(t, int64(n*(n+1)/2), sum.Load())
+}
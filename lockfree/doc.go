@@ -0,0 +1,12 @@
+// Package lockfree is the root of a small tree of lock-free data structures built from
+// the same techniques as the locks in this module:
+//
+//   - mpmc: bounded multi-producer/multi-consumer queue with ticket-style slot claims
+//   - spsc: single-producer/single-consumer ring buffer with batch operations
+//   - deque: Chase–Lev work-stealing deque
+//   - msqueue: Michael–Scott unbounded queue
+//   - stack: Treiber stack with elimination backoff
+//
+// The structures never block in the runtime. Blocking variants of their operations,
+// where offered, wait with the module's spin-then-yield policy.
+package lockfree
@@ -0,0 +1,163 @@
+// Package mpmc provides a bounded multi-producer/multi-consumer queue after Dmitry
+// Vyukov's design.
+//
+// Producers and consumers claim slots with the ticket technique of ticket.Lock: an
+// enqueue and a dequeue counter hand out positions, and every cell carries a sequence
+// number that says whose turn it is. A producer at position p may fill the cell at
+// p % capacity once its sequence equals p, and publishes it by setting the sequence to
+// p+1; a consumer at position p may empty it once the sequence equals p+1, and hands it
+// to the producer of the next lap by setting it to p+capacity. Each operation is one
+// CAS on a counter plus plain accesses to its own cell, so producers and consumers
+// only contend with their own kind.
+//
+// Example usage:
+//
+//	q := mpmc.New[int](1024)
+//
+//	if !q.TryPush(42) {
+//	    // Queue full
+//	}
+//	v, ok := q.TryPop()
+//
+//	q.Push(43)     // Waits while full
+//	v = q.Pop()    // Waits while empty
+package mpmc
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/spin"
+)
+
+type cell[T any] struct {
+	seq atomic.Uint64
+	val T
+
+	_ pad.CacheLinePad
+}
+
+// Queue is a bounded MPMC queue. It must be created with New.
+type Queue[T any] struct {
+	_     pad.CacheLinePad
+	enq   atomic.Uint64 // Next enqueue position
+	_     pad.CacheLinePad
+	deq   atomic.Uint64 // Next dequeue position
+	_     pad.CacheLinePad
+	mask  uint64
+	cells []cell[T]
+}
+
+// New creates a queue holding at least capacity elements, rounded up to a power of two.
+func New[T any](capacity int) *Queue[T] {
+	n := uint64(1)
+	for n < uint64(max(capacity, 1)) {
+		n <<= 1
+	}
+	q := &Queue[T]{mask: n - 1, cells: make([]cell[T], n)}
+	for i := range q.cells {
+		q.cells[i].seq.Store(uint64(i))
+	}
+	return q
+}
+
+// Cap returns the capacity of the queue.
+func (q *Queue[T]) Cap() int { return len(q.cells) }
+
+// Len returns the number of elements in the queue. The value is a snapshot and may be
+// stale by the time it is used.
+func (q *Queue[T]) Len() int {
+	deq := q.deq.Load()
+	enq := q.enq.Load()
+	if enq < deq {
+		return 0 // Loaded deq after a concurrent dequeue overtook our enq
+	}
+	return int(min(enq-deq, uint64(len(q.cells))))
+}
+
+// TryPush appends v to the queue, returning false without waiting if it is full.
+func (q *Queue[T]) TryPush(v T) bool {
+	pos := q.enq.Load()
+	for {
+		c := &q.cells[pos&q.mask]
+		switch dif := int64(c.seq.Load() - pos); {
+		case dif == 0: // Cell free for this lap; claim the position
+			if q.enq.CompareAndSwap(pos, pos+1) {
+				c.val = v
+				c.seq.Store(pos + 1) // Publish to the consumer
+				return true
+			}
+			pos = q.enq.Load()
+		case dif < 0: // Consumer of the previous lap hasn't emptied it: full
+			return false
+		default: // Another producer claimed pos; catch up
+			pos = q.enq.Load()
+		}
+	}
+}
+
+// TryPop removes and returns the element at the front of the queue, returning false
+// without waiting if it is empty.
+func (q *Queue[T]) TryPop() (T, bool) {
+	pos := q.deq.Load()
+	for {
+		c := &q.cells[pos&q.mask]
+		switch dif := int64(c.seq.Load() - (pos + 1)); {
+		case dif == 0: // Cell published for this position; claim it
+			if q.deq.CompareAndSwap(pos, pos+1) {
+				v := c.val
+				var zero T
+				c.val = zero                  // Don't retain the element
+				c.seq.Store(pos + q.mask + 1) // Hand the cell to the next lap
+				return v, true
+			}
+			pos = q.deq.Load()
+		case dif < 0: // Producer hasn't published yet: empty
+			var zero T
+			return zero, false
+		default: // Another consumer claimed pos; catch up
+			pos = q.deq.Load()
+		}
+	}
+}
+
+// Push appends v to the queue, waiting while it is full.
+func (q *Queue[T]) Push(v T) {
+	wait(func() bool { return q.TryPush(v) })
+}
+
+// Pop removes and returns the element at the front of the queue, waiting while it is
+// empty.
+func (q *Queue[T]) Pop() T {
+	var v T
+	wait(func() bool {
+		var ok bool
+		v, ok = q.TryPop()
+		return ok
+	})
+	return v
+}
+
+// wait retries op until it succeeds, spinning briefly before yielding.
+func wait(op func() bool) {
+	spinLimit := 0 // No spinning if the other side can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	for i := 0; !op(); i++ {
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+}
@@ -0,0 +1,83 @@
+package mpmc
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTryPushPop(t *testing.T) {
+	q := New[int](3)
+	assert.Equal(t, 4, q.Cap())
+
+	for i := range 4 {
+		assert.True(t, q.TryPush(i))
+	}
+	assert.False(t, q.TryPush(4), "Push into a full queue should fail")
+	assert.Equal(t, 4, q.Len())
+
+	for i := range 4 {
+		v, ok := q.TryPop()
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+	_, ok := q.TryPop()
+	assert.False(t, ok, "Pop from an empty queue should fail")
+	assert.Zero(t, q.Len())
+}
+
+func TestConcurrentProducersConsumers(t *testing.T) {
+	q := New[int](16)
+	const numProducers = 4
+	const numConsumers = 4
+	const perProducer = 2000
+	var wg sync.WaitGroup
+	sums := make([]int, numConsumers)
+
+	wg.Add(numProducers + numConsumers)
+	for p := range numProducers {
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				q.Push(p*perProducer + i + 1)
+			}
+		}()
+	}
+	for c := range numConsumers {
+		go func() {
+			defer wg.Done()
+			for range numProducers * perProducer / numConsumers {
+				sums[c] += q.Pop()
+			}
+		}()
+	}
+	wg.Wait()
+
+	n := numProducers * perProducer
+	total := 0
+	for _, s := range sums {
+		total += s
+	}
+	assert.Equal(t, n*(n+1)/2, total, "Every element should be consumed exactly once")
+}
+
+func BenchmarkPushPop(b *testing.B) {
+	q := New[int](1024)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			q.Push(1)
+			q.Pop()
+		}
+	})
+}
+
+func BenchmarkChannel(b *testing.B) {
+	ch := make(chan int, 1024)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ch <- 1
+			<-ch
+		}
+	})
+}
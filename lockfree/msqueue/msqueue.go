@@ -0,0 +1,99 @@
+// Package msqueue provides the Michael–Scott unbounded lock-free FIFO queue.
+//
+// The queue is a singly linked list with a dummy node at the head. Producers link a new
+// node after the tail with a CAS and then swing the tail forward; consumers swing the
+// head forward with a CAS and take the value from the new head, which becomes the next
+// dummy. Any goroutine that finds the tail lagging behind helps advance it, so no
+// operation waits for another to finish.
+//
+// In C the algorithm needs a safe memory reclamation scheme, such as hazard pointers or
+// epochs, so that a node is not freed, or freed and reused, while a slow goroutine
+// still reads it; reuse is also what causes the ABA problem for its CASes. In Go the
+// garbage collector provides exactly that guarantee: a dequeued node stays valid for as
+// long as anyone references it, and a new node never has the address of one still
+// referenced. The queue therefore needs no separate reclamation.
+//
+// Example usage:
+//
+//	q := msqueue.New[string]()
+//
+//	q.Enqueue("a")
+//	v, ok := q.Dequeue()
+package msqueue
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/pad"
+)
+
+type node[T any] struct {
+	val  T
+	next atomic.Pointer[node[T]]
+}
+
+// Queue is an unbounded MPMC FIFO queue. It must be created with New.
+type Queue[T any] struct {
+	head atomic.Pointer[node[T]] // Dummy node; the front element is head.next
+	_    pad.CacheLinePad
+	tail atomic.Pointer[node[T]] // Last node, or lagging one behind it
+	_    pad.CacheLinePad
+}
+
+// New creates an empty queue.
+func New[T any]() *Queue[T] {
+	q := new(Queue[T])
+	dummy := new(node[T])
+	q.head.Store(dummy)
+	q.tail.Store(dummy)
+	return q
+}
+
+// Enqueue appends v to the queue.
+func (q *Queue[T]) Enqueue(v T) {
+	n := &node[T]{val: v}
+	for {
+		tail := q.tail.Load()
+		next := tail.next.Load()
+		if tail != q.tail.Load() {
+			continue // tail moved while we read next
+		}
+		if next != nil {
+			q.tail.CompareAndSwap(tail, next) // Help a lagging tail along
+			continue
+		}
+		if tail.next.CompareAndSwap(nil, n) {
+			q.tail.CompareAndSwap(tail, n) // Failure means someone helped already
+			return
+		}
+	}
+}
+
+// Dequeue removes and returns the element at the front of the queue, returning false
+// if it is empty.
+func (q *Queue[T]) Dequeue() (T, bool) {
+	for {
+		head := q.head.Load()
+		tail := q.tail.Load()
+		next := head.next.Load()
+		if head != q.head.Load() {
+			continue // head moved while we read next
+		}
+		if next == nil {
+			var zero T
+			return zero, false
+		}
+		if head == tail {
+			q.tail.CompareAndSwap(tail, next) // Tail is lagging; help before moving head past it
+			continue
+		}
+		v := next.val
+		if q.head.CompareAndSwap(head, next) {
+			return v, true
+		}
+	}
+}
+
+// Empty reports whether the queue is empty. The value is a snapshot and may be stale
+// by the time it is used.
+func (q *Queue[T]) Empty() bool { return q.head.Load().next.Load() == nil }
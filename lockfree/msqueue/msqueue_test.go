@@ -0,0 +1,84 @@
+package msqueue
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFIFO(t *testing.T) {
+	q := New[int]()
+	assert.True(t, q.Empty())
+	for i := range 3 {
+		q.Enqueue(i)
+	}
+	for i := range 3 {
+		v, ok := q.Dequeue()
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+	_, ok := q.Dequeue()
+	assert.False(t, ok)
+	assert.True(t, q.Empty())
+}
+
+func TestConcurrentEnqueueDequeue(t *testing.T) {
+	q := New[int]()
+	const numProducers = 4
+	const numConsumers = 4
+	const perProducer = 2000
+	const total = numProducers * perProducer
+	var taken, sum atomic.Int64
+	var wg sync.WaitGroup
+
+	wg.Add(numProducers + numConsumers)
+	for p := range numProducers {
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				q.Enqueue(p*perProducer + i + 1)
+			}
+		}()
+	}
+	for range numConsumers {
+		go func() {
+			defer wg.Done()
+			for taken.Load() < total {
+				if v, ok := q.Dequeue(); ok {
+					sum.Add(int64(v))
+					taken.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(total*(total+1)/2), sum.Load(), "Every element should be dequeued exactly once")
+	assert.True(t, q.Empty())
+}
+
+func TestPerProducerOrder(t *testing.T) {
+	q := New[[2]int]()
+	const numProducers = 4
+	const perProducer = 1000
+	var wg sync.WaitGroup
+
+	wg.Add(numProducers)
+	for p := range numProducers {
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				q.Enqueue([2]int{p, i})
+			}
+		}()
+	}
+	wg.Wait()
+
+	next := make([]int, numProducers)
+	for v, ok := q.Dequeue(); ok; v, ok = q.Dequeue() {
+		assert.Equal(t, next[v[0]], v[1], "Elements from one producer should stay in order")
+		next[v[0]]++
+	}
+}
@@ -0,0 +1,185 @@
+// Package spsc provides a lock-free single-producer/single-consumer ring buffer.
+//
+// With exactly one producer and one consumer, neither side needs a read-modify-write
+// instruction: the producer alone advances the tail and the consumer alone advances
+// the head, each publishing its progress with a single atomic store. Head and tail sit
+// on separate cache lines, and each side keeps a private cached copy of the other's
+// index, so in steady state the two only exchange cache lines when the cached copy runs
+// out. Batch operations move many elements per index update.
+//
+// Example usage:
+//
+//	r := spsc.New[int](1024)
+//
+//	// Producer goroutine:
+//	r.Push(1)
+//	n := r.PushBatch(items) // Pushes as many as fit
+//
+//	// Consumer goroutine:
+//	v := r.Pop()
+//	m := r.PopBatch(buf) // Pops up to len(buf)
+//
+// Using a Ring from more than one producer or more than one consumer corrupts it.
+package spsc
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// Ring is an SPSC ring buffer. It must be created with New.
+type Ring[T any] struct {
+	_ pad.CacheLinePad
+
+	// Consumer side.
+	head       atomic.Uint64 // Next position to pop
+	cachedTail uint64        // Consumer's last view of tail
+	_          pad.CacheLinePad
+
+	// Producer side.
+	tail       atomic.Uint64 // Next position to push
+	cachedHead uint64        // Producer's last view of head
+	_          pad.CacheLinePad
+
+	mask uint64
+	buf  []T
+}
+
+// New creates a ring holding at least capacity elements, rounded up to a power of two.
+func New[T any](capacity int) *Ring[T] {
+	n := uint64(1)
+	for n < uint64(max(capacity, 1)) {
+		n <<= 1
+	}
+	return &Ring[T]{mask: n - 1, buf: make([]T, n)}
+}
+
+// Cap returns the capacity of the ring.
+func (r *Ring[T]) Cap() int { return len(r.buf) }
+
+// Len returns the number of elements in the ring. The value is a snapshot and may be
+// stale by the time it is used.
+func (r *Ring[T]) Len() int {
+	head := r.head.Load()
+	return int(r.tail.Load() - head)
+}
+
+// free returns how many elements the producer can push, refreshing its view of head
+// only when the cached one shows fewer than want free.
+func (r *Ring[T]) free(tail uint64, want int) int {
+	n := uint64(len(r.buf))
+	if free := n - (tail - r.cachedHead); free >= uint64(want) {
+		return int(free)
+	}
+	r.cachedHead = r.head.Load()
+	return int(n - (tail - r.cachedHead))
+}
+
+// avail returns how many elements the consumer can pop, refreshing its view of tail
+// only when the cached one shows fewer than want available.
+func (r *Ring[T]) avail(head uint64, want int) int {
+	if avail := r.cachedTail - head; avail >= uint64(want) {
+		return int(avail)
+	}
+	r.cachedTail = r.tail.Load()
+	return int(r.cachedTail - head)
+}
+
+// TryPush appends v, returning false if the ring is full. Producer only.
+func (r *Ring[T]) TryPush(v T) bool {
+	tail := r.tail.Load()
+	if r.free(tail, 1) == 0 {
+		return false
+	}
+	r.buf[tail&r.mask] = v
+	r.tail.Store(tail + 1)
+	return true
+}
+
+// PushBatch appends as many leading elements of vs as fit and returns how many it
+// pushed. Producer only.
+func (r *Ring[T]) PushBatch(vs []T) int {
+	tail := r.tail.Load()
+	n := min(r.free(tail, len(vs)), len(vs))
+	for i := range n {
+		r.buf[(tail+uint64(i))&r.mask] = vs[i]
+	}
+	if n > 0 {
+		r.tail.Store(tail + uint64(n))
+	}
+	return n
+}
+
+// TryPop removes and returns the oldest element, returning false if the ring is empty.
+// Consumer only.
+func (r *Ring[T]) TryPop() (T, bool) {
+	var zero T
+	head := r.head.Load()
+	if r.avail(head, 1) == 0 {
+		return zero, false
+	}
+	v := r.buf[head&r.mask]
+	r.buf[head&r.mask] = zero // Don't retain the element
+	r.head.Store(head + 1)
+	return v, true
+}
+
+// PopBatch removes up to len(buf) of the oldest elements into buf and returns how many
+// it popped. Consumer only.
+func (r *Ring[T]) PopBatch(buf []T) int {
+	var zero T
+	head := r.head.Load()
+	n := min(r.avail(head, len(buf)), len(buf))
+	for i := range n {
+		j := (head + uint64(i)) & r.mask
+		buf[i] = r.buf[j]
+		r.buf[j] = zero
+	}
+	if n > 0 {
+		r.head.Store(head + uint64(n))
+	}
+	return n
+}
+
+// Push appends v, waiting while the ring is full. Producer only.
+func (r *Ring[T]) Push(v T) {
+	wait(func() bool { return r.TryPush(v) })
+}
+
+// Pop removes and returns the oldest element, waiting while the ring is empty.
+// Consumer only.
+func (r *Ring[T]) Pop() T {
+	var v T
+	wait(func() bool {
+		var ok bool
+		v, ok = r.TryPop()
+		return ok
+	})
+	return v
+}
+
+// wait retries op until it succeeds, spinning briefly before yielding.
+func wait(op func() bool) {
+	spinLimit := 0 // No spinning if the other side can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	for i := 0; !op(); i++ {
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+}
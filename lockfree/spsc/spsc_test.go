@@ -0,0 +1,85 @@
+package spsc
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushPop(t *testing.T) {
+	r := New[int](4)
+	for i := range 4 {
+		assert.True(t, r.TryPush(i))
+	}
+	assert.False(t, r.TryPush(4), "Push into a full ring should fail")
+	assert.Equal(t, 4, r.Len())
+
+	for i := range 4 {
+		v, ok := r.TryPop()
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+	_, ok := r.TryPop()
+	assert.False(t, ok, "Pop from an empty ring should fail")
+}
+
+func TestBatch(t *testing.T) {
+	r := New[int](4)
+	assert.Equal(t, 4, r.PushBatch([]int{1, 2, 3, 4, 5}), "Only as many as fit are pushed")
+
+	buf := make([]int, 3)
+	assert.Equal(t, 3, r.PopBatch(buf))
+	assert.Equal(t, []int{1, 2, 3}, buf)
+
+	assert.Equal(t, 2, r.PushBatch([]int{6, 7})) // Wraps around
+	assert.Equal(t, 3, r.PopBatch(buf))
+	assert.Equal(t, []int{4, 6, 7}, buf)
+	assert.Zero(t, r.PopBatch(buf))
+}
+
+func TestProducerConsumer(t *testing.T) {
+	r := New[int](8)
+	const n = 20000
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		batch := make([]int, 0, 5)
+		for i := 0; i < n; {
+			batch = batch[:0]
+			for j := 0; j < 5 && i+j < n; j++ {
+				batch = append(batch, i+j)
+			}
+			pushed := 0
+			for pushed < len(batch) {
+				if n := r.PushBatch(batch[pushed:]); n > 0 {
+					pushed += n
+					continue
+				}
+				runtime.Gosched() // Full; let the consumer catch up
+			}
+			i += len(batch)
+		}
+	}()
+
+	for i := range n {
+		assert.Equal(t, i, r.Pop(), "Elements should arrive in order")
+	}
+	<-done
+}
+
+func BenchmarkPushPop(b *testing.B) {
+	r := New[int](1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range b.N {
+			r.Pop()
+		}
+	}()
+	for i := range b.N {
+		r.Push(i)
+	}
+	<-done
+}
@@ -0,0 +1,140 @@
+// Package stack provides a Treiber lock-free stack with an elimination backoff array.
+//
+// The stack itself is a linked list whose top is swung with a CAS. Under contention
+// every operation fights for that one word, so an operation whose CAS fails backs off
+// into an elimination array instead of retrying straight away: it parks an offer in a
+// random slot for a short while, and a push and a pop that meet in the same slot cancel
+// out, the pop taking the pushed value without either touching the list. A pair of
+// operations eliminated this way is linearizable as the push immediately followed by
+// the pop. An offer that finds no partner is withdrawn and the operation goes back to
+// the list.
+//
+// Example usage:
+//
+//	s := stack.New[int]()
+//
+//	s.Push(1)
+//	v, ok := s.Pop()
+package stack
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// elimWait is how many times a parked offer is polled before it is withdrawn.
+const elimWait = 64
+
+type node[T any] struct {
+	val  T
+	next *node[T]
+}
+
+// offer is an operation parked in an elimination slot. A pop offer's val is filled in
+// by the push that takes it, before done is set.
+type offer[T any] struct {
+	push bool
+	val  T
+	done atomic.Bool
+}
+
+type slot[T any] struct {
+	o atomic.Pointer[offer[T]]
+	_ pad.CacheLinePad
+}
+
+// Stack is a lock-free LIFO stack. It must be created with New.
+type Stack[T any] struct {
+	top  atomic.Pointer[node[T]]
+	_    pad.CacheLinePad
+	elim []slot[T]
+}
+
+// New creates an empty stack with an elimination array sized for GOMAXPROCS.
+func New[T any]() *Stack[T] {
+	return &Stack[T]{elim: make([]slot[T], max(runtime.GOMAXPROCS(0)/2, 1))}
+}
+
+// Push adds v to the top of the stack.
+func (s *Stack[T]) Push(v T) {
+	n := &node[T]{val: v}
+	for {
+		top := s.top.Load()
+		n.next = top
+		if s.top.CompareAndSwap(top, n) {
+			return
+		}
+		if _, ok := s.eliminate(&offer[T]{push: true, val: v}); ok {
+			return
+		}
+	}
+}
+
+// Pop removes and returns the top of the stack, returning false if it is empty.
+func (s *Stack[T]) Pop() (T, bool) {
+	for {
+		top := s.top.Load()
+		if top == nil {
+			var zero T
+			return zero, false
+		}
+		if s.top.CompareAndSwap(top, top.next) {
+			return top.val, true
+		}
+		if v, ok := s.eliminate(&offer[T]{}); ok {
+			return v, true
+		}
+	}
+}
+
+// Empty reports whether the stack is empty. The value is a snapshot and may be stale
+// by the time it is used.
+func (s *Stack[T]) Empty() bool { return s.top.Load() == nil }
+
+// eliminate tries to pair my with an opposite operation in a random slot. It reports
+// whether it succeeded; for a pop the popped value is returned as well.
+func (s *Stack[T]) eliminate(my *offer[T]) (T, bool) {
+	var zero T
+	sl := &s.elim[rand.IntN(len(s.elim))]
+
+	// Take a waiting partner if there is one.
+	if o := sl.o.Load(); o != nil {
+		if o.push == my.push || !sl.o.CompareAndSwap(o, nil) {
+			return zero, false
+		}
+		if my.push {
+			o.val = my.val
+		}
+		o.done.Store(true)
+		return o.val, true
+	}
+
+	// Otherwise park and wait for one.
+	if !sl.o.CompareAndSwap(nil, my) {
+		return zero, false
+	}
+	canSpin := spin.CanSpin()
+	for range elimWait {
+		if my.done.Load() {
+			return my.val, true
+		}
+		if canSpin {
+			archspin.Relax()
+		} else {
+			spin.Yield()
+		}
+	}
+	if sl.o.CompareAndSwap(my, nil) {
+		return zero, false // Withdrawn
+	}
+	// A partner removed the offer just now; it completes it shortly.
+	for !my.done.Load() {
+		spin.Yield()
+	}
+	return my.val, true
+}
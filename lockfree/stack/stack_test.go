@@ -0,0 +1,75 @@
+package stack
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLIFO(t *testing.T) {
+	s := New[int]()
+	assert.True(t, s.Empty())
+	for i := range 3 {
+		s.Push(i)
+	}
+	for i := 2; i >= 0; i-- {
+		v, ok := s.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+	_, ok := s.Pop()
+	assert.False(t, ok)
+}
+
+func TestEliminationPairsOperations(t *testing.T) {
+	s := New[int]()
+	s.elim = make([]slot[int], 1) // One slot so the two offers must meet
+
+	done := make(chan int)
+	go func() {
+		v, ok := s.eliminate(&offer[int]{})
+		assert.True(t, ok)
+		done <- v
+	}()
+	for {
+		if _, ok := s.eliminate(&offer[int]{push: true, val: 7}); ok {
+			break
+		}
+	}
+	assert.Equal(t, 7, <-done, "The pop should receive the pushed value")
+	assert.True(t, s.Empty(), "Eliminated operations should not touch the list")
+}
+
+func TestConcurrentPushPop(t *testing.T) {
+	s := New[int]()
+	const numWorkers = 8
+	const perWorker = 2000
+	const total = numWorkers * perWorker
+	var popped, sum atomic.Int64
+	var wg sync.WaitGroup
+
+	wg.Add(2 * numWorkers)
+	for w := range numWorkers {
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				s.Push(w*perWorker + i + 1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for popped.Load() < total {
+				if v, ok := s.Pop(); ok {
+					sum.Add(int64(v))
+					popped.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(total*(total+1)/2), sum.Load(), "Every element should be popped exactly once")
+	assert.True(t, s.Empty())
+}
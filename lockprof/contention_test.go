@@ -0,0 +1,76 @@
+package lockprof_test
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/alock"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestContendedLocksAreRecorded(t *testing.T) {
+	tests := []struct {
+		name     string
+		newLock  func() sync.Locker
+		function string // Function expected at the top of the recorded stack
+	}{
+		{"ticket", func() sync.Locker { return ticket.NewLock() }, "ticket.(*Lock).Lock"},
+		{"alock", func() sync.Locker { return alock.NewArrayLock(2) }, "alock.(*ArrayLock).Lock"},
+		{"mcs", func() sync.Locker { return mcs.NewLocker() }, "mcs.(*Lock).Lock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := lockprof.SetProfileFraction(1)
+			defer lockprof.SetProfileFraction(prev)
+			lockprof.Reset()
+			defer lockprof.Reset()
+
+			lock := tt.newLock()
+			lock.Lock()
+
+			done := make(chan struct{})
+			go func() {
+				lock.Lock() // Blocks until the holder releases
+				lock.Unlock()
+				close(done)
+			}()
+
+			time.Sleep(20 * time.Millisecond) // Let the waiter enter the slow path
+			lock.Unlock()
+			<-done
+
+			var buf bytes.Buffer
+			assert.NoError(t, lockprof.WriteTo(&buf))
+			assert.True(t, topFunctions(t, buf.String())[tt.function],
+				"Expected a contention sample in %s, got:\n%s", tt.function, buf.String())
+		})
+	}
+}
+
+// topFunctions returns the names of the innermost function of every sample in profile.
+func topFunctions(t *testing.T, profile string) map[string]bool {
+	funcs := make(map[string]bool)
+	for _, line := range strings.Split(profile, "\n") {
+		_, stack, ok := strings.Cut(line, " @ ")
+		if !ok {
+			continue
+		}
+		pc, err := strconv.ParseUint(strings.Fields(stack)[0], 0, 64)
+		assert.NoError(t, err)
+		if fn := runtime.FuncForPC(uintptr(pc) - 1); fn != nil {
+			name := fn.Name()
+			funcs[name[strings.LastIndex(name, "/")+1:]] = true
+		}
+	}
+	return funcs
+}
@@ -0,0 +1,133 @@
+package lockprof
+
+import (
+	"bufio"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	modulePrefix = "github.com/ahrav/go-locks/"
+	observeFrame = modulePrefix + "observe.(*Locker).lockContended" // Waits observe sampled
+)
+
+// WriteFolded writes the recorded contention events to w as folded stacks, one line
+// per distinct stack: the frames from the root of the stack down to the lock's slow
+// path, separated by semicolons, followed by the estimated nanoseconds spent waiting
+// there. Each stack's first frame is the lock that was waited for, so that a flame
+// graph drawn from the output, for example with flamegraph.pl or speedscope, groups the
+// code waiting on each lock under its name:
+//
+//	accounts;main.main;main.transfer;github.com/ahrav/go-locks/observe.(*Locker).Lock 1200000
+//
+// Waits recorded by a lock's own slow path are left out when an observe.Locker saw them
+// coming and recorded them itself, under its name or, if it has none, its lock's type.
+func WriteFolded(w io.Writer) error {
+	mu.Lock()
+	samples := make(map[namedKey]int64, len(records)+len(named)) // Unnamed under ""
+	for stk, rec := range records {
+		samples[namedKey{stk: stk}] += rec.delay
+	}
+	for k, rec := range named {
+		samples[k] += rec.delay
+	}
+	mu.Unlock()
+
+	totals := make(map[string]int64) // Folded stack to delay
+	period := max(rate.Load(), 1)
+	for k, delay := range samples {
+		frames := symbolize(k.stk[:])
+		if len(frames) == 0 {
+			continue
+		}
+		root := k.name
+		if root == "" {
+			if anyPrefixed(frames, observeFrame) {
+				continue
+			}
+			root = lockType(frames[len(frames)-1])
+		}
+		totals[fold(root, frames)] += delay * period
+	}
+
+	stacks := make([]string, 0, len(totals))
+	for s := range totals {
+		stacks = append(stacks, s)
+	}
+	sort.Strings(stacks)
+
+	bw := bufio.NewWriter(w)
+	for _, s := range stacks {
+		bw.WriteString(s)
+		bw.WriteByte(' ')
+		bw.WriteString(strconv.FormatInt(totals[s], 10))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// symbolize returns the functions of the zero-terminated stack stk, root first.
+func symbolize(stk []uintptr) []string {
+	n := 0
+	for n < len(stk) && stk[n] != 0 {
+		n++
+	}
+	var funcs []string
+	frames := runtime.CallersFrames(stk[:n])
+	for {
+		f, more := frames.Next()
+		if f.Function != "" && f.Function != "runtime.goexit" {
+			funcs = append(funcs, f.Function)
+		}
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(funcs)-1; i < j; i, j = i+1, j-1 {
+		funcs[i], funcs[j] = funcs[j], funcs[i]
+	}
+	return funcs
+}
+
+// anyPrefixed reports whether any of funcs starts with prefix.
+func anyPrefixed(funcs []string, prefix string) bool {
+	for _, f := range funcs {
+		if strings.HasPrefix(f, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fold joins root and frames into a folded stack. Semicolons and spaces, which delimit
+// the format, are replaced in the root.
+func fold(root string, frames []string) string {
+	root = strings.NewReplacer(";", "_", " ", "_").Replace(root)
+	return root + ";" + strings.Join(frames, ";")
+}
+
+// lockType names the lock a slow path function belongs to, such as "ticket.Lock" for
+// "github.com/ahrav/go-locks/ticket.(*Lock).Lock", or returns fn itself if it is not a
+// method of this module.
+func lockType(fn string) string {
+	name, ok := strings.CutPrefix(fn, modulePrefix)
+	if !ok {
+		return fn
+	}
+	pkg, rest, ok := strings.Cut(name, ".")
+	if !ok {
+		return fn
+	}
+	recv, _, ok := strings.Cut(rest, ".")
+	if !ok {
+		return fn
+	}
+	recv = strings.TrimSuffix(strings.TrimPrefix(recv, "(*"), ")")
+	if i := strings.IndexByte(recv, '['); i >= 0 {
+		recv = recv[:i]
+	}
+	return pkg + "." + recv
+}
@@ -0,0 +1,61 @@
+package lockprof_test
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/observe"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// contend makes a goroutine wait for l once.
+func contend(l sync.Locker) {
+	l.Lock()
+	done := make(chan struct{})
+	go func() {
+		l.Lock()
+		l.Unlock()
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	l.Unlock()
+	<-done
+}
+
+func TestWriteFoldedRootsStacksAtLocks(t *testing.T) {
+	prev := lockprof.SetProfileFraction(1)
+	defer lockprof.SetProfileFraction(prev)
+	lockprof.Reset()
+	defer lockprof.Reset()
+
+	contend(ticket.NewLock())
+	contend(observe.Wrap(ticket.NewLock(), observe.NopObserver{}, observe.WithName("accounts; main")))
+	contend(observe.Wrap(ticket.NewLock(), observe.NopObserver{}))
+
+	var buf bytes.Buffer
+	assert.NoError(t, lockprof.WriteFolded(&buf))
+	roots := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		stack, value, ok := strings.Cut(line, " ")
+		if !assert.True(t, ok, line) {
+			continue
+		}
+		frames := strings.Split(stack, ";")
+		roots[frames[0]]++
+		ns, err := strconv.ParseInt(value, 10, 64)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, ns, int64(10*time.Millisecond), line)
+		assert.Contains(t, frames, "github.com/ahrav/go-locks/lockprof_test.contend.func1", "Waiter's frames: %s", line)
+	}
+	// The named lock's own slow path is folded under its name, not counted twice, while
+	// the unnamed one's stays under its type
+	assert.Equal(t, map[string]int{"ticket.Lock": 2, "accounts__main": 1}, roots, buf.String())
+	assert.Contains(t, buf.String(), "ticket.Lock;github.com/ahrav/go-locks/lockprof_test.contend.func1;github.com/ahrav/go-locks/observe.(*Locker).Lock ")
+}
@@ -0,0 +1,188 @@
+// Package lockprof records contention on the locks in this module so that it can be
+// inspected with the standard pprof tooling.
+//
+// The Go runtime only feeds its mutex and block profiles from sync.Mutex, sync.RWMutex,
+// channels and friends; the profiling hooks it uses are not reachable from user code.
+// lockprof is a surrogate for those hooks: every lock in this module reports the time a
+// goroutine spent waiting on its slow path here, keyed by the waiter's call stack, and
+// WriteTo renders the result in the legacy text contention format understood by
+// `go tool pprof`.
+//
+// Example usage:
+//
+//	lockprof.SetProfileFraction(5) // Sample roughly 1 in 5 contention events
+//
+//	// ... run the workload ...
+//
+//	f, _ := os.Create("locks.prof")
+//	lockprof.WriteTo(f)
+//	f.Close()
+//
+//	// $ go tool pprof locks.prof
+//
+// Like runtime.SetMutexProfileFraction, profiling is disabled by default and costs a
+// single atomic load per contended acquisition while disabled.
+//
+// WriteFolded renders the same samples as folded stacks for flame graph tools, rooted
+// at the lock that was waited for: its name for locks wrapped by a named
+// observe.Locker, such as those in the registry, and its type otherwise.
+package lockprof
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const maxStack = 32
+
+// rate is the sampling fraction; on average 1/rate contention events are recorded.
+// A rate of 0 disables profiling.
+var rate atomic.Int64
+
+// epoch anchors the monotonic timestamps returned by Start.
+var epoch = time.Now()
+
+type record struct {
+	count int64 // Number of sampled contention events
+	delay int64 // Total nanoseconds spent waiting
+}
+
+var (
+	mu      sync.Mutex
+	records = make(map[[maxStack]uintptr]*record)
+	named   = make(map[namedKey]*record) // Samples from RecordNamed
+)
+
+type namedKey struct {
+	name string
+	stk  [maxStack]uintptr
+}
+
+// SetProfileFraction controls the fraction of contention events that are reported in
+// the profile. On average 1/r events are reported. The previous rate is returned.
+//
+// To turn off profiling entirely, pass r = 0. To just read the current rate, pass r < 0.
+func SetProfileFraction(r int) int {
+	if r < 0 {
+		return int(rate.Load())
+	}
+	return int(rate.Swap(int64(r)))
+}
+
+// Start is called by a lock when a goroutine enters its contended slow path. It returns
+// a non-zero timestamp if the event was selected for sampling, and zero otherwise.
+// The result must be handed to Record once the lock has been acquired.
+func Start() int64 {
+	r := rate.Load()
+	if r <= 0 {
+		return 0
+	}
+	if r > 1 && rand.Int64N(r) != 0 {
+		return 0
+	}
+	return int64(time.Since(epoch)) + 1 // Never return 0 for a sampled event
+}
+
+// Record attributes the time elapsed since start to the call stack of the waiting
+// goroutine. skip is the number of stack frames to omit, with 0 identifying the caller
+// of Record. Record is a no-op when start is zero.
+func Record(start int64, skip int) {
+	if start == 0 {
+		return
+	}
+	delay := int64(time.Since(epoch)) + 1 - start
+	if delay < 0 {
+		delay = 0
+	}
+
+	var stk [maxStack]uintptr
+	runtime.Callers(skip+2, stk[:])
+
+	mu.Lock()
+	rec, ok := records[stk]
+	if !ok {
+		rec = new(record)
+		records[stk] = rec
+	}
+	rec.count++
+	rec.delay += delay
+	mu.Unlock()
+}
+
+// RecordNamed is Record for a wait on a lock called name. Its samples appear in
+// WriteFolded only, under name; WriteTo leaves them out, since the lock's own slow path
+// records the same waits.
+func RecordNamed(start int64, skip int, name string) {
+	if start == 0 {
+		return
+	}
+	delay := max(int64(time.Since(epoch))+1-start, 0)
+
+	k := namedKey{name: name}
+	runtime.Callers(skip+2, k.stk[:])
+
+	mu.Lock()
+	rec, ok := named[k]
+	if !ok {
+		rec = new(record)
+		named[k] = rec
+	}
+	rec.count++
+	rec.delay += delay
+	mu.Unlock()
+}
+
+// Reset discards all recorded contention events.
+func Reset() {
+	mu.Lock()
+	clear(records)
+	clear(named)
+	mu.Unlock()
+}
+
+// WriteTo writes the recorded contention events to w in the legacy text contention
+// format, which `go tool pprof` reads directly.
+func WriteTo(w io.Writer) error {
+	type entry struct {
+		stk []uintptr
+		rec record
+	}
+
+	mu.Lock()
+	entries := make([]entry, 0, len(records))
+	for stk, rec := range records {
+		n := 0
+		for n < len(stk) && stk[n] != 0 {
+			n++
+		}
+		entries = append(entries, entry{stk: append([]uintptr(nil), stk[:n]...), rec: *rec})
+	}
+	mu.Unlock()
+
+	// Hottest stacks first, matching the runtime's own profiles.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].rec.delay > entries[j].rec.delay })
+
+	period := rate.Load()
+	if period <= 0 {
+		period = 1
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "--- contention:\ncycles/second=%d\nsampling period=%d\n", time.Second.Nanoseconds(), period)
+	for _, e := range entries {
+		// Values are written unscaled; pprof multiplies them by the sampling period.
+		fmt.Fprintf(bw, "%d %d @", e.rec.delay, e.rec.count)
+		for _, pc := range e.stk {
+			fmt.Fprintf(bw, " %#x", pc)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,50 @@
+package lockprof
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisabledByDefault(t *testing.T) {
+	assert.Equal(t, 0, SetProfileFraction(-1))
+	assert.Zero(t, Start(), "Start should not sample while profiling is disabled")
+}
+
+func TestRecordAndWrite(t *testing.T) {
+	prev := SetProfileFraction(1)
+	defer SetProfileFraction(prev)
+	defer Reset()
+
+	for range 3 {
+		start := Start()
+		assert.NotZero(t, start, "Every event should be sampled with a fraction of 1")
+		time.Sleep(time.Millisecond)
+		Record(start, 0)
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteTo(&buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, "--- contention:", lines[0])
+	assert.Equal(t, "cycles/second=1000000000", lines[1])
+	assert.Equal(t, "sampling period=1", lines[2])
+	assert.Len(t, lines, 4, "Events from the same call site should be aggregated: %s", buf.String())
+	assert.Regexp(t, `^[0-9]+ 3 @( 0x[0-9a-f]+)+$`, lines[3])
+}
+
+func TestReset(t *testing.T) {
+	prev := SetProfileFraction(1)
+	defer SetProfileFraction(prev)
+
+	Record(Start(), 0)
+	Reset()
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteTo(&buf))
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"), "Only the header should remain after Reset")
+}
@@ -0,0 +1,12 @@
+// Package locks provides type-safe building blocks on top of the lock algorithms
+// implemented in this module's subpackages (ticket, mcs, alock, ...).
+//
+// The subpackages expose each algorithm in its raw form. The types in this package tie
+// data to the lock that guards it, so that the "forgot to lock" class of bugs cannot be
+// expressed:
+//
+//	counter := locks.NewMutex(0, ticket.NewLock())
+//
+//	counter.With(func(n *int) { *n++ })
+//	n := locks.Get(counter, func(n int) int { return n })
+package locks
@@ -0,0 +1,72 @@
+package locksync
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// States of a hybrid lock, as in Drepper's "Futexes Are Tricky".
+const (
+	unlocked  = iota
+	locked    // Held, nobody parked
+	contended // Held, and someone may be parked
+)
+
+// hybrid spins for the lock, then parks on a channel that Unlock signals when a waiter
+// may be parked. The channel holds at most one token, playing the part of a futex: a
+// wakeup sent before its waiter parks is kept for it rather than lost.
+type hybrid struct {
+	state atomic.Int32
+	wake  chan struct{}
+}
+
+func newHybrid() *hybrid { return &hybrid{wake: make(chan struct{}, 1)} }
+
+func (h *hybrid) Lock() {
+	if h.state.CompareAndSwap(unlocked, locked) {
+		return
+	}
+	h.lockSlow()
+}
+
+func (h *hybrid) lockSlow() {
+	start := lockprof.Start()
+	if spin.CanSpin() && spin.Reserve() {
+		for range spin.Current().Spin {
+			chaos.Point()
+			archspin.Relax()
+			if h.state.Load() == unlocked && h.state.CompareAndSwap(unlocked, locked) {
+				spin.Release()
+				lockprof.Record(start, 1)
+				return
+			}
+		}
+		spin.Release()
+	}
+	// Taking the lock as contended may cost a needless wakeup later, but never loses one
+	for h.state.Swap(contended) != unlocked {
+		<-h.wake
+	}
+	lockprof.Record(start, 1)
+}
+
+func (h *hybrid) TryLock() bool { return h.state.CompareAndSwap(unlocked, locked) }
+
+func (h *hybrid) Unlock() {
+	old := h.state.Swap(unlocked)
+	if invariant.Enabled {
+		invariant.Check(old != unlocked, "locksync: Unlock of unlocked Mutex")
+	}
+	chaos.Point()
+	if old == contended {
+		select {
+		case h.wake <- struct{}{}:
+		default: // A wakeup is already pending
+		}
+	}
+}
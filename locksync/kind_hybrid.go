@@ -0,0 +1,5 @@
+//go:build lockshybrid
+
+package locksync
+
+const defaultKind = Hybrid
@@ -0,0 +1,5 @@
+//go:build locksmcs
+
+package locksync
+
+const defaultKind = MCS
@@ -0,0 +1,5 @@
+//go:build locksstd
+
+package locksync
+
+const defaultKind = Std
@@ -0,0 +1,5 @@
+//go:build !locksstd && !locksmcs && !lockshybrid
+
+package locksync
+
+const defaultKind = Ticket
@@ -0,0 +1,123 @@
+// Package locksync is a drop-in replacement for the standard sync package whose Mutex
+// is backed by one of this module's locks, so that fair locking can be trialled across a
+// codebase by changing imports alone:
+//
+//	import sync "github.com/ahrav/go-locks/locksync"
+//
+// Mutex and RWMutex have exactly the method sets of their sync counterparts, and their
+// zero values are unlocked. RWMutex is always a fair rwticket.Lock. The other sync types
+// and functions are re-exported unchanged, so code using WaitGroup, Once, Pool and the
+// rest keeps compiling.
+//
+// The lock behind a Mutex is chosen from these Kinds:
+//   - Ticket: a ticket.Lock, strictly FIFO; the default
+//   - MCS: an MCS queue lock, FIFO with each waiter spinning on a line of its own
+//   - Hybrid: spins briefly, then parks on a channel; not FIFO, but never yields in a loop
+//   - Std: a sync.Mutex, to switch the trial off without touching imports again
+//
+// Pick one at build time with the locksstd, locksmcs or lockshybrid tag (at most one),
+// or at startup with SetKind. A Mutex binds to the Kind in effect when it is first
+// locked and keeps it, so SetKind should run before any Mutex is used, typically from an
+// init function of package main.
+//
+// Example usage:
+//
+//	func init() { locksync.SetKind(locksync.MCS) }
+//
+//	var mu sync.Mutex // With sync being this package
+//	mu.Lock()
+//	// ... critical section ...
+//	mu.Unlock()
+//
+// Binding on first use costs an allocation per Mutex and an extra pointer load per
+// operation compared to the backing lock used directly.
+package locksync
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Kind selects the lock behind a Mutex.
+type Kind uint32
+
+const (
+	Ticket Kind = iota // ticket.Lock
+	MCS                // mcs.Locker
+	Hybrid             // Spin, then park
+	Std                // sync.Mutex
+)
+
+var kind atomic.Uint32
+
+func init() { kind.Store(uint32(defaultKind)) }
+
+// SetKind selects the lock behind Mutexes that are first locked from now on, and returns
+// the previous Kind.
+func SetKind(k Kind) Kind { return Kind(kind.Swap(uint32(k))) }
+
+// CurrentKind returns the Kind that newly used Mutexes bind to.
+func CurrentKind() Kind { return Kind(kind.Load()) }
+
+type tryLocker interface {
+	sync.Locker
+	TryLock() bool
+}
+
+// backend holds the lock a Mutex is bound to.
+type backend struct{ l tryLocker }
+
+// slab supplies the nodes of every MCS-backed Mutex, which would otherwise each need a
+// slab of their own.
+var slab = sync.OnceValue(func() *mcs.Slab { return mcs.NewSlab(4 * runtime.GOMAXPROCS(0)) })
+
+func newBackend(k Kind) *backend {
+	switch k {
+	case MCS:
+		return &backend{mcs.NewLocker(mcs.WithSlab(slab()))}
+	case Hybrid:
+		return &backend{newHybrid()}
+	case Std:
+		return &backend{new(sync.Mutex)}
+	default:
+		return &backend{ticket.NewLock()}
+	}
+}
+
+// Mutex is a mutual exclusion lock with the API of sync.Mutex. The zero value is an
+// unlocked mutex. A Mutex must not be copied after first use.
+type Mutex struct {
+	b atomic.Pointer[backend]
+}
+
+// lock returns the lock m is bound to, binding it on first use.
+func (m *Mutex) lock() tryLocker {
+	if b := m.b.Load(); b != nil {
+		return b.l
+	}
+	b := newBackend(CurrentKind())
+	if !m.b.CompareAndSwap(nil, b) {
+		b = m.b.Load()
+	}
+	return b.l
+}
+
+// Lock locks m, blocking until it is available.
+func (m *Mutex) Lock() { m.lock().Lock() }
+
+// TryLock tries to lock m and reports whether it succeeded.
+func (m *Mutex) TryLock() bool { return m.lock().TryLock() }
+
+// Unlock unlocks m. It panics if m was never locked. As with sync.Mutex, a locked Mutex
+// is not associated with a goroutine, and may be unlocked by another one.
+func (m *Mutex) Unlock() {
+	b := m.b.Load()
+	if b == nil {
+		panic("locksync: Unlock of unlocked Mutex")
+	}
+	b.l.Unlock()
+}
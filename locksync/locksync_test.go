@@ -0,0 +1,106 @@
+package locksync
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var kinds = []struct {
+	name string
+	kind Kind
+}{
+	{"Ticket", Ticket},
+	{"MCS", MCS},
+	{"Hybrid", Hybrid},
+	{"Std", Std},
+}
+
+// withKind runs fn with k selected, restoring the previous Kind afterwards.
+func withKind(t *testing.T, k Kind, fn func(t *testing.T)) {
+	prev := SetKind(k)
+	defer SetKind(prev)
+	fn(t)
+}
+
+func TestMutualExclusion(t *testing.T) {
+	const numGoroutines = 8
+	const iterations = 200
+	for _, tc := range kinds {
+		t.Run(tc.name, func(t *testing.T) {
+			withKind(t, tc.kind, func(t *testing.T) {
+				var mu Mutex
+				var wg sync.WaitGroup
+				counter := 0
+
+				wg.Add(numGoroutines)
+				for range numGoroutines {
+					go func() {
+						defer wg.Done()
+						for range iterations {
+							mu.Lock()
+							counter++
+							mu.Unlock()
+						}
+					}()
+				}
+				wg.Wait()
+				assert.Equal(t, numGoroutines*iterations, counter)
+			})
+		})
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	for _, tc := range kinds {
+		t.Run(tc.name, func(t *testing.T) {
+			withKind(t, tc.kind, func(t *testing.T) {
+				var mu Mutex
+				assert.True(t, mu.TryLock())
+				assert.False(t, mu.TryLock())
+
+				done := make(chan struct{})
+				go func() { // Unlocked by another goroutine, as sync.Mutex allows
+					mu.Unlock()
+					close(done)
+				}()
+				<-done
+				mu.Lock()
+				mu.Unlock()
+			})
+		})
+	}
+}
+
+func TestMutexKeepsKindOfFirstUse(t *testing.T) {
+	withKind(t, Std, func(t *testing.T) {
+		var mu Mutex
+		mu.Lock()
+		SetKind(Ticket)
+		assert.False(t, mu.TryLock())
+		mu.Unlock()
+		assert.IsType(t, (*sync.Mutex)(nil), mu.b.Load().l)
+	})
+}
+
+func TestUnlockOfUnusedMutexPanics(t *testing.T) {
+	var mu Mutex
+	assert.PanicsWithValue(t, "locksync: Unlock of unlocked Mutex", mu.Unlock)
+}
+
+func TestReexportsSync(t *testing.T) {
+	var once Once
+	var wg WaitGroup
+	n := 0
+	wg.Add(2)
+	for range 2 {
+		go func() {
+			defer wg.Done()
+			once.Do(func() { n++ })
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 7, OnceValue(func() int { return 7 })())
+}
@@ -0,0 +1,45 @@
+package locksync
+
+import (
+	"sync"
+
+	"github.com/ahrav/go-locks/rwticket"
+)
+
+// RWMutex is a reader-writer lock with the API of sync.RWMutex. The zero value is an
+// unlocked mutex. A RWMutex must not be copied after first use.
+//
+// Unlike sync.RWMutex, it is fair: readers and writers are admitted in the order they
+// arrive, as with rwticket.Lock, which backs it whatever the Kind. A writer waits only
+// for those ahead of it, and readers that arrive after a waiting writer wait for it.
+// As with sync.RWMutex, a goroutine must therefore not RLock again while it holds a
+// read lock, or a writer queued in between deadlocks them both.
+type RWMutex struct {
+	l rwticket.Lock
+}
+
+// Lock locks rw for writing.
+func (rw *RWMutex) Lock() { rw.l.Lock() }
+
+// TryLock tries to lock rw for writing and reports whether it succeeded.
+func (rw *RWMutex) TryLock() bool { return rw.l.TryLock() }
+
+// Unlock unlocks rw for writing.
+func (rw *RWMutex) Unlock() { rw.l.Unlock() }
+
+// RLock locks rw for reading.
+func (rw *RWMutex) RLock() { rw.l.RLock() }
+
+// TryRLock tries to lock rw for reading and reports whether it succeeded.
+func (rw *RWMutex) TryRLock() bool { return rw.l.TryRLock() }
+
+// RUnlock undoes a single RLock call.
+func (rw *RWMutex) RUnlock() { rw.l.RUnlock() }
+
+// RLocker returns a Locker that locks and unlocks rw for reading.
+func (rw *RWMutex) RLocker() sync.Locker { return (*rlocker)(rw) }
+
+type rlocker RWMutex
+
+func (r *rlocker) Lock()   { (*RWMutex)(r).RLock() }
+func (r *rlocker) Unlock() { (*RWMutex)(r).RUnlock() }
@@ -0,0 +1,71 @@
+package locksync
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	locks "github.com/ahrav/go-locks"
+)
+
+// RWMutex has at least the method set of sync.RWMutex.
+var _ interface {
+	locks.RWLocker
+	TryLock() bool
+	TryRLock() bool
+	RLocker() sync.Locker
+} = (*RWMutex)(nil)
+
+func TestRWMutexReadersShareWritersExclude(t *testing.T) {
+	var rw RWMutex
+	rw.RLock()
+	assert.True(t, rw.TryRLock())
+	assert.False(t, rw.TryLock())
+	rw.RUnlock()
+	rw.RUnlock()
+
+	assert.True(t, rw.TryLock())
+	assert.False(t, rw.TryRLock())
+	rw.Unlock()
+}
+
+func TestRWMutexReadersQueueBehindWriter(t *testing.T) {
+	var rw RWMutex
+	rw.RLock()
+	writing := make(chan struct{})
+	go func() {
+		rw.Lock()
+		close(writing)
+		rw.Unlock()
+	}()
+	for rw.l.QueueDepth() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.False(t, rw.TryRLock(), "A reader overtook a waiting writer")
+	rw.RUnlock()
+	<-writing
+}
+
+func TestRLocker(t *testing.T) {
+	const numGoroutines = 4
+	var rw RWMutex
+	r := rw.RLocker()
+	var wg sync.WaitGroup
+
+	r.Lock()
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() { // Readers don't exclude each other
+			defer wg.Done()
+			r.Lock()
+			r.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.False(t, rw.TryLock())
+	r.Unlock()
+	assert.True(t, rw.TryLock())
+	rw.Unlock()
+}
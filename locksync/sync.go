@@ -0,0 +1,25 @@
+package locksync
+
+import "sync"
+
+// The rest of the sync package, unchanged.
+type (
+	Cond      = sync.Cond
+	Locker    = sync.Locker
+	Map       = sync.Map
+	Once      = sync.Once
+	Pool      = sync.Pool
+	WaitGroup = sync.WaitGroup
+)
+
+// NewCond returns a new Cond with Locker l.
+func NewCond(l Locker) *Cond { return sync.NewCond(l) }
+
+// OnceFunc is sync.OnceFunc.
+func OnceFunc(f func()) func() { return sync.OnceFunc(f) }
+
+// OnceValue is sync.OnceValue.
+func OnceValue[T any](f func() T) func() T { return sync.OnceValue(f) }
+
+// OnceValues is sync.OnceValues.
+func OnceValues[T1, T2 any](f func() (T1, T2)) func() (T1, T2) { return sync.OnceValues(f) }
@@ -0,0 +1,120 @@
+package mcs
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// IndexNode is a queue node of an IndexLock. Nodes live in an array shared by the locks
+// that use them, and refer to each other by position in it.
+type IndexNode struct {
+	next    atomic.Uint32 // Index+1 of the successor, 0 if none has linked in yet
+	waiting atomic.Uint32
+	_       pad.CacheLinePad
+}
+
+// IndexLock is an MCS lock whose queue is linked by array indexes instead of pointers.
+//
+// Neither IndexLock nor IndexNode contains a pointer, so both can be placed in memory
+// the garbage collector doesn't manage, such as a large arena allocated with mmap, where
+// the pointer links of Lock and QNode would not keep their nodes alive. The zero value
+// is an unlocked lock, and a zeroed node is ready for use.
+//
+// Every operation takes the node array and the index of the caller's node in it. All
+// operations on one lock must pass the same array, and a node must not be used for two
+// acquisitions at once. Waiters spin and then yield rather than park, so the lock works
+// the same wherever the memory lives.
+//
+//	nodes := unsafe.Slice((*mcs.IndexNode)(arena.Alloc(n*size)), n)
+//	l := (*mcs.IndexLock)(arena.Alloc(8))
+//
+//	l.Lock(nodes, me)
+//	// ... critical section ...
+//	l.Unlock(nodes, me)
+type IndexLock struct {
+	tail atomic.Uint32 // Index+1 of the last queued node, 0 when free
+	spin spin.Adaptive // Self-tuning spin limit for waiters
+}
+
+// TryLock acquires the lock with node i if it is free, without blocking.
+func (l *IndexLock) TryLock(nodes []IndexNode, i uint32) bool {
+	nodes[i].next.Store(0)
+	return l.tail.CompareAndSwap(0, i+1)
+}
+
+// Lock acquires the lock with node i.
+func (l *IndexLock) Lock(nodes []IndexNode, i uint32) {
+	nodes[i].next.Store(0)
+	pred := l.tail.Swap(i + 1)
+	chaos.Point()
+	if pred != 0 {
+		l.lockSlow(nodes, i, pred-1)
+	}
+}
+
+// lockSlow queues node i behind node pred and waits for pred to signal it.
+func (l *IndexLock) lockSlow(nodes []IndexNode, i, pred uint32) {
+	start := lockprof.Start()
+	node := &nodes[i]
+	node.waiting.Store(1)
+	chaos.Point()
+	nodes[pred].next.Store(i + 1)
+
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = l.spin.Limit()
+	}
+	n := 0
+	for ; node.waiting.Load() != 0; n++ {
+		chaos.Point()
+		if n < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(n - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+	if spinLimit > 0 && n > 0 {
+		l.spin.Update(min(n, spinLimit), n < spinLimit)
+	}
+	lockprof.Record(start, 1)
+}
+
+// Unlock releases the lock held with node i.
+func (l *IndexLock) Unlock(nodes []IndexNode, i uint32) {
+	node := &nodes[i]
+	if invariant.Enabled {
+		invariant.Check(l.tail.Load() != 0, "mcs: Unlock of an unlocked IndexLock")
+		invariant.Check(node.waiting.Load() == 0, "mcs: unlocking node %d is still waiting", i)
+	}
+
+	chaos.Point()
+	if node.next.Load() == 0 && l.tail.CompareAndSwap(i+1, 0) {
+		return
+	}
+	succ := node.next.Load()
+	for succ == 0 { // The successor has swapped the tail but not linked in yet
+		spin.Yield()
+		succ = node.next.Load()
+	}
+	if invariant.Enabled {
+		invariant.Check(succ != i+1, "mcs: node %d is linked to itself", i)
+	}
+	nodes[succ-1].waiting.Store(0)
+}
+
+// IsFree reports whether the lock is currently free.
+func (l *IndexLock) IsFree() bool { return l.tail.Load() == 0 }
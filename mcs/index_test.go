@@ -0,0 +1,65 @@
+package mcs
+
+import (
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+	"github.com/ahrav/go-locks/internal/offheap"
+)
+
+func TestIndexLockIsPointerFree(t *testing.T) {
+	offheap.NoPointers(t, IndexLock{})
+	offheap.NoPointers(t, IndexNode{})
+}
+
+func TestIndexLockInRawMemory(t *testing.T) {
+	const numGoroutines = 4
+	const iterations = 500
+	// Lay the lock and its nodes out in one untyped block, as in an arena
+	nodeSize := unsafe.Sizeof(IndexNode{})
+	words := make([]uint64, (nodeSize*(numGoroutines+1))/8)
+	base := unsafe.Pointer(&words[0])
+	nodes := unsafe.Slice((*IndexNode)(base), numGoroutines)
+	l := (*IndexLock)(unsafe.Add(base, nodeSize*numGoroutines))
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				l.Lock(nodes, uint32(g))
+				counter++
+				l.Unlock(nodes, uint32(g))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+	assert.True(t, l.IsFree())
+}
+
+func TestIndexLockTryLock(t *testing.T) {
+	var l IndexLock
+	nodes := make([]IndexNode, 2)
+	assert.True(t, l.TryLock(nodes, 0))
+	assert.False(t, l.TryLock(nodes, 1))
+	assert.False(t, l.IsFree())
+	l.Unlock(nodes, 0)
+	assert.True(t, l.IsFree())
+}
+
+func TestIndexLockDoesNotAllocate(t *testing.T) {
+	var l IndexLock
+	nodes := make([]IndexNode, 1)
+	allocs.Zero(t, func() {
+		l.Lock(nodes, 0)
+		l.Unlock(nodes, 0)
+	})
+}
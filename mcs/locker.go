@@ -0,0 +1,109 @@
+package mcs
+
+import (
+	"runtime"
+
+	"github.com/ahrav/go-locks/pad"
+)
+
+// Locker adapts an MCS Lock to sync.Locker for callers that cannot thread a QNode
+// through their code. Each Lock call takes a QNode from a Slab and the holder's node is
+// remembered until Unlock returns it, so a single Locker may be shared by any number of
+// goroutines.
+//
+// The zero value is usable, but allocates a node for every acquisition.
+type Locker struct {
+	lock Lock
+	held *QNode // Node of the current holder; only touched while holding the lock
+	slab *Slab
+}
+
+// LockerOption configures a Locker.
+type LockerOption func(*Locker)
+
+// WithSlab makes the Locker draw its nodes from s, which may be shared with other
+// Lockers.
+func WithSlab(s *Slab) LockerOption { return func(l *Locker) { l.slab = s } }
+
+// NewLocker creates a new MCS-backed sync.Locker. Unless WithSlab is given, it gets a
+// slab of its own with a node for every P.
+func NewLocker(opts ...LockerOption) *Locker {
+	l := new(Locker)
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.slab == nil {
+		l.slab = NewSlab(runtime.GOMAXPROCS(0))
+	}
+	return l
+}
+
+// Lock acquires the lock.
+func (l *Locker) Lock() {
+	node := l.slab.Get()
+	l.lock.Lock(node)
+	l.held = node
+}
+
+// TryLock attempts to acquire the lock without blocking.
+// Returns true if lock was acquired, false otherwise.
+func (l *Locker) TryLock() bool {
+	node := l.slab.Get()
+	if !l.lock.TryLock(node) {
+		l.slab.Put(node)
+		return false
+	}
+	l.held = node
+	return true
+}
+
+// Unlock releases the lock.
+func (l *Locker) Unlock() {
+	node := l.held
+	l.held = nil
+	l.lock.Unlock(node)
+	l.slab.Put(node) // Once unlocked, no other goroutine references node
+}
+
+// IsFree returns true if the lock is currently free.
+func (l *Locker) IsFree() bool { return l.lock.IsFree() }
+
+// LockArray is a fixed set of MCS locks, for striping a data structure across many
+// locks, that share one Slab of nodes. Since a goroutine waits on at most one lock at a
+// time, the slab only needs a node per concurrent goroutine, not per goroutine per lock.
+type LockArray struct {
+	locks []paddedLocker
+}
+
+type paddedLocker struct {
+	Locker
+	_ pad.CacheLinePad
+}
+
+// NewLockArray creates n locks drawing their nodes from slab. A nil slab gets a slab
+// with a node for every P.
+func NewLockArray(n int, slab *Slab) *LockArray {
+	if slab == nil {
+		slab = NewSlab(runtime.GOMAXPROCS(0))
+	}
+	a := &LockArray{locks: make([]paddedLocker, n)}
+	for i := range a.locks {
+		a.locks[i].slab = slab
+	}
+	return a
+}
+
+// Len returns the number of locks in the array.
+func (a *LockArray) Len() int { return len(a.locks) }
+
+// At returns the i'th lock.
+func (a *LockArray) At(i int) *Locker { return &a.locks[i].Locker }
+
+// Lock acquires the i'th lock.
+func (a *LockArray) Lock(i int) { a.locks[i].Lock() }
+
+// TryLock attempts to acquire the i'th lock without blocking.
+func (a *LockArray) TryLock(i int) bool { return a.locks[i].TryLock() }
+
+// Unlock releases the i'th lock.
+func (a *LockArray) Unlock(i int) { a.locks[i].Unlock() }
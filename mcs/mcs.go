@@ -23,24 +23,41 @@
 //	}
 //
 // Each goroutine must maintain its own QNode instance. A single QNode should not be
-// used concurrently by multiple goroutines. For scenarios requiring multiple locks,
-// use NewLockArray and NewQNodeArray to efficiently manage multiple lock instances.
+// used concurrently by multiple goroutines. Locker wraps a Lock as a sync.Locker that
+// manages the nodes itself, drawing them from a preallocated Slab. For scenarios
+// requiring multiple locks, NewLockArray creates a set of Lockers sharing one Slab.
+//
+// Lock and QNode link the queue with pointers, so they must live on the Go heap.
+// IndexLock links it with indexes into an array of IndexNodes instead, for locks and
+// nodes embedded in off-heap memory.
 package mcs
 
 import (
-	"runtime"
 	"sync/atomic"
+	"unsafe"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/sema"
+	"github.com/ahrav/go-locks/spin"
 )
 
-// QNode represents a queue node in the MCS lock.
+// QNode represents a queue node in the MCS lock. Its owner spins on waiting, so the
+// node is padded to a full cache line to keep nodes allocated side by side from
+// disturbing each other's waiters.
 type QNode struct {
 	next    atomic.Pointer[QNode]
 	waiting uint32
+	_       pad.CacheLinePad
 }
 
 // Lock represents the MCS lock.
 type Lock struct {
 	tail atomic.Pointer[QNode]
+	spin spin.Adaptive // Self-tuning spin limit for waiters
 }
 
 // NewLock creates a new MCS lock.
@@ -57,46 +74,93 @@ func (l *Lock) TryLock(node *QNode) bool {
 func (l *Lock) Lock(node *QNode) {
 	node.next.Store(nil)
 	pred := l.tail.Swap(node) // Atomically put ourselves at the tail
-
-	if pred == nil { // No predecessor, lock acquired
-		return
+	chaos.Point()
+	if pred != nil { // Someone else is holding the lock
+		l.lockSlow(node, pred)
 	}
+}
 
-	// Someone else is holding the lock, wait for predecessor to signal us.
+// lockSlow queues node behind pred and waits for pred to signal it. Lock and Unlock
+// can't be inlined whatever their size, since atomic pointer operations carry GC write
+// barriers that exceed the inlining budget on their own, but keeping the slow paths
+// out of line still keeps the uncontended calls short. The barrier also rules out an
+// assembly XCHG for the enqueue: a pointer stored from assembly bypasses it, and the
+// garbage collector could free a queued node.
+func (l *Lock) lockSlow(node, pred *QNode) {
+	start := lockprof.Start()
 	atomic.StoreUint32(&node.waiting, 1)
+	chaos.Point()
 	pred.next.Store(node) // Link to predecessor
 
-	// Spin until predecessor signals us.
-	for atomic.LoadUint32(&node.waiting) != 0 {
-		// Similar to PAUSE in the C version, not sure if this is correct?
-		// Maybe just use a for loop?
-		runtime.Gosched()
+	// Spin until predecessor signals us, yielding once the spin budget is exhausted.
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = l.spin.Limit()
+	}
+	i := 0
+	for ; atomic.LoadUint32(&node.waiting) != 0; i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax() // PAUSE, as in the C version
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		if sema.Enabled { // Park until our predecessor wakes us
+			sema.Wait(uintptr(unsafe.Pointer(node)), func() bool { return atomic.LoadUint32(&node.waiting) == 0 })
+			continue
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+	if spinLimit > 0 && i > 0 {
+		l.spin.Update(min(i, spinLimit), i < spinLimit)
 	}
+	lockprof.Record(start, 1)
 }
 
 // Unlock releases the lock.
 func (l *Lock) Unlock(node *QNode) {
-	// Check if there's a successor.
-	if node.next.Load() == nil {
-		// No one waiting? Try to set tail to nil.
-		if l.tail.CompareAndSwap(node, nil) {
-			return
-		}
+	if invariant.Enabled {
+		invariant.Check(l.tail.Load() != nil, "mcs: Unlock of an unlocked lock")
+		invariant.Check(atomic.LoadUint32(&node.waiting) == 0, "mcs: unlocking node %p is still waiting", node)
+	}
 
-		// Someone in the process of enqueuing, wait for them.
-		for {
-			succ := node.next.Load()
-			if succ != nil {
-				atomic.StoreUint32(&succ.waiting, 0) // Signal successor
-				return
-			}
-			runtime.Gosched()
-		}
+	// No one waiting? Try to set tail to nil.
+	chaos.Point()
+	if node.next.Load() == nil && l.tail.CompareAndSwap(node, nil) {
+		return
 	}
+	unlockSlow(node)
+}
 
-	// Signal our successor.
+// unlockSlow signals node's successor, waiting for it to finish linking itself in if
+// it is still in the process of enqueuing.
+func unlockSlow(node *QNode) {
 	succ := node.next.Load()
-	atomic.StoreUint32(&succ.waiting, 0)
+	for succ == nil {
+		spin.Yield()
+		succ = node.next.Load()
+	}
+	if invariant.Enabled {
+		checkSuccessor(node, succ)
+	}
+	atomic.StoreUint32(&succ.waiting, 0) // Signal successor
+	if sema.Enabled {
+		sema.Wake(uintptr(unsafe.Pointer(succ)))
+	}
+}
+
+// checkSuccessor verifies that the queue link from node to succ is well formed.
+func checkSuccessor(node, succ *QNode) {
+	invariant.Check(succ != node, "mcs: node %p is linked to itself", node)
+	invariant.Check(atomic.LoadUint32(&succ.waiting) == 1,
+		"mcs: successor %p of node %p is not waiting", succ, node)
 }
 
 // IsFree returns true if the lock is currently free.
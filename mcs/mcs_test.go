@@ -0,0 +1,140 @@
+package mcs
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestLockConcurrentAccess(t *testing.T) {
+	// Multiple Ps let waiters take the relax path before falling back to Gosched on
+	// machines with more than one CPU.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	lock := NewLock()
+	const numGoroutines = 10
+	const iterations = 500
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			node := &QNode{} // Each goroutine owns its node
+			for range iterations {
+				lock.Lock(node)
+				counter++
+				lock.Unlock(node)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+	assert.True(t, lock.IsFree())
+}
+
+func TestLockTryLock(t *testing.T) {
+	lock := NewLock()
+	a, b := &QNode{}, &QNode{}
+
+	assert.True(t, lock.TryLock(a))
+	assert.False(t, lock.TryLock(b), "TryLock should fail while the lock is held")
+	assert.False(t, lock.IsFree())
+	lock.Unlock(a)
+	assert.True(t, lock.IsFree())
+}
+
+func TestLockerConcurrentAccess(t *testing.T) {
+	lock := NewLocker()
+	const numGoroutines = 10
+	const iterations = 500
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				lock.Lock()
+				counter++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+	assert.True(t, lock.IsFree())
+}
+
+func TestLockerTryLock(t *testing.T) {
+	lock := NewLocker()
+
+	assert.True(t, lock.TryLock())
+	assert.False(t, lock.TryLock(), "TryLock should fail while the lock is held")
+	lock.Unlock()
+	assert.True(t, lock.IsFree())
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	lock := NewLock()
+	node := new(QNode)
+	allocs.Zero(t, func() {
+		lock.Lock(node)
+		lock.Unlock(node)
+		assert.True(t, lock.TryLock(node))
+		lock.Unlock(node)
+	}, "Uncontended Lock, TryLock and Unlock should not allocate")
+
+	locker := NewLocker()
+	allocs.Zero(t, func() {
+		locker.Lock()
+		locker.Unlock()
+		assert.True(t, locker.TryLock())
+		locker.Unlock()
+	}, "Locker should reuse its embedded node")
+}
+
+func TestLockArraySharesSlab(t *testing.T) {
+	slab := NewSlab(4)
+	a := NewLockArray(8, slab)
+	const numGoroutines = 4
+	const iterations = 500
+	counters := make([]int, a.Len())
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for i := range iterations {
+				stripe := (g + i) % a.Len()
+				a.Lock(stripe)
+				counters[stripe]++
+				a.Unlock(stripe)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for i, c := range counters {
+		total += c
+		assert.True(t, a.At(i).IsFree())
+	}
+	assert.Equal(t, numGoroutines*iterations, total)
+
+	allocs.Zero(t, func() {
+		a.Lock(0)
+		a.Lock(1)
+		a.Unlock(1)
+		a.Unlock(0)
+	}, "Locks of an array should draw their nodes from the shared slab")
+}
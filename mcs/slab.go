@@ -0,0 +1,120 @@
+package mcs
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ahrav/go-locks/pad"
+)
+
+// slabNode rounds a QNode up to a whole number of cache lines, so that every node in a
+// slab starts on a line of its own.
+type slabNode struct {
+	QNode
+	_ [(pad.CacheLineSize - unsafe.Sizeof(QNode{})%pad.CacheLineSize) % pad.CacheLineSize]byte
+}
+
+// Slab is a fixed set of QNodes allocated up front in one contiguous block, from which
+// Lockers draw the node for each acquisition.
+//
+// Drawing nodes from a sync.Pool scatters them across the heap, and the handoff between
+// a holder and its successor then pays for cache misses on whatever lines the nodes
+// happen to share with unrelated objects. A slab keeps every node on its own cache
+// lines, next to the other nodes of the same locks. Go gives no control over NUMA
+// placement, but the kernel places a page on the node of the thread that first writes
+// it; NewSlab writes every node, so a slab created from a goroutine pinned with
+// affinity.PinNode lives on that node.
+//
+// A slab should hold as many nodes as goroutines are expected to hold or wait for its
+// locks at once. When it runs dry, Get falls back to allocating nodes on the heap,
+// which Put then discards, so an undersized slab costs allocations but not correctness.
+// A nil *Slab always allocates.
+type Slab struct {
+	nodes []slabNode
+	next  []atomic.Uint32 // Free list links; next[i] is the index+1 of the node below i
+	head  atomic.Uint64   // ABA tag in the high 32 bits, index+1 of the top free node in the low
+}
+
+// NewSlab creates a slab of n nodes.
+func NewSlab(n int) *Slab {
+	n = max(n, 1)
+	s := &Slab{nodes: alignedNodes(n), next: make([]atomic.Uint32, n)}
+	for i := range s.nodes {
+		s.nodes[i].waiting = 0 // First touch places the page on the caller's NUMA node
+		if i+1 < n {
+			s.next[i].Store(uint32(i + 2))
+		}
+	}
+	s.head.Store(1)
+	return s
+}
+
+// alignedNodes allocates n nodes starting on a cache line boundary.
+//
+// The allocator aligns a []slabNode only as far as its size class does, and puts a
+// header in front of larger objects that contain pointers, so the nodes are allocated
+// as an array of pointer words one line longer than they need and sliced from its first
+// line boundary. Every word of the array is one the garbage collector scans as a
+// pointer, so a node's next link is found wherever the node starts; its other words
+// only ever hold zeros and the waiting flag, which the collector ignores.
+func alignedNodes(n int) []slabNode {
+	words := make([]unsafe.Pointer, (uintptr(n)*unsafe.Sizeof(slabNode{})+pad.CacheLineSize)/unsafe.Sizeof(unsafe.Pointer(nil)))
+	base := unsafe.Pointer(&words[0])
+	skip := (pad.CacheLineSize - uintptr(base)%pad.CacheLineSize) % pad.CacheLineSize
+	return unsafe.Slice((*slabNode)(unsafe.Add(base, skip)), n)
+}
+
+// Cap returns the number of nodes in the slab.
+func (s *Slab) Cap() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.nodes)
+}
+
+// Get takes a free node from the slab, or allocates one if the slab has none left.
+func (s *Slab) Get() *QNode {
+	if s == nil {
+		return new(QNode)
+	}
+	for {
+		h := s.head.Load()
+		top := uint32(h)
+		if top == 0 {
+			return new(QNode)
+		}
+		next := s.next[top-1].Load()
+		if s.head.CompareAndSwap(h, (h>>32+1)<<32|uint64(next)) {
+			return &s.nodes[top-1].QNode
+		}
+	}
+}
+
+// Put returns a node taken with Get. node must no longer be linked into any lock's
+// queue. Nodes that Get allocated on the heap are left to the garbage collector.
+func (s *Slab) Put(node *QNode) {
+	i, ok := s.index(node)
+	if !ok {
+		return
+	}
+	for {
+		h := s.head.Load()
+		s.next[i].Store(uint32(h))
+		if s.head.CompareAndSwap(h, (h>>32+1)<<32|uint64(i+1)) {
+			return
+		}
+	}
+}
+
+// index returns the position of node in the slab, and false if node isn't from it.
+func (s *Slab) index(node *QNode) (int, bool) {
+	if s == nil {
+		return 0, false
+	}
+	base := uintptr(unsafe.Pointer(&s.nodes[0]))
+	off := uintptr(unsafe.Pointer(node)) - base // Wraps around for nodes below base
+	if off >= uintptr(len(s.nodes))*unsafe.Sizeof(slabNode{}) {
+		return 0, false
+	}
+	return int(off / unsafe.Sizeof(slabNode{})), true
+}
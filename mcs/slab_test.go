@@ -0,0 +1,101 @@
+package mcs
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/pad"
+)
+
+func TestSlabReusesNodes(t *testing.T) {
+	s := NewSlab(2)
+	a, b := s.Get(), s.Get()
+	assert.NotSame(t, a, b)
+
+	s.Put(a)
+	assert.Same(t, a, s.Get(), "A returned node should be handed out again")
+	s.Put(a)
+	s.Put(b)
+}
+
+func TestSlabNodesOnOwnCacheLines(t *testing.T) {
+	for _, n := range []int{1, 4, 8, 100, 1000} {
+		s := NewSlab(n)
+		for i := range s.nodes {
+			addr := uintptr(unsafe.Pointer(&s.nodes[i]))
+			assert.Zero(t, addr%pad.CacheLineSize, "Node %d of %d should start on a cache line boundary", i, n)
+		}
+	}
+}
+
+func TestSlabNodesSurviveGC(t *testing.T) {
+	s := NewSlab(8)
+	a, b := s.Get(), s.Get()
+	heap := &QNode{waiting: 7}
+	a.next.Store(b)
+	b.next.Store(heap) // Only reachable through the slab
+	heap = nil
+	runtime.GC()
+	runtime.GC()
+	assert.Same(t, b, a.next.Load())
+	assert.Equal(t, uint32(7), b.next.Load().waiting, "The heap node should have been kept alive")
+}
+
+func TestSlabOverflowAllocates(t *testing.T) {
+	s := NewSlab(1)
+	a := s.Get()
+	b := s.Get() // Slab is empty
+	assert.NotNil(t, b)
+	_, ok := s.index(b)
+	assert.False(t, ok, "An overflow node should come from the heap")
+
+	s.Put(b) // Discarded
+	s.Put(a)
+	assert.Same(t, a, s.Get())
+	_, ok = s.index(s.Get())
+	assert.False(t, ok, "Put of a heap node should not add it to the slab")
+}
+
+func TestNilSlab(t *testing.T) {
+	var s *Slab
+	node := s.Get()
+	assert.NotNil(t, node)
+	s.Put(node)
+	assert.Zero(t, s.Cap())
+}
+
+func TestSlabConcurrentGetPut(t *testing.T) {
+	s := NewSlab(4)
+	const numGoroutines = 8
+	const iterations = 1000
+	var inUse sync.Map
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				node := s.Get()
+				_, loaded := inUse.LoadOrStore(node, true)
+				assert.False(t, loaded, "A node should not be handed out twice")
+				inUse.Delete(node)
+				s.Put(node)
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[*QNode]bool)
+	for range s.Cap() {
+		node := s.Get()
+		_, ok := s.index(node)
+		assert.True(t, ok, "Every slab node should be free again")
+		assert.False(t, seen[node])
+		seen[node] = true
+	}
+}
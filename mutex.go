@@ -0,0 +1,43 @@
+package locks
+
+import (
+	"sync"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Mutex owns a value of type T and the lock guarding it. The value is only reachable
+// through With and Get, both of which hold the lock for the duration of the callback.
+//
+// The callbacks must not retain the pointer or any references into the value past
+// their return, and must not call back into the same Mutex.
+type Mutex[T any] struct {
+	l sync.Locker
+	v T
+}
+
+// NewMutex creates a Mutex guarding v with l, which makes the algorithm selectable per
+// value: ticket.NewLock(), mcs.NewLocker(), or alock.NewArrayLock(n) as long as no more
+// than n goroutines contend at once. A nil l selects a ticket.Lock.
+func NewMutex[T any](v T, l sync.Locker) *Mutex[T] {
+	if l == nil {
+		l = ticket.NewLock()
+	}
+	return &Mutex[T]{l: l, v: v}
+}
+
+// With runs fn with exclusive access to the guarded value.
+func (m *Mutex[T]) With(fn func(*T)) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	fn(&m.v)
+}
+
+// Get runs fn with a copy of the value guarded by m while holding the lock, and returns
+// its result. It is a function rather than a method because Go methods cannot declare
+// their own type parameters.
+func Get[T, R any](m *Mutex[T], fn func(T) R) R {
+	m.l.Lock()
+	defer m.l.Unlock()
+	return fn(m.v)
+}
@@ -0,0 +1,68 @@
+package locks
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/alock"
+	"github.com/ahrav/go-locks/internal/allocs"
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestMutexWithConcurrent(t *testing.T) {
+	const numGoroutines = 8
+	const iterations = 200
+
+	tests := []struct {
+		name string
+		lock sync.Locker
+	}{
+		{"default", nil},
+		{"ticket", ticket.NewLock()},
+		{"mcs", mcs.NewLocker()},
+		{"alock", alock.NewArrayLock(numGoroutines)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMutex(0, tt.lock)
+			var wg sync.WaitGroup
+
+			wg.Add(numGoroutines)
+			for range numGoroutines {
+				go func() {
+					defer wg.Done()
+					for range iterations {
+						m.With(func(n *int) { *n++ })
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, numGoroutines*iterations, Get(m, func(n int) int { return n }))
+		})
+	}
+}
+
+func TestMutexUnlocksOnPanic(t *testing.T) {
+	m := NewMutex("", nil)
+
+	assert.Panics(t, func() {
+		m.With(func(*string) { panic("boom") })
+	})
+
+	// The lock must have been released by the deferred Unlock.
+	m.With(func(s *string) { *s = "ok" })
+	assert.Equal(t, "ok", Get(m, func(s string) string { return s }))
+}
+
+func TestMutexDoesNotAllocate(t *testing.T) {
+	m := NewMutex(0, nil)
+	allocs.Zero(t, func() {
+		m.With(func(n *int) { *n++ })
+		_ = Get(m, func(n int) int { return n })
+	}, "With and Get should not allocate")
+}
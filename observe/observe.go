@@ -0,0 +1,275 @@
+// Package observe is the extension point for instrumenting the locks in this module.
+//
+// An Observer receives a callback at each stage of a lock's life cycle. Wrapping any
+// sync.Locker with Wrap routes its operations through an Observer, and metrics,
+// tracing, and logging backends are all built as Observers:
+//
+//	lock := observe.Wrap(ticket.NewLock(), myMetrics)
+//
+//	lock.Lock()   // OnAcquire, OnContended (if queued), OnAcquired
+//	lock.Unlock() // OnRelease
+//
+// Observation is opt-in per lock. An unwrapped lock pays nothing for it, so the
+// uninstrumented fast paths stay free of branches and allocations.
+//
+// A Locker named with WithName can also label the goroutines waiting for it in CPU and
+// goroutine profiles, so that a pile-up in a goroutine dump shows which lock it is on.
+// Turn that on with SetProfileLabels; waiters then carry the pprof labels
+// lock=<name> and lock_phase=wait until they acquire the lock:
+//
+//	observe.SetProfileLabels(true)
+//	lock := observe.Wrap(ticket.NewLock(), myMetrics, observe.WithName("accounts"))
+//
+// pprof has no way to read a goroutine's current labels, so after a wait Lock resets
+// them to none. A goroutine running under labels of its own, set with pprof.Do, should
+// acquire with LockLabeled, passing the context that carries them.
+//
+// While a runtime/trace is being recorded, Lockers can also mark contended acquisitions
+// and holds as user regions, and log holds that ran long, so that go tool trace shows
+// lock waits next to the GC and scheduler events around them. Select what to emit with
+// SetTrace:
+//
+//	observe.SetTrace(observe.TraceOptions{Waits: true, LongHold: time.Millisecond})
+package observe
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ahrav/go-locks/lockprof"
+)
+
+var profileLabels atomic.Bool
+
+// SetProfileLabels turns pprof labelling of goroutines waiting for named Lockers on or
+// off, and returns the previous setting. It is off by default.
+func SetProfileLabels(on bool) bool { return profileLabels.Swap(on) }
+
+// TraceOptions selects what Lockers emit to runtime/trace. Nothing is emitted while no
+// trace is being recorded.
+type TraceOptions struct {
+	Waits    bool          // A region around each contended acquisition
+	Holds    bool          // A region around each hold
+	LongHold time.Duration // Log each hold longer than this; 0 logs none
+}
+
+var traceOpts atomic.Pointer[TraceOptions]
+
+func init() { traceOpts.Store(&TraceOptions{}) }
+
+// SetTrace selects what Lockers emit to runtime/trace and returns the previous
+// selection. By default they emit nothing.
+func SetTrace(opts TraceOptions) TraceOptions { return *traceOpts.Swap(&opts) }
+
+// Observer receives lock life-cycle events. Callbacks run on the goroutine performing
+// the operation and must be safe for concurrent use; they should be cheap, since
+// OnAcquired and OnRelease run while the lock is held.
+type Observer interface {
+	// OnAcquire is called when a goroutine starts acquiring the lock.
+	OnAcquire()
+	// OnAcquired is called once the lock is held, with the time spent waiting for it.
+	OnAcquired(wait time.Duration)
+	// OnRelease is called after the lock is released, with the time it was held.
+	OnRelease(hold time.Duration)
+	// OnContended is called before waiting when the lock is already held, with the
+	// number of goroutines holding or queued for the lock, or -1 if the lock cannot
+	// report its queue depth.
+	OnContended(depth int)
+}
+
+// NopObserver implements Observer with no-op methods. Embed it to implement only the
+// callbacks of interest.
+type NopObserver struct{}
+
+func (NopObserver) OnAcquire()               {}
+func (NopObserver) OnAcquired(time.Duration) {}
+func (NopObserver) OnRelease(time.Duration)  {}
+func (NopObserver) OnContended(int)          {}
+
+// QueueDepther is implemented by locks that can cheaply report how many goroutines
+// hold or are queued for them, such as ticket.Lock and rwticket.Lock.
+type QueueDepther interface {
+	QueueDepth() int
+}
+
+// TryLocker is a sync.Locker that also supports non-blocking acquisition.
+type TryLocker interface {
+	sync.Locker
+	TryLock() bool
+}
+
+// Locker is a sync.Locker that reports its operations to an Observer.
+type Locker struct {
+	l        sync.Locker
+	obs      Observer
+	name     string
+	profName string          // Name of lockprof samples: name, or the lock's type
+	waitCtx  context.Context // Labels of a waiter with no labels of its own
+	waitType string          // Trace region types
+	holdType string
+	acquired time.Time     // Set by the holder; only read by the holder
+	hold     *trace.Region // Likewise
+}
+
+// Option configures a Locker.
+type Option func(*Locker)
+
+// WithName names the lock in the pprof labels of its waiters and in its trace regions.
+func WithName(name string) Option { return func(o *Locker) { o.name = name } }
+
+// Wrap returns l instrumented with obs.
+func Wrap(l sync.Locker, obs Observer, opts ...Option) *Locker {
+	o := &Locker{l: l, obs: obs}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.waitType, o.holdType = "lock wait", "lock hold"
+	o.profName = o.name
+	if o.name == "" {
+		o.profName = typeName(l)
+	}
+	if o.name != "" {
+		o.waitCtx = pprof.WithLabels(context.Background(), pprof.Labels("lock", o.name, "lock_phase", "wait"))
+		o.waitType += ": " + o.name
+		o.holdType += ": " + o.name
+	}
+	return o
+}
+
+// Lock acquires the underlying lock, reporting each stage to the Observer.
+func (o *Locker) Lock() { o.lock(nil) }
+
+// LockLabeled is Lock for a goroutine running under the pprof labels carried by ctx,
+// which it gets back once it has acquired the lock.
+func (o *Locker) LockLabeled(ctx context.Context) { o.lock(ctx) }
+
+// lock acquires the underlying lock, labelling the goroutine while it waits on top of
+// ctx's labels, or of none if ctx is nil.
+func (o *Locker) lock(ctx context.Context) {
+	o.obs.OnAcquire()
+	start := time.Now()
+
+	var w wait
+	switch l := o.l.(type) {
+	case QueueDepther:
+		if depth := l.QueueDepth(); depth > 0 {
+			w = o.contended(ctx, depth)
+			o.lockContended()
+		} else {
+			o.l.Lock()
+		}
+	case TryLocker:
+		if !l.TryLock() {
+			w = o.contended(ctx, -1)
+			o.lockContended()
+		}
+	default:
+		o.l.Lock()
+	}
+	if w.region != nil {
+		w.region.End()
+	}
+	lockprof.RecordNamed(w.prof, 1, o.profName)
+	o.startHold(ctx)
+	if w.labeled {
+		pprof.SetGoroutineLabels(orBackground(ctx))
+	}
+
+	now := time.Now()
+	o.acquired = now
+	o.obs.OnAcquired(now.Sub(start))
+}
+
+// lockContended acquires the underlying lock after contended has reported the wait.
+// lock samples the wait itself, so lockprof.WriteFolded leaves out the samples the
+// lock's slow path takes below this frame.
+func (o *Locker) lockContended() { o.l.Lock() }
+
+// typeName returns the type of l without package path, pointer or type arguments, such
+// as "ticket.Lock".
+func typeName(l sync.Locker) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", l), "*")
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// wait is what lock has to undo or record once a contended acquisition succeeds.
+type wait struct {
+	labeled bool          // The goroutine was labelled for the wait
+	region  *trace.Region // Trace region of the wait, if tracing waits
+	prof    int64         // lockprof sample of the wait, if sampled
+}
+
+// contended reports a wait to the Observer, and starts labelling, tracing and
+// profiling it as enabled.
+func (o *Locker) contended(ctx context.Context, depth int) wait {
+	o.obs.OnContended(depth)
+	var w wait
+	if traceOpts.Load().Waits && trace.IsEnabled() {
+		w.region = trace.StartRegion(orBackground(ctx), o.waitType)
+	}
+	w.prof = lockprof.Start()
+	if o.name == "" || !profileLabels.Load() {
+		return w
+	}
+	if ctx == nil {
+		pprof.SetGoroutineLabels(o.waitCtx)
+	} else {
+		pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels("lock", o.name, "lock_phase", "wait")))
+	}
+	w.labeled = true
+	return w
+}
+
+// startHold starts a trace region for the hold just begun if tracing holds.
+func (o *Locker) startHold(ctx context.Context) {
+	if traceOpts.Load().Holds && trace.IsEnabled() {
+		o.hold = trace.StartRegion(orBackground(ctx), o.holdType)
+	}
+}
+
+func orBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// TryLock attempts to acquire the underlying lock without blocking. Only successful
+// attempts are reported. It returns false if the underlying lock has no TryLock.
+func (o *Locker) TryLock() bool {
+	l, ok := o.l.(TryLocker)
+	if !ok {
+		return false
+	}
+	if !l.TryLock() {
+		return false
+	}
+	o.obs.OnAcquire()
+	o.acquired = time.Now()
+	o.startHold(nil)
+	o.obs.OnAcquired(0)
+	return true
+}
+
+// Unlock releases the underlying lock and reports how long it was held.
+func (o *Locker) Unlock() {
+	hold := time.Since(o.acquired)
+	if r := o.hold; r != nil {
+		o.hold = nil
+		r.End()
+	}
+	o.l.Unlock()
+	o.obs.OnRelease(hold)
+	if long := traceOpts.Load().LongHold; long > 0 && hold > long && trace.IsEnabled() {
+		trace.Log(context.Background(), o.holdType, hold.String())
+	}
+}
@@ -0,0 +1,196 @@
+package observe
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+type recorder struct {
+	mu                            sync.Mutex
+	acquires, acquired, releases  int
+	contended                     []int
+	totalWait, totalHold, maxHold time.Duration
+}
+
+func (r *recorder) OnAcquire() { r.mu.Lock(); r.acquires++; r.mu.Unlock() }
+func (r *recorder) OnAcquired(wait time.Duration) {
+	r.mu.Lock()
+	r.acquired++
+	r.totalWait += wait
+	r.mu.Unlock()
+}
+func (r *recorder) OnRelease(hold time.Duration) {
+	r.mu.Lock()
+	r.releases++
+	r.totalHold += hold
+	r.maxHold = max(r.maxHold, hold)
+	r.mu.Unlock()
+}
+func (r *recorder) OnContended(depth int) {
+	r.mu.Lock()
+	r.contended = append(r.contended, depth)
+	r.mu.Unlock()
+}
+
+func TestWrapReportsLifeCycle(t *testing.T) {
+	rec := new(recorder)
+	lock := Wrap(ticket.NewLock(), rec)
+
+	lock.Lock()
+	time.Sleep(time.Millisecond)
+	lock.Unlock()
+
+	assert.Equal(t, 1, rec.acquires)
+	assert.Equal(t, 1, rec.acquired)
+	assert.Equal(t, 1, rec.releases)
+	assert.Empty(t, rec.contended, "Uncontended acquisition should not report contention")
+	assert.GreaterOrEqual(t, rec.maxHold, time.Millisecond)
+}
+
+func TestWrapReportsContention(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		lock  TryLocker
+		depth int
+	}{
+		{"depth", ticket.NewLock(), 1},
+		{"trylock", mcs.NewLocker(), -1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := new(recorder)
+			lock := Wrap(tt.lock, rec)
+
+			tt.lock.Lock() // Held outside the observer
+			done := make(chan struct{})
+			go func() {
+				lock.Lock()
+				lock.Unlock()
+				close(done)
+			}()
+			time.Sleep(10 * time.Millisecond)
+			tt.lock.Unlock()
+			<-done
+
+			assert.Equal(t, []int{tt.depth}, rec.contended)
+			assert.GreaterOrEqual(t, rec.totalWait, 5*time.Millisecond)
+		})
+	}
+}
+
+func TestWrapTryLock(t *testing.T) {
+	rec := new(recorder)
+	lock := Wrap(ticket.NewLock(), rec)
+
+	assert.True(t, lock.TryLock())
+	assert.False(t, lock.TryLock())
+	lock.Unlock()
+
+	assert.Equal(t, 1, rec.acquires, "Failed TryLock should not be reported")
+	assert.Equal(t, 1, rec.acquired)
+	assert.Equal(t, 1, rec.releases)
+}
+
+func TestNopObserver(t *testing.T) {
+	lock := Wrap(ticket.NewLock(), NopObserver{})
+	lock.Lock()
+	lock.Unlock()
+}
+
+func TestLockerDoesNotAllocate(t *testing.T) {
+	l := Wrap(ticket.NewLock(), NopObserver{})
+	allocs.Zero(t, func() {
+		l.Lock()
+		l.Unlock()
+		assert.True(t, l.TryLock())
+		l.Unlock()
+	}, "Uncontended Lock, TryLock and Unlock should not allocate")
+}
+
+// goroutines returns the goroutine profile in its text form, which lists each stack's
+// labels.
+func goroutines() string {
+	var b strings.Builder
+	_ = pprof.Lookup("goroutine").WriteTo(&b, 1)
+	return b.String()
+}
+
+func TestWaitersCarryProfileLabels(t *testing.T) {
+	defer SetProfileLabels(SetProfileLabels(true))
+	l := Wrap(ticket.NewLock(), NopObserver{}, WithName("accounts"))
+	const waiting = `"lock":"accounts", "lock_phase":"wait"`
+
+	for _, own := range []bool{false, true} {
+		l.Lock()
+		acquired, release := make(chan struct{}), make(chan struct{})
+		go func() {
+			if own {
+				pprof.Do(context.Background(), pprof.Labels("req", "r1"), func(ctx context.Context) {
+					l.LockLabeled(ctx)
+					close(acquired)
+					<-release
+				})
+			} else {
+				l.Lock()
+				close(acquired)
+				<-release
+			}
+			l.Unlock()
+		}()
+		for !strings.Contains(goroutines(), waiting) {
+			time.Sleep(time.Millisecond)
+		}
+		if own {
+			assert.Contains(t, goroutines(), `"lock":"accounts", "lock_phase":"wait", "req":"r1"`)
+		}
+
+		l.Unlock()
+		<-acquired
+		dump := goroutines()
+		assert.NotContains(t, dump, `"lock":"accounts"`, "Labels kept after acquiring")
+		if own {
+			assert.Contains(t, dump, `# labels: {"req":"r1"}`, "Own labels not restored")
+		}
+		close(release)
+	}
+}
+
+func TestTraceRegions(t *testing.T) {
+	defer SetTrace(SetTrace(TraceOptions{Waits: true, Holds: true, LongHold: time.Millisecond}))
+	l := Wrap(ticket.NewLock(), NopObserver{}, WithName("journal"))
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skip("tracing unavailable:", err)
+	}
+
+	l.Lock()
+	done := make(chan struct{})
+	go func() {
+		l.Lock()
+		l.Unlock()
+		close(done)
+	}()
+	for l.l.(QueueDepther).QueueDepth() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(2 * time.Millisecond) // A long hold
+	l.Unlock()
+	<-done
+	trace.Stop()
+
+	// The trace's string table names every region type and log category used
+	for _, s := range []string{"lock wait: journal", "lock hold: journal"} {
+		assert.Contains(t, buf.String(), s)
+	}
+}
@@ -0,0 +1,241 @@
+// Package olc provides optimistic lock coupling, the synchronization scheme of
+// concurrent in-memory indexes such as the adaptive radix tree and B-trees from Leis
+// et al., "The ART of Practical Synchronization".
+//
+// Every node of the tree carries a Lock, a version word in which writers set a lock
+// bit. Readers never write to a node: they note its version, read what they need, and
+// check that the version is unchanged before trusting what they read. Traversals couple
+// these checks from node to node, as lock coupling does with real locks: the child's
+// version is taken before the parent's is validated, so a reader that validated every
+// step followed a path that existed as a whole. Any failed check means a writer got in
+// between, and the operation restarts from the root.
+//
+// A writer upgrades the versions it read to write locks, which fails if anything
+// changed since. A node removed from the tree is marked obsolete when its lock is
+// released, so that a traversal that still reaches it restarts instead of using it.
+//
+// Coupling packages the traversal and upgrade steps, and Retry the restart loop.
+//
+// Example usage:
+//
+//	olc.Retry(func() bool {
+//	    var c olc.Coupling
+//	    if !c.Start(&root.lock) {
+//	        return false
+//	    }
+//	    n := root
+//	    for !n.leaf() {
+//	        child := n.child(key) // Read with atomic loads
+//	        if !c.Descend(&child.lock) {
+//	            return false // Restart from the root
+//	        }
+//	        n = child
+//	    }
+//	    v := n.lookup(key)
+//	    if !c.Validate() {
+//	        return false
+//	    }
+//	    result = v
+//	    return true
+//	})
+//
+// As with vlock, node contents read optimistically must be read with sync/atomic and
+// not acted upon until validated.
+package olc
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// Bits of a Lock's word; the version counts up from bit 2.
+const (
+	obsolete = 1 << iota // The node was removed from the structure
+	locked               // A writer holds the node
+)
+
+// Lock is an optimistic node lock. The zero value is an unlocked lock. Being a single
+// word with no pointers, it can be embedded in nodes allocated from an off-heap arena.
+type Lock struct {
+	word atomic.Uint64
+}
+
+// ReadLockOrRestart waits until the node is not locked for writing and returns its
+// version. It returns false if the node is obsolete.
+func (l *Lock) ReadLockOrRestart() (uint64, bool) {
+	w := l.word.Load()
+	if w&locked != 0 {
+		w = l.waitUnlocked()
+	}
+	return w, w&obsolete == 0
+}
+
+// waitUnlocked spins, then yields, until the lock bit is clear, and returns the word.
+func (l *Lock) waitUnlocked() uint64 {
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	w := l.word.Load()
+	for i := 0; w&locked != 0; i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+		} else {
+			if reserved {
+				spin.Release()
+				reserved = false
+			}
+			spin.Wait(i - spinLimit)
+		}
+		w = l.word.Load()
+	}
+	if reserved {
+		spin.Release()
+	}
+	lockprof.Record(start, 1)
+	return w
+}
+
+// CheckOrRestart reports whether the node is still at version v: unlocked, not
+// obsolete, and unchanged since v was read.
+func (l *Lock) CheckOrRestart(v uint64) bool {
+	chaos.Point()
+	return l.word.Load() == v
+}
+
+// UpgradeToWriteLockOrRestart locks the node for writing if it is still at version v.
+func (l *Lock) UpgradeToWriteLockOrRestart(v uint64) bool {
+	return l.word.CompareAndSwap(v, v|locked)
+}
+
+// WriteLockOrRestart locks the node for writing, waiting for other writers. It returns
+// false if the node is or becomes obsolete.
+func (l *Lock) WriteLockOrRestart() bool {
+	for {
+		v, ok := l.ReadLockOrRestart()
+		if !ok {
+			return false
+		}
+		if l.UpgradeToWriteLockOrRestart(v) {
+			return true
+		}
+	}
+}
+
+// WriteUnlock releases the write lock and advances the version, invalidating every
+// version read before.
+func (l *Lock) WriteUnlock() {
+	if invariant.Enabled {
+		invariant.Check(l.word.Load()&locked != 0, "olc: WriteUnlock of an unlocked node")
+	}
+	l.word.Add(locked) // Clears the lock bit and carries into the version
+}
+
+// WriteUnlockObsolete releases the write lock and marks the node obsolete, for a node
+// that was unlinked from the structure while locked.
+func (l *Lock) WriteUnlockObsolete() {
+	if invariant.Enabled {
+		invariant.Check(l.word.Load()&locked != 0, "olc: WriteUnlockObsolete of an unlocked node")
+	}
+	l.word.Add(locked | obsolete)
+}
+
+// abort releases a write lock under which nothing changed, keeping the version.
+func (l *Lock) abort() { l.word.Add(^uint64(locked - 1)) } // Subtracts locked
+
+// IsObsolete reports whether the node has been marked obsolete.
+func (l *Lock) IsObsolete() bool { return l.word.Load()&obsolete != 0 }
+
+// Coupling tracks an optimistic traversal: the node it is at and that node's parent,
+// each with the version it was read at. The zero value is ready for Start.
+type Coupling struct {
+	node, parent   *Lock
+	version, pvers uint64
+}
+
+// Start begins a traversal at root. It returns false if the traversal must restart.
+func (c *Coupling) Start(root *Lock) bool {
+	v, ok := root.ReadLockOrRestart()
+	*c = Coupling{node: root, version: v}
+	return ok
+}
+
+// Descend moves the traversal to child, which the caller read from the current node.
+// It takes child's version and then validates the current node, which guarantees that
+// child was still linked from it when child's version was taken. It returns false if the
+// traversal must restart.
+func (c *Coupling) Descend(child *Lock) bool {
+	if !c.node.CheckOrRestart(c.version) {
+		return false // What the caller read from the node, child included, is stale
+	}
+	v, ok := child.ReadLockOrRestart()
+	if !ok || !c.node.CheckOrRestart(c.version) {
+		return false
+	}
+	c.parent, c.pvers = c.node, c.version
+	c.node, c.version = child, v
+	return true
+}
+
+// Validate reports whether the current node is still at the version the traversal read,
+// so that what was read from it may be trusted.
+func (c *Coupling) Validate() bool { return c.node.CheckOrRestart(c.version) }
+
+// Node returns the lock of the node the traversal is at, and the version it was read
+// at.
+func (c *Coupling) Node() (*Lock, uint64) { return c.node, c.version }
+
+// Upgrade locks the current node for writing if it is unchanged. The caller releases it
+// with WriteUnlock or WriteUnlockObsolete.
+func (c *Coupling) Upgrade() bool { return c.node.UpgradeToWriteLockOrRestart(c.version) }
+
+// UpgradeWithParent locks the current node and its parent for writing if both are
+// unchanged, for changes that reach into the parent, such as splitting or removing the
+// current node. It takes the parent first, as every writer must, and holds neither on
+// failure. The traversal must have descended at least once.
+func (c *Coupling) UpgradeWithParent() bool {
+	if !c.parent.UpgradeToWriteLockOrRestart(c.pvers) {
+		return false
+	}
+	if !c.node.UpgradeToWriteLockOrRestart(c.version) {
+		c.parent.abort()
+		return false
+	}
+	return true
+}
+
+// Retry runs op until it reports success, backing off between attempts as lock waiters
+// do. op is an optimistic operation that returns false to restart.
+func Retry(op func() bool) {
+	if op() {
+		return
+	}
+	spinLimit := 0
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	for i := 0; !op(); i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+}
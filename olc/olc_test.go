@@ -0,0 +1,169 @@
+package olc
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/offheap"
+)
+
+func TestLockVersions(t *testing.T) {
+	var l Lock
+	v, ok := l.ReadLockOrRestart()
+	assert.True(t, ok)
+	assert.True(t, l.CheckOrRestart(v))
+
+	assert.True(t, l.UpgradeToWriteLockOrRestart(v))
+	assert.False(t, l.CheckOrRestart(v), "A locked node validates no version")
+	assert.False(t, l.UpgradeToWriteLockOrRestart(v))
+	l.WriteUnlock()
+	assert.False(t, l.CheckOrRestart(v))
+	assert.False(t, l.UpgradeToWriteLockOrRestart(v), "Stale version")
+
+	assert.True(t, l.WriteLockOrRestart())
+	l.WriteUnlockObsolete()
+	assert.True(t, l.IsObsolete())
+	_, ok = l.ReadLockOrRestart()
+	assert.False(t, ok)
+	assert.False(t, l.WriteLockOrRestart())
+}
+
+func TestUpgradeWithParentHoldsNeitherOnFailure(t *testing.T) {
+	var parent, child Lock
+	var c Coupling
+	assert.True(t, c.Start(&parent))
+	assert.True(t, c.Descend(&child))
+
+	assert.True(t, child.WriteLockOrRestart()) // A writer changes the child meanwhile
+	child.WriteUnlock()
+
+	pv := c.pvers
+	assert.False(t, c.UpgradeWithParent())
+	assert.True(t, parent.CheckOrRestart(pv), "The parent is released unchanged")
+}
+
+// list is a sorted linked list synchronized with optimistic lock coupling, with the
+// head sentinel as the root and each node as the parent of its successor.
+type list struct {
+	head node
+}
+
+type node struct {
+	lock Lock
+	key  int
+	next atomic.Pointer[node]
+}
+
+func (l *list) contains(key int) bool {
+	var found bool
+	Retry(func() bool {
+		var c Coupling
+		if !c.Start(&l.head.lock) {
+			return false
+		}
+		for n := l.head.next.Load(); n != nil; n = n.next.Load() {
+			if !c.Descend(&n.lock) {
+				return false
+			}
+			if n.key >= key {
+				found = n.key == key
+				return c.Validate()
+			}
+		}
+		found = false
+		return c.Validate()
+	})
+	return found
+}
+
+func (l *list) insert(key int) {
+	Retry(func() bool {
+		var c Coupling
+		if !c.Start(&l.head.lock) {
+			return false
+		}
+		pred := &l.head
+		next := pred.next.Load()
+		for next != nil && next.key < key {
+			if !c.Descend(&next.lock) {
+				return false
+			}
+			pred, next = next, next.next.Load()
+		}
+		if !c.Upgrade() { // Also validates that next is still pred's successor
+			return false
+		}
+		n := &node{key: key}
+		n.next.Store(next)
+		pred.next.Store(n)
+		pred.lock.WriteUnlock()
+		return true
+	})
+}
+
+func (l *list) remove(key int) {
+	Retry(func() bool {
+		var c Coupling
+		if !c.Start(&l.head.lock) {
+			return false
+		}
+		pred := &l.head
+		for n := pred.next.Load(); n != nil; n = n.next.Load() {
+			if !c.Descend(&n.lock) {
+				return false
+			}
+			if n.key == key {
+				if !c.UpgradeWithParent() {
+					return false
+				}
+				pred.next.Store(n.next.Load())
+				pred.lock.WriteUnlock()
+				n.lock.WriteUnlockObsolete()
+				return true
+			}
+			pred = n
+		}
+		return c.Validate()
+	})
+}
+
+func TestListCoupling(t *testing.T) {
+	var l list
+	const numGoroutines = 4
+	const perGoroutine = 200
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for i := range perGoroutine {
+				key := i*numGoroutines + g
+				l.insert(key)
+				assert.True(t, l.contains(key))
+				if key%2 == 1 {
+					l.remove(key)
+					assert.False(t, l.contains(key))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	prev, count := -1, 0
+	for n := l.head.next.Load(); n != nil; n = n.next.Load() {
+		assert.Less(t, prev, n.key, "List must stay sorted")
+		assert.Zero(t, n.key%2, "Removed keys must be gone")
+		assert.False(t, n.lock.IsObsolete())
+		prev = n.key
+		count++
+	}
+	assert.Equal(t, numGoroutines*perGoroutine/2, count)
+}
+
+func TestLockIsPointerFree(t *testing.T) {
+	offheap.NoPointers(t, Lock{})
+}
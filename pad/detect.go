@@ -0,0 +1,16 @@
+package pad
+
+import "sync"
+
+var detectOnce = sync.OnceValue(func() int {
+	if n := detect(); n > 0 {
+		return n
+	}
+	return CacheLineSize
+})
+
+// Detect returns the cache line size of the machine the program is running on, as
+// reported by the operating system, falling back to CacheLineSize where it can't be
+// determined. Padding must be a compile-time constant, so CacheLinePad always uses
+// CacheLineSize; Detect lets a program check that the build-time value is large enough.
+func Detect() int { return detectOnce() }
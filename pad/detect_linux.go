@@ -0,0 +1,20 @@
+package pad
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// detect reads the coherency line size of the first CPU's L1 data cache from sysfs.
+func detect() int {
+	b, err := os.ReadFile("/sys/devices/system/cpu/cpu0/cache/index0/coherency_line_size")
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0
+	}
+	return n
+}
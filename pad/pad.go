@@ -0,0 +1,21 @@
+// Package pad provides padding that keeps hot fields on separate cache lines.
+//
+// Two variables written by different processors that happen to share a cache line
+// force the line to bounce between the processors' caches on every write, even though
+// neither touches the other's data. Locks are particularly prone to this false sharing:
+// a lock word sitting next to unrelated fields slows down both the lock and every
+// access to its neighbours. The locks in this module pad their own hot state with
+// CacheLinePad, and code embedding them in its own structs can do the same.
+//
+// Example usage:
+//
+//	type counters struct {
+//	    mu   ticket.Lock
+//	    _    pad.CacheLinePad // Keep the lock off the line holding hits
+//	    hits atomic.Uint64
+//	}
+package pad
+
+// CacheLinePad occupies a whole cache line. Placed between two fields, it guarantees
+// they are on different lines whatever the alignment of the enclosing struct.
+type CacheLinePad struct{ _ [CacheLineSize]byte }
@@ -0,0 +1,24 @@
+package pad
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheLinePadSeparatesFields(t *testing.T) {
+	var s struct {
+		a uint64
+		_ CacheLinePad
+		b uint64
+	}
+	assert.Equal(t, uintptr(CacheLineSize), unsafe.Sizeof(CacheLinePad{}))
+	assert.GreaterOrEqual(t, unsafe.Offsetof(s.b)-unsafe.Offsetof(s.a), uintptr(CacheLineSize))
+}
+
+func TestDetect(t *testing.T) {
+	n := Detect()
+	assert.Positive(t, n)
+	assert.Zero(t, n&(n-1), "Cache line sizes are powers of two")
+}
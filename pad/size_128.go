@@ -0,0 +1,8 @@
+//go:build arm64 || ppc64 || ppc64le
+
+package pad
+
+// CacheLineSize is the cache line size of the target architecture, in bytes, as fixed
+// at build time. Some arm64 parts, notably Apple's, use 128-byte lines, so arm64 pads
+// for the larger size like the Go runtime does.
+const CacheLineSize = 128
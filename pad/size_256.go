@@ -0,0 +1,7 @@
+//go:build s390x
+
+package pad
+
+// CacheLineSize is the cache line size of the target architecture, in bytes, as fixed
+// at build time.
+const CacheLineSize = 256
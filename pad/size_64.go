@@ -0,0 +1,7 @@
+//go:build !arm64 && !ppc64 && !ppc64le && !s390x
+
+package pad
+
+// CacheLineSize is the cache line size of the target architecture, in bytes, as fixed
+// at build time.
+const CacheLineSize = 64
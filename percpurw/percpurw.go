@@ -0,0 +1,147 @@
+// Package percpurw implements a read-biased reader-writer lock in the style of Linux's
+// percpu-rwsem: readers are nearly free, and writers pay for it.
+//
+// Readers count themselves in one of GOMAXPROCS padded counters instead of a single
+// shared word, so the read path never contends on a cache line with other readers. Only
+// the sum of the counters matters, so a reader may arrive on one counter and leave from
+// another. A writer flips the lock into its slow mode, which sends every new reader to a
+// slow path where it waits for the writer, and then waits for the counters to drain.
+//
+// This is the right tool for "freeze the world rarely, read constantly" situations,
+// such as guarding configuration that is reloaded once in a while. Under frequent
+// writes it performs far worse than rwticket.
+//
+// Example usage:
+//
+//	rw := percpurw.New()
+//
+//	rw.RLock()
+//	// ... read the current configuration ...
+//	rw.RUnlock()
+//
+//	rw.Lock()
+//	// ... swap in a new configuration ...
+//	rw.Unlock()
+package percpurw
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/spin"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+type counter struct {
+	n atomic.Int64
+
+	_ pad.CacheLinePad
+}
+
+// RWLock is a reader-writer lock with distributed read counters.
+type RWLock struct {
+	readers []counter
+	block   atomic.Bool  // Set while a writer holds or is acquiring the lock
+	writers *ticket.Lock // Serializes writers
+}
+
+// New creates an RWLock with one read counter per P.
+func New() *RWLock {
+	return &RWLock{
+		readers: make([]counter, runtime.GOMAXPROCS(0)),
+		writers: ticket.NewLock(),
+	}
+}
+
+// counter picks a read counter. Go has no stable notion of the current P, so readers
+// spread themselves randomly, which keeps them apart just as well.
+func (rw *RWLock) counter() *counter {
+	return &rw.readers[rand.Uint32()%uint32(len(rw.readers))]
+}
+
+// RLock acquires the lock for reading.
+func (rw *RWLock) RLock() {
+	c := rw.counter()
+	for {
+		c.n.Add(1)
+		chaos.Point()
+		if !rw.block.Load() {
+			return // Fast path: no writer around
+		}
+
+		// A writer is active: back out so it can see the counters drain, and wait for
+		// it to finish before trying again.
+		c.n.Add(-1)
+		rw.wait(func() bool { return !rw.block.Load() })
+	}
+}
+
+// RUnlock releases a read lock.
+func (rw *RWLock) RUnlock() {
+	chaos.Point()
+	rw.counter().n.Add(-1)
+}
+
+// Lock acquires the lock for writing.
+func (rw *RWLock) Lock() {
+	rw.writers.Lock()
+	rw.block.Store(true) // New readers now take the slow path
+	chaos.Point()
+	rw.wait(func() bool { return rw.activeReaders() == 0 })
+}
+
+// Unlock releases a write lock.
+func (rw *RWLock) Unlock() {
+	if invariant.Enabled {
+		// Readers arriving meanwhile count themselves before backing out, so the
+		// counters needn't have drained
+		invariant.Check(rw.block.Load(), "percpurw: Unlock of a lock not held for writing")
+	}
+	rw.block.Store(false)
+	rw.writers.Unlock()
+}
+
+// activeReaders sums the read counters. Individual counters may be negative when a
+// reader left from a different counter than it arrived on.
+func (rw *RWLock) activeReaders() int64 {
+	var n int64
+	for i := range rw.readers {
+		n += rw.readers[i].n.Load()
+	}
+	return n
+}
+
+// wait spins, then yields, until cond reports true.
+func (rw *RWLock) wait(cond func() bool) {
+	if cond() {
+		return
+	}
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	for i := 0; !cond(); i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+	lockprof.Record(start, 1)
+}
@@ -0,0 +1,114 @@
+package percpurw
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	locks "github.com/ahrav/go-locks"
+	"github.com/ahrav/go-locks/internal/allocs"
+	"github.com/ahrav/go-locks/spin"
+)
+
+var _ locks.RWLocker = (*RWLock)(nil)
+
+func TestConcurrentAccess(t *testing.T) {
+	rw := New()
+	const numReaders = 8
+	const numWriters = 2
+	const iterations = 300
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numReaders + numWriters)
+	for range numWriters {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				rw.Lock()
+				counter++
+				rw.Unlock()
+			}
+		}()
+	}
+	for range numReaders {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				rw.RLock()
+				_ = counter
+				rw.RUnlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numWriters*iterations, counter)
+	assert.Zero(t, rw.activeReaders())
+	assert.False(t, rw.block.Load())
+}
+
+func TestWriterWaitsForReaders(t *testing.T) {
+	rw := New()
+	rw.RLock()
+
+	locked := make(chan struct{})
+	go func() {
+		rw.Lock() //lockcheck:ignore The test unlocks it
+		close(locked)
+	}()
+	for !rw.block.Load() {
+		runtime.Gosched() // Wait for the writer to flip readers to the slow path
+	}
+
+	select {
+	case <-locked:
+		t.Fatal("Writer acquired the lock while a reader was inside")
+	default:
+	}
+	rw.RUnlock()
+	<-locked
+	rw.Unlock()
+}
+
+func BenchmarkRLock(b *testing.B) {
+	rw := New()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rw.RLock()
+			rw.RUnlock()
+		}
+	})
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	rw := New()
+	allocs.Zero(t, func() {
+		rw.Lock()
+		rw.Unlock()
+		rw.RLock()
+		rw.RUnlock()
+	}, "Uncontended acquisitions and releases should not allocate")
+}
+
+func TestYieldingWaiterLeavesSpinBudget(t *testing.T) {
+	if runtime.NumCPU() < 2 {
+		t.Skip("waiters never spin on one CPU")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+	rw := New()
+	rw.Lock()
+	done := make(chan struct{})
+	go func() {
+		rw.RLock()
+		rw.RUnlock()
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond) // Long past the waiter's spin limit
+	assert.Zero(t, spin.Spinners(), "A waiter that stopped spinning should give its place back")
+	rw.Unlock()
+	<-done
+}
@@ -0,0 +1,52 @@
+package locks
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/ahrav/go-locks/spin"
+)
+
+// Profile is the set of waiting parameters shared by every lock in this module: how
+// long waiters spin, how many may spin at once, and when they stop yielding and park.
+// See spin.Profile for the individual fields.
+type Profile = spin.Profile
+
+// Presets for SetProfile. Most programs only need to pick one of these rather than
+// tune the fields themselves:
+//
+//	locks.SetProfile(locks.ProfileEfficiency)
+var (
+	// ProfileLatency spins long and never parks, trading CPU time for the shortest
+	// handoffs. Suited to dedicated machines where cores would otherwise sit idle.
+	ProfileLatency = Profile{
+		Spin:    1024,
+		MinSpin: 64,
+		MaxSpin: 16384,
+	}
+
+	// ProfileBalanced is the default: moderate, self-tuning spinning, and parking only
+	// for ticket.Lock waiters far back in the queue.
+	ProfileBalanced = Profile{
+		Spin:    spin.DefaultSpinLimit,
+		MinSpin: spin.MinSpinLimit,
+		MaxSpin: spin.MaxSpinLimit,
+		Park:    spin.DefaultPark,
+	}
+
+	// ProfileEfficiency spins briefly, lets at most half the CPUs spin at once, and
+	// parks waiters that keep finding the lock taken. Suited to shared or
+	// oversubscribed machines, at the cost of slower handoffs under contention.
+	ProfileEfficiency = Profile{
+		Spin:    16,
+		MinSpin: 1,
+		MaxSpin: 256,
+		Budget:  max(runtime.NumCPU()/2, 1),
+		Yields:  8,
+		Park:    100 * time.Microsecond,
+	}
+)
+
+// SetProfile applies p to every lock in the process and returns the previous profile.
+// It is typically called once at startup with one of the presets.
+func SetProfile(p Profile) Profile { return spin.SetProfile(p) }
@@ -0,0 +1,42 @@
+package locks
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/spin"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestProfileBalancedIsDefault(t *testing.T) {
+	assert.Equal(t, ProfileBalanced, spin.Current())
+}
+
+func TestSetProfile(t *testing.T) {
+	for _, p := range []Profile{ProfileLatency, ProfileEfficiency, ProfileBalanced} {
+		prev := SetProfile(p)
+		assert.Equal(t, p, spin.Current())
+
+		for _, l := range []sync.Locker{ticket.NewLock(), mcs.NewLocker()} {
+			counter := 0
+			var wg sync.WaitGroup
+			for range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range 200 {
+						l.Lock()
+						counter++
+						l.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, 800, counter)
+		}
+		SetProfile(prev)
+	}
+}
@@ -0,0 +1,82 @@
+package proptest
+
+import (
+	"math/rand"
+	"reflect"
+)
+
+// maxGoroutines bounds the goroutines of generated histories and programs.
+const maxGoroutines = 4
+
+// MutexHistory is a sequential history of operations on a mutex by several goroutines,
+// each of which the MutexModel allows in the state the operations before it leave.
+type MutexHistory []Op
+
+// Generate returns a MutexHistory of size operations by up to four goroutines.
+func (MutexHistory) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(MutexHistory(history(r, size, new(MutexModel))))
+}
+
+// RWHistory is a sequential history of operations on a reader-writer lock by several
+// goroutines, each of which the RWModel allows in the state the operations before it
+// leave.
+type RWHistory []Op
+
+// Generate returns an RWHistory of size operations by up to four goroutines.
+func (RWHistory) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(RWHistory(history(r, size, new(RWModel))))
+}
+
+// model is a MutexModel or an RWModel.
+type model interface {
+	Enabled(g int) []Kind
+	Apply(op Op) bool
+}
+
+// history draws size operations of random goroutines from those m enables.
+func history(r *rand.Rand, size int, m model) []Op {
+	n := 1 + r.Intn(maxGoroutines)
+	ops := make([]Op, 0, size)
+	for range size {
+		g := r.Intn(n)
+		enabled := m.Enabled(g)
+		op := Op{Goroutine: g, Kind: enabled[r.Intn(len(enabled))]}
+		m.Apply(op)
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// MutexProgram is a concurrent program for a mutex: for each goroutine, the ways it
+// acquires the lock in turn, Lock or TryLock. Each acquisition that succeeds is
+// followed by a release.
+type MutexProgram [][]Kind
+
+// Generate returns a MutexProgram of two to four goroutines with up to size
+// acquisitions each.
+func (MutexProgram) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(MutexProgram(program(r, size, Lock, TryLock)))
+}
+
+// RWProgram is a concurrent program for a reader-writer lock: for each goroutine, the
+// ways it acquires the lock in turn, in either mode, blocking or not. Each acquisition
+// that succeeds is followed by the matching release.
+type RWProgram [][]Kind
+
+// Generate returns an RWProgram of two to four goroutines with up to size acquisitions
+// each.
+func (RWProgram) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(RWProgram(program(r, size, Lock, TryLock, RLock, TryRLock)))
+}
+
+// program draws scripts of acquisitions of the given kinds.
+func program(r *rand.Rand, size int, kinds ...Kind) [][]Kind {
+	scripts := make([][]Kind, 2+r.Intn(maxGoroutines-1))
+	for i := range scripts {
+		scripts[i] = make([]Kind, 1+r.Intn(max(size, 1)))
+		for j := range scripts[i] {
+			scripts[i][j] = kinds[r.Intn(len(kinds))]
+		}
+	}
+	return scripts
+}
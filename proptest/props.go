@@ -0,0 +1,234 @@
+package proptest
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stepTimeout bounds how long an operation the model says can't block may take before
+// the lock is deemed to have blocked, and hangTimeout how long a program may take under
+// the properties that don't set a timeout of their own.
+const (
+	stepTimeout = 10 * time.Second
+	hangTimeout = time.Minute
+)
+
+// MatchesMutexModel returns the property that a mutex made by newLock agrees with
+// MutexModel on a history: every TryLock succeeds exactly when the model's does, and
+// Lock returns at once whenever the lock is free. Each goroutine of the history is run
+// by a goroutine of its own, so locks that check their owner can be tested too.
+func MatchesMutexModel(newLock func() TryLocker) func(MutexHistory) bool {
+	return func(h MutexHistory) bool {
+		l := newLock()
+		return matches(h, new(MutexModel), func(k Kind) bool {
+			switch k {
+			case Lock:
+				l.Lock()
+			case Unlock:
+				l.Unlock()
+			case TryLock:
+				return l.TryLock()
+			}
+			return true
+		})
+	}
+}
+
+// MatchesRWModel returns the property that a reader-writer lock made by newLock agrees
+// with RWModel on a history, as MatchesMutexModel does for mutexes.
+func MatchesRWModel(newLock func() RWTryLocker) func(RWHistory) bool {
+	return func(h RWHistory) bool {
+		l := newLock()
+		return matches(h, new(RWModel), func(k Kind) bool {
+			switch k {
+			case Lock:
+				l.Lock()
+			case Unlock:
+				l.Unlock()
+			case TryLock:
+				return l.TryLock()
+			case RLock:
+				l.RLock() //lockcheck:ignore A later RUnlock of the history releases it
+			case RUnlock:
+				l.RUnlock()
+			case TryRLock:
+				return l.TryRLock()
+			}
+			return true
+		})
+	}
+}
+
+// matches runs the operations of h one at a time, each on the goroutine standing for
+// its own, and reports whether do returns what m expects for every one.
+func matches(h []Op, m model, do func(Kind) bool) bool {
+	workers := make(map[int]chan Kind)
+	defer func() {
+		for _, w := range workers {
+			close(w)
+		}
+	}()
+	results := make(chan bool, 1)
+
+	for _, op := range h {
+		w := workers[op.Goroutine]
+		if w == nil {
+			w = make(chan Kind)
+			workers[op.Goroutine] = w
+			go func() {
+				for k := range w {
+					results <- do(k)
+				}
+			}()
+		}
+		want := m.Apply(op)
+		w <- op.Kind
+		t := time.NewTimer(stepTimeout)
+		select {
+		case got := <-results:
+			t.Stop()
+			if got != want {
+				return false
+			}
+		case <-t.C:
+			return false
+		}
+	}
+	return true
+}
+
+// MutualExclusion returns the property that a lock made by newLock never admits two
+// goroutines of a program at once, and lets the program finish. TryLock acquisitions
+// use TryLock if the lock has it and Lock otherwise.
+func MutualExclusion(newLock func() sync.Locker) func(MutexProgram) bool {
+	return func(p MutexProgram) bool {
+		exclusive, finished := run(p, mutexOps(newLock()), hangTimeout)
+		return exclusive && finished
+	}
+}
+
+// EventualAcquisition returns the property that every acquisition of a program
+// completes within timeout on a lock made by newLock, so no waiter is lost or starved
+// for good. A program that fails it leaves its stuck goroutines behind.
+func EventualAcquisition(newLock func() sync.Locker, timeout time.Duration) func(MutexProgram) bool {
+	return func(p MutexProgram) bool {
+		_, finished := run(p, mutexOps(newLock()), timeout)
+		return finished
+	}
+}
+
+// RWExclusion returns the property that a reader-writer lock made by newLock never
+// admits a writer together with another writer or any reader, and lets the program
+// finish. Try acquisitions fall back to blocking ones on locks without them.
+func RWExclusion(newLock func() RWLocker) func(RWProgram) bool {
+	return func(p RWProgram) bool {
+		exclusive, finished := run(p, rwOps(newLock()), hangTimeout)
+		return exclusive && finished
+	}
+}
+
+// RWEventualAcquisition is EventualAcquisition for reader-writer locks.
+func RWEventualAcquisition(newLock func() RWLocker, timeout time.Duration) func(RWProgram) bool {
+	return func(p RWProgram) bool {
+		_, finished := run(p, rwOps(newLock()), timeout)
+		return finished
+	}
+}
+
+// ops acquires and releases a lock in each kind of a program.
+type ops struct {
+	acquire func(Kind) bool
+	release func(Kind)
+}
+
+func mutexOps(l sync.Locker) ops {
+	tl, canTry := l.(TryLocker)
+	return ops{
+		acquire: func(k Kind) bool {
+			if k == TryLock && canTry {
+				return tl.TryLock()
+			}
+			l.Lock()
+			return true
+		},
+		release: func(Kind) { l.Unlock() },
+	}
+}
+
+func rwOps(l RWLocker) ops {
+	tl, canTry := l.(RWTryLocker)
+	return ops{
+		acquire: func(k Kind) bool {
+			switch {
+			case k == TryLock && canTry:
+				return tl.TryLock()
+			case k == TryRLock && canTry:
+				return tl.TryRLock()
+			case isRead(k):
+				l.RLock() //lockcheck:ignore release releases it
+			default:
+				l.Lock()
+			}
+			return true
+		},
+		release: func(k Kind) {
+			if isRead(k) {
+				l.RUnlock()
+			} else {
+				l.Unlock()
+			}
+		},
+	}
+}
+
+func isRead(k Kind) bool { return k == RLock || k == TryRLock }
+
+// run runs each script of p on a goroutine of its own, and reports whether no
+// conflicting acquisitions overlapped and whether the program finished within timeout.
+func run(p [][]Kind, o ops, timeout time.Duration) (exclusive, finished bool) {
+	var writers, readers atomic.Int32
+	var overlap atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(len(p))
+	for _, script := range p {
+		go func() {
+			defer wg.Done()
+			for _, k := range script {
+				if !o.acquire(k) {
+					continue
+				}
+				if isRead(k) {
+					readers.Add(1)
+					if writers.Load() != 0 {
+						overlap.Store(true)
+					}
+					runtime.Gosched() // Give conflicting goroutines a chance to get in
+					readers.Add(-1)
+				} else {
+					if writers.Add(1) != 1 || readers.Load() != 0 {
+						overlap.Store(true)
+					}
+					runtime.Gosched()
+					writers.Add(-1)
+				}
+				o.release(k)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-done:
+		finished = true
+	case <-t.C:
+	}
+	return !overlap.Load(), finished
+}
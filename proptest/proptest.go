@@ -0,0 +1,179 @@
+// Package proptest describes the valid operation sequences of mutexes and reader-writer
+// locks, and their correctness properties, for property-based testing.
+//
+// Hand-written lock tests cover the orderings their authors thought of. Property-based
+// tests instead generate many random operation sequences and check that a property
+// holds for each. This package provides the pieces, compatible with testing/quick:
+//
+//   - Models: MutexModel and RWModel are state machines specifying what each operation
+//     should do, and which operations a goroutine may perform in each state. They can
+//     drive any property-testing library.
+//   - Generators: MutexHistory and RWHistory are sequential histories that only ever
+//     perform operations the model allows; MutexProgram and RWProgram are concurrent
+//     programs, a list of acquisitions for each goroutine. All four implement
+//     quick.Generator.
+//   - Properties: functions of a generated value that report whether a lock satisfies
+//     the property on it, for passing to quick.Check. MatchesMutexModel and
+//     MatchesRWModel run a history step by step and compare each TryLock with the
+//     model; MutualExclusion and RWExclusion run a program and check that no two
+//     goroutines ever hold the lock in conflicting modes; EventualAcquisition and
+//     RWEventualAcquisition check that every acquisition of a program completes.
+//
+// Example usage:
+//
+//	err := quick.Check(proptest.MutualExclusion(func() sync.Locker { return ticket.NewLock() }), nil)
+//	err = quick.Check(proptest.MatchesRWModel(func() proptest.RWTryLocker { return rwticket.NewLock() }), nil)
+package proptest
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Kind is an operation on a lock.
+type Kind uint8
+
+const (
+	Lock Kind = iota
+	Unlock
+	TryLock
+	RLock
+	RUnlock
+	TryRLock
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Lock:
+		return "Lock"
+	case Unlock:
+		return "Unlock"
+	case TryLock:
+		return "TryLock"
+	case RLock:
+		return "RLock"
+	case RUnlock:
+		return "RUnlock"
+	case TryRLock:
+		return "TryRLock"
+	}
+	return fmt.Sprintf("Kind(%d)", uint8(k))
+}
+
+// Op is an operation performed by one goroutine of a history.
+type Op struct {
+	Goroutine int
+	Kind      Kind
+}
+
+func (op Op) String() string { return fmt.Sprintf("G%d.%v", op.Goroutine, op.Kind) }
+
+// TryLocker is a mutex with TryLock, such as ticket.Lock.
+type TryLocker interface {
+	sync.Locker
+	TryLock() bool
+}
+
+// RWLocker is a reader-writer lock such as adaptiverw.RWLock or sync.RWMutex.
+type RWLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
+}
+
+// RWTryLocker is a reader-writer lock with TryLock and TryRLock, such as rwticket.Lock.
+type RWTryLocker interface {
+	RWLocker
+	TryLock() bool
+	TryRLock() bool
+}
+
+// MutexModel specifies a mutex. The zero value is unlocked.
+type MutexModel struct {
+	holder int // Holding goroutine plus one; 0 if none
+}
+
+// Enabled returns the operations goroutine g may perform without blocking: Unlock if
+// it holds the lock, and otherwise TryLock, and Lock if the lock is free.
+func (m *MutexModel) Enabled(g int) []Kind {
+	switch m.holder {
+	case 0:
+		return []Kind{Lock, TryLock}
+	case g + 1:
+		return []Kind{Unlock}
+	}
+	return []Kind{TryLock}
+}
+
+// Apply performs op on the model and returns its result: whether a TryLock succeeds,
+// and true for other operations. It panics if op isn't enabled.
+func (m *MutexModel) Apply(op Op) bool {
+	mustBeEnabled(m.Enabled(op.Goroutine), op)
+	switch op.Kind {
+	case Unlock:
+		m.holder = 0
+	case Lock, TryLock:
+		if m.holder != 0 {
+			return false
+		}
+		m.holder = op.Goroutine + 1
+	}
+	return true
+}
+
+// RWModel specifies a reader-writer lock. The zero value is unlocked.
+type RWModel struct {
+	writer  int // Writing goroutine plus one; 0 if none
+	readers map[int]bool
+}
+
+// Enabled returns the operations goroutine g may perform without blocking. A goroutine
+// holding the lock may only release it, as none of the locks is reentrant. One that
+// doesn't may always try either mode, read lock unless a writer holds the lock, and
+// write lock if nobody does.
+func (m *RWModel) Enabled(g int) []Kind {
+	switch {
+	case m.writer == g+1:
+		return []Kind{Unlock}
+	case m.readers[g]:
+		return []Kind{RUnlock}
+	case m.writer != 0:
+		return []Kind{TryLock, TryRLock}
+	case len(m.readers) > 0:
+		return []Kind{RLock, TryLock, TryRLock}
+	}
+	return []Kind{Lock, RLock, TryLock, TryRLock}
+}
+
+// Apply performs op on the model and returns its result: whether a TryLock or TryRLock
+// succeeds, and true for other operations. It panics if op isn't enabled.
+func (m *RWModel) Apply(op Op) bool {
+	mustBeEnabled(m.Enabled(op.Goroutine), op)
+	switch op.Kind {
+	case Unlock:
+		m.writer = 0
+	case RUnlock:
+		delete(m.readers, op.Goroutine)
+	case Lock, TryLock:
+		if m.writer != 0 || len(m.readers) > 0 {
+			return false
+		}
+		m.writer = op.Goroutine + 1
+	case RLock, TryRLock:
+		if m.writer != 0 {
+			return false
+		}
+		if m.readers == nil {
+			m.readers = make(map[int]bool)
+		}
+		m.readers[op.Goroutine] = true
+	}
+	return true
+}
+
+func mustBeEnabled(enabled []Kind, op Op) {
+	if !slices.Contains(enabled, op.Kind) {
+		panic(fmt.Sprintf("proptest: %v is not enabled", op))
+	}
+}
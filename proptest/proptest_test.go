@@ -0,0 +1,97 @@
+package proptest
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/adaptiverw"
+	"github.com/ahrav/go-locks/locksync"
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/percpurw"
+	"github.com/ahrav/go-locks/reentry"
+	"github.com/ahrav/go-locks/rwticket"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// concurrent bounds the programs run by the concurrent properties.
+var concurrent = &quick.Config{MaxCount: 20}
+
+var (
+	_ TryLocker   = (*ticket.Lock)(nil)
+	_ RWTryLocker = (*rwticket.Lock)(nil)
+	_ RWTryLocker = (*locksync.RWMutex)(nil)
+	_ RWLocker    = (*adaptiverw.RWLock)(nil)
+)
+
+func TestLocksMatchModels(t *testing.T) {
+	for name, newLock := range map[string]func() TryLocker{
+		"ticket": func() TryLocker { return ticket.NewLock() },
+		"mcs":    func() TryLocker { return mcs.NewLocker() },
+		"owned":  func() TryLocker { return ticket.NewOwnedLock() },
+		"sync":   func() TryLocker { return new(sync.Mutex) },
+	} {
+		assert.NoError(t, quick.Check(MatchesMutexModel(newLock), nil), name)
+	}
+	for name, newLock := range map[string]func() RWTryLocker{
+		"rwticket": func() RWTryLocker { return rwticket.NewLock() },
+		"locksync": func() RWTryLocker { return new(locksync.RWMutex) },
+		"sync":     func() RWTryLocker { return new(sync.RWMutex) },
+	} {
+		assert.NoError(t, quick.Check(MatchesRWModel(newLock), nil), name)
+	}
+}
+
+func TestLocksExcludeAndFinish(t *testing.T) {
+	for name, newLock := range map[string]func() sync.Locker{
+		"ticket":  func() sync.Locker { return ticket.NewLock() },
+		"mcs":     func() sync.Locker { return mcs.NewLocker() },
+		"reentry": func() sync.Locker { return reentry.Wrap(ticket.NewLock(), "reentry") },
+	} {
+		assert.NoError(t, quick.Check(MutualExclusion(newLock), concurrent), name)
+		assert.NoError(t, quick.Check(EventualAcquisition(newLock, 10*time.Second), concurrent), name)
+	}
+	for name, newLock := range map[string]func() RWLocker{
+		"rwticket":   func() RWLocker { return rwticket.NewLock() },
+		"adaptiverw": func() RWLocker { return adaptiverw.New() },
+		"percpurw":   func() RWLocker { return percpurw.New() },
+	} {
+		assert.NoError(t, quick.Check(RWExclusion(newLock), concurrent), name)
+		assert.NoError(t, quick.Check(RWEventualAcquisition(newLock, 10*time.Second), concurrent), name)
+	}
+}
+
+// broken is a mutex that excludes nobody and whose TryLock always succeeds.
+type broken struct{}
+
+func (*broken) Lock()         {}
+func (*broken) Unlock()       {}
+func (*broken) TryLock() bool { return true }
+
+func TestPropertiesCatchBrokenLock(t *testing.T) {
+	assert.Error(t, quick.Check(MatchesMutexModel(func() TryLocker { return new(broken) }), nil))
+	assert.Error(t, quick.Check(MutualExclusion(func() sync.Locker { return new(broken) }), concurrent))
+}
+
+func TestHistoriesFollowModel(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for range 100 {
+		h := MutexHistory{}.Generate(r, 50).Interface().(MutexHistory)
+		assert.Len(t, h, 50)
+		var m MutexModel
+		for _, op := range h {
+			assert.NotPanics(t, func() { m.Apply(op) })
+		}
+	}
+
+	var m RWModel
+	assert.True(t, m.Apply(Op{0, RLock}))
+	assert.True(t, m.Apply(Op{1, TryRLock}))
+	assert.False(t, m.Apply(Op{2, TryLock}), "Readers hold the lock")
+	assert.Equal(t, []Kind{RUnlock}, m.Enabled(0))
+	assert.Panics(t, func() { m.Apply(Op{2, Lock}) }, "Lock would block")
+}
@@ -0,0 +1,130 @@
+// Package ratelimit provides a rate limiter that releases blocked callers in the order
+// they arrived.
+//
+// Token-bucket limiters typically wake every blocked caller when a token frees up and
+// let them race for it, so an unlucky caller can lose every race and wait far longer
+// than the rate implies. This Limiter instead hands out release times: callers take
+// their turn on a ticket.Lock, each is assigned the earliest time the rate and burst
+// allow after the caller before it, and then sleeps until that time without holding
+// anything. Release times never decrease along the queue, so callers proceed in
+// arrival order, and a caller's wait is fixed when it arrives.
+//
+// Example usage:
+//
+//	lim := ratelimit.NewLimiter(100, 10) // 100 per second, bursts of up to 10
+//
+//	if err := lim.Wait(ctx); err != nil {
+//	    return err // ctx was done, or its deadline is sooner than our turn
+//	}
+//	send(req)
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ahrav/go-locks/clock"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// ErrDeadline is returned by Wait when the context's deadline is earlier than the time
+// the caller would be released.
+var ErrDeadline = errors.New("ratelimit: wait would exceed context deadline")
+
+// Limiter releases callers at a fixed rate, allowing bursts, in FIFO order. Create it
+// with NewLimiter.
+type Limiter struct {
+	mu       *ticket.Lock
+	interval time.Duration // Time per event
+	burst    int
+	clock    clock.Clock
+	next     time.Time // Theoretical time of the next event at the steady rate; guarded by mu
+}
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithClock makes the limiter read the time from c instead of clock.Real. Waits still
+// sleep in real time, for the durations measured on c.
+func WithClock(c clock.Clock) Option { return func(l *Limiter) { l.clock = c } }
+
+// NewLimiter creates a limiter that allows perSecond events per second on average, and
+// up to burst events at once after a quiet period. It panics unless both are positive.
+func NewLimiter(perSecond float64, burst int, opts ...Option) *Limiter {
+	if perSecond <= 0 || burst <= 0 {
+		panic("ratelimit: rate and burst must be positive")
+	}
+	l := &Limiter{
+		mu:       ticket.NewLock(),
+		interval: time.Duration(float64(time.Second) / perSecond),
+		burst:    burst,
+		clock:    clock.Real,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Wait blocks until the caller may proceed, behind every caller that arrived before it.
+// It returns ctx.Err() if ctx is done first, and ErrDeadline without waiting if ctx's
+// deadline is sooner than the caller's turn.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline, hasDeadline := ctx.Deadline()
+	at, d, ok := l.reserve(func(d time.Duration) bool { return !hasDeadline || d <= time.Until(deadline) })
+	if !ok {
+		return ErrDeadline
+	}
+	if d == 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(at)
+		return ctx.Err()
+	}
+}
+
+// Allow reports whether an event may happen now, taking it if so. It never takes a
+// turn ahead of callers already waiting.
+func (l *Limiter) Allow() bool {
+	_, _, ok := l.reserve(func(d time.Duration) bool { return d == 0 })
+	return ok
+}
+
+// reserve assigns the next release time, if accept agrees to the wait until it, and
+// returns it along with the wait.
+func (l *Limiter) reserve(accept func(wait time.Duration) bool) (at time.Time, wait time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	next := l.next
+	if next.Before(now) {
+		next = now // Idle: the bucket has refilled
+	}
+	at = next.Add(-time.Duration(l.burst-1) * l.interval)
+	wait = max(at.Sub(now), 0)
+	if !accept(wait) {
+		return time.Time{}, 0, false
+	}
+	l.next = next.Add(l.interval)
+	return at, wait, true
+}
+
+// cancel returns the turn released at at, if no later caller has been given a turn
+// since. Otherwise the turn is lost: later callers' release times are already fixed.
+func (l *Limiter) cancel(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.next.Add(-time.Duration(l.burst) * l.interval).Equal(at) {
+		l.next = l.next.Add(-l.interval)
+	}
+}
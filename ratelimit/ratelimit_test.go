@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/clock"
+)
+
+func TestAllowBurstThenRate(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	l := NewLimiter(10, 3, WithClock(fake))
+
+	for range 3 {
+		assert.True(t, l.Allow())
+	}
+	assert.False(t, l.Allow(), "Burst exhausted")
+	fake.Advance(100 * time.Millisecond)
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+
+	fake.Advance(time.Second) // Idle long enough to refill the whole burst, but no more
+	for range 3 {
+		assert.True(t, l.Allow())
+	}
+	assert.False(t, l.Allow())
+}
+
+func TestReleaseTimesFollowArrivalOrder(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	l := NewLimiter(10, 2, WithClock(fake))
+	accept := func(time.Duration) bool { return true }
+
+	var times []time.Duration
+	for range 5 {
+		_, wait, _ := l.reserve(accept)
+		times = append(times, wait)
+	}
+	assert.Equal(t, []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}, times)
+}
+
+func TestWaitReleasesAtRate(t *testing.T) {
+	l := NewLimiter(200, 1)
+	start := time.Now()
+	for range 3 {
+		assert.NoError(t, l.Wait(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond, "Two full intervals after the first")
+}
+
+func TestWaitRespectsContext(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	l := NewLimiter(1, 1, WithClock(fake))
+	assert.True(t, l.Allow())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.Wait(ctx), ErrDeadline, "Our turn is a second away")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Wait(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	fake.Advance(time.Second)
+	assert.True(t, l.Allow(), "The cancelled turn was returned")
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, l.Wait(cancelled), context.Canceled)
+}
@@ -0,0 +1,96 @@
+// Package rcu implements read-copy-update with epoch-based grace periods, and Value, a
+// typed value whose old versions are reclaimed only once no reader can still use them.
+//
+// Readers mark their read-side critical sections with ReadLock and ReadUnlock, which
+// never wait. A writer publishes a new version of the data, calls Synchronize, which
+// waits for a grace period, that is, until every read section that was running when it
+// was called has ended, and then reclaims the old version: by then no reader can still
+// hold it. Go's garbage collector already keeps memory alive for as long as it is
+// referenced, so what reclamation is for here is releasing what the collector doesn't
+// manage: closing files and connections, returning buffers to a pool, unmapping memory.
+//
+// A Domain counts readers in two counters, selected by the low bit of an epoch.
+// Synchronize flips the epoch, so that new readers count in the other counter, and waits
+// for the old one to drain; it does so twice, so that a reader that read the epoch just
+// before a flip but counted itself just after is waited for too. Grace periods of
+// concurrent Synchronize calls are serialized on a ticket.Lock. Workers that can name
+// points where they hold no references can avoid even that read-side cost with the
+// reclaim package's QSBR.
+//
+// Example usage:
+//
+//	conn := rcu.NewValue(dial(primary), rcu.WithReclaim(func(c *Conn) { c.Close() }))
+//
+//	conn.Read(func(c *Conn) { // c is not closed before fn returns
+//	    c.Send(msg)
+//	})
+//
+//	conn.Update(func(*Conn) *Conn { return dial(secondary) }) // Closes the old one
+package rcu
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/spin"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Domain tracks read-side critical sections and the grace periods that wait for them.
+// Readers and writers of the same data must use the same Domain.
+type Domain struct {
+	epoch   atomic.Uint32
+	readers [2]struct {
+		n atomic.Int64
+		_ pad.CacheLinePad // Readers of the two epochs don't share a line
+	}
+	mu *ticket.Lock // Serializes grace periods
+}
+
+// NewDomain creates a Domain.
+func NewDomain() *Domain { return &Domain{mu: ticket.NewLock()} }
+
+// Default is the Domain used by Values created without WithDomain.
+var Default = NewDomain()
+
+// Reader identifies a read-side critical section to ReadUnlock.
+type Reader uint32
+
+// ReadLock begins a read-side critical section. It never waits, and sections may nest.
+func (d *Domain) ReadLock() Reader {
+	r := Reader(d.epoch.Load() & 1)
+	d.readers[r].n.Add(1)
+	chaos.Point()
+	return r
+}
+
+// ReadUnlock ends the read-side critical section begun by the ReadLock that returned r.
+func (d *Domain) ReadUnlock(r Reader) {
+	chaos.Point()
+	n := d.readers[r].n.Add(-1)
+	if invariant.Enabled {
+		invariant.Check(n >= 0, "rcu: ReadUnlock without ReadLock")
+	}
+}
+
+// Synchronize waits for a grace period: it returns once every read-side critical
+// section that began before the call has ended. It must not be called from within a
+// read-side critical section of d, which would wait for itself.
+func (d *Domain) Synchronize() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for range 2 {
+		old := d.epoch.Add(1) - 1
+		d.drain(old & 1)
+	}
+}
+
+// drain waits until no reader counts in readers[i].
+func (d *Domain) drain(i uint32) {
+	for n := 0; d.readers[i].n.Load() != 0; n++ {
+		chaos.Point()
+		spin.Wait(n)
+	}
+}
@@ -0,0 +1,93 @@
+package rcu
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSynchronizeWaitsForReaders(t *testing.T) {
+	d := NewDomain()
+	r := d.ReadLock()
+	inner := d.ReadLock() // Sections nest
+	d.ReadUnlock(inner)
+
+	done := make(chan struct{})
+	go func() {
+		d.Synchronize()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Synchronize returned while a reader was in its section")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	d.ReadUnlock(r)
+	<-done
+	d.Synchronize() // No readers: returns at once
+}
+
+type resource struct {
+	closed atomic.Bool
+}
+
+func TestValueNeverReclaimsWhileRead(t *testing.T) {
+	var reclaimed atomic.Int32
+	v := NewValue(&resource{}, WithDomain[*resource](NewDomain()), WithReclaim(func(r *resource) {
+		r.closed.Store(true)
+		reclaimed.Add(1)
+	}))
+	const numReaders = 4
+	const updates = 50
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(numReaders)
+	for range numReaders {
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				v.Read(func(r *resource) {
+					assert.False(t, r.closed.Load(), "Read saw a reclaimed version")
+				})
+			}
+		}()
+	}
+	for range updates {
+		v.Update(func(*resource) *resource { return &resource{} })
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, int32(updates), reclaimed.Load())
+	assert.False(t, v.Load().closed.Load())
+}
+
+func TestValueUpdateSeesCurrentVersion(t *testing.T) {
+	v := NewValue(0)
+	const numGoroutines = 4
+	const iterations = 100
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				v.Update(func(n int) int { return n + 1 })
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, v.Load())
+}
@@ -0,0 +1,76 @@
+package rcu
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Value holds a value of type T that is read without locking and replaced by copying.
+// Create it with NewValue.
+type Value[T any] struct {
+	p       atomic.Pointer[T]
+	d       *Domain
+	mu      *ticket.Lock // Serializes updates
+	reclaim func(T)
+}
+
+// Option configures a Value.
+type Option[T any] func(*Value[T])
+
+// WithReclaim sets a function to release replaced versions of the value. Update calls it
+// on the version it replaced once a grace period has passed, so no reader in Read can
+// still be using it.
+func WithReclaim[T any](fn func(old T)) Option[T] {
+	return func(v *Value[T]) { v.reclaim = fn }
+}
+
+// WithDomain makes the Value wait for read sections of d instead of Default, for data
+// whose readers hold d's ReadLock across reads of several Values.
+func WithDomain[T any](d *Domain) Option[T] { return func(v *Value[T]) { v.d = d } }
+
+// NewValue creates a Value holding x.
+func NewValue[T any](x T, opts ...Option[T]) *Value[T] {
+	v := &Value[T]{d: Default, mu: ticket.NewLock()}
+	for _, opt := range opts {
+		opt(v)
+	}
+	v.p.Store(&x)
+	return v
+}
+
+// Load returns the current version. It is wait-free, but the version it returns may be
+// reclaimed at any time after; use Read, or hold the Domain's ReadLock, to keep it from
+// being reclaimed while in use. Without WithReclaim there is no such concern.
+func (v *Value[T]) Load() T { return *v.p.Load() }
+
+// Read runs fn with the current version, which is not reclaimed before fn returns. fn
+// must not call Update on a Value of the same Domain.
+func (v *Value[T]) Read(fn func(T)) {
+	r := v.d.ReadLock()
+	defer v.d.ReadUnlock(r)
+	fn(*v.p.Load())
+}
+
+// Update replaces the value with fn's result. fn is given the current version and must
+// not modify anything reachable from it that readers may be looking at; it builds a new
+// version instead. Updates are serialized. Once the new version is published, Update
+// waits for a grace period and reclaims the old version, so it must not be called from
+// within Read.
+func (v *Value[T]) Update(fn func(old T) T) {
+	old := v.swap(fn)
+	if v.reclaim != nil {
+		v.d.Synchronize()
+		v.reclaim(old)
+	}
+}
+
+// swap publishes fn's result under the update lock and returns the version it replaced.
+func (v *Value[T]) swap(fn func(old T) T) T {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	old := *v.p.Load()
+	next := fn(old)
+	v.p.Store(&next)
+	return old
+}
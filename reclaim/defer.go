@@ -0,0 +1,110 @@
+package reclaim
+
+import (
+	"sync"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Deferrer runs actions, such as freeing buffers or closing files, once a grace period
+// of its Domain has passed. Create it with NewDeferrer.
+//
+// Actions run in the order they were deferred, on a goroutine the Deferrer starts while
+// it has work. Actions deferred while a grace period is in progress are batched behind
+// it and share the next one, so a busy Deferrer waits for far fewer grace periods than
+// it runs actions. An action that panics crashes the program.
+type Deferrer struct {
+	d       Domain
+	mu      *ticket.Lock
+	batch   []func() // Guarded by mu
+	running bool     // Guarded by mu
+}
+
+// NewDeferrer creates a Deferrer waiting for grace periods of d.
+func NewDeferrer(d Domain) *Deferrer { return &Deferrer{d: d, mu: ticket.NewLock()} }
+
+// Defer schedules fns to run after a grace period that begins after the call. It never
+// waits for the grace period.
+func (r *Deferrer) Defer(fns ...func()) {
+	r.mu.Lock()
+	r.batch = append(r.batch, fns...)
+	start := !r.running
+	r.running = true
+	r.mu.Unlock()
+	if start {
+		go r.run()
+	}
+}
+
+// run waits for grace periods and runs batches until nothing is deferred.
+func (r *Deferrer) run() {
+	for {
+		r.mu.Lock()
+		batch := r.batch
+		r.batch = nil
+		if len(batch) == 0 {
+			r.running = false
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+
+		r.d.Synchronize()
+		for _, fn := range batch {
+			fn()
+		}
+	}
+}
+
+// Flush waits until every action deferred before it has run. Like Synchronize, it must
+// not be called from a reader of the Domain.
+func (r *Deferrer) Flush() {
+	done := make(chan struct{})
+	r.Defer(func() { close(done) })
+	<-done
+}
+
+// Locker wraps a lock so that its holder can defer actions until after the lock is
+// released and a grace period has passed, keeping them out of the critical section.
+// Create it with NewLocker.
+//
+// Example usage:
+//
+//	l := reclaim.NewLocker(nil, reclaim.NewDeferrer(rcu.Default))
+//
+//	l.Lock()
+//	old := table.swap(next)
+//	l.Defer(old.release) // Runs once readers of old are done, outside the lock
+//	l.Unlock()
+type Locker struct {
+	l       sync.Locker
+	r       *Deferrer
+	pending []func() // Guarded by l
+}
+
+// NewLocker wraps l, handing deferred actions to r. A nil l selects a ticket.Lock.
+// Several Lockers may share a Deferrer, and their actions then share grace periods.
+func NewLocker(l sync.Locker, r *Deferrer) *Locker {
+	if l == nil {
+		l = ticket.NewLock()
+	}
+	return &Locker{l: l, r: r}
+}
+
+// Lock acquires the lock.
+func (l *Locker) Lock() { l.l.Lock() }
+
+// Unlock releases the lock, then hands the actions deferred while it was held to the
+// Deferrer.
+func (l *Locker) Unlock() {
+	batch := l.pending
+	l.pending = nil
+	l.l.Unlock()
+	if len(batch) > 0 {
+		l.r.Defer(batch...)
+	}
+}
+
+// Defer schedules fn to run after the lock is released and a grace period has passed.
+// The lock must be held.
+func (l *Locker) Defer(fn func()) { l.pending = append(l.pending, fn) }
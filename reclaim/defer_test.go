@@ -0,0 +1,66 @@
+package reclaim
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/rcu"
+)
+
+var _ sync.Locker = (*Locker)(nil)
+
+func TestDeferredActionsWaitForUnlockAndGracePeriod(t *testing.T) {
+	q := NewQSBR()
+	reader := q.Register()
+	r := NewDeferrer(q)
+	l := NewLocker(nil, r)
+	var ran atomic.Bool
+
+	l.Lock()
+	l.Defer(func() { ran.Store(true) })
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, ran.Load(), "Ran while the lock was held")
+	l.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, ran.Load(), "Ran before the reader was quiescent")
+	reader.Offline() // Flush waits for a grace period of its own
+	r.Flush()
+	assert.True(t, ran.Load())
+}
+
+func TestDeferredActionsRunInOrder(t *testing.T) {
+	const numLockers = 4
+	const iterations = 100
+	r := NewDeferrer(rcu.NewDomain())
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+
+	wg.Add(numLockers)
+	for g := range numLockers {
+		go func() {
+			defer wg.Done()
+			l := NewLocker(nil, r)
+			last := -1
+			for i := range iterations {
+				l.Lock()
+				l.Defer(func() {
+					mu.Lock()
+					assert.Equal(t, last+1, i, "Locker %d ran out of order", g)
+					last = i
+					order = append(order, i)
+					mu.Unlock()
+				})
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	r.Flush()
+	assert.Len(t, order, numLockers*iterations)
+}
@@ -0,0 +1,96 @@
+package reclaim
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/spin"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// QSBR is a quiescent-state-based reclamation domain. Create it with NewQSBR.
+type QSBR struct {
+	period  atomic.Uint64 // Current grace period; starts at 1
+	mu      *ticket.Lock  // Guards threads and serializes grace periods
+	threads []*Thread
+}
+
+// NewQSBR creates a QSBR domain with no registered threads.
+func NewQSBR() *QSBR {
+	q := &QSBR{mu: ticket.NewLock()}
+	q.period.Store(1)
+	return q
+}
+
+// Thread is a worker registered with a QSBR domain. Its methods must be called from
+// the goroutine that uses it.
+type Thread struct {
+	q    *QSBR
+	seen atomic.Uint64 // Period of the last quiescent point; 0 while offline
+	_    pad.CacheLinePad
+}
+
+// Register adds an online Thread to the domain. Grace periods wait for it from then
+// on, until it goes offline or is unregistered.
+func (q *QSBR) Register() *Thread {
+	t := &Thread{q: q}
+	t.seen.Store(q.period.Load())
+	q.mu.Lock()
+	q.threads = append(q.threads, t)
+	q.mu.Unlock()
+	return t
+}
+
+// Unregister removes t from its domain. t must not be used afterwards.
+func (t *Thread) Unregister() {
+	t.seen.Store(0) // A grace period in progress stops waiting for t
+	q := t.q
+	q.mu.Lock()
+	q.threads = slices.DeleteFunc(q.threads, func(x *Thread) bool { return x == t })
+	q.mu.Unlock()
+}
+
+// Quiescent announces that t holds no references to data protected by the domain,
+// ending every grace period that was waiting for it. It is a load and a store.
+func (t *Thread) Quiescent() {
+	if invariant.Enabled {
+		invariant.Check(t.seen.Load() != 0, "reclaim: Quiescent of an offline Thread")
+	}
+	chaos.Point()
+	t.seen.Store(t.q.period.Load())
+}
+
+// Offline announces that t will hold no references to protected data until it calls
+// Online, so that grace periods don't wait for it meanwhile. Call it before blocking
+// for long, such as before waiting for the next request.
+func (t *Thread) Offline() {
+	chaos.Point()
+	t.seen.Store(0)
+}
+
+// Online ends an Offline period. t may read protected data once it returns.
+func (t *Thread) Online() {
+	t.seen.Store(t.q.period.Load())
+	chaos.Point()
+}
+
+// Synchronize waits for a grace period: it returns once every Thread online when it was
+// called has passed a quiescent point or gone offline. It must not be called from an
+// online Thread of q, which would wait for itself; call Offline first.
+func (q *QSBR) Synchronize() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	target := q.period.Add(1)
+	for _, t := range q.threads {
+		for n := 0; ; n++ {
+			if seen := t.seen.Load(); seen == 0 || seen >= target {
+				break
+			}
+			chaos.Point()
+			spin.Wait(n)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package reclaim
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/rcu"
+)
+
+var (
+	_ Domain = (*QSBR)(nil)
+	_ Domain = (*rcu.Domain)(nil)
+)
+
+func TestSynchronizeWaitsForQuiescentPoint(t *testing.T) {
+	q := NewQSBR()
+	th := q.Register()
+	done := make(chan struct{})
+	go func() {
+		q.Synchronize()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Synchronize returned before the thread was quiescent")
+	case <-time.After(20 * time.Millisecond):
+	}
+	th.Quiescent()
+	<-done
+}
+
+func TestOfflineThreadsAreNotWaitedFor(t *testing.T) {
+	q := NewQSBR()
+	th := q.Register()
+	th.Offline()
+	q.Synchronize()
+	th.Online()
+
+	gone := q.Register()
+	gone.Unregister()
+	th.Unregister()
+	q.Synchronize()
+}
+
+func TestNoReaderSeesReclaimedData(t *testing.T) {
+	const numReaders = 4
+	const updates = 200
+	type node struct{ freed atomic.Bool }
+	q := NewQSBR()
+	var cur atomic.Pointer[node]
+	cur.Store(&node{})
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+
+	wg.Add(numReaders)
+	for range numReaders {
+		go func() {
+			defer wg.Done()
+			th := q.Register()
+			defer th.Unregister()
+			for i := 0; !stop.Load(); i++ {
+				n := cur.Load()
+				assert.False(t, n.freed.Load(), "Read a reclaimed node")
+				th.Quiescent()
+				if i%16 == 0 { // Workers that go idle now and then
+					th.Offline()
+					th.Online()
+				}
+			}
+		}()
+	}
+	for range updates {
+		old := cur.Swap(&node{})
+		q.Synchronize()
+		old.freed.Store(true)
+	}
+	stop.Store(true)
+	wg.Wait()
+}
@@ -0,0 +1,45 @@
+// Package reclaim provides quiescent-state-based reclamation (QSBR), and Domain, the
+// interface shared by it and the epoch-based rcu.Domain, so that code that frees retired
+// data can be written once for either scheme.
+//
+// Both schemes answer the same question for a writer that has unpublished some data:
+// when can no reader still be using it? They differ in what readers do. With epochs,
+// every read section is bracketed by ReadLock and ReadUnlock, two atomic updates of a
+// shared counter per section. With QSBR, readers do nothing while reading; instead each
+// worker goroutine registers a Thread and calls Quiescent at points where it holds no
+// references to shared data, typically once per iteration of its main loop. A grace
+// period ends once every registered Thread has passed a quiescent point, or gone
+// offline. Reads are then free, at the cost of grace periods lasting as long as the
+// slowest worker's iteration, and of a worker that stops calling Quiescent without going
+// offline stalling reclamation altogether.
+//
+// Example usage:
+//
+//	q := reclaim.NewQSBR()
+//
+//	// Worker
+//	t := q.Register()
+//	defer t.Unregister()
+//	for req := range requests {
+//	    serve(routes.Load(), req) // No read-side marking
+//	    t.Quiescent()             // Holds no reference to routes here
+//	}
+//
+//	// Writer
+//	old := routes.Swap(next)
+//	q.Synchronize()
+//	old.Release()
+//
+// Waiting for a grace period inside a critical section, or even freeing what was
+// replaced, lengthens the time the lock is held. A Locker lets the holder defer that
+// work instead: the actions run on a Deferrer's goroutine once the lock is released and
+// a grace period of either kind of Domain has passed.
+package reclaim
+
+// Domain is a reclamation scheme: something that can wait for a grace period.
+// *rcu.Domain and *QSBR implement it.
+type Domain interface {
+	// Synchronize returns once every reader that might have seen data unpublished
+	// before the call has stopped using it.
+	Synchronize()
+}
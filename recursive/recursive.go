@@ -0,0 +1,152 @@
+// Package recursive provides a reader-writer lock that the goroutine holding it may
+// re-enter.
+//
+// Code ported from environments with recursive locks often nests read sections deeply,
+// taking the read lock again in a callee that its caller already holds. With an
+// ordinary FIFO or writer-preferring RW lock that deadlocks as soon as a writer queues
+// between the two acquisitions. RWLock tracks which goroutines hold it and satisfies a
+// nested acquisition from the goroutine's existing hold instead of queueing again:
+//   - A reader may take the read lock again any number of times
+//   - The writer may take the write lock again, and may take the read lock inside it
+//
+// Each acquisition must be matched by a release of the same kind. A reader may not
+// upgrade to the write lock, since two upgrading readers would deadlock each other;
+// Lock panics if the calling goroutine holds the read lock.
+//
+// Example usage:
+//
+//	rw := recursive.New()
+//
+//	rw.RLock()
+//	rw.RLock() // Re-entering does not queue behind waiting writers
+//	rw.RUnlock()
+//	rw.RUnlock()
+//
+// Owner tracking identifies goroutines by ID, which is slow to obtain in Go, so every
+// operation costs a microsecond or so more than on rwticket.Lock, which RWLock wraps.
+package recursive
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/rwticket"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// RWLock is a reentrant reader-writer lock.
+type RWLock struct {
+	rw *rwticket.Lock
+
+	writer      atomic.Int64 // ID of the goroutine holding the write lock, 0 if none
+	writeDepth  int          // Nested write acquisitions; owned by the writer
+	writerReads int          // Read acquisitions made by the writer; owned by the writer
+
+	mu      *ticket.Lock
+	readers map[int64]int // Read depth per goroutine; guarded by mu
+}
+
+// New creates a reentrant reader-writer lock.
+func New() *RWLock {
+	return &RWLock{
+		rw:      rwticket.NewLock(),
+		mu:      ticket.NewLock(),
+		readers: make(map[int64]int),
+	}
+}
+
+// RLock acquires the lock for reading, or re-enters a hold the calling goroutine
+// already has.
+func (l *RWLock) RLock() {
+	g := gid.Get()
+	if l.writer.Load() == g {
+		l.writerReads++
+		return
+	}
+
+	l.mu.Lock()
+	if n := l.readers[g]; n > 0 {
+		l.readers[g] = n + 1
+		l.mu.Unlock()
+		return
+	}
+	l.mu.Unlock()
+
+	l.rw.RLock()
+	l.mu.Lock()
+	l.readers[g] = 1
+	l.mu.Unlock()
+}
+
+// RUnlock releases one read acquisition made by the calling goroutine.
+func (l *RWLock) RUnlock() {
+	g := gid.Get()
+	if l.writer.Load() == g {
+		if l.writerReads == 0 {
+			panic("recursive: RUnlock of a read lock not held by this goroutine")
+		}
+		l.writerReads--
+		return
+	}
+
+	l.mu.Lock()
+	n := l.readers[g]
+	switch n {
+	case 0:
+		l.mu.Unlock()
+		panic("recursive: RUnlock of a read lock not held by this goroutine")
+	case 1:
+		delete(l.readers, g)
+		l.mu.Unlock()
+		l.rw.RUnlock()
+	default:
+		l.readers[g] = n - 1
+		l.mu.Unlock()
+	}
+}
+
+// Lock acquires the lock for writing, or re-enters the write hold the calling goroutine
+// already has. It panics if the calling goroutine holds the read lock.
+func (l *RWLock) Lock() {
+	g := gid.Get()
+	if l.writer.Load() == g {
+		l.writeDepth++
+		return
+	}
+
+	l.mu.Lock()
+	reading := l.readers[g] > 0
+	l.mu.Unlock()
+	if reading {
+		panic("recursive: Lock while holding the read lock")
+	}
+
+	l.rw.Lock()
+	l.writer.Store(g)
+	l.writeDepth = 1
+}
+
+// AdoptLock makes the calling goroutine the owner of the write lock, which another
+// goroutine holds and is handing over, for example through a locks.Token. It panics if
+// the write lock is not held.
+func (l *RWLock) AdoptLock() {
+	if l.writer.Load() == 0 {
+		panic("recursive: AdoptLock of a write lock that is not held")
+	}
+	l.writer.Store(gid.Get())
+}
+
+// Unlock releases one write acquisition made by the calling goroutine.
+func (l *RWLock) Unlock() {
+	if l.writer.Load() != gid.Get() {
+		panic("recursive: Unlock of a write lock not held by this goroutine")
+	}
+	if l.writeDepth == 1 && l.writerReads > 0 {
+		panic("recursive: Unlock of the write lock with read acquisitions outstanding")
+	}
+	if l.writeDepth--; l.writeDepth > 0 {
+		return
+	}
+	l.writer.Store(0)
+	l.rw.Unlock()
+}
@@ -0,0 +1,123 @@
+package recursive
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestNestedReadsDoNotQueueBehindWriter(t *testing.T) {
+	rw := New()
+	rw.RLock()
+
+	locked := make(chan struct{})
+	go func() {
+		rw.Lock()
+		close(locked)
+		rw.Unlock()
+	}()
+	for rw.rw.QueueDepth() < 2 {
+		time.Sleep(time.Millisecond) // Wait for the writer to queue behind us
+	}
+
+	rw.RLock() // Would deadlock on a plain rwticket.Lock
+	rw.RUnlock()
+	rw.RUnlock()
+	<-locked
+}
+
+func TestWriterReentry(t *testing.T) {
+	rw := New()
+	rw.Lock()
+	rw.Lock()
+	rw.RLock()
+	rw.RUnlock()
+	rw.Unlock()
+	assert.NotZero(t, rw.writer.Load(), "The outer write acquisition should still hold the lock")
+	rw.Unlock()
+	assert.Zero(t, rw.writer.Load())
+	assert.True(t, rw.rw.TryLock(), "The underlying lock should be free")
+}
+
+func TestMisuse(t *testing.T) {
+	rw := New()
+	assert.Panics(t, rw.RUnlock)
+	assert.Panics(t, rw.Unlock)
+
+	rw.RLock()
+	assert.Panics(t, rw.Lock, "Upgrading a read lock should panic")
+	rw.RUnlock()
+
+	rw.Lock()
+	rw.RLock()
+	assert.Panics(t, rw.Unlock, "Releasing the write lock under a nested read should panic")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Panics(t, rw.Unlock, "Only the writer may release the write lock")
+	}()
+	<-done
+	rw.RUnlock()
+	rw.Lock()
+	rw.Unlock()
+	assert.NotZero(t, rw.writer.Load(), "The failed releases should have left the hold intact")
+	rw.Unlock()
+	assert.True(t, rw.rw.TryLock(), "The underlying lock should be free")
+	rw.rw.Unlock()
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	rw := New()
+	const numGoroutines = 6
+	const iterations = 200
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				if g%2 == 0 {
+					rw.Lock()
+					rw.Lock()
+					counter++
+					rw.Unlock()
+					rw.Unlock()
+					continue
+				}
+				rw.RLock()
+				rw.RLock()
+				_ = counter
+				rw.RUnlock()
+				rw.RUnlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines/2*iterations, counter)
+	assert.Empty(t, rw.readers)
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	rw := New()
+	rw.RLock() // Let the readers map grow before measuring
+	rw.RUnlock()
+	allocs.Zero(t, func() {
+		rw.Lock()
+		rw.Lock()
+		rw.RLock()
+		rw.RUnlock()
+		rw.Unlock()
+		rw.Unlock()
+		rw.RLock()
+		rw.RLock()
+		rw.RUnlock()
+		rw.RUnlock()
+	}, "Acquisitions and re-entries should not allocate")
+}
@@ -0,0 +1,151 @@
+// Package reentry catches a goroutine acquiring a non-reentrant lock that it already
+// holds, which would otherwise deadlock it silently.
+//
+// None of the locks in this module is reentrant, and none records its holder, so a
+// goroutine that calls Lock twice simply waits on itself forever. The goroutine dump
+// then shows it parked in a wait loop with no indication of which lock it is stuck on,
+// or that it holds that lock. Wrapping a lock with Wrap or WrapRW adds the missing
+// bookkeeping, and a recursive acquisition panics with the lock's name instead.
+//
+// The check needs the calling goroutine's ID on every acquisition, which costs a few
+// microseconds (see package gid), so it is meant for tests and debug builds rather than
+// production hot paths.
+//
+// Example usage:
+//
+//	accounts := reentry.Wrap(ticket.NewLock(), "accounts")
+//
+//	accounts.Lock()
+//	accounts.Lock() // panic: reentry: goroutine 7 called Lock on "accounts" (*ticket.Lock), which it already holds
+package reentry
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Locker is a sync.Locker that panics on recursive acquisition.
+type Locker struct {
+	l     sync.Locker
+	name  string
+	owner atomic.Int64 // ID of the holding goroutine, 0 while free
+}
+
+// Wrap returns l checked for recursive acquisition. name identifies the lock in panic
+// messages.
+func Wrap(l sync.Locker, name string) *Locker { return &Locker{l: l, name: name} }
+
+// Lock acquires the lock, panicking if the calling goroutine already holds it.
+func (c *Locker) Lock() {
+	g := gid.Get()
+	if c.owner.Load() == g {
+		panic(recursion(g, "Lock", c.name, c.l))
+	}
+	c.l.Lock()
+	c.owner.Store(g)
+}
+
+// TryLock attempts to acquire the lock without blocking. It returns false if the
+// wrapped lock has no TryLock. A TryLock by the holder fails rather than deadlocking,
+// so it is not checked.
+func (c *Locker) TryLock() bool {
+	tl, ok := c.l.(interface{ TryLock() bool })
+	if !ok || !tl.TryLock() {
+		return false
+	}
+	c.owner.Store(gid.Get())
+	return true
+}
+
+// Unlock releases the lock.
+func (c *Locker) Unlock() {
+	c.owner.Store(0)
+	c.l.Unlock()
+}
+
+// RWLocker is a reader-writer lock such as rwticket.Lock or sync.RWMutex.
+type RWLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
+}
+
+// RWLock is a reader-writer lock that panics on recursive acquisition in any mode:
+// taking the write lock while holding either lock, or the read lock while holding the
+// write lock, always deadlocks, and taking the read lock again deadlocks as soon as a
+// writer queues in between on a fair or writer-preferring lock.
+//
+// Read holds are tracked per goroutine, so a read lock must be released by the
+// goroutine that acquired it; RUnlock panics otherwise.
+type RWLock struct {
+	l      RWLocker
+	name   string
+	writer atomic.Int64 // ID of the goroutine holding the write lock, 0 if none
+
+	mu      *ticket.Lock
+	readers map[int64]int // Read holds per goroutine; guarded by mu
+}
+
+// WrapRW returns l checked for recursive acquisition. name identifies the lock in panic
+// messages.
+func WrapRW(l RWLocker, name string) *RWLock {
+	return &RWLock{l: l, name: name, mu: ticket.NewLock(), readers: make(map[int64]int)}
+}
+
+// Lock acquires the write lock, panicking if the calling goroutine already holds the
+// lock in either mode.
+func (c *RWLock) Lock() {
+	g := gid.Get()
+	if c.writer.Load() == g || c.reading(g) {
+		panic(recursion(g, "Lock", c.name, c.l))
+	}
+	c.l.Lock()
+	c.writer.Store(g)
+}
+
+// Unlock releases the write lock.
+func (c *RWLock) Unlock() {
+	c.writer.Store(0)
+	c.l.Unlock()
+}
+
+// RLock acquires the read lock, panicking if the calling goroutine already holds the
+// lock in either mode.
+func (c *RWLock) RLock() {
+	g := gid.Get()
+	if c.writer.Load() == g || c.reading(g) {
+		panic(recursion(g, "RLock", c.name, c.l))
+	}
+	c.l.RLock()
+	c.mu.Lock()
+	c.readers[g]++
+	c.mu.Unlock()
+}
+
+// RUnlock releases the read lock held by the calling goroutine. It panics if the
+// calling goroutine doesn't hold the read lock.
+func (c *RWLock) RUnlock() {
+	g := gid.Get()
+	c.mu.Lock()
+	if c.readers[g] == 0 {
+		c.mu.Unlock()
+		panic(fmt.Sprintf("reentry: goroutine %d called RUnlock on %q (%T), which it doesn't hold for reading", g, c.name, c.l))
+	}
+	delete(c.readers, g) // RLock allows one read hold per goroutine
+	c.mu.Unlock()
+	c.l.RUnlock()
+}
+
+func (c *RWLock) reading(g int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.readers[g] > 0
+}
+
+func recursion(g int64, op, name string, l any) string {
+	return fmt.Sprintf("reentry: goroutine %d called %s on %q (%T), which it already holds", g, op, name, l)
+}
@@ -0,0 +1,105 @@
+package reentry
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/rwticket"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestLockerPanicsOnRecursiveLock(t *testing.T) {
+	l := Wrap(ticket.NewLock(), "accounts")
+	l.Lock()
+	want := fmt.Sprintf(`reentry: goroutine %d called Lock on "accounts" (*ticket.Lock), which it already holds`, gid.Get())
+	assert.PanicsWithValue(t, want, l.Lock)
+	l.Unlock()
+
+	l.Lock() // Free again after Unlock
+	l.Unlock()
+}
+
+func TestLockerTryLockByHolderFails(t *testing.T) {
+	l := Wrap(ticket.NewLock(), "accounts")
+	assert.True(t, l.TryLock())
+	assert.False(t, l.TryLock())
+	assert.Panics(t, l.Lock, "TryLock should record the holder")
+	l.Unlock()
+}
+
+func TestLockerAllowsOtherGoroutines(t *testing.T) {
+	l := Wrap(ticket.NewLock(), "counter")
+	const numGoroutines = 4
+	const iterations = 50
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				l.Lock()
+				counter++
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+}
+
+func TestRWLockPanicsOnRecursiveAcquisition(t *testing.T) {
+	l := WrapRW(rwticket.NewLock(), "config")
+
+	l.RLock()
+	assert.Panics(t, l.RLock, "Nested RLock may deadlock behind a queued writer")
+	assert.Panics(t, l.Lock, "Upgrading deadlocks")
+	l.RUnlock()
+
+	l.Lock()
+	assert.Panics(t, l.RLock, "RLock under the write lock deadlocks")
+	assert.Panics(t, l.Lock)
+	l.Unlock()
+
+	l.RLock() // Free again
+	l.RUnlock()
+}
+
+func TestRWLockRUnlockByAnotherGoroutinePanics(t *testing.T) {
+	l := WrapRW(rwticket.NewLock(), "config")
+
+	l.RLock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Panics(t, l.RUnlock, "Only the reader may release its read lock")
+	}()
+	<-done
+	assert.Len(t, l.readers, 1, "The reader's hold should still be recorded")
+	l.RUnlock()
+	assert.Empty(t, l.readers)
+	assert.True(t, l.l.(*rwticket.Lock).TryLock(), "The underlying lock should be free")
+}
+
+func TestRWLockConcurrentReaders(t *testing.T) {
+	l := WrapRW(rwticket.NewLock(), "config")
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				l.RLock()
+				l.RUnlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Empty(t, l.readers)
+}
@@ -0,0 +1,148 @@
+// Package refcount implements a split reference count: references are counted in
+// per-P shards while the object is live, and in one shared counter once its owner is
+// done with it, after the design of Linux's percpu_ref.
+//
+// A plain atomic counter puts every Incr and Decr on one cache line, which bounces
+// between cores, and between sockets, when many goroutines take and drop references.
+// Ref spreads them over GOMAXPROCS padded shards. A reference may be taken on one shard
+// and dropped on another, so a shard's count means nothing by itself and can go
+// negative; only the sum does. Summing the shards on every Decr to look for zero would
+// defeat the point, so a Ref cannot reach zero while it is live: it starts with one
+// reference belonging to its owner, which is dropped by Kill. Kill folds every shard into
+// the shared counter and marks it dead, and from then on references are counted there
+// and the Decr that takes the count to zero calls the zero callback.
+//
+// Example usage:
+//
+//	r := refcount.New(func() { buf.Free() })
+//
+//	// Users
+//	if r.TryIncr() {
+//	    use(buf)
+//	    r.Decr()
+//	}
+//
+//	// Owner, when retiring buf
+//	r.Kill() // Frees buf once the last user's Decr runs
+package refcount
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/pad"
+)
+
+// A shard word holds the shard's count, doubled, with the low bit set once the shard is
+// dead and references go to the shared counter instead. Adding two leaves the bit alone.
+const (
+	dead = 1
+	unit = 2
+)
+
+// bias stands in for the owner's reference in the shared counter, and is large enough to
+// keep it from reaching zero while Kill is still folding shards into it, when
+// references may already be dropped there that were taken on a shard not yet folded.
+const bias = 1 << 62
+
+type shard struct {
+	w atomic.Int64
+
+	_ pad.CacheLinePad
+}
+
+// Ref is a reference count. Create it with New.
+type Ref struct {
+	shards []shard
+	shared atomic.Int64
+	killed atomic.Bool
+	onZero func()
+}
+
+// New creates a live Ref holding its owner's reference. onZero is called, on the
+// goroutine that drops the last reference, once the count reaches zero after Kill.
+func New(onZero func()) *Ref {
+	r := &Ref{shards: make([]shard, runtime.GOMAXPROCS(0)), onZero: onZero}
+	r.shared.Store(bias)
+	return r
+}
+
+// shard picks a shard. As in percpurw, Go has no stable notion of the current P, so
+// references spread randomly.
+func (r *Ref) shard() *shard { return &r.shards[rand.Uint32()%uint32(len(r.shards))] }
+
+// Incr takes a reference. The caller must already hold one, or know the Ref is live;
+// use TryIncr otherwise.
+func (r *Ref) Incr() {
+	if !r.add(unit) {
+		n := r.shared.Add(1)
+		if invariant.Enabled {
+			invariant.Check(n > 1, "refcount: Incr of a Ref that reached zero")
+		}
+	}
+}
+
+// TryIncr takes a reference unless the count has reached zero, and reports whether it
+// did.
+func (r *Ref) TryIncr() bool {
+	if r.add(unit) {
+		return true
+	}
+	for n := r.shared.Load(); n > 0; n = r.shared.Load() {
+		if r.shared.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decr drops a reference, calling the zero callback if it was the last one.
+func (r *Ref) Decr() {
+	if !r.add(-unit) {
+		r.dropShared(1)
+	}
+}
+
+// add adds delta to a shard, unless the shard is dead, and reports whether it did.
+func (r *Ref) add(delta int64) bool {
+	s := r.shard()
+	for w := s.w.Load(); w&dead == 0; w = s.w.Load() {
+		if s.w.CompareAndSwap(w, w+delta) {
+			return true
+		}
+	}
+	return false
+}
+
+// Kill drops the owner's reference and switches the Ref to counting in the shared
+// counter, so that dropping the last reference calls the zero callback, possibly within
+// Kill. It panics if called twice.
+func (r *Ref) Kill() {
+	if !r.killed.CompareAndSwap(false, true) {
+		panic("refcount: Kill of a killed Ref")
+	}
+	for i := range r.shards {
+		old := r.shards[i].w.Or(dead)
+		r.shared.Add(old / unit) // Exact: the dead bit was clear
+		chaos.Point()
+	}
+	r.dropShared(bias) // What is left is what users hold, the owner's reference gone
+}
+
+// dropShared subtracts n from the shared counter and calls the zero callback if that
+// took it to zero.
+func (r *Ref) dropShared(n int64) {
+	left := r.shared.Add(-n)
+	if invariant.Enabled {
+		invariant.Check(left >= 0, "refcount: Decr of a Ref that reached zero")
+	}
+	if left == 0 {
+		r.onZero()
+	}
+}
+
+// Live reports whether Kill has not been called.
+func (r *Ref) Live() bool { return !r.killed.Load() }
@@ -0,0 +1,81 @@
+package refcount
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestZeroCallbackAfterKillAndLastDecr(t *testing.T) {
+	zeroed := 0
+	r := New(func() { zeroed++ })
+	r.Incr()
+	r.Incr()
+	r.Decr()
+	assert.Equal(t, 0, zeroed, "Reached zero while live")
+
+	r.Kill()
+	assert.False(t, r.Live())
+	assert.Equal(t, 0, zeroed)
+	assert.True(t, r.TryIncr())
+	r.Decr()
+	r.Decr()
+	assert.Equal(t, 1, zeroed)
+	assert.False(t, r.TryIncr(), "Took a reference after zero")
+	assert.Panics(t, r.Kill)
+}
+
+func TestKillWithoutReferencesCallsBack(t *testing.T) {
+	zeroed := false
+	r := New(func() { zeroed = true })
+	r.Kill()
+	assert.True(t, zeroed)
+}
+
+func TestConcurrentReferencesAcrossKill(t *testing.T) {
+	const numGoroutines = 8
+	const iterations = 2000
+	var zeroed atomic.Int32
+	var holders atomic.Int64
+	r := New(func() {
+		assert.Zero(t, holders.Load(), "Reached zero with references held")
+		zeroed.Add(1)
+	})
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		r.Incr() // Taken on behalf of the goroutine while surely live
+		go func() {
+			defer wg.Done()
+			defer r.Decr()
+			<-start
+			for range iterations {
+				if !r.TryIncr() {
+					t.Error("TryIncr failed while a reference was held")
+					return
+				}
+				holders.Add(1)
+				holders.Add(-1)
+				r.Decr()
+			}
+		}()
+	}
+	close(start)
+	r.Kill()
+	wg.Wait()
+	assert.Equal(t, int32(1), zeroed.Load())
+}
+
+func TestLiveReferencesDoNotAllocate(t *testing.T) {
+	r := New(func() {})
+	allocs.Zero(t, func() {
+		r.Incr()
+		r.Decr()
+	})
+}
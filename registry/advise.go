@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Thresholds of the rules in Advise.
+const (
+	splitContended = 0.1 // Share of acquisitions that waited for a lock to be worth splitting
+	coarsenShare   = 0.9 // Share of both locks' acquisitions made together to coarsen them
+)
+
+// Action is the kind of change an Advice suggests.
+type Action int
+
+const (
+	Split   Action = iota // Split or stripe the lock
+	Coarsen               // Merge the locks into one
+)
+
+func (a Action) String() string {
+	if a == Split {
+		return "split"
+	}
+	return "coarsen"
+}
+
+// Advice is a suggested change to the locking of a program.
+type Advice struct {
+	Action Action
+	Locks  []string // The lock to split, or the locks to merge
+	Reason string
+}
+
+// Advise suggests locks to split and groups of locks to coarsen from stats, a Snapshot
+// or the difference between two, most pressing first.
+//
+// A lock is worth splitting or striping when at least 10% of its acquisitions waited
+// and goroutines spent at least as long waiting for it as holding it, making it a
+// bottleneck rather than merely busy. Two locks are worth coarsening into one when one
+// is acquired inside the other in at least 90% of the acquisitions of each: they are
+// always taken together, so one lock would protect the same sections for half the
+// acquisitions. Finding those needs the HeldWith counts, which are only kept while
+// holders are tracked (see SetTrackHolders).
+func Advise(stats []Stats) []Advice {
+	type ranked struct {
+		Advice
+		weight time.Duration
+	}
+	var out []ranked
+	byName := make(map[string]Stats, len(stats))
+	for _, s := range stats {
+		byName[s.Name] = s
+	}
+
+	for _, s := range stats {
+		if s.Acquired == 0 || float64(s.Contended) < splitContended*float64(s.Acquired) || s.TotalWait < s.TotalHold {
+			continue
+		}
+		out = append(out, ranked{Advice{Split, []string{s.Name}, fmt.Sprintf(
+			"%.0f%% of %d acquisitions waited, for %v in total against %v held",
+			100*float64(s.Contended)/float64(s.Acquired), s.Acquired, s.TotalWait, s.TotalHold,
+		)}, s.TotalWait})
+	}
+
+	for _, inner := range stats {
+		for name, n := range inner.HeldWith {
+			outer, ok := byName[name]
+			if !ok || float64(n) < coarsenShare*float64(inner.Acquired) || float64(n) < coarsenShare*float64(outer.Acquired) {
+				continue
+			}
+			out = append(out, ranked{Advice{Coarsen, []string{outer.Name, inner.Name}, fmt.Sprintf(
+				"%d of %d acquisitions of %s were made holding %s, which was acquired %d times",
+				n, inner.Acquired, inner.Name, outer.Name, outer.Acquired,
+			)}, outer.TotalHold})
+		}
+	}
+
+	slices.SortStableFunc(out, func(a, b ranked) int { return cmp.Compare(b.weight, a.weight) })
+	advice := make([]Advice, len(out))
+	for i, r := range out {
+		advice[i] = r.Advice
+	}
+	return advice
+}
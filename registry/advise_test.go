@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestAdviseSplitsBottlenecks(t *testing.T) {
+	advice := Advise([]Stats{
+		{Name: "busy", Acquired: 1000, Contended: 50, TotalWait: time.Second, TotalHold: 2 * time.Second},
+		{Name: "hot", Acquired: 1000, Contended: 400, TotalWait: 3 * time.Second, TotalHold: time.Second},
+		{Name: "hotter", Acquired: 1000, Contended: 900, TotalWait: 9 * time.Second, TotalHold: time.Second},
+		{Name: "slow", Acquired: 10, Contended: 5, TotalWait: time.Second, TotalHold: 5 * time.Second},
+		{Name: "unused"},
+	})
+	if assert.Len(t, advice, 2) {
+		assert.Equal(t, Advice{Split, []string{"hotter"}, "90% of 1000 acquisitions waited, for 9s in total against 1s held"}, advice[0])
+		assert.Equal(t, []string{"hot"}, advice[1].Locks)
+	}
+}
+
+func TestAdviseCoarsensLocksTakenTogether(t *testing.T) {
+	defer SetTrackHolders(SetTrackHolders(true))
+	r := New()
+	accounts := r.Register("accounts", ticket.NewLock(), nil)
+	ledger := r.Register("ledger", ticket.NewLock(), nil)
+	audit := r.Register("audit", ticket.NewLock(), nil)
+	for i := range 20 {
+		accounts.Lock()
+		ledger.Lock()
+		if i%4 == 0 { // Only sometimes inside accounts
+			audit.Lock()
+			audit.Unlock()
+		}
+		ledger.Unlock()
+		accounts.Unlock()
+		if i%4 != 0 {
+			audit.Lock()
+			audit.Unlock()
+		}
+	}
+
+	snap := r.Snapshot()
+	assert.Equal(t, map[string]uint64{"accounts": 20}, snap[2].HeldWith)
+	advice := Advise(snap)
+	if assert.Len(t, advice, 1) {
+		assert.Equal(t, Coarsen, advice[0].Action)
+		assert.Equal(t, []string{"accounts", "ledger"}, advice[0].Locks)
+		assert.Equal(t, "20 of 20 acquisitions of ledger were made holding accounts, which was acquired 20 times", advice[0].Reason)
+	}
+}
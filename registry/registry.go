@@ -0,0 +1,257 @@
+// Package registry keeps a process-wide directory of named locks and their statistics.
+//
+// Registering a lock wraps it with an observe.Locker that feeds a set of counters, and
+// Snapshot reports the current state of every registered lock in one call, answering
+// "which locks are hot right now?":
+//
+//	accounts := registry.Register("accounts", ticket.NewLock(), map[string]string{"tier": "db"})
+//
+//	accounts.Lock()
+//	// ... critical section ...
+//	accounts.Unlock()
+//
+//	for _, s := range registry.Snapshot() {
+//	    fmt.Println(s.Name, s.Held, s.Waiters, s.Contended)
+//	}
+//
+// Which goroutine holds each lock is only tracked when SetTrackHolders is on, as it is
+// by default under the locksparanoid tag, since identifying the goroutine takes a few
+// microseconds per acquisition. Tracking also records which other registered locks a
+// goroutine holds whenever it acquires one, which Advise uses to find locks that are
+// always taken together. The debuglocks package serves snapshots over HTTP, and with
+// observe.SetProfileLabels on, goroutines waiting for a registered lock carry its name
+// in their pprof labels.
+package registry
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/observe"
+)
+
+// recentWaits is the number of most recent acquisitions whose waits the percentiles in
+// Stats are taken over.
+const recentWaits = 256
+
+var trackHolders atomic.Bool
+
+func init() { trackHolders.Store(invariant.Enabled) }
+
+// SetTrackHolders turns tracking of each lock's holding goroutine on or off and returns
+// the previous setting. It is on by default only under the locksparanoid tag.
+func SetTrackHolders(on bool) bool { return trackHolders.Swap(on) }
+
+// Stats is the state of a registered lock at the time of a Snapshot.
+type Stats struct {
+	Name   string
+	Labels map[string]string
+
+	Held       bool  // Whether the lock is currently held
+	Holder     int64 // ID of the holding goroutine if holders are tracked, else 0
+	Waiters    int   // Goroutines currently blocked acquiring the lock
+	QueueDepth int   // Goroutines holding or queued for the lock
+
+	Acquired  uint64        // Total successful acquisitions
+	Contended uint64        // Acquisitions that found the lock held
+	TotalWait time.Duration // Cumulative time spent waiting to acquire
+	TotalHold time.Duration // Cumulative time the lock was held
+	MaxWait   time.Duration // Longest single wait
+
+	// Acquisitions made while the same goroutine held another registered lock, by the
+	// other lock's name; only counted while holders are tracked
+	HeldWith map[string]uint64
+
+	// Percentiles of the waits of the most recent acquisitions, up to 256
+	WaitP50, WaitP90, WaitP99 time.Duration
+}
+
+// entry is the Observer attached to a registered lock.
+type entry struct {
+	r      *Registry
+	name   string
+	labels map[string]string
+	depth  observe.QueueDepther // nil if the lock can't report its queue depth
+
+	acquiring atomic.Int64 // Goroutines between OnAcquire and OnAcquired
+	acquired  atomic.Uint64
+	released  atomic.Uint64
+	contended atomic.Uint64
+	waitNanos atomic.Int64
+	holdNanos atomic.Int64
+	maxWait   atomic.Int64
+	holder    atomic.Int64
+
+	recent [recentWaits]atomic.Int64 // Ring of recent waits, indexed by acquired
+
+	mu       sync.Mutex
+	heldWith map[string]uint64 // Guarded by mu
+}
+
+func (e *entry) OnAcquire() { e.acquiring.Add(1) }
+
+func (e *entry) OnAcquired(wait time.Duration) {
+	e.acquiring.Add(-1)
+	n := e.acquired.Add(1)
+	e.recent[(n-1)%recentWaits].Store(int64(wait))
+	e.waitNanos.Add(int64(wait))
+	if trackHolders.Load() {
+		g := gid.Get()
+		e.holder.Store(g)
+		e.countHeldWith(g)
+	}
+	for {
+		cur := e.maxWait.Load()
+		if int64(wait) <= cur || e.maxWait.CompareAndSwap(cur, int64(wait)) {
+			break
+		}
+	}
+}
+
+func (e *entry) OnRelease(hold time.Duration) {
+	if trackHolders.Load() {
+		e.holder.CompareAndSwap(gid.Get(), 0) // Unless the next holder already got in
+	}
+	e.released.Add(1)
+	e.holdNanos.Add(int64(hold))
+}
+
+func (e *entry) OnContended(int) { e.contended.Add(1) }
+
+// countHeldWith counts an acquisition by goroutine g against every other lock g holds.
+func (e *entry) countHeldWith(g int64) {
+	for _, o := range *e.r.all.Load() {
+		if o == e || o.holder.Load() != g {
+			continue
+		}
+		e.mu.Lock()
+		if e.heldWith == nil {
+			e.heldWith = make(map[string]uint64)
+		}
+		e.heldWith[o.name]++
+		e.mu.Unlock()
+	}
+}
+
+func (e *entry) stats() Stats {
+	acquired := e.acquired.Load()
+	s := Stats{
+		Name:      e.name,
+		Labels:    e.labels,
+		Held:      acquired > e.released.Load(),
+		Waiters:   int(max(e.acquiring.Load(), 0)),
+		Acquired:  acquired,
+		Contended: e.contended.Load(),
+		TotalWait: time.Duration(e.waitNanos.Load()),
+		TotalHold: time.Duration(e.holdNanos.Load()),
+		MaxWait:   time.Duration(e.maxWait.Load()),
+	}
+	if s.Held {
+		s.Holder = e.holder.Load()
+	}
+	e.mu.Lock()
+	if len(e.heldWith) > 0 {
+		s.HeldWith = maps.Clone(e.heldWith)
+	}
+	e.mu.Unlock()
+	if e.depth != nil {
+		s.QueueDepth = e.depth.QueueDepth()
+	} else if s.QueueDepth = s.Waiters; s.Held {
+		s.QueueDepth++
+	}
+
+	waits := make([]time.Duration, min(acquired, recentWaits))
+	for i := range waits {
+		waits[i] = time.Duration(e.recent[i].Load())
+	}
+	slices.Sort(waits)
+	s.WaitP50, s.WaitP90, s.WaitP99 = percentile(waits, 50), percentile(waits, 90), percentile(waits, 99)
+	return s
+}
+
+// percentile returns the p-th percentile of sorted, by the nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)*p+99)/100-1]
+}
+
+// Registry is a set of named locks.
+type Registry struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	all     atomic.Pointer[[]*entry] // The entries as a slice, replaced on every change
+}
+
+// New creates an empty Registry.
+func New() *Registry {
+	r := &Registry{entries: make(map[string]*entry)}
+	r.all.Store(new([]*entry))
+	return r
+}
+
+// publish refreshes r.all from r.entries. r.mu must be held.
+func (r *Registry) publish() {
+	all := slices.Collect(maps.Values(r.entries))
+	r.all.Store(&all)
+}
+
+// Default is the process-wide Registry used by the package-level functions.
+var Default = New()
+
+// Register instruments l under name and returns the instrumented lock, which must be
+// used in place of l for its operations to be counted. It panics if name is already
+// registered, like expvar.Publish.
+func (r *Registry) Register(name string, l sync.Locker, labels map[string]string) *observe.Locker {
+	e := &entry{r: r, name: name, labels: labels}
+	e.depth, _ = l.(observe.QueueDepther)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.entries[name]; dup {
+		panic("registry: reuse of lock name " + name)
+	}
+	r.entries[name] = e
+	r.publish()
+	return observe.Wrap(l, e, observe.WithName(name))
+}
+
+// Unregister removes name from the registry. The lock keeps working but is no longer
+// reported.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.entries, name)
+	r.publish()
+	r.mu.Unlock()
+}
+
+// Snapshot returns the current statistics of every registered lock, sorted by name.
+func (r *Registry) Snapshot() []Stats {
+	r.mu.Lock()
+	out := make([]Stats, 0, len(r.entries))
+	for _, e := range r.entries {
+		out = append(out, e.stats())
+	}
+	r.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Register instruments l under name in the Default registry.
+func Register(name string, l sync.Locker, labels map[string]string) *observe.Locker {
+	return Default.Register(name, l, labels)
+}
+
+// Unregister removes name from the Default registry.
+func Unregister(name string) { Default.Unregister(name) }
+
+// Snapshot returns the statistics of every lock in the Default registry.
+func Snapshot() []Stats { return Default.Snapshot() }
@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestSnapshotReportsState(t *testing.T) {
+	r := New()
+	a := r.Register("a", ticket.NewLock(), map[string]string{"tier": "db"})
+	r.Register("b", mcs.NewLocker(), nil)
+
+	a.Lock()
+	done := make(chan struct{})
+	go func() {
+		a.Lock()
+		a.Unlock()
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	snap := r.Snapshot()
+	assert.Len(t, snap, 2)
+	assert.Equal(t, "a", snap[0].Name)
+	assert.Equal(t, "db", snap[0].Labels["tier"])
+	assert.True(t, snap[0].Held)
+	assert.Equal(t, 1, snap[0].Waiters)
+	assert.Equal(t, uint64(1), snap[0].Contended)
+	assert.False(t, snap[1].Held)
+
+	a.Unlock()
+	<-done
+
+	snap = r.Snapshot()
+	assert.False(t, snap[0].Held)
+	assert.Zero(t, snap[0].Waiters)
+	assert.Equal(t, uint64(2), snap[0].Acquired)
+	assert.GreaterOrEqual(t, snap[0].MaxWait, 5*time.Millisecond)
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	r := New()
+	r.Register("dup", ticket.NewLock(), nil)
+	assert.Panics(t, func() { r.Register("dup", ticket.NewLock(), nil) })
+
+	r.Unregister("dup")
+	assert.NotPanics(t, func() { r.Register("dup", ticket.NewLock(), nil) })
+}
+
+func TestSnapshotReportsHolderAndDepth(t *testing.T) {
+	defer SetTrackHolders(SetTrackHolders(true))
+	r := New()
+	a := r.Register("a", ticket.NewLock(), nil)
+	b := r.Register("b", mcs.NewLocker(), nil)
+
+	a.Lock()
+	b.Lock()
+	snap := r.Snapshot()
+	assert.Equal(t, gid.Get(), snap[0].Holder)
+	assert.Equal(t, 1, snap[0].QueueDepth, "From the lock itself")
+	assert.Equal(t, 1, snap[1].QueueDepth, "From the counters")
+	a.Unlock()
+	b.Unlock()
+
+	snap = r.Snapshot()
+	assert.Zero(t, snap[0].Holder)
+	assert.Zero(t, snap[0].QueueDepth)
+	assert.Zero(t, snap[1].QueueDepth)
+}
+
+func TestWaitPercentiles(t *testing.T) {
+	r := New()
+	r.Register("a", ticket.NewLock(), nil)
+	e := r.entries["a"]
+	for i := range 2 * recentWaits { // Only the most recent recentWaits count
+		e.OnAcquire()
+		if i < recentWaits {
+			e.OnAcquired(time.Hour)
+		} else {
+			e.OnAcquired(time.Duration(i-recentWaits+1) * time.Millisecond)
+		}
+		e.OnRelease(0)
+	}
+
+	s := r.Snapshot()[0]
+	assert.Equal(t, 128*time.Millisecond, s.WaitP50)
+	assert.Equal(t, 231*time.Millisecond, s.WaitP90)
+	assert.Equal(t, 254*time.Millisecond, s.WaitP99)
+	assert.Equal(t, time.Hour, s.MaxWait)
+}
@@ -0,0 +1,93 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// magic starts every encoded trace.
+const magic = "go-locks trace 1\n"
+
+// ErrFormat is returned by ReadTrace for input that isn't an encoded trace.
+var ErrFormat = errors.New("replay: malformed trace")
+
+// WriteTo encodes t to w: the lock names, then each event as its lock and op packed into
+// one varint, its goroutine, and the time since the previous event, so that an event
+// usually takes four to six bytes.
+func (t *Trace) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	var buf [binary.MaxVarintLen64]byte
+	put := func(v uint64) {
+		k, _ := bw.Write(buf[:binary.PutUvarint(buf[:], v)])
+		n += int64(k)
+	}
+
+	k, _ := bw.WriteString(magic)
+	n += int64(k)
+	put(uint64(len(t.Locks)))
+	for _, name := range t.Locks {
+		put(uint64(len(name)))
+		k, _ := bw.WriteString(name)
+		n += int64(k)
+	}
+	var prev time.Duration
+	for _, e := range t.Events {
+		put(uint64(e.Lock)<<2 | uint64(e.Op))
+		put(uint64(e.Goroutine))
+		put(uint64(max(e.Time-prev, 0)))
+		prev = max(e.Time, prev)
+	}
+	return n, bw.Flush()
+}
+
+// ReadTrace decodes a trace written by Trace.WriteTo.
+func ReadTrace(r io.Reader) (*Trace, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, head); err != nil || string(head) != magic {
+		return nil, ErrFormat
+	}
+
+	numLocks, err := binary.ReadUvarint(br)
+	if err != nil || numLocks > math.MaxUint32 {
+		return nil, ErrFormat
+	}
+	t := &Trace{}
+	for range numLocks {
+		size, err := binary.ReadUvarint(br)
+		if err != nil || size > 1<<16 {
+			return nil, ErrFormat
+		}
+		name := make([]byte, size)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return nil, ErrFormat
+		}
+		t.Locks = append(t.Locks, string(name))
+	}
+
+	var now time.Duration
+	for {
+		lockOp, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return t, nil
+		}
+		g, err2 := binary.ReadUvarint(br)
+		delta, err3 := binary.ReadUvarint(br)
+		if err := errors.Join(err, err2, err3); err != nil {
+			return nil, ErrFormat
+		}
+		e := Event{Lock: uint32(lockOp >> 2), Goroutine: int64(g), Op: Op(lockOp & 3)}
+		if lockOp>>2 >= uint64(len(t.Locks)) || e.Op > Release {
+			return nil, fmt.Errorf("%w: event %d is %v of lock %d", ErrFormat, len(t.Events), e.Op, lockOp>>2)
+		}
+		now += time.Duration(delta)
+		e.Time = now
+		t.Events = append(t.Events, e)
+	}
+}
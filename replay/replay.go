@@ -0,0 +1,126 @@
+// Package replay records the lock operations of a running program and re-executes them
+// against any lock implementation.
+//
+// A Recorder is an observe.Observer that logs, for every operation on the locks it
+// instruments, the lock, the goroutine, the stage (Acquire, Acquired, Release) and when
+// it happened. The log is a Trace, which WriteTo encodes in a few bytes per event so
+// that it can be captured in production and carried elsewhere:
+//
+//	rec := replay.NewRecorder()
+//	accounts := rec.Wrap(ticket.NewLock(), "accounts")
+//	// ... run the program ...
+//	rec.Trace().WriteTo(f)
+//
+// Replay then runs one goroutine per recorded goroutine, each repeating its recorded
+// sequence of acquisitions and releases with the same gaps between them, against fresh
+// locks from a constructor. Comparing the waits of replays against the current and a
+// candidate algorithm evaluates the candidate on the interleaving production produced:
+//
+//	tr, err := replay.ReadTrace(f)
+//	res, err := replay.Replay(tr, func(string) sync.Locker { return mcs.NewLocker() })
+//	fmt.Println(res.Recorded.P99, res.Wait.P99)
+//
+// Recording identifies the goroutine of each event with gid.Get, which costs a few
+// microseconds, so a Recorder is for capturing a trace rather than for leaving on.
+package replay
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/observe"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Op is the stage of a lock operation an Event records.
+type Op uint8
+
+const (
+	Acquire  Op = iota // Lock was called
+	Acquired           // Lock returned
+	Release            // Unlock returned
+)
+
+func (op Op) String() string {
+	switch op {
+	case Acquire:
+		return "acquire"
+	case Acquired:
+		return "acquired"
+	case Release:
+		return "release"
+	}
+	return "unknown"
+}
+
+// Event is one recorded lock operation.
+type Event struct {
+	Lock      uint32 // Index of the lock in Trace.Locks
+	Goroutine int64
+	Op        Op
+	Time      time.Duration // Since recording started
+}
+
+// Trace is a recorded sequence of lock operations, in the order they happened.
+type Trace struct {
+	Locks  []string // Names of the locks, indexed by Event.Lock
+	Events []Event
+}
+
+// Recorder records the operations of the locks it observes into a Trace. Create it with
+// NewRecorder.
+type Recorder struct {
+	start  time.Time
+	mu     *ticket.Lock // Guards locks and events
+	locks  []string
+	events []Event
+}
+
+// NewRecorder creates a Recorder whose trace starts now.
+func NewRecorder() *Recorder {
+	return &Recorder{start: time.Now(), mu: ticket.NewLock()}
+}
+
+// Observer returns an Observer that records the operations of one lock under name.
+// Each call adds a new lock to the trace, even if name was used before.
+func (r *Recorder) Observer(name string) observe.Observer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.locks = append(r.locks, name)
+	return &observer{r: r, id: uint32(len(r.locks) - 1)}
+}
+
+// Wrap returns l instrumented to record its operations under name.
+func (r *Recorder) Wrap(l sync.Locker, name string) *observe.Locker {
+	return observe.Wrap(l, r.Observer(name), observe.WithName(name))
+}
+
+// Trace returns a copy of everything recorded so far.
+func (r *Recorder) Trace() *Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Trace{
+		Locks:  append([]string(nil), r.locks...),
+		Events: append([]Event(nil), r.events...),
+	}
+}
+
+// record appends an event, timestamped under r.mu so that events are in time order.
+func (r *Recorder) record(id uint32, op Op) {
+	g := gid.Get()
+	r.mu.Lock()
+	r.events = append(r.events, Event{Lock: id, Goroutine: g, Op: op, Time: time.Since(r.start)})
+	r.mu.Unlock()
+}
+
+// observer is the Observer of one recorded lock.
+type observer struct {
+	r  *Recorder
+	id uint32
+}
+
+func (o *observer) OnAcquire()               { o.r.record(o.id, Acquire) }
+func (o *observer) OnAcquired(time.Duration) { o.r.record(o.id, Acquired) }
+func (o *observer) OnRelease(time.Duration)  { o.r.record(o.id, Release) }
+func (o *observer) OnContended(int)          {}
@@ -0,0 +1,132 @@
+package replay
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/mcs"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// record runs goroutines that take outer and then inner, and returns their trace.
+func record(t *testing.T, goroutines, iterations int) *Trace {
+	t.Helper()
+	rec := NewRecorder()
+	outer := rec.Wrap(ticket.NewLock(), "outer")
+	inner := rec.Wrap(ticket.NewLock(), "inner")
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for range goroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				outer.Lock()
+				inner.Lock()
+				inner.Unlock()
+				outer.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return rec.Trace()
+}
+
+func TestRecorderRecordsEveryStage(t *testing.T) {
+	tr := record(t, 1, 1)
+	assert.Equal(t, []string{"outer", "inner"}, tr.Locks)
+	var ops []Op
+	var locks []uint32
+	for i, e := range tr.Events {
+		ops = append(ops, e.Op)
+		locks = append(locks, e.Lock)
+		assert.Equal(t, tr.Events[0].Goroutine, e.Goroutine)
+		if i > 0 {
+			assert.GreaterOrEqual(t, e.Time, tr.Events[i-1].Time)
+		}
+	}
+	assert.Equal(t, []Op{Acquire, Acquired, Acquire, Acquired, Release, Release}, ops)
+	assert.Equal(t, []uint32{0, 0, 1, 1, 1, 0}, locks)
+}
+
+func TestTraceRoundTrip(t *testing.T) {
+	tr := record(t, 4, 50)
+	var buf bytes.Buffer
+	n, err := tr.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	got, err := ReadTrace(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, tr, got)
+
+	_, err = ReadTrace(bytes.NewReader([]byte("not a trace")))
+	assert.ErrorIs(t, err, ErrFormat)
+}
+
+func TestReplayAgainstAnotherLock(t *testing.T) {
+	const goroutines, iterations = 4, 50
+	tr := record(t, goroutines, iterations)
+
+	var names []string
+	res, err := Replay(tr, func(name string) sync.Locker {
+		names = append(names, name)
+		return mcs.NewLocker()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"outer", "inner"}, names)
+	assert.Equal(t, 2*goroutines*iterations, res.Ops)
+	assert.LessOrEqual(t, res.Wait.P50, res.Wait.Max)
+	assert.LessOrEqual(t, res.Recorded.P50, res.Recorded.Max)
+}
+
+func TestReplayPreservesGaps(t *testing.T) {
+	const gap = 20 * time.Millisecond
+	tr := &Trace{Locks: []string{"l"}, Events: []Event{
+		{Goroutine: 1, Op: Acquire, Time: gap},
+		{Goroutine: 1, Op: Acquired, Time: gap},
+		{Goroutine: 1, Op: Release, Time: 2 * gap},
+		{Goroutine: 1, Op: Acquire, Time: 3 * gap}, // Never acquired; dropped
+	}}
+	res, err := Replay(tr, func(string) sync.Locker { return ticket.NewLock() })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Ops)
+	assert.GreaterOrEqual(t, res.Elapsed, 2*gap)
+}
+
+func TestReplayReleasesLocksHeldAtEnd(t *testing.T) {
+	const gap = 5 * time.Millisecond
+	tr := &Trace{Locks: []string{"l"}, Events: []Event{
+		{Goroutine: 1, Op: Acquire},
+		{Goroutine: 1, Op: Acquired}, // Still held when the trace ends
+		{Goroutine: 2, Op: Acquire, Time: gap},
+		{Goroutine: 2, Op: Acquired, Time: gap},
+		{Goroutine: 2, Op: Release, Time: 2 * gap},
+	}}
+	done := make(chan Result)
+	go func() {
+		res, err := Replay(tr, func(string) sync.Locker { return ticket.NewLock() })
+		assert.NoError(t, err)
+		done <- res
+	}()
+	select {
+	case res := <-done:
+		assert.Equal(t, 2, res.Ops)
+		assert.GreaterOrEqual(t, res.Elapsed, 2*gap, "Goroutine 1 should hold the lock until the end")
+	case <-time.After(10 * time.Second):
+		t.Fatal("Replay waits for good on a lock held at the end of the trace")
+	}
+}
+
+func TestReplayRejectsForeignRelease(t *testing.T) {
+	tr := &Trace{Locks: []string{"l"}, Events: []Event{
+		{Goroutine: 1, Op: Acquire},
+		{Goroutine: 1, Op: Acquired},
+		{Goroutine: 2, Op: Release},
+	}}
+	_, err := Replay(tr, func(string) sync.Locker { return ticket.NewLock() })
+	assert.ErrorContains(t, err, `goroutine 2 releases "l" without holding it`)
+}
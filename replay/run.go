@@ -0,0 +1,166 @@
+package replay
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ahrav/go-locks/workload"
+)
+
+// Result is the outcome of a replay.
+type Result struct {
+	Ops      int                  // Acquisitions replayed
+	Elapsed  time.Duration        // Time from the start of the replay until the last goroutine finished
+	Wait     workload.Percentiles // Waits of the replayed acquisitions
+	Recorded workload.Percentiles // Waits of the same acquisitions in the trace
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("ops=%d elapsed=%v wait p50=%v p99=%v max=%v (recorded p50=%v p99=%v max=%v)",
+		r.Ops, r.Elapsed, r.Wait.P50, r.Wait.P99, r.Wait.Max, r.Recorded.P50, r.Recorded.P99, r.Recorded.Max)
+}
+
+// step is one operation of a replayed goroutine.
+type step struct {
+	lock    uint32
+	release bool          // Unlock rather than Lock
+	gap     time.Duration // Time since the goroutine's previous step, not counting waits
+	held    bool          // Whether the goroutine holds a lock during the gap
+}
+
+// Replay re-executes t against locks made by newLock, called once per lock in the trace
+// with its name, and reports the waits of the replayed acquisitions next to the
+// recorded ones.
+//
+// Each recorded goroutine is replayed by a goroutine of its own, which performs its
+// acquisitions and releases in the recorded order, with the recorded time between the
+// end of one and the start of the next: spent busy while it holds a lock, standing in
+// for the critical section, and asleep otherwise. Waits are not replayed but happen
+// afresh, so a lock that hands off faster or slower shifts everything after.
+//
+// Replay returns an error, without running anything, if a goroutine in t releases a
+// lock it didn't acquire; replaying needs every lock to be unlocked by the goroutine
+// that locked it. A trailing acquisition that the trace ended before completing is
+// dropped, and locks still held when the trace ends are released at its end, so that
+// no replayed goroutine waits for good on one.
+func Replay(t *Trace, newLock func(name string) sync.Locker) (Result, error) {
+	scripts, recorded, err := compile(t)
+	if err != nil {
+		return Result{}, err
+	}
+	locks := make([]sync.Locker, len(t.Locks))
+	for i, name := range t.Locks {
+		locks[i] = newLock(name)
+	}
+
+	waits := make([][]time.Duration, len(scripts))
+	var wg sync.WaitGroup
+	wg.Add(len(scripts))
+	start := time.Now()
+	for i, script := range scripts {
+		go func() {
+			defer wg.Done()
+			for _, s := range script {
+				if s.held {
+					workload.Busy(s.gap)
+				} else if s.gap > 0 {
+					time.Sleep(s.gap)
+				}
+				if s.release {
+					locks[s.lock].Unlock()
+					continue
+				}
+				arrival := time.Now()
+				locks[s.lock].Lock()
+				waits[i] = append(waits[i], time.Since(arrival))
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var all []time.Duration
+	for _, w := range waits {
+		all = append(all, w...)
+	}
+	return Result{
+		Ops:      len(all),
+		Elapsed:  elapsed,
+		Wait:     workload.Summarize(all),
+		Recorded: workload.Summarize(recorded),
+	}, nil
+}
+
+// compile splits t into the steps of each goroutine and collects its recorded waits.
+func compile(t *Trace) ([][]step, []time.Duration, error) {
+	type state struct {
+		script  []step
+		last    time.Duration // Time of the goroutine's last event
+		held    []uint32      // Locks it holds
+		pending *Event        // Acquire not yet Acquired
+	}
+	goroutines := make(map[int64]*state)
+	var order []int64
+	var waits []time.Duration
+
+	for i := range t.Events {
+		e := &t.Events[i]
+		if int(e.Lock) >= len(t.Locks) {
+			return nil, nil, fmt.Errorf("replay: event %d is of unknown lock %d", i, e.Lock)
+		}
+		g := goroutines[e.Goroutine]
+		if g == nil {
+			g = &state{}
+			goroutines[e.Goroutine] = g
+			order = append(order, e.Goroutine)
+		}
+		name := t.Locks[e.Lock]
+
+		switch e.Op {
+		case Acquire:
+			if g.pending != nil {
+				return nil, nil, fmt.Errorf("replay: goroutine %d acquires %q while acquiring %q", e.Goroutine, name, t.Locks[g.pending.Lock])
+			}
+			g.pending = e
+		case Acquired:
+			if g.pending == nil || g.pending.Lock != e.Lock {
+				return nil, nil, fmt.Errorf("replay: goroutine %d acquired %q without acquiring it", e.Goroutine, name)
+			}
+			g.script = append(g.script, step{lock: e.Lock, gap: g.pending.Time - g.last, held: len(g.held) > 0})
+			waits = append(waits, e.Time-g.pending.Time)
+			g.held = append(g.held, e.Lock)
+			g.pending, g.last = nil, e.Time
+		case Release:
+			j := len(g.held) - 1
+			for j >= 0 && g.held[j] != e.Lock {
+				j--
+			}
+			if j < 0 {
+				return nil, nil, fmt.Errorf("replay: goroutine %d releases %q without holding it", e.Goroutine, name)
+			}
+			g.script = append(g.script, step{lock: e.Lock, release: true, gap: e.Time - g.last, held: true})
+			g.held = append(g.held[:j], g.held[j+1:]...)
+			g.last = e.Time
+		default:
+			return nil, nil, fmt.Errorf("replay: event %d has unknown op %d", i, e.Op)
+		}
+	}
+
+	var end time.Duration
+	if len(t.Events) > 0 {
+		end = t.Events[len(t.Events)-1].Time
+	}
+	scripts := make([][]step, 0, len(order))
+	for _, id := range order {
+		g := goroutines[id]
+		for j := len(g.held) - 1; j >= 0; j-- { // Innermost first
+			g.script = append(g.script, step{lock: g.held[j], release: true, gap: max(end-g.last, 0), held: true})
+			g.last = max(g.last, end)
+		}
+		if len(g.script) > 0 {
+			scripts = append(scripts, g.script)
+		}
+	}
+	return scripts, waits, nil
+}
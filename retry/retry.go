@@ -0,0 +1,93 @@
+// Package retry acquires locks through TryLock with backoff between attempts, for call
+// sites that must not block in Lock indefinitely.
+//
+// Acquire retries until the lock is taken or the context is done, sleeping between
+// attempts for durations chosen by a Backoff. The sleeps are jittered so that
+// goroutines that failed together don't retry in lockstep, and they end early when the
+// context is cancelled or its deadline passes.
+//
+// Example usage:
+//
+//	attempts, err := retry.Acquire(ctx, lock, retry.Exponential(time.Microsecond, time.Millisecond))
+//	if err != nil {
+//	    return err // ctx was done before the lock could be taken
+//	}
+//	defer lock.Unlock()
+//	metrics.Observe(attempts)
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// TryLocker is a lock with a non-blocking acquisition, such as ticket.Lock.
+type TryLocker interface {
+	TryLock() bool
+}
+
+// Backoff chooses how long to wait before the next attempt.
+type Backoff interface {
+	// Delay returns the wait after the given number of failed attempts, starting at 1.
+	Delay(failures int) time.Duration
+}
+
+// BackoffFunc adapts a function to a Backoff.
+type BackoffFunc func(failures int) time.Duration
+
+// Delay calls f.
+func (f BackoffFunc) Delay(failures int) time.Duration { return f(failures) }
+
+// Constant waits a duration drawn uniformly from [d/2, d] between attempts.
+func Constant(d time.Duration) Backoff {
+	return BackoffFunc(func(int) time.Duration { return jitter(d) })
+}
+
+// Exponential doubles the wait after every failed attempt, starting at base and capped
+// at max, and draws each wait uniformly from [w/2, w] around the current value w.
+func Exponential(base, max time.Duration) Backoff {
+	return BackoffFunc(func(failures int) time.Duration {
+		d := base
+		for i := 1; i < failures && d < max; i++ {
+			d *= 2
+		}
+		return jitter(min(d, max))
+	})
+}
+
+// jitter returns a duration drawn uniformly from [d/2, d].
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Acquire calls l.TryLock until it succeeds or ctx is done, waiting between attempts as
+// directed by b. It returns the number of attempts made. The lock is held if and only if
+// the returned error is nil; otherwise the error is ctx.Err().
+func Acquire(ctx context.Context, l TryLocker, b Backoff) (int, error) {
+	var timer *time.Timer
+	for attempts := 1; ; attempts++ {
+		if err := ctx.Err(); err != nil {
+			return attempts - 1, err
+		}
+		if l.TryLock() {
+			return attempts, nil
+		}
+
+		d := b.Delay(attempts)
+		if timer == nil {
+			timer = time.NewTimer(d)
+			defer timer.Stop()
+		} else {
+			timer.Reset(d)
+		}
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return attempts, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestExponential(t *testing.T) {
+	b := Exponential(time.Millisecond, 8*time.Millisecond)
+	for i, want := range []time.Duration{1, 2, 4, 8, 8, 8} {
+		failures := i + 1
+		d := b.Delay(failures)
+		want *= time.Millisecond
+		assert.GreaterOrEqual(t, d, want/2, "failure %d", failures)
+		assert.LessOrEqual(t, d, want, "failure %d", failures)
+	}
+}
+
+func TestAcquireFree(t *testing.T) {
+	lock := ticket.NewLock()
+	attempts, err := Acquire(context.Background(), lock, Constant(time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	lock.Unlock()
+}
+
+func TestAcquireRetriesUntilReleased(t *testing.T) {
+	lock := ticket.NewLock()
+	lock.Lock()
+	time.AfterFunc(5*time.Millisecond, lock.Unlock)
+
+	attempts, err := Acquire(context.Background(), lock, Constant(time.Millisecond))
+	assert.NoError(t, err)
+	assert.Greater(t, attempts, 1)
+	lock.Unlock()
+}
+
+func TestAcquireHonorsDeadline(t *testing.T) {
+	lock := ticket.NewLock()
+	lock.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	attempts, err := Acquire(ctx, lock, Constant(time.Hour))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, attempts)
+	assert.Less(t, time.Since(start), time.Second, "The backoff sleep should end at the deadline")
+
+	cancel()
+	attempts, err = Acquire(ctx, lock, Constant(time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, attempts, "No attempt should be made once ctx is done")
+	lock.Unlock()
+}
@@ -0,0 +1,202 @@
+// Package rtlock implements a FIFO lock with auditable waiting bounds, for code that
+// must be able to state its worst case rather than its average.
+//
+// The lock is a ticket lock with no barging: a goroutine takes a ticket on arrival and
+// enters exactly when its ticket is served, so no later arrival ever enters ahead of it
+// (its bypass bound is zero). A waiter that finds n goroutines ahead of it waits for at
+// most n critical sections, whatever the scheduler does. Unlike ticket.Lock, waiters
+// never sleep: a sleep can outlast the waiter's turn, and every goroutine behind it
+// would wait out the rest. They spin for a fixed number of relax instructions, not an
+// adaptively tuned one, and then yield until their turn comes, however long that is
+// and whatever the spin profile's Park says.
+//
+// Every contended acquisition records its wait and the queue depth it arrived to. Stats
+// reports the worst of each, and WithBound sets a bound on the wait: an acquisition that
+// waited longer is counted as a violation and reported to an alarm callback, so that
+// overruns show up when they happen rather than in a later percentile. Uncontended
+// acquisitions don't read the clock and count as waiting zero.
+//
+// Example usage:
+//
+//	l := rtlock.NewLock(rtlock.WithBound(200*time.Microsecond, func(v rtlock.Violation) {
+//	    log.Printf("lock wait %v behind %d holders exceeded bound", v.Wait, v.Ahead)
+//	}))
+//
+//	l.Lock()
+//	// ... critical section ...
+//	l.Unlock()
+//
+//	s := l.Stats()
+//	fmt.Println(s.MaxWait, s.MaxAhead, s.Violations)
+package rtlock
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// Violation describes an acquisition that waited longer than the lock's bound.
+type Violation struct {
+	Wait  time.Duration // How long the acquisition waited
+	Ahead int           // Goroutines holding or queued for the lock when it arrived
+	Bound time.Duration // The bound it exceeded
+}
+
+// Stats summarizes a lock's waits since it was created or last reset.
+type Stats struct {
+	Acquisitions uint64        // All acquisitions
+	Contended    uint64        // Acquisitions that had to wait
+	MaxWait      time.Duration // Longest wait of any acquisition
+	MaxAhead     int           // Deepest queue any acquisition arrived to
+	Violations   uint64        // Acquisitions that waited longer than the bound
+}
+
+// Option configures a Lock.
+type Option func(*Lock)
+
+// WithBound sets the longest an acquisition may wait. Acquisitions that wait longer are
+// counted in Stats.Violations, and reported to alarm if it isn't nil. alarm runs on the
+// goroutine that waited, while it holds the lock, so it must be quick and must not
+// acquire the lock again.
+func WithBound(d time.Duration, alarm func(Violation)) Option {
+	return func(l *Lock) { l.bound, l.alarm = d, alarm }
+}
+
+// WithSpin sets how many relax instructions a waiter spins before it starts yielding.
+// It defaults to the spin profile's Spin when the lock is created.
+func WithSpin(n int) Option { return func(l *Lock) { l.spinLimit = max(n, 0) } }
+
+// Lock is a strict FIFO lock with wait tracking. Create it with NewLock.
+type Lock struct {
+	head atomic.Uint32 // Ticket being served
+	tail atomic.Uint32 // Next ticket to be issued
+
+	spinLimit int
+	bound     time.Duration
+	alarm     func(Violation)
+
+	_ pad.CacheLinePad // Keeps statistics updates off the ticket counters' line
+
+	acquisitions atomic.Uint64
+	contended    atomic.Uint64
+	maxWait      atomic.Int64
+	maxAhead     atomic.Int64
+	violations   atomic.Uint64
+}
+
+// NewLock creates a new lock.
+func NewLock(opts ...Option) *Lock {
+	l := &Lock{spinLimit: spin.Current().Spin}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Lock acquires the lock, after every goroutine that called Lock before it.
+func (l *Lock) Lock() {
+	me := l.tail.Add(1) - 1
+	chaos.Point()
+	l.acquisitions.Add(1)
+	if head := l.head.Load(); head != me {
+		l.wait(me, me-head)
+	}
+}
+
+// wait waits for ticket me to be served, ahead goroutines being in front of it on
+// arrival, and records the wait.
+func (l *Lock) wait(me, ahead uint32) {
+	start := time.Now()
+	prof := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = l.spinLimit
+	}
+	for i := 0; l.head.Load() != me; i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Yield() // Never park; see the package doc
+	}
+	if reserved {
+		spin.Release()
+	}
+	lockprof.Record(prof, 1)
+
+	waited := time.Since(start)
+	l.contended.Add(1)
+	raise(&l.maxWait, int64(waited))
+	raise(&l.maxAhead, int64(ahead))
+	if l.bound > 0 && waited > l.bound {
+		l.violations.Add(1)
+		if l.alarm != nil {
+			l.alarm(Violation{Wait: waited, Ahead: int(ahead), Bound: l.bound})
+		}
+	}
+}
+
+// raise lifts *m to v if v is larger.
+func raise(m *atomic.Int64, v int64) {
+	for cur := m.Load(); v > cur; cur = m.Load() {
+		if m.CompareAndSwap(cur, v) {
+			return
+		}
+	}
+}
+
+// TryLock acquires the lock if it is free and nobody is queued, without blocking.
+func (l *Lock) TryLock() bool {
+	head := l.head.Load()
+	if !l.tail.CompareAndSwap(head, head+1) {
+		return false
+	}
+	l.acquisitions.Add(1)
+	return true
+}
+
+// Unlock releases the lock to the next goroutine in line.
+func (l *Lock) Unlock() {
+	if invariant.Enabled {
+		invariant.Check(l.head.Load() != l.tail.Load(), "rtlock: Unlock of an unlocked lock")
+	}
+	chaos.Point()
+	l.head.Add(1)
+}
+
+// QueueDepth returns the number of goroutines holding or waiting for the lock.
+func (l *Lock) QueueDepth() int { return int(int32(l.tail.Load() - l.head.Load())) }
+
+// Stats returns the lock's wait statistics. The counters are read one at a time while
+// acquisitions may be updating them.
+func (l *Lock) Stats() Stats {
+	return Stats{
+		Acquisitions: l.acquisitions.Load(),
+		Contended:    l.contended.Load(),
+		MaxWait:      time.Duration(l.maxWait.Load()),
+		MaxAhead:     int(l.maxAhead.Load()),
+		Violations:   l.violations.Load(),
+	}
+}
+
+// ResetStats clears the statistics, starting a new observation period.
+func (l *Lock) ResetStats() {
+	l.acquisitions.Store(0)
+	l.contended.Store(0)
+	l.maxWait.Store(0)
+	l.maxAhead.Store(0)
+	l.violations.Store(0)
+}
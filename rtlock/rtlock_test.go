@@ -0,0 +1,115 @@
+package rtlock
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+	"github.com/ahrav/go-locks/spin"
+)
+
+var _ sync.Locker = (*Lock)(nil)
+
+func TestStrictFIFO(t *testing.T) {
+	l := NewLock()
+	l.Lock()
+
+	const numWaiters = 5
+	var order []int
+	var wg sync.WaitGroup
+	for i := range numWaiters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Lock()
+			order = append(order, i)
+			l.Unlock()
+		}()
+		for l.QueueDepth() != i+2 {
+			runtime.Gosched() // Queue the waiters in index order
+		}
+	}
+	l.Unlock()
+	wg.Wait()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, order)
+	s := l.Stats()
+	assert.Equal(t, uint64(numWaiters+1), s.Acquisitions)
+	assert.Equal(t, uint64(numWaiters), s.Contended)
+	assert.Equal(t, numWaiters, s.MaxAhead, "The last waiter arrived behind the holder and 4 waiters")
+}
+
+func TestBoundViolationTripsAlarm(t *testing.T) {
+	var got []Violation
+	l := NewLock(WithBound(time.Millisecond, func(v Violation) { got = append(got, v) }))
+
+	l.Lock()
+	done := make(chan struct{})
+	go func() {
+		l.Lock()
+		l.Unlock()
+		close(done)
+	}()
+	for l.QueueDepth() != 2 {
+		runtime.Gosched()
+	}
+	time.Sleep(5 * time.Millisecond)
+	l.Unlock()
+	<-done
+
+	assert.Len(t, got, 1)
+	assert.GreaterOrEqual(t, got[0].Wait, 5*time.Millisecond)
+	assert.Equal(t, 1, got[0].Ahead)
+	assert.Equal(t, time.Millisecond, got[0].Bound)
+	s := l.Stats()
+	assert.Equal(t, uint64(1), s.Violations)
+	assert.Equal(t, got[0].Wait, s.MaxWait)
+
+	l.ResetStats()
+	assert.Zero(t, l.Stats())
+}
+
+func TestWaiterNeverParks(t *testing.T) {
+	prev := spin.SetProfile(spin.Profile{MaxSpin: 1, Yields: 1, Park: time.Hour})
+	defer spin.SetProfile(prev)
+
+	l := NewLock()
+	l.Lock()
+	done := make(chan struct{})
+	go func() {
+		l.Lock()
+		l.Unlock()
+		close(done)
+	}()
+	for l.QueueDepth() != 2 {
+		runtime.Gosched()
+	}
+	time.Sleep(time.Millisecond) // Long enough for the waiter to use up its yields
+	l.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("The waiter parked for the profile's Park")
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	l := NewLock()
+	assert.True(t, l.TryLock())
+	assert.False(t, l.TryLock())
+	l.Unlock()
+	assert.Zero(t, l.QueueDepth())
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	l := NewLock()
+	allocs.Zero(t, func() {
+		l.Lock()
+		l.Unlock()
+	}, "Uncontended acquisitions should not allocate")
+}
@@ -0,0 +1,55 @@
+package locks
+
+import (
+	"context"
+	"time"
+)
+
+// ContextLocker is a lock whose acquisition can be abandoned when a context is done,
+// such as a ChanLocker or handoff.Lock.
+type ContextLocker interface {
+	// LockContext acquires the lock, or returns ctx.Err() without holding it if ctx is
+	// done first.
+	LockContext(ctx context.Context) error
+	Unlock()
+}
+
+// RunOption configures Run.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	holdBudget time.Duration
+}
+
+// WithHoldBudget bounds how long fn may hold the lock: the context passed to fn is
+// cancelled once d has elapsed after the lock was acquired.
+func WithHoldBudget(d time.Duration) RunOption {
+	return func(c *runConfig) { c.holdBudget = d }
+}
+
+// Run acquires l with ctx, runs fn while holding it, and releases it when fn returns or
+// panics. fn receives a context derived from ctx, additionally cancelled after the hold
+// budget if one is given, which it should honor to keep its critical section short.
+//
+// Run returns ctx.Err() if the lock could not be acquired, and fn's error otherwise.
+func Run(ctx context.Context, l ContextLocker, fn func(context.Context) error, opts ...RunOption) error {
+	var cfg runConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if err := l.LockContext(ctx); err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	var cancel context.CancelFunc
+	if cfg.holdBudget > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cfg.holdBudget)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel() // Runs before Unlock, so fn's context is done once the lock is free
+
+	return fn(ctx)
+}
@@ -0,0 +1,57 @@
+package locks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/handoff"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+var _ ContextLocker = (*handoff.Lock)(nil)
+
+func TestRunReleasesOnReturnAndPanic(t *testing.T) {
+	lock := ticket.NewLock()
+	cl := NewChanLocker(lock)
+	errFn := errors.New("fn failed")
+
+	err := Run(context.Background(), cl, func(ctx context.Context) error {
+		assert.False(t, lock.TryLock(), "fn should run with the lock held")
+		return errFn
+	})
+	assert.ErrorIs(t, err, errFn)
+
+	assert.Panics(t, func() {
+		_ = Run(context.Background(), cl, func(context.Context) error { panic("boom") })
+	})
+	assert.True(t, lock.TryLock(), "The lock should be released after a panic")
+	lock.Unlock()
+}
+
+func TestRunAbandonsAcquisition(t *testing.T) {
+	lock := handoff.NewLock()
+	lock.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	ran := false
+	err := Run(ctx, lock, func(context.Context) error { ran = true; return nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, ran)
+	lock.Unlock()
+	assert.True(t, lock.TryLock(), "The abandoned acquisition should not hold the lock")
+}
+
+func TestRunHoldBudget(t *testing.T) {
+	lock := handoff.NewLock()
+	err := Run(context.Background(), lock, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithHoldBudget(5*time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, lock.TryLock())
+}
@@ -0,0 +1,84 @@
+package locks
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/rwticket"
+)
+
+// RWLocker is a reader-writer lock such as rwticket.Lock or sync.RWMutex.
+type RWLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
+}
+
+// RWMutex owns a value of type T and the reader-writer lock guarding it. Readers see
+// the value through Read and run concurrently with each other; writers get exclusive
+// access through Write.
+//
+// A value that is only written during initialization can be sealed with Seal, after
+// which reads skip the lock entirely.
+//
+// The callbacks must not retain references into the value past their return, and must
+// not call back into the same RWMutex.
+type RWMutex[T any] struct {
+	l      RWLocker
+	v      T
+	sealed atomic.Bool
+}
+
+// NewRWMutex creates an RWMutex guarding v with l. A nil l selects an rwticket.Lock,
+// which admits readers and writers in FIFO order.
+func NewRWMutex[T any](v T, l RWLocker) *RWMutex[T] {
+	if l == nil {
+		l = rwticket.NewLock()
+	}
+	return &RWMutex[T]{l: l, v: v}
+}
+
+// Read runs fn with a copy of the guarded value while holding the lock for reading.
+func (m *RWMutex[T]) Read(fn func(T)) {
+	if m.sealed.Load() {
+		fn(m.v)
+		return
+	}
+	m.l.RLock()
+	defer m.l.RUnlock()
+	fn(m.v)
+}
+
+// Write runs fn with exclusive access to the guarded value. It panics if m is sealed.
+func (m *RWMutex[T]) Write(fn func(*T)) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if m.sealed.Load() {
+		panic("locks: Write to a sealed RWMutex")
+	}
+	fn(&m.v)
+}
+
+// Seal makes the guarded value permanently read-only. It waits for writers and readers
+// holding the lock to finish; from then on Read and RGet see the value with a plain
+// load, without touching the lock, and Write panics. Sealing an already sealed RWMutex
+// does nothing.
+func (m *RWMutex[T]) Seal() {
+	m.l.Lock()
+	m.sealed.Store(true) // Publishes every earlier write to readers that observe it
+	m.l.Unlock()
+}
+
+// Sealed reports whether m has been sealed.
+func (m *RWMutex[T]) Sealed() bool { return m.sealed.Load() }
+
+// RGet runs fn with a copy of the value guarded by m while holding the lock for
+// reading, and returns its result.
+func RGet[T, R any](m *RWMutex[T], fn func(T) R) R {
+	if m.sealed.Load() {
+		return fn(m.v)
+	}
+	m.l.RLock()
+	defer m.l.RUnlock()
+	return fn(m.v)
+}
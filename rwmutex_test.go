@@ -0,0 +1,113 @@
+package locks
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestRWMutexReadWrite(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		lock RWLocker
+	}{
+		{"default", nil},
+		{"sync", new(sync.RWMutex)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewRWMutex(map[string]int{}, tt.lock)
+			const numGoroutines = 8
+			const iterations = 200
+			var wg sync.WaitGroup
+
+			wg.Add(numGoroutines)
+			for i := range numGoroutines {
+				go func() {
+					defer wg.Done()
+					for range iterations {
+						if i%2 == 0 {
+							m.Write(func(v *map[string]int) { (*v)["n"]++ })
+						} else {
+							m.Read(func(v map[string]int) { _ = v["n"] })
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			got := RGet(m, func(v map[string]int) int { return v["n"] })
+			assert.Equal(t, numGoroutines/2*iterations, got)
+		})
+	}
+}
+
+func TestRWMutexSeal(t *testing.T) {
+	m := NewRWMutex(map[string]int{}, nil)
+	m.Write(func(v *map[string]int) { (*v)["port"] = 8080 })
+	assert.False(t, m.Sealed())
+
+	m.Seal()
+	assert.True(t, m.Sealed())
+	m.Seal() // Idempotent
+
+	assert.Panics(t, func() { m.Write(func(v *map[string]int) { (*v)["port"] = 0 }) })
+	assert.Equal(t, 8080, RGet(m, func(v map[string]int) int { return v["port"] }))
+
+	m.l.Lock() // Sealed reads must not touch the lock
+	defer m.l.Unlock()
+	const numGoroutines = 4
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			m.Read(func(v map[string]int) { assert.Equal(t, 8080, v["port"]) })
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRWMutexSealWaitsForWriter(t *testing.T) {
+	m := NewRWMutex(0, nil)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	go m.Write(func(n *int) {
+		close(entered)
+		<-release
+		*n = 42
+	})
+	<-entered
+
+	sealed := make(chan struct{})
+	go func() {
+		m.Seal()
+		close(sealed)
+	}()
+	select {
+	case <-sealed:
+		t.Fatal("Seal returned while a writer held the lock")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	<-sealed
+	assert.Equal(t, 42, RGet(m, func(n int) int { return n }))
+}
+
+func TestRWMutexDoesNotAllocate(t *testing.T) {
+	m := NewRWMutex(0, nil)
+	allocs.Zero(t, func() {
+		m.Write(func(n *int) { *n++ })
+		m.Read(func(int) {})
+		_ = RGet(m, func(n int) int { return n })
+	}, "Read, Write and RGet should not allocate")
+
+	m.Seal()
+	allocs.Zero(t, func() {
+		m.Read(func(int) {})
+		_ = RGet(m, func(n int) int { return n })
+	}, "Sealed reads should not allocate")
+}
@@ -0,0 +1,162 @@
+// Package rwticket implements a fair reader-writer ticket lock.
+//
+// Every acquisition, read or write, takes a ticket from a single counter, so readers
+// and writers are admitted in strict FIFO order: a waiting writer is never starved by a
+// stream of readers, and readers that queue behind a writer wait for it. Consecutive
+// readers are admitted together.
+//
+// The lock uses three counters:
+//   - users: the next ticket to be issued
+//   - read: the next ticket allowed to enter as a reader
+//   - write: the next ticket allowed to enter as a writer
+//
+// A reader with ticket t enters once read == t and immediately bumps read so that a
+// reader behind it can enter too; it bumps write when it leaves. A writer with ticket t
+// enters once write == t, i.e. once everyone ahead of it has left, and bumps both
+// counters when it leaves.
+//
+// Example usage:
+//
+//	lock := rwticket.NewLock()
+//
+//	lock.RLock()
+//	// ... read shared state ...
+//	lock.RUnlock()
+//
+//	lock.Lock()
+//	// ... modify shared state ...
+//	lock.Unlock()
+package rwticket
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// Lock is a fair reader-writer lock. The zero value is an unlocked lock.
+type Lock struct {
+	users uint32 // Next ticket to be issued
+	read  uint32 // Next ticket allowed to read
+	write uint32 // Next ticket allowed to write
+
+	spin spin.Adaptive // Self-tuning spin limit for waiters
+}
+
+// NewLock creates a new reader-writer ticket lock.
+func NewLock() *Lock { return new(Lock) }
+
+// Lock acquires the lock for writing.
+func (l *Lock) Lock() {
+	me := atomic.AddUint32(&l.users, 1) - 1
+	chaos.Point()
+	if atomic.LoadUint32(&l.write) != me {
+		l.lockSlow(me)
+	}
+}
+
+// lockSlow waits for write ticket me. It only exists to keep Lock inlinable: calling
+// waitFor directly costs more than the inlining budget allows, and lockSlow itself
+// must not be inlined back into Lock.
+//
+//go:noinline
+func (l *Lock) lockSlow(me uint32) { l.waitFor(&l.write, me) }
+
+// Unlock releases a write lock, admitting the next ticket in line.
+func (l *Lock) Unlock() {
+	if invariant.Enabled {
+		l.checkCounters()
+	}
+	atomic.AddUint32(&l.read, 1)
+	chaos.Point()
+	atomic.AddUint32(&l.write, 1)
+}
+
+// RLock acquires the lock for reading.
+func (l *Lock) RLock() {
+	me := atomic.AddUint32(&l.users, 1) - 1
+	chaos.Point()
+	if atomic.LoadUint32(&l.read) != me {
+		l.waitFor(&l.read, me)
+	}
+	atomic.AddUint32(&l.read, 1) // Let the next reader in line join us
+}
+
+// RUnlock releases a read lock.
+func (l *Lock) RUnlock() {
+	if invariant.Enabled {
+		l.checkCounters()
+	}
+	atomic.AddUint32(&l.write, 1)
+}
+
+// checkCounters verifies that write <= read <= users in ticket order and that the
+// lock is held by someone.
+func (l *Lock) checkCounters() {
+	write := atomic.LoadUint32(&l.write)
+	read := atomic.LoadUint32(&l.read)
+	users := atomic.LoadUint32(&l.users)
+	invariant.Check(int32(read-write) >= 0 && int32(users-read) >= 0,
+		"rwticket: counters out of order: write %d, read %d, users %d", write, read, users)
+	invariant.Check(users != write, "rwticket: unlock of an unlocked lock (write %d, users %d)", write, users)
+}
+
+// TryLock attempts to acquire the lock for writing without blocking. It succeeds only
+// if no one holds or is waiting for the lock.
+func (l *Lock) TryLock() bool {
+	w := atomic.LoadUint32(&l.write)
+	return atomic.CompareAndSwapUint32(&l.users, w, w+1)
+}
+
+// TryRLock attempts to acquire the lock for reading without blocking. It succeeds if
+// no writer holds or is waiting for the lock.
+func (l *Lock) TryRLock() bool {
+	r := atomic.LoadUint32(&l.read)
+	if !atomic.CompareAndSwapUint32(&l.users, r, r+1) {
+		return false
+	}
+	atomic.AddUint32(&l.read, 1)
+	return true
+}
+
+// QueueDepth returns the number of goroutines holding or waiting for the lock, readers
+// and writers alike. The value is a snapshot and may be stale by the time it is used.
+func (l *Lock) QueueDepth() int {
+	write := atomic.LoadUint32(&l.write)
+	users := atomic.LoadUint32(&l.users)
+	return int(int32(users - write))
+}
+
+// waitFor blocks until *addr reaches ticket.
+func (l *Lock) waitFor(addr *uint32, ticket uint32) {
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = l.spin.Limit()
+	}
+	i := 0
+	for ; atomic.LoadUint32(addr) != ticket; i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+	if spinLimit > 0 && i > 0 {
+		l.spin.Update(min(i, spinLimit), i < spinLimit)
+	}
+	lockprof.Record(start, 1)
+}
@@ -0,0 +1,104 @@
+package rwticket
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestLockConcurrentReadersAndWriters(t *testing.T) {
+	lock := NewLock()
+	const numGoroutines = 10
+	const iterations = 300
+	var readers, writers atomic.Int32
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for i := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for j := range iterations {
+				if (i+j)%4 == 0 {
+					lock.Lock()
+					assert.Equal(t, int32(1), writers.Add(1), "Writers must be exclusive")
+					assert.Zero(t, readers.Load(), "Readers must not overlap a writer")
+					counter++
+					writers.Add(-1)
+					lock.Unlock()
+				} else {
+					lock.RLock()
+					readers.Add(1)
+					assert.Zero(t, writers.Load(), "Writers must not overlap a reader")
+					readers.Add(-1)
+					lock.RUnlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	expected := 0
+	for i := range numGoroutines {
+		for j := range iterations {
+			if (i+j)%4 == 0 {
+				expected++
+			}
+		}
+	}
+	assert.Equal(t, expected, counter)
+}
+
+func TestReadersShareTheLock(t *testing.T) {
+	lock := NewLock()
+
+	lock.RLock()
+	assert.True(t, lock.TryRLock(), "A second reader should be admitted")
+	assert.False(t, lock.TryLock(), "A writer must wait for readers")
+	lock.RUnlock()
+	lock.RUnlock()
+
+	assert.True(t, lock.TryLock())
+	assert.False(t, lock.TryRLock(), "A reader must wait for the writer")
+	lock.Unlock()
+}
+
+func TestWriterIsNotStarvedByReaders(t *testing.T) {
+	lock := NewLock()
+	lock.RLock()
+
+	acquired := make(chan struct{})
+	go func() {
+		lock.Lock()
+		close(acquired)
+		lock.Unlock()
+	}()
+
+	// Wait for the writer to take its ticket.
+	for atomic.LoadUint32(&lock.users) != 2 {
+		runtime.Gosched()
+	}
+	assert.False(t, lock.TryRLock(), "New readers must queue behind the waiting writer")
+
+	lock.RUnlock()
+	<-acquired
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	lock := NewLock()
+	allocs.Zero(t, func() {
+		lock.Lock()
+		lock.Unlock()
+		assert.True(t, lock.TryLock())
+		lock.Unlock()
+		lock.RLock()
+		lock.RUnlock()
+		assert.True(t, lock.TryRLock())
+		lock.RUnlock()
+	}, "Uncontended acquisitions and releases should not allocate")
+}
@@ -0,0 +1,115 @@
+// Package sema is an optional backend that lets waiters of the locks in this module
+// block on the Go runtime's semaphores instead of yielding or sleeping.
+//
+// The locks' own waiting strategies stay in user space: a waiter spins, then calls
+// runtime.Gosched or sleeps until the lock's state says it may proceed. That needs no
+// cooperation from the unlocker, but a yielding waiter stays runnable and keeps being
+// scheduled just to find the lock still taken, and a sleeping waiter notices its turn
+// only when its timer fires. With the lockssema build tag, ticket.Lock, mcs.Lock and
+// alock.ArrayLock waiters that would yield or sleep instead park on a runtime semaphore,
+// the same mechanism behind sync.Mutex, and the unlocker wakes exactly the waiter it
+// hands the lock to:
+//
+//	go build -tags lockssema ./...
+//
+// Trade-offs versus the user-space strategies:
+//   - Parked waiters cost no CPU at all, and a handoff wakes the next waiter directly
+//     instead of waiting for it to be rescheduled or for a timer
+//   - Every Unlock that hands the lock on checks for a parked waiter, an extra atomic
+//     load on a shared table even when nobody waits, and waking one costs a runtime call
+//   - Waking a parked goroutine takes microseconds, far longer than a spinning waiter
+//     needs to notice a handoff, so waiters still spin first where spinning is allowed
+//   - The semaphores are reached through go:linkname into the runtime, entry points that
+//     the runtime keeps for compatibility but does not promise; a future Go release may
+//     break the build with the tag, never without it
+//   - While every waiter is parked, a holder that sleeps briefly leaves its P idle, and
+//     an idle P's timers fire with about a millisecond's resolution on Linux, so short
+//     sleeps inside critical sections stretch by orders of magnitude
+//   - Parked waiters show up in the runtime's block profile and goroutine dumps as
+//     semacquire, like sync.Mutex waiters
+//
+// Wait and Wake are keyed by a number the lock chooses, typically the address of the
+// word its waiter polls. Each parked goroutine has a semaphore of its own, and Wake
+// releases only those parked under its key.
+package sema
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/pad"
+)
+
+const tableSize = 251 // Prime, so that keys with a common stride spread out
+
+// waiter is a goroutine parked in Wait, on a semaphore of its own.
+type waiter struct {
+	sema uint32
+	key  uintptr
+	next *waiter
+}
+
+var waiters = sync.Pool{New: func() any { return new(waiter) }}
+
+type slot struct {
+	mu      sync.Mutex
+	head    *waiter      // Parked waiters; guarded by mu
+	waiting atomic.Int32 // Length of the head list, readable without mu
+	_       pad.CacheLinePad
+}
+
+var table [tableSize]slot
+
+func slotFor(key uintptr) *slot { return &table[(key>>3)%tableSize] }
+
+// Wait blocks the calling goroutine until done reports true, parking it between checks.
+// The goroutine that makes done true must call Wake with the same key afterwards.
+func Wait(key uintptr, done func() bool) {
+	s := slotFor(key)
+	w := waiters.Get().(*waiter)
+	w.key = key
+	for {
+		s.mu.Lock()
+		s.waiting.Add(1) // Before the check, so that a Wake after it finds us
+		if done() {
+			s.waiting.Add(-1)
+			s.mu.Unlock()
+			waiters.Put(w)
+			return
+		}
+		w.next = s.head
+		s.head = w
+		s.mu.Unlock()
+		semacquire(&w.sema) // Another lock may share key, so check again once woken
+	}
+}
+
+// Wake wakes the goroutines waiting on key. It costs a single atomic load if nobody
+// waits on key or any key sharing its slot.
+func Wake(key uintptr) {
+	s := slotFor(key)
+	if s.waiting.Load() == 0 {
+		return
+	}
+
+	var woken *waiter
+	s.mu.Lock()
+	for p := &s.head; *p != nil; {
+		w := *p
+		if w.key != key {
+			p = &w.next
+			continue
+		}
+		*p = w.next
+		w.next = woken
+		woken = w
+		s.waiting.Add(-1)
+	}
+	s.mu.Unlock()
+
+	for woken != nil {
+		w := woken
+		woken = w.next // Read before the release, after which w may be reused
+		semrelease(&w.sema)
+	}
+}
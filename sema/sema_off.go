@@ -0,0 +1,13 @@
+//go:build !lockssema
+
+package sema
+
+// Enabled reports whether the runtime semaphore backend is compiled in.
+const Enabled = false
+
+// Without the lockssema build tag the locks never call Wait or Wake, but they still
+// have to compile.
+
+func semacquire(*uint32) { panic("sema: backend not compiled in; build with -tags lockssema") }
+
+func semrelease(*uint32) { panic("sema: backend not compiled in; build with -tags lockssema") }
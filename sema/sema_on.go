@@ -0,0 +1,18 @@
+//go:build lockssema
+
+package sema
+
+import _ "unsafe" // For go:linkname
+
+// Enabled reports whether the runtime semaphore backend is compiled in.
+const Enabled = true
+
+//go:linkname semacquire sync.runtime_Semacquire
+func semacquire(addr *uint32)
+
+//go:linkname runtimeSemrelease sync.runtime_Semrelease
+func runtimeSemrelease(addr *uint32, handoff bool, skipframes int)
+
+// semrelease wakes one goroutine parked on addr. It does not hand over the processor,
+// so the unlocker keeps running.
+func semrelease(addr *uint32) { runtimeSemrelease(addr, false, 0) }
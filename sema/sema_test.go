@@ -0,0 +1,69 @@
+package sema
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitReturnsWhenDone(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	Wait(1, ready.Load) // Must not park
+	assert.Zero(t, slotFor(1).waiting.Load())
+}
+
+func TestWakeReleasesWaiter(t *testing.T) {
+	if !Enabled {
+		t.Skip("requires the lockssema build tag")
+	}
+	var ready atomic.Bool
+	key := uintptr(8)
+	done := make(chan struct{})
+	go func() {
+		Wait(key, ready.Load)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond) // Let the waiter park
+	select {
+	case <-done:
+		t.Fatal("Wait returned before its condition held")
+	default:
+	}
+
+	ready.Store(true)
+	Wake(key)
+	<-done
+	assert.Zero(t, slotFor(key).waiting.Load())
+}
+
+func TestWakeOnlyMatchingKey(t *testing.T) {
+	if !Enabled {
+		t.Skip("requires the lockssema build tag")
+	}
+	// Keys tableSize*8 apart share a slot.
+	a, b := uintptr(16), uintptr(16+tableSize*8)
+	var readyA, readyB atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); Wait(a, readyA.Load) }()
+	go func() { defer wg.Done(); Wait(b, readyB.Load) }()
+	for slotFor(a).waiting.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	readyA.Store(true)
+	Wake(a)
+	for slotFor(a).waiting.Load() > 1 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, int32(1), slotFor(b).waiting.Load(), "The waiter on b should stay parked")
+
+	readyB.Store(true)
+	Wake(b)
+	wg.Wait()
+}
@@ -0,0 +1,34 @@
+package semaphore
+
+import "context"
+
+// Limiter runs functions with at most a fixed number executing at once. Callers beyond
+// the limit queue and are admitted strictly in arrival order, unlike errgroup.Group's
+// SetLimit, whose blocked callers proceed in no particular order.
+type Limiter struct {
+	sem *Weighted
+}
+
+// NewLimiter creates a Limiter allowing n concurrent executions.
+func NewLimiter(n int) *Limiter { return &Limiter{sem: NewWeighted(int64(n))} }
+
+// Do runs fn once fewer than the limit are running and everyone queued before the
+// caller has been admitted. If ctx is done while the caller is queued, Do returns
+// ctx.Err() without running fn; otherwise it returns fn's error. fn receives ctx.
+func (l *Limiter) Do(ctx context.Context, fn func(context.Context) error) error {
+	if err := l.sem.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	defer l.sem.Release(1)
+	return fn(ctx)
+}
+
+// TryDo runs fn if it can be admitted without waiting, and reports whether it ran it,
+// along with fn's error.
+func (l *Limiter) TryDo(ctx context.Context, fn func(context.Context) error) (bool, error) {
+	if !l.sem.TryAcquire(1) {
+		return false, nil
+	}
+	defer l.sem.Release(1)
+	return true, fn(ctx)
+}
@@ -0,0 +1,120 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitQueued waits until n callers are queued on l.
+func waitQueued(l *Limiter, n int) {
+	for {
+		l.sem.mu.Lock()
+		queued := l.sem.waiters.Len()
+		l.sem.mu.Unlock()
+		if queued >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitFull waits until l has no room left.
+func waitFull(l *Limiter) {
+	for {
+		if ok, _ := l.TryDo(context.Background(), func(context.Context) error { return nil }); !ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimiterAdmitsInArrivalOrder(t *testing.T) {
+	l := NewLimiter(1)
+	release := make(chan struct{})
+	go func() {
+		_ = l.Do(context.Background(), func(context.Context) error {
+			<-release
+			return nil
+		})
+	}()
+	waitFull(l)
+
+	var order []int // Only appended to by admitted callers, one at a time
+	var wg sync.WaitGroup
+	wg.Add(5)
+	for i := range 5 {
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, l.Do(context.Background(), func(context.Context) error {
+				order = append(order, i)
+				return nil
+			}))
+		}()
+		waitQueued(l, i+1) // Queue them one at a time
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, order)
+}
+
+func TestLimiterBoundsConcurrency(t *testing.T) {
+	const limit = 3
+	l := NewLimiter(limit)
+	var active, peak atomic.Int32
+	var wg sync.WaitGroup
+
+	wg.Add(20)
+	for range 20 {
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, l.Do(context.Background(), func(context.Context) error {
+				n := active.Add(1)
+				for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+				}
+				time.Sleep(time.Millisecond)
+				active.Add(-1)
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, peak.Load(), int32(limit))
+}
+
+func TestLimiterCancelWhileQueued(t *testing.T) {
+	l := NewLimiter(1)
+	errBoom := errors.New("boom")
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- l.Do(context.Background(), func(context.Context) error {
+			<-release
+			return errBoom
+		})
+	}()
+	waitFull(l)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ran := false
+	err := l.Do(ctx, func(context.Context) error {
+		ran = true
+		return nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, ran)
+
+	close(release)
+	assert.ErrorIs(t, <-done, errBoom)
+	ok, err := l.TryDo(context.Background(), func(context.Context) error { return nil })
+	assert.True(t, ok, "The slot was returned")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,164 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Weighted is adapted from golang.org/x/sync/semaphore, with the mutex replaced by a
+// ticket lock.
+
+// Package semaphore provides a fair weighted semaphore whose API matches
+// golang.org/x/sync/semaphore, so that it can replace it by changing only the import
+// path.
+//
+// Waiters are admitted in strict FIFO order: a large request at the head of the queue
+// blocks smaller requests behind it instead of being bypassed, and TryAcquire never
+// jumps ahead of queued waiters. The internal state is guarded by a ticket lock, so
+// goroutines also reach the queue in the order they arrive.
+//
+// Example usage:
+//
+//	sem := semaphore.NewWeighted(10)
+//
+//	if err := sem.Acquire(ctx, 3); err != nil {
+//	    return err // ctx was done before the units became available
+//	}
+//	defer sem.Release(3)
+//
+// Limiter wraps a semaphore to run functions with bounded concurrency, admitting them
+// in the same FIFO order.
+package semaphore
+
+import (
+	"container/list"
+	"context"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+type waiter struct {
+	n     int64
+	ready chan struct{} // Closed when the semaphore grants the units
+}
+
+// Weighted provides a way to bound concurrent access to a resource. The callers can
+// request access with a given weight.
+type Weighted struct {
+	size    int64
+	cur     int64
+	mu      *ticket.Lock
+	waiters list.List
+}
+
+// NewWeighted creates a new weighted semaphore with the given maximum combined weight
+// for concurrent access.
+func NewWeighted(n int64) *Weighted {
+	return &Weighted{size: n, mu: ticket.NewLock()}
+}
+
+// Acquire acquires the semaphore with a weight of n, blocking until resources are
+// available or ctx is done. On success, returns nil. On failure, returns ctx.Err() and
+// leaves the semaphore unchanged.
+func (s *Weighted) Acquire(ctx context.Context, n int64) error {
+	done := ctx.Done()
+
+	s.mu.Lock()
+	select {
+	case <-done:
+		// ctx becoming done has "happened before" acquiring the semaphore, whether it
+		// became done before the call began or while we were waiting for the lock.
+		s.mu.Unlock()
+		return ctx.Err()
+	default:
+	}
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	if n > s.size {
+		// Don't make other Acquire calls block on one that's doomed to fail.
+		s.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(waiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-done:
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// Acquired the semaphore after we were canceled; pretend we didn't and
+			// put the tokens back.
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// If we're at the front and there're extra tokens left, notify other waiters.
+			if isFront && s.size > s.cur {
+				s.notifyWaiters()
+			}
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+
+	case <-ready:
+		// Acquired the semaphore. Check that ctx isn't already done; we don't want to
+		// let the caller proceed if it raced with cancellation.
+		select {
+		case <-done:
+			s.Release(n)
+			return ctx.Err()
+		default:
+		}
+		return nil
+	}
+}
+
+// TryAcquire acquires the semaphore with a weight of n without blocking. On success,
+// returns true. On failure, returns false and leaves the semaphore unchanged.
+func (s *Weighted) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	success := s.size-s.cur >= n && s.waiters.Len() == 0
+	if success {
+		s.cur += n
+	}
+	s.mu.Unlock()
+	return success
+}
+
+// Release releases the semaphore with a weight of n.
+func (s *Weighted) Release(n int64) {
+	s.mu.Lock()
+	s.cur -= n
+	if s.cur < 0 {
+		s.mu.Unlock()
+		panic("semaphore: released more than held")
+	}
+	s.notifyWaiters()
+	s.mu.Unlock()
+}
+
+// notifyWaiters grants units to queued waiters in FIFO order. It stops at the first
+// waiter that doesn't fit, so later, smaller requests can't starve it.
+func (s *Weighted) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			break // No more waiters blocked.
+		}
+
+		w := next.Value.(waiter)
+		if s.size-s.cur < w.n {
+			break
+		}
+
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
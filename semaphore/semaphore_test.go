@@ -0,0 +1,98 @@
+package semaphore
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestWeightedBoundsConcurrency(t *testing.T) {
+	const limit = 3
+	sem := NewWeighted(limit)
+	var mu sync.Mutex
+	active, peak := 0, 0
+	var wg sync.WaitGroup
+
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, sem.Acquire(context.Background(), 1))
+			mu.Lock()
+			active++
+			peak = max(peak, active)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			active--
+			mu.Unlock()
+			sem.Release(1)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, peak, limit)
+}
+
+func TestTryAcquireDoesNotBypassWaiters(t *testing.T) {
+	sem := NewWeighted(2)
+	assert.True(t, sem.TryAcquire(2))
+
+	acquired := make(chan struct{})
+	go func() {
+		assert.NoError(t, sem.Acquire(context.Background(), 2))
+		close(acquired)
+	}()
+	for waiters(sem) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	sem.Release(1)
+	assert.False(t, sem.TryAcquire(1), "TryAcquire must not jump ahead of a queued waiter")
+
+	sem.Release(1)
+	<-acquired
+	sem.Release(2)
+	assert.True(t, sem.TryAcquire(2))
+}
+
+func TestAcquireCancelled(t *testing.T) {
+	sem := NewWeighted(1)
+	assert.True(t, sem.TryAcquire(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, sem.Acquire(ctx, 1), context.DeadlineExceeded)
+
+	sem.Release(1)
+	assert.True(t, sem.TryAcquire(1), "Cancelled waiter must leave the semaphore unchanged")
+}
+
+func TestReleaseMoreThanHeldPanics(t *testing.T) {
+	sem := NewWeighted(1)
+	assert.PanicsWithValue(t, "semaphore: released more than held", func() { sem.Release(1) })
+}
+
+func waiters(s *Weighted) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiters.Len()
+}
+
+func TestAcquireDoesNotAllocate(t *testing.T) {
+	s := NewWeighted(1)
+	ctx := context.Background()
+	allocs.Zero(t, func() {
+		_ = s.Acquire(ctx, 1)
+		s.Release(1)
+		assert.True(t, s.TryAcquire(1))
+		s.Release(1)
+	}, "Uncontended Acquire, TryAcquire and Release should not allocate")
+}
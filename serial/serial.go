@@ -0,0 +1,137 @@
+// Package serial runs tasks one at a time in the order they were submitted, without a
+// dedicated worker goroutine.
+//
+// The tasks form an MCS queue: Submit appends a node with a single atomic swap of the
+// queue's tail, as an MCS lock enqueues a waiter, so submitting never waits for the
+// running task, another submitter, or a lock. Where an MCS lock would hand the lock to
+// the next node, the runner runs the next node's task instead. The submitter that finds
+// the queue empty starts a goroutine to run it, and that goroutine exits once the queue
+// drains, so an idle Serializer costs nothing.
+//
+// A Serializer holds at most the number of pending tasks it was created with. Submit
+// blocks while it is full, queueing behind other blocked submitters in FIFO order, which
+// pushes back on producers instead of letting an unbounded channel grow; TrySubmit
+// reports failure instead.
+//
+// Example usage:
+//
+//	s := serial.New(1024)
+//
+//	s.Submit(func() { state.apply(event) }) // Runs after every earlier task
+//	s.Flush()                               // Waits for everything submitted so far
+//
+// A task that panics crashes the program, as a panic on any goroutine does.
+package serial
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/spin"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// task is a queue node.
+type task struct {
+	fn   func()
+	next atomic.Pointer[task]
+}
+
+// Serializer runs submitted tasks one at a time in submission order. Create it with
+// New.
+type Serializer struct {
+	tail    atomic.Pointer[task]
+	pending atomic.Int64 // Tasks submitted and not yet finished
+	limit   int64
+	full    *ticket.Lock // Queues submitters waiting for room
+}
+
+// New creates a Serializer holding up to limit pending tasks. It panics unless limit is
+// positive.
+func New(limit int) *Serializer {
+	if limit <= 0 {
+		panic("serial: limit must be positive")
+	}
+	return &Serializer{limit: int64(limit), full: ticket.NewLock()}
+}
+
+// Submit schedules fn to run after every task submitted before it. It returns without
+// waiting unless limit tasks are pending, in which case it waits for room first.
+func (s *Serializer) Submit(fn func()) {
+	if !s.tryReserve() {
+		s.full.Lock()
+		for n := 0; !s.reserve(); n++ {
+			chaos.Point()
+			spin.Wait(n)
+		}
+		s.full.Unlock()
+	}
+	s.enqueue(fn)
+}
+
+// TrySubmit schedules fn as Submit does if there is room and no submitter is waiting
+// for it, and reports whether it did. It never waits.
+func (s *Serializer) TrySubmit(fn func()) bool {
+	if !s.tryReserve() {
+		return false
+	}
+	s.enqueue(fn)
+	return true
+}
+
+// tryReserve takes room for one task unless submitters are already waiting for room,
+// which it must not overtake.
+func (s *Serializer) tryReserve() bool { return s.full.QueueDepth() == 0 && s.reserve() }
+
+// reserve takes room for one task, if there is any.
+func (s *Serializer) reserve() bool {
+	for {
+		n := s.pending.Load()
+		if n >= s.limit {
+			return false
+		}
+		if s.pending.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// enqueue appends a task for fn, starting a runner if the queue was empty.
+func (s *Serializer) enqueue(fn func()) {
+	t := &task{fn: fn}
+	prev := s.tail.Swap(t)
+	chaos.Point()
+	if prev == nil {
+		go s.run(t)
+		return
+	}
+	prev.next.Store(t) // The runner waits for this link if it gets to prev first
+}
+
+// run runs t and every task queued behind it, until the queue is empty.
+func (s *Serializer) run(t *task) {
+	for {
+		t.fn()
+		s.pending.Add(-1)
+		next := t.next.Load()
+		if next == nil {
+			if s.tail.CompareAndSwap(t, nil) {
+				return
+			}
+			for next = t.next.Load(); next == nil; next = t.next.Load() {
+				spin.Yield() // A submitter has swapped the tail but not linked in yet
+			}
+		}
+		t = next
+	}
+}
+
+// Flush waits until every task submitted before it has run.
+func (s *Serializer) Flush() {
+	done := make(chan struct{})
+	s.Submit(func() { close(done) })
+	<-done
+}
+
+// Pending returns the number of tasks submitted and not yet finished.
+func (s *Serializer) Pending() int { return int(s.pending.Load()) }
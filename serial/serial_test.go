@@ -0,0 +1,68 @@
+package serial
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksRunInSubmissionOrder(t *testing.T) {
+	s := New(16)
+	var got []int // Only touched by tasks, which never overlap
+	for i := range 100 {
+		s.Submit(func() { got = append(got, i) })
+	}
+	s.Flush()
+
+	want := make([]int, 100)
+	for i := range want {
+		want[i] = i
+	}
+	assert.Equal(t, want, got)
+	assert.Zero(t, s.Pending())
+}
+
+func TestConcurrentSubmittersKeepPerProducerOrder(t *testing.T) {
+	s := New(8) // Small enough that submitters block
+	const numProducers = 4
+	const perProducer = 200
+	last := make([]int, numProducers)
+	running := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numProducers)
+	for p := range numProducers {
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= perProducer; i++ {
+				s.Submit(func() {
+					running++
+					assert.Equal(t, 1, running, "Tasks overlapped")
+					assert.Equal(t, i-1, last[p], "Producer %d's tasks ran out of order", p)
+					last[p] = i
+					running--
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	s.Flush()
+
+	for p := range numProducers {
+		assert.Equal(t, perProducer, last[p])
+	}
+}
+
+func TestTrySubmitWhenFull(t *testing.T) {
+	s := New(1)
+	release := make(chan struct{})
+	assert.True(t, s.TrySubmit(func() { <-release }))
+	assert.False(t, s.TrySubmit(func() {}), "The blocked task fills the Serializer")
+	assert.Equal(t, 1, s.Pending())
+
+	close(release)
+	s.Flush()
+	assert.True(t, s.TrySubmit(func() {}))
+	s.Flush()
+}
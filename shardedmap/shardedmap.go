@@ -0,0 +1,173 @@
+// Package shardedmap provides a concurrent map whose keys are spread across a fixed
+// number of shards, each guarded by its own ticket lock.
+//
+// Striping the map over many locks lets operations on different shards proceed in
+// parallel, while the ticket locks keep access to each shard FIFO-fair. Per-shard
+// statistics expose how evenly the keys and the contention are spread.
+//
+// Example usage:
+//
+//	m := shardedmap.New[string, int](16, shardedmap.HashString)
+//
+//	m.Set("a", 1)
+//	v, ok := m.Get("a")
+//	m.Delete("a")
+package shardedmap
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/pad"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+type shard[K comparable, V any] struct {
+	lock *ticket.Lock
+	m    map[K]V
+
+	gets      atomic.Uint64
+	sets      atomic.Uint64
+	deletes   atomic.Uint64
+	contended atomic.Uint64
+
+	_ pad.CacheLinePad
+}
+
+// acquire locks the shard, counting acquisitions that had to wait.
+func (s *shard[K, V]) acquire() {
+	if !s.lock.TryLock() {
+		s.contended.Add(1)
+		s.lock.Lock() //lockcheck:ignore The caller unlocks it
+	}
+}
+
+// Map is a concurrent map sharded across ticket-locked buckets.
+type Map[K comparable, V any] struct {
+	shards []shard[K, V]
+	hash   func(K) uint64
+}
+
+// New creates a Map with n shards (at least 1) that distributes keys using hash.
+func New[K comparable, V any](n int, hash func(K) uint64) *Map[K, V] {
+	n = max(n, 1)
+	m := &Map[K, V]{shards: make([]shard[K, V], n), hash: hash}
+	for i := range m.shards {
+		m.shards[i].lock = ticket.NewLock()
+		m.shards[i].m = make(map[K]V)
+	}
+	return m
+}
+
+func (m *Map[K, V]) shardFor(key K) *shard[K, V] {
+	return &m.shards[m.hash(key)%uint64(len(m.shards))]
+}
+
+// Get returns the value stored for key and whether it was present.
+func (m *Map[K, V]) Get(key K) (V, bool) {
+	s := m.shardFor(key)
+	s.gets.Add(1)
+	s.acquire()
+	v, ok := s.m[key]
+	s.lock.Unlock()
+	return v, ok
+}
+
+// Set stores value for key.
+func (m *Map[K, V]) Set(key K, value V) {
+	s := m.shardFor(key)
+	s.sets.Add(1)
+	s.acquire()
+	s.m[key] = value
+	s.lock.Unlock()
+}
+
+// Delete removes key from the map.
+func (m *Map[K, V]) Delete(key K) {
+	s := m.shardFor(key)
+	s.deletes.Add(1)
+	s.acquire()
+	delete(s.m, key)
+	s.lock.Unlock()
+}
+
+// Len returns the number of entries across all shards. Shards are counted one at a
+// time, so the result is not a consistent snapshot under concurrent modification.
+func (m *Map[K, V]) Len() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.acquire()
+		n += len(s.m)
+		s.lock.Unlock()
+	}
+	return n
+}
+
+// Range calls fn for each entry until fn returns false. Each shard is copied under its
+// lock and fn runs without any lock held, so fn may modify the map; entries changed
+// after their shard was copied may or may not be observed.
+func (m *Map[K, V]) Range(fn func(K, V) bool) {
+	type entry struct {
+		k K
+		v V
+	}
+	var buf []entry
+	for i := range m.shards {
+		s := &m.shards[i]
+		buf = buf[:0]
+		s.acquire()
+		for k, v := range s.m {
+			buf = append(buf, entry{k, v})
+		}
+		s.lock.Unlock()
+
+		for _, e := range buf {
+			if !fn(e.k, e.v) {
+				return
+			}
+		}
+	}
+}
+
+// ShardStats describes the activity on a single shard.
+type ShardStats struct {
+	Len       int    // Entries currently stored
+	Gets      uint64 // Get calls
+	Sets      uint64 // Set calls
+	Deletes   uint64 // Delete calls
+	Contended uint64 // Acquisitions that found the shard's lock held
+}
+
+// Stats returns the statistics of every shard, in shard order.
+func (m *Map[K, V]) Stats() []ShardStats {
+	stats := make([]ShardStats, len(m.shards))
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.lock.Lock()
+		stats[i].Len = len(s.m)
+		s.lock.Unlock()
+		stats[i].Gets = s.gets.Load()
+		stats[i].Sets = s.sets.Load()
+		stats[i].Deletes = s.deletes.Load()
+		stats[i].Contended = s.contended.Load()
+	}
+	return stats
+}
+
+var seed = maphash.MakeSeed()
+
+// HashString hashes a string key for use with New.
+func HashString(s string) uint64 { return maphash.String(seed, s) }
+
+// HashInt hashes an integer key for use with New.
+func HashInt(i int) uint64 {
+	// Finalizer from SplitMix64, so sequential keys spread across shards.
+	x := uint64(i)
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
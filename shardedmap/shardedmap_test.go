@@ -0,0 +1,107 @@
+package shardedmap
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapBasicOperations(t *testing.T) {
+	m := New[string, int](4, HashString)
+
+	m.Set("a", 1)
+	m.Set("b", 2)
+	v, ok := m.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	m.Delete("a")
+	_, ok = m.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, m.Len())
+}
+
+func TestMapConcurrentAccess(t *testing.T) {
+	m := New[int, int](8, HashInt)
+	const numGoroutines = 8
+	const keys = 500
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for k := range keys {
+				m.Set(g*keys+k, k)
+				_, _ = m.Get(g*keys + k)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*keys, m.Len())
+
+	var sets uint64
+	nonEmpty := 0
+	for _, s := range m.Stats() {
+		sets += s.Sets
+		if s.Len > 0 {
+			nonEmpty++
+		}
+	}
+	assert.Equal(t, uint64(numGoroutines*keys), sets)
+	assert.Equal(t, 8, nonEmpty, "Keys should spread across every shard")
+}
+
+func TestMapRangeAllowsMutation(t *testing.T) {
+	m := New[string, int](2, HashString)
+	for i := range 10 {
+		m.Set(strconv.Itoa(i), i)
+	}
+
+	seen := 0
+	m.Range(func(k string, v int) bool {
+		m.Delete(k) // Must not deadlock
+		seen++
+		return true
+	})
+	assert.Equal(t, 10, seen)
+	assert.Zero(t, m.Len())
+
+	m.Set("x", 1)
+	m.Set("y", 2)
+	calls := 0
+	m.Range(func(string, int) bool { calls++; return false })
+	assert.Equal(t, 1, calls, "Range should stop when fn returns false")
+}
+
+func BenchmarkShardedMapParallel(b *testing.B) {
+	m := New[int, int](64, HashInt)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Set(i%1024, i)
+			m.Get((i + 7) % 1024)
+			i++
+		}
+	})
+}
+
+func BenchmarkMutexMapParallel(b *testing.B) {
+	var mu sync.Mutex
+	m := make(map[int]int)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			mu.Lock()
+			m[i%1024] = i
+			mu.Unlock()
+			mu.Lock()
+			_ = m[(i+7)%1024]
+			mu.Unlock()
+			i++
+		}
+	})
+}
@@ -0,0 +1,79 @@
+//go:build cgo
+
+package shmlock
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/shmlock/internal/cshm"
+)
+
+func TestTicketMatchesC(t *testing.T) {
+	assert.Equal(t, uintptr(TicketSize), cshm.TicketSize())
+
+	var l Ticket
+	p := unsafe.Pointer(&l)
+	assert.True(t, cshm.TicketTryLock(p))
+	assert.False(t, l.TryLock(), "Go sees the lock C took")
+	l.Unlock()
+	assert.True(t, l.TryLock())
+	assert.False(t, cshm.TicketTryLock(p), "C sees the lock Go took")
+	cshm.TicketUnlock(p)
+}
+
+func TestTicketMutualExclusionWithC(t *testing.T) {
+	var l Ticket
+	p := unsafe.Pointer(&l)
+	const iterations = 500
+	// The race detector can't see C's atomics, so exclusion is checked with atomics too.
+	var inside atomic.Bool
+	var counter atomic.Int64
+	enter := func() {
+		assert.False(t, inside.Swap(true), "Two holders at once")
+		counter.Add(1)
+		inside.Store(false)
+	}
+	var wg sync.WaitGroup
+
+	wg.Add(4)
+	for i := range 4 {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				if i%2 == 0 {
+					cshm.TicketLock(p)
+					enter()
+					cshm.TicketUnlock(p)
+				} else {
+					l.Lock()
+					enter()
+					l.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(4*iterations), counter.Load())
+}
+
+func TestRobustMatchesC(t *testing.T) {
+	assert.Equal(t, uintptr(RobustSize), cshm.RobustSize())
+
+	var l Robust
+	p := unsafe.Pointer(&l)
+	assert.Equal(t, cshm.OK, cshm.RobustTryLock(p))
+	assert.ErrorIs(t, l.TryLock(), ErrLocked, "Go sees the lock C took")
+	assert.Equal(t, int(pid), l.Holder(), "C records the same PID")
+	cshm.RobustUnlock(p)
+
+	atomic.OrUint32(&l.flags, robustInconsistent)
+	assert.ErrorIs(t, l.Lock(), ErrOwnerDead, "Go sees C's flags")
+	l.Unlock()
+	assert.Equal(t, cshm.NotRecoverable, cshm.RobustLock(p), "C sees Go's flags")
+}
@@ -0,0 +1,52 @@
+//go:build cgo
+
+// Package cshm exposes shmlock.h to Go, so that tests can check that C and Go agree on
+// the shared-memory lock protocols.
+package cshm
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/../..
+#include "shmlock.h"
+*/
+import "C"
+
+import "unsafe"
+
+// TicketLock calls go_locks_ticket_lock on the lock at p.
+func TicketLock(p unsafe.Pointer) { C.go_locks_ticket_lock((*C.go_locks_ticket)(p)) }
+
+// TicketTryLock calls go_locks_ticket_trylock on the lock at p.
+func TicketTryLock(p unsafe.Pointer) bool {
+	return C.go_locks_ticket_trylock((*C.go_locks_ticket)(p)) != 0
+}
+
+// TicketUnlock calls go_locks_ticket_unlock on the lock at p.
+func TicketUnlock(p unsafe.Pointer) { C.go_locks_ticket_unlock((*C.go_locks_ticket)(p)) }
+
+// TicketSize returns sizeof(go_locks_ticket).
+func TicketSize() uintptr { return unsafe.Sizeof(C.go_locks_ticket{}) }
+
+// RobustLock calls go_locks_robust_lock on the lock at p and returns its result.
+func RobustLock(p unsafe.Pointer) int { return int(C.go_locks_robust_lock((*C.go_locks_robust)(p))) }
+
+// RobustTryLock calls go_locks_robust_trylock on the lock at p and returns its result.
+func RobustTryLock(p unsafe.Pointer) int {
+	return int(C.go_locks_robust_trylock((*C.go_locks_robust)(p)))
+}
+
+// RobustConsistent calls go_locks_robust_consistent on the lock at p.
+func RobustConsistent(p unsafe.Pointer) { C.go_locks_robust_consistent((*C.go_locks_robust)(p)) }
+
+// RobustUnlock calls go_locks_robust_unlock on the lock at p.
+func RobustUnlock(p unsafe.Pointer) { C.go_locks_robust_unlock((*C.go_locks_robust)(p)) }
+
+// RobustSize returns sizeof(go_locks_robust).
+func RobustSize() uintptr { return unsafe.Sizeof(C.go_locks_robust{}) }
+
+// Results of the robust lock functions.
+const (
+	OK             = C.GO_LOCKS_OK
+	OwnerDead      = C.GO_LOCKS_OWNERDEAD
+	NotRecoverable = C.GO_LOCKS_NOTRECOVERABLE
+	Busy           = C.GO_LOCKS_BUSY
+)
@@ -0,0 +1,164 @@
+package shmlock
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/spin"
+)
+
+var (
+	// ErrOwnerDead is returned by Robust.Lock when it acquired the lock from a process
+	// that died holding it. The lock is held; the state it guards may be half-updated.
+	ErrOwnerDead = errors.New("shmlock: previous holder died while holding the lock")
+
+	// ErrNotRecoverable is returned by Robust.Lock once a holder that acquired the lock
+	// with ErrOwnerDead released it without calling Consistent. The lock is not held.
+	ErrNotRecoverable = errors.New("shmlock: lock state not recoverable")
+
+	// ErrLocked is returned by Robust.TryLock when the lock is held.
+	ErrLocked = errors.New("shmlock: lock is held")
+)
+
+// Bits of Robust.flags.
+const (
+	robustInconsistent   = 1 << iota // Taken over from a dead holder; not yet repaired
+	robustNotRecoverable             // Released while inconsistent; unusable for good
+)
+
+// livenessEvery is how many waiting rounds pass between checks that the holder is
+// still alive, each of which costs a system call.
+const livenessEvery = 64
+
+// Robust is a lock that survives the death of the process holding it, in the manner
+// of a robust pthread mutex. It has a C-compatible layout:
+//
+//	offset 0: uint32 owner, the PID of the holding process, 0 when free
+//	offset 4: uint32 flags, bit 0 inconsistent, bit 1 not recoverable
+//
+// aligned to 8 bytes, RobustSize bytes in all, and valid when zeroed. In C it is
+// go_locks_robust.
+//
+// A waiter periodically checks whether the owning process still exists. If it
+// doesn't, the waiter takes the lock over, marks it inconsistent, and Lock returns
+// ErrOwnerDead. The new holder is then the designated recovery path: it repairs the
+// guarded state and calls Consistent before Unlock. If it unlocks without doing so,
+// the lock becomes not recoverable, and every later Lock fails with ErrNotRecoverable.
+//
+// Ownership is per process, not per goroutine; goroutines of one process exclude each
+// other all the same. Death is detected by PID, so a dead holder whose PID has been
+// reused by a new process goes unnoticed until that process exits too. Liveness checks
+// are only implemented on Unix; elsewhere a dead holder is never detected. Unlike
+// Ticket, Robust is not FIFO: waiters compete for the lock each time it is released.
+type Robust struct {
+	_     [0]atomic.Uint64
+	owner uint32
+	flags uint32
+}
+
+const (
+	RobustSize  = 8 // Size of a Robust in bytes
+	RobustAlign = 8 // Required alignment of a Robust
+)
+
+func init() {
+	var l Robust
+	if unsafe.Sizeof(l) != RobustSize || unsafe.Alignof(l) != RobustAlign ||
+		unsafe.Offsetof(l.owner) != 0 || unsafe.Offsetof(l.flags) != 4 {
+		panic("shmlock: Robust layout does not match LayoutVersion 1")
+	}
+}
+
+// RobustAt returns the Robust lock stored in the first RobustSize bytes of b, which
+// must be 8-byte aligned.
+func RobustAt(b []byte) (*Robust, error) {
+	if len(b) < RobustSize || uintptr(unsafe.Pointer(unsafe.SliceData(b)))%RobustAlign != 0 {
+		return nil, ErrLayout
+	}
+	return (*Robust)(unsafe.Pointer(unsafe.SliceData(b))), nil
+}
+
+var pid = uint32(os.Getpid()) // Owner value of locks held by this process
+
+// Lock acquires the lock. It returns nil, or ErrOwnerDead with the lock held, or
+// ErrNotRecoverable without it.
+func (l *Robust) Lock() error {
+	if atomic.CompareAndSwapUint32(&l.owner, 0, pid) {
+		return l.acquired()
+	}
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	defer func() {
+		if reserved {
+			spin.Release()
+		}
+	}()
+	for i := 1; ; i++ {
+		chaos.Point()
+		o := atomic.LoadUint32(&l.owner)
+		if o == 0 && atomic.CompareAndSwapUint32(&l.owner, 0, pid) {
+			return l.acquired()
+		}
+		if o != 0 && i%livenessEvery == 0 && !alive(o) && atomic.CompareAndSwapUint32(&l.owner, o, pid) {
+			atomic.OrUint32(&l.flags, robustInconsistent)
+			return ErrOwnerDead
+		}
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+}
+
+// acquired reports the state of a lock just taken normally.
+func (l *Robust) acquired() error {
+	switch f := atomic.LoadUint32(&l.flags); {
+	case f&robustNotRecoverable != 0:
+		atomic.StoreUint32(&l.owner, 0)
+		return ErrNotRecoverable
+	case f&robustInconsistent != 0:
+		return ErrOwnerDead // A previous recovery died too
+	}
+	return nil
+}
+
+// TryLock acquires the lock if it is free, without blocking or checking the holder's
+// liveness. Its results are those of Lock, with ErrLocked when the lock is held.
+func (l *Robust) TryLock() error {
+	if !atomic.CompareAndSwapUint32(&l.owner, 0, pid) {
+		return ErrLocked
+	}
+	return l.acquired()
+}
+
+// Consistent marks the state guarded by a lock acquired with ErrOwnerDead as repaired.
+// The caller must hold the lock.
+func (l *Robust) Consistent() {
+	atomic.AndUint32(&l.flags, ^uint32(robustInconsistent))
+}
+
+// Unlock releases the lock. Releasing it while inconsistent makes it not recoverable.
+func (l *Robust) Unlock() {
+	if atomic.LoadUint32(&l.flags)&robustInconsistent != 0 {
+		atomic.OrUint32(&l.flags, robustNotRecoverable)
+	}
+	chaos.Point()
+	if !atomic.CompareAndSwapUint32(&l.owner, pid, 0) {
+		panic("shmlock: Unlock of a Robust lock not held by this process")
+	}
+}
+
+// Holder returns the PID of the process holding the lock, or 0 if it is free.
+func (l *Robust) Holder() int { return int(atomic.LoadUint32(&l.owner)) }
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package shmlock
+
+// alive reports whether process pid exists. Without a way to check, every holder is
+// assumed alive.
+func alive(uint32) bool { return true }
@@ -0,0 +1,73 @@
+package shmlock
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRobustZeroValueIsUnlocked(t *testing.T) {
+	var l Robust
+	assert.NoError(t, l.TryLock())
+	assert.Equal(t, int(pid), l.Holder())
+	assert.ErrorIs(t, l.TryLock(), ErrLocked)
+	l.Unlock()
+	assert.Zero(t, l.Holder())
+	assert.NoError(t, l.Lock())
+	l.Unlock()
+}
+
+func TestRobustAt(t *testing.T) {
+	words := make([]uint64, 2)
+	mem := unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), 16)
+
+	l, err := RobustAt(mem[8:])
+	assert.NoError(t, err)
+	assert.NoError(t, l.Lock())
+	assert.Equal(t, [2]uint32{pid, 0}, *(*[2]uint32)(unsafe.Pointer(&words[1])), "owner is the first word")
+	l.Unlock()
+
+	_, err = RobustAt(mem[4:])
+	assert.ErrorIs(t, err, ErrLayout)
+}
+
+func TestRobustUnlockByOtherProcessPanics(t *testing.T) {
+	l := Robust{owner: pid + 1}
+	assert.Panics(t, l.Unlock)
+}
+
+func TestRobustMutualExclusion(t *testing.T) {
+	var l Robust
+	const numGoroutines = 4
+	const iterations = 500
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				assert.NoError(t, l.Lock())
+				counter++
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+}
+
+func TestRobustInconsistentUnlockIsNotRecoverable(t *testing.T) {
+	var l Robust
+	atomic.StoreUint32(&l.flags, robustInconsistent) // As left by a recovery that was abandoned
+	assert.ErrorIs(t, l.Lock(), ErrOwnerDead)
+	l.Unlock()                                     // Without Consistent
+	assert.ErrorIs(t, l.Lock(), ErrNotRecoverable) //lockcheck:ignore Fails, so nothing is held
+	assert.ErrorIs(t, l.TryLock(), ErrNotRecoverable)
+	assert.Zero(t, l.Holder(), "A failed Lock doesn't hold the lock")
+}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package shmlock
+
+import "syscall"
+
+// alive reports whether process pid exists. EPERM means it exists but belongs to
+// another user.
+func alive(pid uint32) bool {
+	err := syscall.Kill(int(pid), 0)
+	return err == nil || err == syscall.EPERM
+}
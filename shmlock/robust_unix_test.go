@@ -0,0 +1,54 @@
+//go:build unix
+
+package shmlock
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// deadPID returns the PID of a process that has exited.
+func deadPID(t *testing.T) uint32 {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return uint32(cmd.Process.Pid)
+}
+
+func TestRobustRecoversFromDeadHolder(t *testing.T) {
+	var l Robust
+	atomic.StoreUint32(&l.owner, deadPID(t)) // The holder crashed inside its critical section
+
+	assert.ErrorIs(t, l.Lock(), ErrOwnerDead)
+	assert.Equal(t, int(pid), l.Holder())
+	l.Consistent() // ... repair the shared state ...
+	l.Unlock()
+
+	assert.NoError(t, l.Lock())
+	l.Unlock()
+}
+
+func TestRobustWaitsForLiveHolder(t *testing.T) {
+	var l Robust
+	assert.NoError(t, l.Lock())
+	acquired := make(chan error)
+	go func() { acquired <- l.Lock() }() //lockcheck:ignore The test unlocks it
+
+	for range 1000 {
+		select {
+		case <-acquired:
+			t.Fatal("Lock taken over from a live holder")
+		default:
+		}
+		runtime.Gosched()
+	}
+	l.Unlock()
+	assert.NoError(t, <-acquired)
+	l.Unlock()
+}
@@ -0,0 +1,148 @@
+// Package shmlock provides locks with a fixed, documented memory layout, for memory
+// shared with other processes, including processes written in C or C++.
+//
+// The locks contain no Go pointers and are valid when zeroed, so they can be placed in
+// a freshly created shared mapping as is, and they don't depend on anything
+// process-local: every process that maps the memory and follows the same protocol
+// takes part in the same lock. shmlock.h implements the protocol for C and C++, so a Go
+// process and a C++ process sharing, say, a ring buffer can guard it with one lock.
+//
+// Layouts are versioned by LayoutVersion and will not change within a version. Waiters
+// spin and then yield; nothing parks in the kernel, so a waiter never needs its
+// holder's process to wake it.
+//
+// A process that crashes while holding a Ticket wedges it for everyone. Robust is a
+// lock that notices when its holder's process has died and hands it to a waiter along
+// with ErrOwnerDead, so that the waiter can repair the shared state.
+//
+// Example usage:
+//
+//	mem, err := syscall.Mmap(fd, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+//	if err != nil {
+//	    return err
+//	}
+//	l, err := shmlock.TicketAt(mem[off:])
+//	if err != nil {
+//	    return err
+//	}
+//
+//	l.Lock()
+//	// ... touch the shared ring buffer ...
+//	l.Unlock()
+//
+// The C side includes shmlock.h and calls go_locks_ticket_lock and
+// go_locks_ticket_unlock on the same bytes.
+package shmlock
+
+import (
+	"errors"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// LayoutVersion is the version of the layouts described in this package and in
+// shmlock.h. Processes sharing a lock must agree on it.
+const LayoutVersion = 1
+
+// ErrLayout is returned when memory is too small or misaligned for a lock.
+var ErrLayout = errors.New("shmlock: memory too small or misaligned for the lock")
+
+// Ticket is a FIFO ticket lock with a C-compatible layout:
+//
+//	offset 0: uint32 head, the ticket being served
+//	offset 4: uint32 tail, the next ticket to be issued
+//
+// aligned to 8 bytes, TicketSize bytes in all. It is free when head equals tail, so the
+// zero value is an unlocked lock. Both fields are only ever accessed atomically, and
+// TryLock updates them together with a 64-bit compare-and-swap. In C it is
+// go_locks_ticket.
+//
+// Ticket is not robust. Not only a holder that dies wedges it, but also a waiter that
+// dies after drawing its ticket, since nobody else can release the turn it will be
+// served. Recovering would take a record of which process owns each outstanding
+// ticket, which the fixed layout shared with C has no room for. Memory that must
+// outlive a crashing process should be guarded by a Robust instead.
+type Ticket struct {
+	_    [0]atomic.Uint64 // 8-byte alignment for the 64-bit CAS, even on 32-bit platforms
+	head uint32
+	tail uint32
+}
+
+const (
+	TicketSize  = 8 // Size of a Ticket in bytes
+	TicketAlign = 8 // Required alignment of a Ticket
+)
+
+func init() {
+	var l Ticket
+	if unsafe.Sizeof(l) != TicketSize || unsafe.Alignof(l) != TicketAlign ||
+		unsafe.Offsetof(l.head) != 0 || unsafe.Offsetof(l.tail) != 4 {
+		panic("shmlock: Ticket layout does not match LayoutVersion 1")
+	}
+}
+
+// TicketAt returns the Ticket stored in the first TicketSize bytes of b, which must be
+// 8-byte aligned. b is typically a slice of a shared mapping, and must stay mapped while
+// the lock is in use.
+func TicketAt(b []byte) (*Ticket, error) {
+	if len(b) < TicketSize || uintptr(unsafe.Pointer(unsafe.SliceData(b)))%TicketAlign != 0 {
+		return nil, ErrLayout
+	}
+	return (*Ticket)(unsafe.Pointer(unsafe.SliceData(b))), nil
+}
+
+// Lock acquires the lock.
+func (l *Ticket) Lock() {
+	me := atomic.AddUint32(&l.tail, 1) - 1
+	chaos.Point()
+	if atomic.LoadUint32(&l.head) != me {
+		l.wait(me)
+	}
+}
+
+// wait spins, then yields, until ticket me is served.
+func (l *Ticket) wait(me uint32) {
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = spin.Current().Spin
+	}
+	for i := 0; atomic.LoadUint32(&l.head) != me; i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+}
+
+// TryLock acquires the lock if it is free and nobody is queued, without blocking.
+func (l *Ticket) TryLock() bool {
+	head := atomic.LoadUint32(&l.head)
+	// Build the 64-bit views in field order so the CAS is independent of byte order.
+	expected := [2]uint32{head, head}
+	desired := [2]uint32{head, head + 1}
+	return atomic.CompareAndSwapUint64(
+		(*uint64)(unsafe.Pointer(l)),
+		*(*uint64)(unsafe.Pointer(&expected)),
+		*(*uint64)(unsafe.Pointer(&desired)),
+	)
+}
+
+// Unlock releases the lock to the next ticket in line.
+func (l *Ticket) Unlock() {
+	chaos.Point()
+	atomic.AddUint32(&l.head, 1)
+}
@@ -0,0 +1,140 @@
+/*
+ * shmlock.h: C and C++ implementation of the locks of the Go package
+ * github.com/ahrav/go-locks/shmlock, for memory shared between Go and C processes.
+ *
+ * Layout version 1 (SHMLOCK_LAYOUT_VERSION). Every lock is valid when zeroed.
+ *
+ * Requires GCC or Clang (the __atomic builtins) and POSIX (sched_yield, getpid, kill).
+ */
+#ifndef GO_LOCKS_SHMLOCK_H
+#define GO_LOCKS_SHMLOCK_H
+
+#include <errno.h>
+#include <sched.h>
+#include <signal.h>
+#include <stdint.h>
+#include <string.h>
+#include <unistd.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define SHMLOCK_LAYOUT_VERSION 1
+
+/* go_locks_ticket is shmlock.Ticket: a FIFO ticket lock, free when head == tail. */
+typedef struct {
+	uint32_t head; /* offset 0: ticket being served */
+	uint32_t tail; /* offset 4: next ticket to be issued */
+} __attribute__((aligned(8))) go_locks_ticket;
+
+#define GO_LOCKS_TICKET_SPIN 64 /* Polls before each waiter starts yielding */
+
+static inline void go_locks_relax(void) {
+#if defined(__x86_64__) || defined(__i386__)
+	__builtin_ia32_pause();
+#elif defined(__aarch64__)
+	__asm__ __volatile__("isb" ::: "memory");
+#endif
+}
+
+static inline void go_locks_ticket_lock(go_locks_ticket *l) {
+	uint32_t me = __atomic_fetch_add(&l->tail, 1, __ATOMIC_SEQ_CST);
+	for (int i = 0; __atomic_load_n(&l->head, __ATOMIC_SEQ_CST) != me; i++) {
+		if (i < GO_LOCKS_TICKET_SPIN) {
+			go_locks_relax();
+		} else {
+			sched_yield();
+		}
+	}
+}
+
+/* Returns 1 if the lock was acquired, 0 if it is held or contended. */
+static inline int go_locks_ticket_trylock(go_locks_ticket *l) {
+	uint32_t head = __atomic_load_n(&l->head, __ATOMIC_SEQ_CST);
+	go_locks_ticket expected = {head, head}, desired = {head, head + 1};
+	uint64_t e, d;
+	memcpy(&e, &expected, sizeof e); /* Field order, independent of byte order */
+	memcpy(&d, &desired, sizeof d);
+	return __atomic_compare_exchange_n((uint64_t *)l, &e, d, 0, __ATOMIC_SEQ_CST, __ATOMIC_SEQ_CST);
+}
+
+static inline void go_locks_ticket_unlock(go_locks_ticket *l) {
+	__atomic_fetch_add(&l->head, 1, __ATOMIC_SEQ_CST);
+}
+
+/*
+ * go_locks_robust is shmlock.Robust: a lock owned by a process, which a waiter takes
+ * over when the owning process has died.
+ */
+typedef struct {
+	uint32_t owner; /* offset 0: PID of the holding process, 0 when free */
+	uint32_t flags; /* offset 4: GO_LOCKS_ROBUST_* bits */
+} __attribute__((aligned(8))) go_locks_robust;
+
+#define GO_LOCKS_ROBUST_INCONSISTENT 1u    /* Taken over from a dead holder, not repaired */
+#define GO_LOCKS_ROBUST_NOTRECOVERABLE 2u  /* Released while inconsistent */
+#define GO_LOCKS_ROBUST_LIVENESS_EVERY 64  /* Waiting rounds between liveness checks */
+
+/* Results of go_locks_robust_lock and go_locks_robust_trylock. */
+#define GO_LOCKS_OK 0
+#define GO_LOCKS_OWNERDEAD 1      /* Acquired; the guarded state may be inconsistent */
+#define GO_LOCKS_NOTRECOVERABLE 2 /* Not acquired; the lock is unusable */
+#define GO_LOCKS_BUSY 3           /* Not acquired; the lock is held (trylock only) */
+
+static inline int go_locks_robust_acquired(go_locks_robust *l) {
+	uint32_t f = __atomic_load_n(&l->flags, __ATOMIC_SEQ_CST);
+	if (f & GO_LOCKS_ROBUST_NOTRECOVERABLE) {
+		__atomic_store_n(&l->owner, 0, __ATOMIC_SEQ_CST);
+		return GO_LOCKS_NOTRECOVERABLE;
+	}
+	return (f & GO_LOCKS_ROBUST_INCONSISTENT) ? GO_LOCKS_OWNERDEAD : GO_LOCKS_OK;
+}
+
+static inline int go_locks_robust_lock(go_locks_robust *l) {
+	uint32_t me = (uint32_t)getpid();
+	for (int i = 0;; i++) {
+		uint32_t o = __atomic_load_n(&l->owner, __ATOMIC_SEQ_CST);
+		if (o == 0 && __atomic_compare_exchange_n(&l->owner, &o, me, 0, __ATOMIC_SEQ_CST, __ATOMIC_SEQ_CST)) {
+			return go_locks_robust_acquired(l);
+		}
+		if (o != 0 && i > 0 && i % GO_LOCKS_ROBUST_LIVENESS_EVERY == 0 &&
+		    kill((pid_t)o, 0) != 0 && errno == ESRCH &&
+		    __atomic_compare_exchange_n(&l->owner, &o, me, 0, __ATOMIC_SEQ_CST, __ATOMIC_SEQ_CST)) {
+			__atomic_fetch_or(&l->flags, GO_LOCKS_ROBUST_INCONSISTENT, __ATOMIC_SEQ_CST);
+			return GO_LOCKS_OWNERDEAD;
+		}
+		if (i < GO_LOCKS_TICKET_SPIN) {
+			go_locks_relax();
+		} else {
+			sched_yield();
+		}
+	}
+}
+
+static inline int go_locks_robust_trylock(go_locks_robust *l) {
+	uint32_t free = 0;
+	if (!__atomic_compare_exchange_n(&l->owner, &free, (uint32_t)getpid(), 0, __ATOMIC_SEQ_CST, __ATOMIC_SEQ_CST)) {
+		return GO_LOCKS_BUSY;
+	}
+	return go_locks_robust_acquired(l);
+}
+
+/* Marks the state guarded by a lock acquired with GO_LOCKS_OWNERDEAD as repaired. */
+static inline void go_locks_robust_consistent(go_locks_robust *l) {
+	__atomic_fetch_and(&l->flags, ~GO_LOCKS_ROBUST_INCONSISTENT, __ATOMIC_SEQ_CST);
+}
+
+/* Releasing the lock while inconsistent makes it not recoverable. */
+static inline void go_locks_robust_unlock(go_locks_robust *l) {
+	if (__atomic_load_n(&l->flags, __ATOMIC_SEQ_CST) & GO_LOCKS_ROBUST_INCONSISTENT) {
+		__atomic_fetch_or(&l->flags, GO_LOCKS_ROBUST_NOTRECOVERABLE, __ATOMIC_SEQ_CST);
+	}
+	__atomic_store_n(&l->owner, 0, __ATOMIC_SEQ_CST);
+}
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* GO_LOCKS_SHMLOCK_H */
@@ -0,0 +1,66 @@
+package shmlock
+
+import (
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/offheap"
+)
+
+func TestTicketZeroValueIsUnlocked(t *testing.T) {
+	var l Ticket
+	assert.True(t, l.TryLock())
+	assert.False(t, l.TryLock())
+	l.Unlock()
+	l.Lock()
+	l.Unlock()
+}
+
+func TestTicketAt(t *testing.T) {
+	// Align by hand, like a mapping: uint64s are only 4-byte aligned on 32-bit platforms
+	buf := make([]byte, 4096+TicketAlign)
+	off := -int(uintptr(unsafe.Pointer(&buf[0]))) & (TicketAlign - 1)
+	mem := buf[off : off+4096]
+
+	l, err := TicketAt(mem[8:])
+	assert.NoError(t, err)
+	l.Lock()
+	assert.Equal(t, [2]uint32{0, 1}, *(*[2]uint32)(unsafe.Pointer(&mem[8])), "tail is the second word")
+	l.Unlock()
+
+	_, err = TicketAt(mem[4:])
+	assert.ErrorIs(t, err, ErrLayout)
+	_, err = TicketAt(mem[4088:4092])
+	assert.ErrorIs(t, err, ErrLayout)
+}
+
+func TestTicketMutualExclusion(t *testing.T) {
+	var l Ticket
+	const numGoroutines = 4
+	const iterations = 500
+	counter := 0
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				l.Lock()
+				counter++
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines*iterations, counter)
+}
+
+func TestLocksArePointerFree(t *testing.T) {
+	offheap.NoPointers(t, Ticket{})
+	offheap.NoPointers(t, Robust{})
+}
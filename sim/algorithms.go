@@ -0,0 +1,204 @@
+package sim
+
+import "fmt"
+
+// Naive is the lock everyone writes first: wait for the flag to clear, then set it. Two
+// threads can both see it clear before either sets it.
+var Naive = Algorithm{
+	Name: "naive",
+	Init: func(m *Machine, _ int) { m.Declare("locked", 0) },
+	Lock: []Instr{
+		{"while locked != 0 {}", func(t *Thread) Flow {
+			if t.m.Load("locked") != 0 {
+				return Retry
+			}
+			return Next
+		}},
+		{"locked = 1", func(t *Thread) Flow {
+			t.m.Store("locked", 1)
+			return Next
+		}},
+	},
+	Unlock: []Instr{
+		{"locked = 0", func(t *Thread) Flow {
+			t.m.Store("locked", 0)
+			return Next
+		}},
+	},
+}
+
+// TAS is a test-and-set spinlock, which fixes Naive by testing and setting the flag in
+// one atomic swap.
+var TAS = Algorithm{
+	Name: "test-and-set",
+	Init: func(m *Machine, _ int) { m.Declare("locked", 0) },
+	Lock: []Instr{
+		{"while swap(locked, 1) != 0 {}", func(t *Thread) Flow {
+			if t.m.Swap("locked", 1) != 0 {
+				return Retry
+			}
+			return Next
+		}},
+	},
+	Unlock: []Instr{
+		{"locked = 0", func(t *Thread) Flow {
+			t.m.Store("locked", 0)
+			return Next
+		}},
+	},
+}
+
+// Ticket is the ticket lock of the ticket package: take a ticket from next, and wait
+// until serving reaches it. Threads acquire in the order they took their tickets.
+var Ticket = Algorithm{
+	Name: "ticket",
+	Init: func(m *Machine, _ int) {
+		m.Declare("next", 0)
+		m.Declare("serving", 0)
+	},
+	Lock: []Instr{
+		{"my = fetch_add(next, 1)", func(t *Thread) Flow {
+			t.Local["my"] = t.m.FetchAdd("next", 1)
+			return Next
+		}},
+		{"while serving != my {}", func(t *Thread) Flow {
+			if t.m.Load("serving") != t.Local["my"] {
+				return Retry
+			}
+			return Next
+		}},
+	},
+	Unlock: []Instr{
+		{"serving = my + 1", func(t *Thread) Flow {
+			t.m.Store("serving", t.Local["my"]+1)
+			return Next
+		}},
+	},
+}
+
+// SplitTicket is Ticket with the fetch-and-add split into a load and a store. Two
+// threads can load the same value of next and take the same ticket.
+var SplitTicket = Algorithm{
+	Name: "split ticket",
+	Init: Ticket.Init,
+	Lock: []Instr{
+		{"my = next", func(t *Thread) Flow {
+			t.Local["my"] = t.m.Load("next")
+			return Next
+		}},
+		{"next = my + 1", func(t *Thread) Flow {
+			t.m.Store("next", t.Local["my"]+1)
+			return Next
+		}},
+		Ticket.Lock[1],
+	},
+	Unlock: Ticket.Unlock,
+}
+
+// Peterson is Peterson's algorithm for two threads, mutual exclusion from loads and
+// stores alone, provided memory is sequentially consistent.
+var Peterson = Algorithm{
+	Name:       "peterson",
+	MaxThreads: 2,
+	Init: func(m *Machine, _ int) {
+		m.Declare("flag[0]", 0)
+		m.Declare("flag[1]", 0)
+		m.Declare("turn", 0)
+	},
+	Lock: []Instr{
+		{"flag[me] = 1", func(t *Thread) Flow {
+			t.m.Store(fmt.Sprintf("flag[%d]", t.ID), 1)
+			return Next
+		}},
+		{"turn = other", func(t *Thread) Flow {
+			t.m.Store("turn", 1-t.ID)
+			return Next
+		}},
+		{"while flag[other] == 1 {", func(t *Thread) Flow {
+			if t.m.Load(fmt.Sprintf("flag[%d]", 1-t.ID)) == 0 {
+				return Done
+			}
+			return Next
+		}},
+		{"  if turn != other { break } }", func(t *Thread) Flow {
+			if t.m.Load("turn") != 1-t.ID {
+				return Done
+			}
+			return Goto(2)
+		}},
+	},
+	Unlock: []Instr{
+		{"flag[me] = 0", func(t *Thread) Flow {
+			t.m.Store(fmt.Sprintf("flag[%d]", t.ID), 0)
+			return Next
+		}},
+	},
+}
+
+// MCS is the MCS queue lock of the mcs package. Each thread has a node, and tail points
+// to the node of the last thread queued; node numbers are thread IDs plus one, and 0
+// is nil. A waiter spins on its own node until its predecessor hands it the lock.
+var MCS = Algorithm{
+	Name: "mcs",
+	Init: func(m *Machine, n int) {
+		m.Declare("tail", 0)
+		for i := 1; i <= n; i++ {
+			m.Declare(fmt.Sprintf("node[%d].next", i), 0)
+			m.Declare(fmt.Sprintf("node[%d].locked", i), 0)
+		}
+	},
+	Lock: []Instr{
+		{"me.next = nil; me.locked = 1", func(t *Thread) Flow {
+			t.m.Store(node(t.ID+1, "next"), 0)
+			t.m.Store(node(t.ID+1, "locked"), 1)
+			return Next
+		}},
+		{"pred = swap(tail, me)", func(t *Thread) Flow {
+			t.Local["pred"] = t.m.Swap("tail", t.ID+1)
+			return Next
+		}},
+		{"if pred == nil { return }", func(t *Thread) Flow {
+			if t.Local["pred"] == 0 {
+				return Done
+			}
+			return Next
+		}},
+		{"pred.next = me", func(t *Thread) Flow {
+			t.m.Store(node(t.Local["pred"], "next"), t.ID+1)
+			return Next
+		}},
+		{"while me.locked == 1 {}", func(t *Thread) Flow {
+			if t.m.Load(node(t.ID+1, "locked")) == 1 {
+				return Retry
+			}
+			return Next
+		}},
+	},
+	Unlock: []Instr{
+		{"if me.next == nil {", func(t *Thread) Flow {
+			if t.m.Load(node(t.ID+1, "next")) != 0 {
+				return Goto(3)
+			}
+			return Next
+		}},
+		{"  if cas(tail, me, nil) { return }", func(t *Thread) Flow {
+			if t.m.CAS("tail", t.ID+1, 0) {
+				return Done
+			}
+			return Next
+		}},
+		{"  while me.next == nil {} }", func(t *Thread) Flow {
+			if t.m.Load(node(t.ID+1, "next")) == 0 {
+				return Retry
+			}
+			return Next
+		}},
+		{"me.next.locked = 0", func(t *Thread) Flow {
+			t.m.Store(node(t.m.Load(node(t.ID+1, "next")), "locked"), 0)
+			return Next
+		}},
+	},
+}
+
+// node names a field of an MCS node.
+func node(n int, field string) string { return fmt.Sprintf("node[%d].%s", n, field) }
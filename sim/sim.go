@@ -0,0 +1,330 @@
+// Package sim runs lock algorithms on an abstract machine one step at a time, for
+// teaching how they work and why naive ones don't.
+//
+// The machine has named shared words and a set of threads, each running an Algorithm:
+// its Lock instructions, a critical section, its Unlock instructions, and around again.
+// Every instruction is one atomic step, so the interleavings of the threads are exactly
+// the orders in which they are stepped. Step a thread to advance it, print the machine
+// to see its memory (the ticket counters, an MCS queue's tail and nodes) and where
+// each thread is, and check Violation after each step:
+//
+//	m := sim.New(sim.Ticket, 2)
+//	m.Step(0) // T0: my = fetch_add(next, 1)
+//	m.Step(1) // T1: my = fetch_add(next, 1)
+//	m.Step(1) // T1 spins: serving != my
+//	fmt.Println(m)
+//
+// Explore searches every interleaving up to a number of steps for one that puts two
+// threads in the critical section at once, and returns the shortest. Replaying it with
+// Run and printing History shows how a broken lock fails:
+//
+//	schedule := sim.Explore(sim.Naive, 2, 10)
+//	m := sim.New(sim.Naive, 2)
+//	m.Run(schedule)
+//	fmt.Println(strings.Join(m.History(), "\n"))
+//
+// Memory is sequentially consistent: every thread sees every store as soon as it is
+// made. Real hardware reorders more, which breaks Peterson's algorithm, for one; the
+// litmus package tests the locks of this module for such reorderings.
+package sim
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Flow is where a thread goes after an instruction: Next, Retry, Done, or Goto.
+type Flow int
+
+const (
+	Next  Flow = -1 - iota // To the following instruction
+	Retry                  // To the same instruction again, as a spinning waiter does
+	Done                   // Out of Lock into the critical section, or out of Unlock
+)
+
+// Goto returns the Flow to instruction i of the same sequence.
+func Goto(i int) Flow { return Flow(i) }
+
+// Instr is one atomic step of an algorithm.
+type Instr struct {
+	Text string               // Pseudo-code shown to the student
+	Exec func(t *Thread) Flow // Performs the step on t's machine
+}
+
+// Algorithm is a lock expressed as instructions for the machine.
+type Algorithm struct {
+	Name       string
+	MaxThreads int                     // 0 for any number
+	Init       func(m *Machine, n int) // Declares the shared words for n threads
+	Lock       []Instr
+	Unlock     []Instr
+}
+
+// Phase is the part of its loop a thread is in.
+type Phase int
+
+const (
+	Locking Phase = iota
+	Critical
+	Unlocking
+)
+
+func (p Phase) String() string {
+	switch p {
+	case Locking:
+		return "lock"
+	case Critical:
+		return "critical"
+	case Unlocking:
+		return "unlock"
+	}
+	return "unknown"
+}
+
+// Thread is a thread of the machine.
+type Thread struct {
+	ID    int
+	Phase Phase
+	PC    int            // Next instruction of the phase's sequence
+	Local map[string]int // The thread's registers
+	m     *Machine
+}
+
+// Machine is the abstract machine. Create it with New.
+type Machine struct {
+	alg     Algorithm
+	names   []string // Shared words in declaration order
+	mem     map[string]int
+	threads []*Thread
+	history []string
+}
+
+// New creates a machine running alg on n threads, all about to lock. It panics if alg
+// doesn't support n threads.
+func New(alg Algorithm, n int) *Machine {
+	if n <= 0 || (alg.MaxThreads > 0 && n > alg.MaxThreads) {
+		panic(fmt.Sprintf("sim: %s doesn't run on %d threads", alg.Name, n))
+	}
+	m := &Machine{alg: alg, mem: make(map[string]int)}
+	for i := range n {
+		m.threads = append(m.threads, &Thread{ID: i, Local: make(map[string]int), m: m})
+	}
+	if alg.Init != nil {
+		alg.Init(m, n)
+	}
+	return m
+}
+
+// Declare adds a shared word with an initial value.
+func (m *Machine) Declare(name string, v int) {
+	if _, ok := m.mem[name]; !ok {
+		m.names = append(m.names, name)
+	}
+	m.mem[name] = v
+}
+
+// Load returns the value of a shared word. It panics if the word wasn't declared, which
+// is a bug in the algorithm.
+func (m *Machine) Load(name string) int {
+	v, ok := m.mem[name]
+	if !ok {
+		panic("sim: undeclared word " + name)
+	}
+	return v
+}
+
+// Store sets a shared word.
+func (m *Machine) Store(name string, v int) {
+	m.Load(name)
+	m.mem[name] = v
+}
+
+// Swap sets a shared word and returns its old value.
+func (m *Machine) Swap(name string, v int) int {
+	old := m.Load(name)
+	m.mem[name] = v
+	return old
+}
+
+// FetchAdd adds delta to a shared word and returns its old value.
+func (m *Machine) FetchAdd(name string, delta int) int {
+	old := m.Load(name)
+	m.mem[name] = old + delta
+	return old
+}
+
+// CAS sets a shared word to new if it is old, and reports whether it did.
+func (m *Machine) CAS(name string, old, new int) bool {
+	if m.Load(name) != old {
+		return false
+	}
+	m.mem[name] = new
+	return true
+}
+
+// Machine returns the machine t runs on, for instructions to access shared words.
+func (t *Thread) Machine() *Machine { return t.m }
+
+// Threads returns the machine's threads.
+func (m *Machine) Threads() []*Thread { return m.threads }
+
+// Next returns the text of the instruction thread i executes when stepped.
+func (m *Machine) Next(i int) string {
+	t := m.threads[i]
+	switch t.Phase {
+	case Locking:
+		return m.alg.Lock[t.PC].Text
+	case Unlocking:
+		return m.alg.Unlock[t.PC].Text
+	}
+	return "leave the critical section"
+}
+
+// Step executes one instruction of thread i.
+func (m *Machine) Step(i int) {
+	t := m.threads[i]
+	m.history = append(m.history, fmt.Sprintf("T%d: %s", i, m.Next(i)))
+	var code []Instr
+	switch t.Phase {
+	case Critical:
+		t.Phase, t.PC = Unlocking, 0
+		return
+	case Locking:
+		code = m.alg.Lock
+	case Unlocking:
+		code = m.alg.Unlock
+	}
+
+	switch f := code[t.PC].Exec(t); f {
+	case Next:
+		t.PC++
+	case Retry:
+	case Done:
+		t.PC = len(code)
+	default:
+		t.PC = int(f)
+	}
+	if t.PC < len(code) {
+		return
+	}
+	t.PC = 0
+	if t.Phase == Locking {
+		t.Phase = Critical
+	} else {
+		t.Phase = Locking
+	}
+}
+
+// Run steps the threads in the order given by schedule.
+func (m *Machine) Run(schedule []int) {
+	for _, i := range schedule {
+		m.Step(i)
+	}
+}
+
+// Violation reports whether more than one thread is in the critical section.
+func (m *Machine) Violation() bool {
+	in := 0
+	for _, t := range m.threads {
+		if t.Phase == Critical {
+			in++
+		}
+	}
+	return in > 1
+}
+
+// History returns the instructions executed so far, one per step.
+func (m *Machine) History() []string { return slices.Clone(m.history) }
+
+// String shows the shared words and each thread's registers and next instruction.
+func (m *Machine) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s after %d steps\n", m.alg.Name, len(m.history))
+	for _, name := range m.names {
+		fmt.Fprintf(&b, "  %s = %d\n", name, m.mem[name])
+	}
+	for i, t := range m.threads {
+		fmt.Fprintf(&b, "T%d %-8s %s", i, t.Phase, m.Next(i))
+		if locals := t.locals(); locals != "" {
+			fmt.Fprintf(&b, "  [%s]", locals)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// locals formats t's registers, sorted by name.
+func (t *Thread) locals() string {
+	names := make([]string, 0, len(t.Local))
+	for name := range t.Local {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%s=%d", name, t.Local[name])
+	}
+	return strings.Join(names, " ")
+}
+
+// clone copies m, except its history.
+func (m *Machine) clone() *Machine {
+	c := &Machine{alg: m.alg, names: m.names, mem: make(map[string]int, len(m.mem))}
+	for name, v := range m.mem {
+		c.mem[name] = v
+	}
+	for _, t := range m.threads {
+		ct := &Thread{ID: t.ID, Phase: t.Phase, PC: t.PC, Local: make(map[string]int, len(t.Local)), m: c}
+		for name, v := range t.Local {
+			ct.Local[name] = v
+		}
+		c.threads = append(c.threads, ct)
+	}
+	return c
+}
+
+// state identifies m's memory and threads, for recognising states already explored.
+func (m *Machine) state() string {
+	var b strings.Builder
+	for _, name := range m.names {
+		fmt.Fprintf(&b, "%d,", m.mem[name])
+	}
+	for _, t := range m.threads {
+		fmt.Fprintf(&b, "|%d %d %s", t.Phase, t.PC, t.locals())
+	}
+	return b.String()
+}
+
+// Explore searches the interleavings of alg on n threads, breadth first, for the
+// shortest schedule of at most maxSteps steps that puts two threads in the critical
+// section at once. It returns nil if there is none, which for a correct lock holds for
+// any maxSteps. The search visits each distinct machine state once, so spinning doesn't
+// inflate it, but it still grows quickly with n.
+func Explore(alg Algorithm, n, maxSteps int) []int {
+	type node struct {
+		m        *Machine
+		schedule []int
+	}
+	start := New(alg, n)
+	seen := map[string]bool{start.state(): true}
+	level := []node{{m: start}}
+	for range maxSteps {
+		var next []node
+		for _, nd := range level {
+			for i := range n {
+				m := nd.m.clone()
+				m.Step(i)
+				schedule := append(slices.Clip(nd.schedule), i)
+				if m.Violation() {
+					return schedule
+				}
+				if key := m.state(); !seen[key] {
+					seen[key] = true
+					next = append(next, node{m, schedule})
+				}
+			}
+		}
+		level = next
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package sim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExploreFindsBrokenLocks(t *testing.T) {
+	for _, alg := range []Algorithm{Naive, SplitTicket} {
+		schedule := Explore(alg, 2, 12)
+		assert.NotNil(t, schedule, alg.Name)
+
+		m := New(alg, 2)
+		m.Run(schedule)
+		assert.True(t, m.Violation(), alg.Name)
+		assert.Len(t, m.History(), len(schedule))
+	}
+	assert.Equal(t, []int{0, 1, 0, 1}, Explore(Naive, 2, 12), "Both test the flag before either sets it")
+}
+
+func TestExploreFindsNoViolationInCorrectLocks(t *testing.T) {
+	for _, tc := range []struct {
+		alg     Algorithm
+		threads int
+	}{
+		{TAS, 3},
+		{Ticket, 3},
+		{Peterson, 2},
+		{MCS, 3},
+	} {
+		assert.Nil(t, Explore(tc.alg, tc.threads, 40), tc.alg.Name)
+	}
+}
+
+func TestStepShowsState(t *testing.T) {
+	m := New(Ticket, 2)
+	m.Step(0)
+	m.Step(1)
+	m.Step(1)
+	m.Step(0)
+	assert.Equal(t, Critical, m.Threads()[0].Phase)
+	assert.Equal(t, Locking, m.Threads()[1].Phase)
+	assert.Equal(t, 1, m.Threads()[1].PC, "T1 spins until serving reaches its ticket")
+	assert.Equal(t, []string{
+		"T0: my = fetch_add(next, 1)",
+		"T1: my = fetch_add(next, 1)",
+		"T1: while serving != my {}",
+		"T0: while serving != my {}",
+	}, m.History())
+	assert.Equal(t, `ticket after 4 steps
+  next = 2
+  serving = 0
+T0 critical leave the critical section  [my=0]
+T1 lock     while serving != my {}  [my=1]
+`, m.String())
+
+	m.Step(0)
+	m.Step(0)
+	m.Step(1)
+	assert.Equal(t, Critical, m.Threads()[1].Phase, "T0's unlock hands the lock to T1")
+}
+
+func TestMCSQueues(t *testing.T) {
+	m := New(MCS, 2)
+	m.Run([]int{0, 0, 0}) // T0 finds the queue empty and takes the lock
+	m.Run([]int{1, 1, 1, 1, 1})
+	assert.Equal(t, 2, m.Load("tail"))
+	assert.Equal(t, 2, m.Load("node[1].next"), "T1 links behind T0")
+	assert.Equal(t, 4, m.Threads()[1].PC, "T1 spins on its own node")
+
+	m.Run([]int{0, 0, 0, 1})
+	assert.Equal(t, Critical, m.Threads()[1].Phase)
+	assert.False(t, m.Violation())
+}
+
+func TestNewRejectsTooManyThreads(t *testing.T) {
+	assert.Panics(t, func() { New(Peterson, 3) })
+}
@@ -0,0 +1,102 @@
+// Package singleflight deduplicates concurrent calls for the same key, like
+// golang.org/x/sync/singleflight, but without a stampede when the call fails.
+//
+// In x/sync/singleflight, every caller waiting on a failed call gets its error at
+// once, and typically all of them retry at once, which is exactly the load the
+// deduplication was meant to prevent. Here callers for a key queue on an exclusive lock
+// of an intent.Manager instead, in FIFO order. The first runs the function; if it
+// succeeds, everyone queued behind it receives its result. If it fails, only the leader
+// sees the error, and the next caller in line runs the function in turn, while the rest
+// keep waiting for it. Retries after a failure thus happen one at a time, in arrival
+// order.
+//
+// Example usage:
+//
+//	g := singleflight.NewGroup[*User]()
+//
+//	u, err, shared := g.Do(ctx, id, func() (*User, error) {
+//	    return db.LoadUser(ctx, id) // At most one load of id in flight at a time
+//	})
+package singleflight
+
+import (
+	"context"
+
+	"github.com/ahrav/go-locks/intent"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// call is one deduplicated call, shared by the callers that arrived while it was in
+// flight.
+type call[V any] struct {
+	val  V
+	done bool // The call succeeded and val is its result; guarded by the key's lock
+	refs int  // Callers sharing the call; guarded by Group.mu
+}
+
+// Group deduplicates calls by key. Create it with NewGroup.
+type Group[V any] struct {
+	mu    *ticket.Lock
+	calls map[string]*call[V] // Calls in flight; guarded by mu
+	keys  *intent.Manager
+}
+
+// NewGroup creates a Group with no calls in flight.
+func NewGroup[V any]() *Group[V] {
+	return &Group[V]{mu: ticket.NewLock(), calls: make(map[string]*call[V]), keys: intent.NewManager()}
+}
+
+// Do runs fn for key, unless a call for key is already in flight, in which case it waits
+// for that call. If the call succeeds, Do returns its result, with shared reporting
+// whether other callers received it too. If it fails, its error goes to the caller that
+// ran fn only, and the next waiting caller runs fn itself. Do returns ctx.Err() if ctx
+// is done before the caller gets a result or its turn to run fn, and fn's panic, if any,
+// to the caller that ran it.
+func (g *Group[V]) Do(ctx context.Context, key string, fn func() (V, error)) (v V, err error, shared bool) {
+	c := g.join(key)
+	defer g.leave(key, c)
+	if err := g.keys.Acquire(ctx, key, intent.X); err != nil {
+		var zero V
+		return zero, err, false
+	}
+	defer g.keys.Release(key, intent.X)
+
+	if c.done {
+		return c.val, nil, true
+	}
+	v, err = fn()
+	if err != nil {
+		return v, err, false
+	}
+	c.val, c.done = v, true
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key) // Later callers start a new call
+	}
+	shared = c.refs > 1
+	g.mu.Unlock()
+	return v, nil, shared
+}
+
+// join returns the call in flight for key, starting one if there is none.
+func (g *Group[V]) join(key string) *call[V] {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c := g.calls[key]
+	if c == nil {
+		c = new(call[V])
+		g.calls[key] = c
+	}
+	c.refs++
+	return c
+}
+
+// leave drops the caller's reference to c, forgetting c once it is unused.
+func (g *Group[V]) leave(key string, c *call[V]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c.refs--
+	if c.refs == 0 && g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
@@ -0,0 +1,120 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitRefs waits until n callers share the call for key.
+func waitRefs(g *Group[int], key string, n int) {
+	for {
+		g.mu.Lock()
+		c := g.calls[key]
+		joined := c != nil && c.refs >= n
+		g.mu.Unlock()
+		if joined {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDoDeduplicates(t *testing.T) {
+	g := NewGroup[int]()
+	const numCallers = 5
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fn := func() (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+	var wg sync.WaitGroup
+
+	wg.Add(numCallers)
+	for range numCallers {
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do(context.Background(), "k", fn)
+			assert.NoError(t, err)
+			assert.Equal(t, 42, v)
+			assert.True(t, shared)
+		}()
+	}
+	waitRefs(g, "k", numCallers)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Empty(t, g.calls, "Finished calls are forgotten")
+
+	v, err, shared := g.Do(context.Background(), "k", func() (int, error) { return 7, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 7, v, "A later call runs again")
+	assert.False(t, shared)
+}
+
+func TestFailureRetriesOneAtATime(t *testing.T) {
+	g := NewGroup[int]()
+	const numCallers = 5
+	errFlaky := errors.New("flaky")
+	release := make(chan struct{})
+	var calls, running, failures atomic.Int32
+	fn := func() (int, error) {
+		assert.Equal(t, int32(1), running.Add(1), "Retries stampeded")
+		defer running.Add(-1)
+		if calls.Add(1) <= 2 {
+			<-release
+			return 0, errFlaky
+		}
+		return 1, nil
+	}
+	var wg sync.WaitGroup
+
+	wg.Add(numCallers)
+	for range numCallers {
+		go func() {
+			defer wg.Done()
+			v, err, _ := g.Do(context.Background(), "k", fn)
+			if err != nil {
+				assert.ErrorIs(t, err, errFlaky)
+				failures.Add(1)
+				return
+			}
+			assert.Equal(t, 1, v)
+		}()
+	}
+	waitRefs(g, "k", numCallers)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(3), calls.Load(), "Two failures, then one success shared by the rest")
+	assert.Equal(t, int32(2), failures.Load())
+}
+
+func TestDoHonorsContext(t *testing.T) {
+	g := NewGroup[int]()
+	release := make(chan struct{})
+	go func() {
+		_, _, _ = g.Do(context.Background(), "k", func() (int, error) {
+			<-release
+			return 1, nil
+		})
+	}()
+	waitRefs(g, "k", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitRefs(g, "k", 2)
+		cancel()
+	}()
+	_, err, _ := g.Do(ctx, "k", func() (int, error) { return 2, nil })
+	assert.ErrorIs(t, err, context.Canceled)
+	close(release)
+}
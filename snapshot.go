@@ -0,0 +1,95 @@
+package locks
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/offheap"
+	"github.com/ahrav/go-locks/spin"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// Snapshot holds a struct of several fields, such as a group of counters, that is
+// written under a lock and read as a consistent whole without taking it.
+//
+// It is a seqlock: a writer makes the sequence number odd, stores the value and makes it
+// even again, and a reader copies the value between two reads of the sequence number,
+// retrying if they differ or were odd. Reads never block or write shared memory, so any
+// number of readers cost the writer nothing; a reader only retries while a write is
+// being published. The value is stored and copied a 64-bit word at a time with atomic
+// operations, which is why T must not contain pointers, strings, slices, maps, channels,
+// functions or interfaces: NewSnapshot panics if it does.
+type Snapshot[T any] struct {
+	seq   atomic.Uint64
+	words []uint64 // The value, accessed only atomically
+	l     sync.Locker
+}
+
+// NewSnapshot creates a Snapshot holding v, whose writers serialize on l. A nil l
+// selects a ticket.Lock.
+func NewSnapshot[T any](v T, l sync.Locker) *Snapshot[T] {
+	if path, ok := offheap.Pointer(reflect.TypeFor[T]()); ok {
+		panic(fmt.Sprintf("locks: Snapshot of a type with a pointer at %s", path))
+	}
+	if l == nil {
+		l = ticket.NewLock()
+	}
+	s := &Snapshot[T]{words: make([]uint64, (unsafe.Sizeof(v)+7)/8), l: l}
+	s.store(&padded[T]{v: v})
+	return s
+}
+
+// padded leaves room to copy whole words into a T whose size is not a multiple of 8.
+type padded[T any] struct {
+	v T
+	_ [7]byte
+}
+
+// Read returns a consistent copy of the value, without locking.
+func (s *Snapshot[T]) Read() T {
+	var out padded[T]
+	for n := 0; ; n++ {
+		seq := s.seq.Load()
+		if seq&1 == 0 {
+			s.load(&out)
+			chaos.Point()
+			if s.seq.Load() == seq {
+				return out.v
+			}
+		}
+		spin.Wait(n) // A writer is publishing
+	}
+}
+
+// Write runs fn with exclusive access to a copy of the value, and publishes the result.
+func (s *Snapshot[T]) Write(fn func(*T)) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	var cur padded[T]
+	s.load(&cur)
+	fn(&cur.v)
+	s.seq.Add(1) // Odd: readers retry
+	s.store(&cur)
+	s.seq.Add(1)
+}
+
+// load copies the stored words into p.
+func (s *Snapshot[T]) load(p *padded[T]) {
+	for i := range s.words {
+		w := atomic.LoadUint64(&s.words[i])
+		*(*[8]byte)(unsafe.Add(unsafe.Pointer(p), i*8)) = *(*[8]byte)(unsafe.Pointer(&w))
+	}
+}
+
+// store copies p into the stored words.
+func (s *Snapshot[T]) store(p *padded[T]) {
+	for i := range s.words {
+		var w uint64
+		*(*[8]byte)(unsafe.Pointer(&w)) = *(*[8]byte)(unsafe.Add(unsafe.Pointer(p), i*8))
+		atomic.StoreUint64(&s.words[i], w)
+	}
+}
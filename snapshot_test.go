@@ -0,0 +1,74 @@
+package locks
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+type counters struct {
+	Requests, Hits, Misses uint64
+	Ratio                  float32 // Leaves the struct a size that is not a multiple of 8
+}
+
+func TestSnapshotReadsAreConsistent(t *testing.T) {
+	s := NewSnapshot(counters{}, nil)
+	const numReaders = 4
+	const writes = 500
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(numReaders)
+	for range numReaders {
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				c := s.Read()
+				assert.Equal(t, c.Requests, c.Hits+c.Misses, "Read saw a torn write")
+			}
+		}()
+	}
+	for i := range writes {
+		s.Write(func(c *counters) {
+			c.Requests++
+			if i%3 == 0 {
+				c.Misses++
+			} else {
+				c.Hits++
+			}
+			c.Ratio = float32(c.Hits) / float32(c.Requests)
+		})
+	}
+	close(stop)
+	wg.Wait()
+
+	c := s.Read()
+	assert.Equal(t, uint64(writes), c.Requests)
+	assert.InDelta(t, float32(c.Hits)/float32(writes), c.Ratio, 1e-6)
+}
+
+func TestSnapshotSmallValue(t *testing.T) {
+	s := NewSnapshot([3]byte{1, 2, 3}, nil)
+	assert.Equal(t, [3]byte{1, 2, 3}, s.Read())
+	s.Write(func(b *[3]byte) { b[2] = 9 })
+	assert.Equal(t, [3]byte{1, 2, 9}, s.Read())
+}
+
+func TestSnapshotRejectsPointers(t *testing.T) {
+	assert.PanicsWithValue(t, "locks: Snapshot of a type with a pointer at struct { Name string }.Name", func() {
+		NewSnapshot(struct{ Name string }{}, nil)
+	})
+}
+
+func TestSnapshotReadDoesNotAllocate(t *testing.T) {
+	s := NewSnapshot(counters{}, nil)
+	allocs.Zero(t, func() { _ = s.Read() })
+}
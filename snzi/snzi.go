@@ -0,0 +1,171 @@
+// Package snzi implements a Scalable Non-Zero Indicator (SNZI), after Ellen, Lev,
+// Luchangco and Moir.
+//
+// A SNZI is a counter that can only be asked one question: is it non-zero? Giving up
+// the exact value lets it scale. Arrivals and departures are spread over the leaves of
+// a tree, and a node only propagates to its parent when its own surplus moves between
+// zero and non-zero. Under a steady population of arrivals most operations touch a
+// single leaf, while Query reads a root word that changes only when the whole tree
+// empties or stops being empty.
+//
+// It is the standard building block for scalable reader indicators: readers Arrive on
+// entry and Depart on exit, and a writer checks Query to see whether any reader is
+// still inside.
+//
+// Example usage:
+//
+//	s := snzi.New(8) // 8 leaves
+//
+//	n := s.Arrive() // Pick a leaf and arrive at it
+//	// ... s.Query() reports true ...
+//	s.Depart(n) // Depart from the same leaf
+//
+// Callers with a stable notion of locality, such as a NUMA node or worker index, can
+// use ArriveAt to keep arrivals from the same place on the same leaf.
+package snzi
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/pad"
+)
+
+// Surpluses are stored doubled so that the intermediate value ½, used while a node's
+// first arrival is being propagated to its parent, fits in an integer.
+const (
+	half = 1
+	one  = 2
+)
+
+// pack combines a doubled surplus c and version v into a node state word.
+func pack(c, v uint32) uint64 { return uint64(c)<<32 | uint64(v) }
+
+// unpack splits a node state word into its doubled surplus and version.
+func unpack(x uint64) (c, v uint32) { return uint32(x >> 32), uint32(x) }
+
+// Node is a non-root node of the SNZI tree.
+type Node struct {
+	x      atomic.Uint64 // Doubled surplus and version, see pack
+	parent *Node         // nil for children of the root
+	root   *atomic.Int64
+
+	_ pad.CacheLinePad
+}
+
+// arrive increments the node's surplus, propagating to the parent on the transition
+// away from zero.
+func (n *Node) arrive() {
+	undo := 0 // Surplus arrivals at the parent made while helping others
+	for done := false; !done; {
+		x := n.x.Load()
+		c, v := unpack(x)
+		if c >= one && n.x.CompareAndSwap(x, pack(c+one, v)) {
+			done = true
+		}
+		if c == 0 {
+			next := pack(half, v+1)
+			if n.x.CompareAndSwap(x, next) {
+				done = true
+				x, c, v = next, half, v+1
+			}
+		}
+		if c == half {
+			// Whoever moved the node to ½ still has to arrive at the parent; any
+			// arriver may do it on their behalf, and undoes it if it loses the race.
+			n.arriveParent()
+			if !n.x.CompareAndSwap(x, pack(one, v)) {
+				undo++
+			}
+		}
+	}
+	for ; undo > 0; undo-- {
+		n.departParent()
+	}
+}
+
+// depart decrements the node's surplus, propagating to the parent on the transition
+// back to zero.
+func (n *Node) depart() {
+	for {
+		x := n.x.Load()
+		c, v := unpack(x)
+		if invariant.Enabled {
+			invariant.Check(c >= one, "snzi: Depart from node %p with surplus %d/2", n, c)
+		}
+		if n.x.CompareAndSwap(x, pack(c-one, v)) {
+			if c == one {
+				n.departParent()
+			}
+			return
+		}
+	}
+}
+
+func (n *Node) arriveParent() {
+	if n.parent == nil {
+		n.root.Add(1)
+		return
+	}
+	n.parent.arrive()
+}
+
+func (n *Node) departParent() {
+	if n.parent == nil {
+		n.root.Add(-1)
+		return
+	}
+	n.parent.depart()
+}
+
+// SNZI is a scalable non-zero indicator.
+type SNZI struct {
+	root   atomic.Int64 // Number of root children with a surplus
+	nodes  []Node       // Binary tree in heap order, without the root
+	leaves []*Node
+}
+
+// New creates a SNZI with at least the given number of leaves, rounded up to a power
+// of two. With a single leaf, arrivals go straight to the root.
+func New(leaves int) *SNZI {
+	leaves = 1 << bits.Len(uint(max(leaves, 1)-1))
+	s := &SNZI{nodes: make([]Node, max(2*leaves-2, 1))}
+
+	// nodes[0] and nodes[1] are the root's children; the children of nodes[i] are
+	// nodes[2i+2] and nodes[2i+3].
+	for i := range s.nodes {
+		n := &s.nodes[i]
+		n.root = &s.root
+		if i >= 2 {
+			n.parent = &s.nodes[(i-2)/2]
+		}
+	}
+	s.leaves = make([]*Node, leaves)
+	for i := range s.leaves {
+		s.leaves[i] = &s.nodes[len(s.nodes)-leaves+i]
+	}
+	return s
+}
+
+// Leaves returns the number of leaves in the tree.
+func (s *SNZI) Leaves() int { return len(s.leaves) }
+
+// Arrive arrives at a randomly chosen leaf and returns it; the caller must pass it to
+// Depart.
+func (s *SNZI) Arrive() *Node { return s.ArriveAt(int(rand.Uint32())) }
+
+// ArriveAt arrives at leaf i modulo Leaves and returns it; the caller must pass it to
+// Depart.
+func (s *SNZI) ArriveAt(i int) *Node {
+	n := s.leaves[uint(i)%uint(len(s.leaves))]
+	n.arrive()
+	return n
+}
+
+// Depart undoes an arrival at the leaf returned by Arrive or ArriveAt.
+func (s *SNZI) Depart(n *Node) { n.depart() }
+
+// Query reports whether there are more arrivals than departures.
+func (s *SNZI) Query() bool { return s.root.Load() > 0 }
@@ -0,0 +1,70 @@
+package snzi
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRoundsLeaves(t *testing.T) {
+	assert.Equal(t, 1, New(0).Leaves())
+	assert.Equal(t, 1, New(1).Leaves())
+	assert.Equal(t, 4, New(3).Leaves())
+	assert.Equal(t, 8, New(8).Leaves())
+}
+
+func TestArriveDepart(t *testing.T) {
+	for _, leaves := range []int{1, 2, 8} {
+		s := New(leaves)
+		assert.False(t, s.Query())
+
+		a := s.ArriveAt(0)
+		b := s.ArriveAt(leaves - 1)
+		assert.True(t, s.Query())
+		s.Depart(a)
+		assert.True(t, s.Query(), "One arrival is still outstanding")
+		s.Depart(b)
+		assert.False(t, s.Query())
+	}
+}
+
+func TestConcurrentArriveDepart(t *testing.T) {
+	s := New(4)
+	const numGoroutines = 8
+	const iterations = 2000
+	var missed atomic.Int64
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				n := s.Arrive()
+				if !s.Query() {
+					missed.Add(1)
+				}
+				s.Depart(n)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(t, missed.Load(), "Query must report true while the caller has arrived")
+	assert.False(t, s.Query())
+	for i := range s.nodes {
+		c, _ := unpack(s.nodes[i].x.Load())
+		assert.Zero(t, c, "node %d should have no surplus left", i)
+	}
+}
+
+func BenchmarkArriveDepart(b *testing.B) {
+	s := New(8)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Depart(s.Arrive())
+		}
+	})
+}
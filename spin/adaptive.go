@@ -0,0 +1,57 @@
+package spin
+
+import "sync/atomic"
+
+// Bounds and starting point for an Adaptive spin limit under the default profile, in
+// relax instructions.
+const (
+	DefaultSpinLimit = 64
+	MinSpinLimit     = 4
+	MaxSpinLimit     = 4096
+)
+
+// Adaptive tunes how long a lock's waiters spin before yielding, based on how long
+// recent handoffs actually took. It follows the approach of adaptive mutexes in other
+// runtimes: when waiters keep acquiring the lock while still spinning, the limit moves
+// toward twice the observed spin count; when they keep running out of spin and have to
+// yield anyway, it shrinks. Each update moves the limit 1/8th of the way toward its
+// target, so a single outlier handoff doesn't swing it.
+//
+// The zero value is ready to use and starts at the current profile's Spin, and the
+// limit stays within the profile's MinSpin and MaxSpin. Updates from concurrent waiters
+// may overwrite each other; the limit is a heuristic and only needs to be approximately
+// right.
+type Adaptive struct {
+	limit atomic.Uint32 // 0 means the profile's Spin
+}
+
+// Limit returns the number of relax instructions a waiter should spin before yielding.
+func (a *Adaptive) Limit() int {
+	p := profile.Load()
+	if l := a.limit.Load(); l != 0 {
+		return min(max(int(l), p.MinSpin), p.MaxSpin) // The profile may have changed since
+	}
+	return p.Spin
+}
+
+// Update records the outcome of one wait: spun is the number of relax instructions the
+// waiter executed, and acquired reports whether the lock was handed over before the spin
+// limit ran out.
+func (a *Adaptive) Update(spun int, acquired bool) {
+	cur := a.Limit()
+	target := cur / 2 // Spinning didn't pay off; spin less next time
+	if acquired {
+		target = 2*spun + 10 // Leave headroom above the observed handoff latency
+	}
+	step := (target - cur) / 8
+	if step == 0 && target != cur {
+		step = 1 // Keep converging once the remaining gap is under 8
+		if target < cur {
+			step = -1
+		}
+	}
+	next := cur + step
+	p := profile.Load()
+	next = min(max(next, p.MinSpin), p.MaxSpin)
+	a.limit.Store(uint32(next))
+}
@@ -0,0 +1,37 @@
+package spin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveStartsAtDefault(t *testing.T) {
+	var a Adaptive
+	assert.Equal(t, DefaultSpinLimit, a.Limit())
+}
+
+func TestAdaptiveGrowsWhenSpinningSucceeds(t *testing.T) {
+	var a Adaptive
+	for range 100 {
+		a.Update(a.Limit()-1, true) // Handoffs arrive just before the limit
+	}
+	assert.Greater(t, a.Limit(), DefaultSpinLimit)
+	assert.LessOrEqual(t, a.Limit(), MaxSpinLimit)
+}
+
+func TestAdaptiveConvergesOnHandoffLatency(t *testing.T) {
+	var a Adaptive
+	for range 200 {
+		a.Update(10, true) // Handoffs consistently take ~10 relaxes
+	}
+	assert.InDelta(t, 30, a.Limit(), 8, "Limit should settle near twice the latency plus headroom")
+}
+
+func TestAdaptiveShrinksWhenSpinningFails(t *testing.T) {
+	var a Adaptive
+	for range 200 {
+		a.Update(a.Limit(), false)
+	}
+	assert.Equal(t, MinSpinLimit, a.Limit())
+}
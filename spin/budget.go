@@ -0,0 +1,45 @@
+package spin
+
+import "sync/atomic"
+
+var (
+	budget   atomic.Int32 // Maximum concurrent spinners; 0 means unlimited
+	spinners atomic.Int32 // Waiters currently holding a reservation
+)
+
+// SetBudget caps the number of waiters, across every lock in the process, that may
+// busy-wait at the same time, and returns the previous cap. n <= 0 removes the cap,
+// which is the default.
+//
+// Each lock's spinning is bounded on its own, but many hot locks on an oversubscribed
+// machine can together keep every CPU busy in spin loops while the goroutines that
+// would release them wait to run. Under a budget, a waiter that finds it exhausted skips
+// spinning and yields from its first iteration. A setting of GOMAXPROCS/2, the limit the
+// runtime applies to its own spinning threads, is a reasonable starting point.
+func SetBudget(n int) int { return int(budget.Swap(int32(max(n, 0)))) }
+
+// Reserve claims a place in the spin budget for a waiter about to spin, reporting false
+// if the budget, or the share of a cgroup CPU quota allowed to spin, is exhausted. A
+// waiter keeps its place only while it spins: it must give it back with Release once it
+// acquires the lock or starts yielding or parking instead.
+func Reserve() bool {
+	n := spinners.Add(1)
+	if b := budget.Load(); (b > 0 && n > b) || exceedsQuota(n) {
+		spinners.Add(-1)
+		return false
+	}
+	return true
+}
+
+// Release returns a place claimed by a successful Reserve.
+func Release() { spinners.Add(-1) }
+
+// Spinners returns the number of waiters currently holding a place in the budget.
+func Spinners() int { return int(spinners.Load()) }
+
+// exceedsQuota reports whether n spinners exceed the share of the cgroup's CPU quota
+// they may use.
+func exceedsQuota(n int32) bool {
+	b := quotaBudget.Load()
+	return b > 0 && n > b
+}
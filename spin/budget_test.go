@@ -0,0 +1,34 @@
+package spin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	defer SetBudget(SetBudget(2))
+
+	assert.True(t, Reserve())
+	assert.True(t, Reserve())
+	assert.False(t, Reserve(), "A third spinner should exceed the budget")
+	assert.Equal(t, 2, Spinners())
+
+	Release()
+	assert.True(t, Reserve(), "A released place should be reusable")
+	Release()
+	Release()
+	assert.Zero(t, Spinners())
+}
+
+func TestNoBudgetIsUnlimited(t *testing.T) {
+	defer SetBudget(SetBudget(0))
+
+	for range 100 {
+		assert.True(t, Reserve())
+	}
+	for range 100 {
+		Release()
+	}
+	assert.Zero(t, Spinners())
+}
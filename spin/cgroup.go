@@ -0,0 +1,210 @@
+package spin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Spinning inside a cgroup with a CPU quota burns quota that the lock holder needs. Once
+// the quota of a period is spent, the kernel throttles every thread of the cgroup until
+// the next period, holder included, so a waiter that spun for a few microseconds can
+// cost the holder up to a whole period (100ms by default) of not running. Kubernetes
+// CPU limits are such quotas, and GOMAXPROCS ignores them, so CanSpin consults the
+// quota itself:
+//   - With a quota under two CPUs there is no spinning at all, as on a single CPU
+//   - With a larger quota, at most half the quota's worth of waiters spin at once
+//   - When the cgroup was throttled during the last refresh interval, nobody spins, and
+//     Wait parks waiters after their first yield
+//
+// Only the process's own cgroup is consulted, not quotas set on its ancestors.
+
+// cgroupCPU holds the CPU controller files of the process's cgroup open, so that a
+// refresh, which runs on a waiter's slow path, costs a pread of each and no allocation.
+// A file that could not be opened is nil and reads as empty.
+type cgroupCPU struct {
+	v2     bool
+	quota  *os.File // cpu.max, or cpu.cfs_quota_us in v1
+	period *os.File // cpu.cfs_period_us in v1
+	stat   *os.File // cpu.stat, named the same in v1 and v2
+}
+
+var cgroup, haveCgroup = findCgroup("/")
+
+var (
+	quota       atomic.Int64  // CPU quota in thousandths of a CPU; 0 if unlimited
+	quotaBudget atomic.Int32  // Spinners allowed by the quota; 0 means no cap
+	nrThrottled atomic.Uint64 // Throttled periods counted by the kernel at the last refresh
+	throttled   atomic.Bool   // The cgroup was throttled during the last refresh interval
+)
+
+func init() {
+	if haveCgroup {
+		nrThrottled.Store(cgroup.readThrottled()) // Only throttling from now on counts
+	}
+}
+
+// findCgroup locates the CPU controller of the process's cgroup under the filesystem
+// rooted at root.
+func findCgroup(root string) (cgroupCPU, bool) {
+	data, err := os.ReadFile(filepath.Join(root, "proc/self/cgroup"))
+	if err != nil {
+		return cgroupCPU{}, false
+	}
+
+	mnt := filepath.Join(root, "sys/fs/cgroup")
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// Lines read hierarchy-ID:controller-list:cgroup-path.
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "0" && fields[1] == "" { // Unified hierarchy
+			if dir, ok := firstDir(mnt, fields[2], "cpu.max"); ok {
+				return openCgroup(dir, true), true
+			}
+			continue
+		}
+		for _, ctrl := range strings.Split(fields[1], ",") {
+			if ctrl != "cpu" {
+				continue
+			}
+			for _, m := range []string{fields[1], "cpu"} {
+				if dir, ok := firstDir(filepath.Join(mnt, m), fields[2], "cpu.cfs_quota_us"); ok {
+					return openCgroup(dir, false), true
+				}
+			}
+		}
+	}
+	return cgroupCPU{}, false
+}
+
+// firstDir returns the directory holding file, trying the cgroup path below mnt and
+// then mnt itself, which is where a container with its own cgroup namespace sees its
+// cgroup.
+func firstDir(mnt, path, file string) (string, bool) {
+	for _, dir := range []string{filepath.Join(mnt, path), mnt} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// openCgroup opens the CPU controller files in dir.
+func openCgroup(dir string, v2 bool) cgroupCPU {
+	open := func(name string) *os.File {
+		f, _ := os.Open(filepath.Join(dir, name))
+		return f
+	}
+	c := cgroupCPU{v2: v2, stat: open("cpu.stat")}
+	if v2 {
+		c.quota = open("cpu.max")
+	} else {
+		c.quota, c.period = open("cpu.cfs_quota_us"), open("cpu.cfs_period_us")
+	}
+	return c
+}
+
+// cgroupFileSize bounds the bytes read of a controller file; cpu.stat is the longest at
+// a few hundred.
+const cgroupFileSize = 1024
+
+// read reads f from its start into buf and returns what it read.
+func read(f *os.File, buf []byte) []byte {
+	if f == nil {
+		return nil
+	}
+	n, _ := f.ReadAt(buf, 0) // A short read ends in io.EOF
+	return buf[:n]
+}
+
+// readQuota returns the cgroup's CPU quota in thousandths of a CPU, or 0 if it has none.
+func (c cgroupCPU) readQuota() int64 {
+	var buf [cgroupFileSize]byte
+	if c.v2 {
+		return parseCPUMax(read(c.quota, buf[:]))
+	}
+	q, ok1 := parseInt(bytes.TrimSpace(read(c.quota, buf[:])))
+	p, ok2 := parseInt(bytes.TrimSpace(read(c.period, buf[:])))
+	if !ok1 || !ok2 || q <= 0 || p <= 0 {
+		return 0
+	}
+	return q * 1000 / p
+}
+
+// parseCPUMax parses a cgroup v2 cpu.max file, "$MAX $PERIOD" with a MAX of "max" for
+// no quota.
+func parseCPUMax(data []byte) int64 {
+	limit, period, ok := bytes.Cut(bytes.TrimSpace(data), []byte(" "))
+	if !ok {
+		return 0
+	}
+	q, ok1 := parseInt(limit)
+	p, ok2 := parseInt(period)
+	if !ok1 || !ok2 || q <= 0 || p <= 0 {
+		return 0
+	}
+	return q * 1000 / p
+}
+
+// readThrottled returns the number of periods in which the cgroup was throttled.
+func (c cgroupCPU) readThrottled() uint64 {
+	var buf [cgroupFileSize]byte
+	return parseThrottled(read(c.stat, buf[:]))
+}
+
+// parseThrottled extracts nr_throttled from a cpu.stat file, which has the same name in
+// cgroup v1 and v2.
+func parseThrottled(data []byte) uint64 {
+	for len(data) > 0 {
+		var line []byte
+		line, data, _ = bytes.Cut(data, []byte("\n"))
+		if v, ok := bytes.CutPrefix(line, []byte("nr_throttled ")); ok {
+			n, _ := parseInt(v)
+			return uint64(max(n, 0))
+		}
+	}
+	return 0
+}
+
+// parseInt parses a non-negative decimal number without allocating, as strconv would
+// for the error it returns. "max" and other non-numbers report false.
+func parseInt(b []byte) (int64, bool) {
+	if len(b) == 0 || len(b) > 18 {
+		return 0, false
+	}
+	var n int64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	return n, true
+}
+
+// refreshCgroup reloads the quota and whether the cgroup has been throttled since the
+// previous refresh.
+func refreshCgroup() {
+	q := cgroup.readQuota()
+	b := int32(0)
+	if q > 0 {
+		b = int32(max(q/2000, 1)) // Half the quota, in whole CPUs
+	}
+	quota.Store(q)
+	quotaBudget.Store(b)
+
+	n := cgroup.readThrottled()
+	throttled.Store(n > nrThrottled.Swap(n))
+}
+
+// Quota returns the CPU quota of the process's cgroup in CPUs, or 0 if it has none or
+// none could be found.
+func Quota() float64 { return float64(quota.Load()) / 1000 }
+
+// Throttled reports whether the process's cgroup was throttled for exceeding its CPU
+// quota during the last refresh interval.
+func Throttled() bool { return throttled.Load() }
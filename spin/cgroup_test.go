@@ -0,0 +1,141 @@
+package spin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+// writeFiles creates files with the given contents below root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindCgroupV2(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/self/cgroup":                     "0::/kubepods/pod1\n",
+		"sys/fs/cgroup/kubepods/pod1/cpu.max":  "50000 100000\n",
+		"sys/fs/cgroup/kubepods/pod1/cpu.stat": "usage_usec 100\nnr_periods 10\nnr_throttled 3\nthrottled_usec 900\n",
+	})
+
+	cg, ok := findCgroup(root)
+	assert.True(t, ok)
+	assert.True(t, cg.v2)
+	assert.Equal(t, int64(500), cg.readQuota())
+	assert.Equal(t, uint64(3), cg.readThrottled())
+}
+
+func TestFindCgroupV2Namespaced(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/self/cgroup":      "0::/\n",
+		"sys/fs/cgroup/cpu.max": "max 100000\n",
+	})
+
+	cg, ok := findCgroup(root)
+	assert.True(t, ok)
+	assert.Zero(t, cg.readQuota(), "max should mean no quota")
+}
+
+func TestFindCgroupV1(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/self/cgroup": "12:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  "250000\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpu.cfs_period_us": "100000\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpu.stat":          "nr_periods 5\nnr_throttled 1\nthrottled_time 12\n",
+	})
+
+	cg, ok := findCgroup(root)
+	assert.True(t, ok)
+	assert.False(t, cg.v2)
+	assert.Equal(t, int64(2500), cg.readQuota())
+	assert.Equal(t, uint64(1), cg.readThrottled())
+}
+
+func TestFindCgroupMissing(t *testing.T) {
+	_, ok := findCgroup(t.TempDir())
+	assert.False(t, ok)
+}
+
+func TestParseCPUMax(t *testing.T) {
+	assert.Equal(t, int64(1500), parseCPUMax([]byte("150000 100000\n")))
+	assert.Zero(t, parseCPUMax([]byte("max 100000")))
+	assert.Zero(t, parseCPUMax([]byte("garbage")))
+}
+
+func TestParseThrottled(t *testing.T) {
+	assert.Equal(t, uint64(42), parseThrottled([]byte("nr_periods 50\nnr_throttled 42\nthrottled_usec 9\n")))
+	assert.Zero(t, parseThrottled([]byte("nr_periods 50\n")))
+	assert.Zero(t, parseThrottled(nil))
+}
+
+func TestReadCgroupDoesNotAllocate(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/self/cgroup":           "0::/pod\n",
+		"sys/fs/cgroup/pod/cpu.max":  "50000 100000\n",
+		"sys/fs/cgroup/pod/cpu.stat": "nr_periods 10\nnr_throttled 3\n",
+	})
+	cg, ok := findCgroup(root)
+	assert.True(t, ok)
+
+	// The files are reread, not cached
+	writeFiles(t, root, map[string]string{"sys/fs/cgroup/pod/cpu.stat": "nr_periods 11\nnr_throttled 4\n"})
+	assert.Equal(t, uint64(4), cg.readThrottled())
+
+	allocs.Zero(t, func() {
+		cg.readQuota()
+		cg.readThrottled()
+	})
+}
+
+func TestCanSpinHonorsQuota(t *testing.T) {
+	if runtime.NumCPU() < 2 {
+		t.Skip("requires at least 2 CPUs")
+	}
+	prev := runtime.GOMAXPROCS(4)
+	defer func() {
+		runtime.GOMAXPROCS(prev)
+		quota.Store(0)
+		quotaBudget.Store(0)
+		throttled.Store(false)
+		refresh()
+	}()
+	refresh()
+	nextRefresh.Store(1 << 62) // Keep refresh from overwriting the values below
+
+	quota.Store(500)
+	assert.False(t, CanSpin(), "Spinning should be disabled under half a CPU of quota")
+
+	quota.Store(4000)
+	assert.True(t, CanSpin())
+
+	throttled.Store(true)
+	assert.False(t, CanSpin(), "Spinning should be disabled while throttled")
+}
+
+func TestReserveHonorsQuotaBudget(t *testing.T) {
+	quotaBudget.Store(1)
+	defer quotaBudget.Store(0)
+
+	assert.True(t, Reserve())
+	assert.False(t, Reserve(), "A second spinner should exceed the quota's share")
+	Release()
+	assert.Zero(t, Spinners())
+}
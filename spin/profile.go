@@ -0,0 +1,73 @@
+package spin
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultPark is how long a parked waiter sleeps under the default profile.
+const DefaultPark = time.Millisecond
+
+// Profile is the set of waiting parameters shared by every lock in this module. The
+// root locks package offers presets for common trade-offs between latency and CPU use.
+type Profile struct {
+	// Spin is how many relax instructions a waiter spins before it starts yielding.
+	// Locks that tune their spinning with Adaptive start from it.
+	Spin int
+	// MinSpin and MaxSpin bound the limits Adaptive settles on.
+	MinSpin, MaxSpin int
+	// Budget caps how many waiters may spin at once across all locks, as SetBudget
+	// does. 0 is unlimited.
+	Budget int
+	// Yields is how many times a waiter yields before it parks, sleeping for Park
+	// between checks of the lock instead. 0 never parks after yielding.
+	Yields int
+	// Park is how long a parked waiter sleeps. ticket.Lock also parks waiters that are
+	// far back in its queue for this long. 0 disables parking altogether.
+	Park time.Duration
+}
+
+var profile atomic.Pointer[Profile]
+
+func init() {
+	profile.Store(&Profile{
+		Spin:    DefaultSpinLimit,
+		MinSpin: MinSpinLimit,
+		MaxSpin: MaxSpinLimit,
+		Park:    DefaultPark,
+	})
+}
+
+// SetProfile makes p the waiting profile of every lock in the process and returns the
+// previous profile. It replaces any cap set with SetBudget by p.Budget. Waiters already
+// in a lock's slow path may finish their wait under the previous profile.
+//
+// SetProfile panics if p.MinSpin exceeds p.MaxSpin.
+func SetProfile(p Profile) Profile {
+	if p.MinSpin > p.MaxSpin {
+		panic("spin: Profile with MinSpin greater than MaxSpin")
+	}
+	p.Spin = min(max(p.Spin, p.MinSpin), p.MaxSpin)
+	SetBudget(p.Budget)
+	return *profile.Swap(&p)
+}
+
+// Current returns the waiting profile in effect.
+func Current() Profile { return *profile.Load() }
+
+// Wait gives up the processor for a waiter that has already yielded n times since it
+// stopped spinning: it yields until the current profile's Yields are used up, and
+// parks after that. While the process's cgroup is being throttled, it parks from the
+// second call on, whatever the profile's Yields.
+func Wait(n int) {
+	p := profile.Load()
+	yields := p.Yields
+	if throttled.Load() {
+		yields = 1
+	}
+	if yields > 0 && n >= yields && p.Park > 0 {
+		time.Sleep(p.Park)
+		return
+	}
+	Yield()
+}
@@ -0,0 +1,49 @@
+package spin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetProfileClampsAdaptive(t *testing.T) {
+	var a Adaptive
+	for range 100 {
+		a.Update(2000, true) // Push the limit up
+	}
+	assert.Greater(t, a.Limit(), 1000)
+
+	prev := SetProfile(Profile{Spin: 8, MinSpin: 2, MaxSpin: 32})
+	defer SetProfile(prev)
+	assert.Equal(t, 32, a.Limit(), "A lower MaxSpin should take effect immediately")
+
+	var fresh Adaptive
+	assert.Equal(t, 8, fresh.Limit(), "A new limit should start at the profile's Spin")
+}
+
+func TestSetProfileAppliesBudget(t *testing.T) {
+	prev := SetProfile(Profile{MaxSpin: DefaultSpinLimit, Budget: 3})
+	defer SetProfile(prev)
+	assert.Equal(t, 3, SetBudget(3))
+
+	SetProfile(prev)
+	assert.Equal(t, 0, SetBudget(0), "Restoring the previous profile should lift the cap")
+}
+
+func TestSetProfileRejectsInvertedBounds(t *testing.T) {
+	assert.Panics(t, func() { SetProfile(Profile{MinSpin: 10, MaxSpin: 5}) })
+}
+
+func TestWaitParksAfterYields(t *testing.T) {
+	prev := SetProfile(Profile{MaxSpin: 1, Yields: 2, Park: 20 * time.Millisecond})
+	defer SetProfile(prev)
+
+	start := time.Now()
+	Wait(1)
+	assert.Less(t, time.Since(start), 10*time.Millisecond, "Wait should yield before Yields is reached")
+
+	start = time.Now()
+	Wait(2)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond, "Wait should park once Yields is reached")
+}
@@ -0,0 +1,73 @@
+// Package spin holds the policy shared by the locks in this module for deciding when a
+// waiter may busy-wait and when it must give up the processor instead.
+//
+// Spinning only pays off when the goroutine holding the lock is running on another
+// processor at the same time. With a single P (GOMAXPROCS=1, or a single-CPU machine
+// or container) the holder cannot make progress while a waiter spins, so every spin
+// iteration is pure waste until the scheduler preempts the spinner. The locks consult
+// CanSpin when entering their slow path and skip straight to yielding when it reports
+// false, and wait with Yield instead of runtime.Gosched. How long a waiter spins once
+// spinning is allowed is tuned per lock by Adaptive, and SetBudget caps how many
+// waiters may spin at once across all locks. A Profile, applied with SetProfile, sets
+// these parameters together with when waiters that keep yielding park instead.
+//
+// On js/wasm and wasip1 there is only ever one thread of execution, shared with the
+// host's event loop. CanSpin always reports false there and Yield parks the waiter
+// rather than rescheduling it, so the event loop gets to run.
+package spin
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// refreshInterval bounds how stale the cached GOMAXPROCS value may become.
+const refreshInterval = 100 * time.Millisecond
+
+// numCPU is sampled once; the number of CPUs available to the process is fixed at
+// startup.
+var numCPU = runtime.NumCPU()
+
+// epoch anchors the monotonic timestamps used to schedule refreshes.
+var epoch = time.Now()
+
+var (
+	procs       atomic.Int32 // Cached runtime.GOMAXPROCS(0)
+	nextRefresh atomic.Int64 // Nanoseconds since epoch at which procs goes stale
+)
+
+func init() { refresh() }
+
+// CanSpin reports whether busy-waiting can make progress, which requires more than one
+// P and more than one CPU.
+//
+// It also reports false when the process's cgroup has a CPU quota of less than two CPUs
+// or was recently throttled for exceeding its quota; see Quota and Throttled.
+//
+// runtime.GOMAXPROCS takes the scheduler's global lock, which is exactly what a
+// contended acquisition must not do, so its value is cached, along with the cgroup's
+// state, and refreshed at most once per refreshInterval. A change to GOMAXPROCS is
+// therefore observed with a delay of up to refreshInterval. On a single-CPU machine
+// CanSpin never touches the cache.
+func CanSpin() bool {
+	if singleThreaded || numCPU == 1 {
+		return false
+	}
+	now := int64(time.Since(epoch))
+	if next := nextRefresh.Load(); now >= next && nextRefresh.CompareAndSwap(next, now+int64(refreshInterval)) {
+		refresh() // Only the waiter that won the CAS refreshes
+	}
+	q := quota.Load()
+	return procs.Load() > 1 && (q == 0 || q >= 2000) && !throttled.Load()
+}
+
+// refresh reloads the cached GOMAXPROCS value and cgroup CPU state, and schedules the
+// next refresh.
+func refresh() {
+	nextRefresh.Store(int64(time.Since(epoch) + refreshInterval))
+	procs.Store(int32(runtime.GOMAXPROCS(0)))
+	if haveCgroup {
+		refreshCgroup()
+	}
+}
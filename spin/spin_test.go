@@ -0,0 +1,55 @@
+package spin
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanSpinSingleP(t *testing.T) {
+	prev := runtime.GOMAXPROCS(1)
+	defer func() {
+		runtime.GOMAXPROCS(prev)
+		refresh()
+	}()
+	refresh() // Don't wait for the cached value to go stale
+
+	assert.False(t, CanSpin(), "Spinning should be disabled with a single P")
+}
+
+func TestCanSpinFollowsGOMAXPROCS(t *testing.T) {
+	if runtime.NumCPU() < 2 {
+		t.Skip("requires at least 2 CPUs")
+	}
+	prev := runtime.GOMAXPROCS(2)
+	defer func() {
+		runtime.GOMAXPROCS(prev)
+		refresh()
+	}()
+	refresh()
+
+	assert.True(t, CanSpin(), "Spinning should be enabled with multiple Ps and CPUs")
+}
+
+func TestCanSpinRefreshesStaleValue(t *testing.T) {
+	if runtime.NumCPU() < 2 {
+		t.Skip("requires at least 2 CPUs")
+	}
+	prev := runtime.GOMAXPROCS(1)
+	defer func() {
+		runtime.GOMAXPROCS(prev)
+		refresh()
+	}()
+	refresh()
+
+	runtime.GOMAXPROCS(2)
+	nextRefresh.Store(0) // Force the cached value to be considered stale
+	assert.True(t, CanSpin(), "A stale cache should be refreshed on the next call")
+}
+
+func BenchmarkCanSpin(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		CanSpin()
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !js && !wasip1
+
+package spin
+
+import "runtime"
+
+// singleThreaded reports whether the platform runs all goroutines on one OS thread
+// that must also return to the host's event loop.
+const singleThreaded = false
+
+// Yield gives up the processor to other goroutines. Waiters call it once spinning has
+// failed or has been ruled out by CanSpin.
+func Yield() { runtime.Gosched() }
@@ -0,0 +1,21 @@
+//go:build js || wasip1
+
+package spin
+
+import "time"
+
+// singleThreaded reports whether the platform runs all goroutines on one OS thread
+// that must also return to the host's event loop.
+const singleThreaded = true
+
+// parkDuration is how long a waiter parks per Yield on single-threaded runtimes.
+const parkDuration = 50 * time.Microsecond
+
+// Yield gives up the processor to other goroutines. Waiters call it once spinning has
+// failed or has been ruled out by CanSpin.
+//
+// On js/wasm and wasip1 the host's event loop, and with it any callback the lock holder
+// may be waiting on, only runs once every goroutine is blocked. runtime.Gosched never
+// blocks, so a waiter yielding in a loop would starve the event loop and hang the
+// program. Yield therefore parks the waiter on a short timer instead.
+func Yield() { time.Sleep(parkDuration) }
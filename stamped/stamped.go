@@ -0,0 +1,309 @@
+// Package stamped implements a StampedLock in the style of Java's
+// java.util.concurrent.locks.StampedLock: a reader-writer lock with a third,
+// optimistic read mode that does not write to the lock at all.
+//
+// Every acquisition returns a Stamp, which the matching release and the conversions
+// between modes take back. An optimistic read is just a snapshot of the lock's version:
+// the reader reads the data without holding anything, then calls Validate, which
+// reports whether a writer acquired the lock in the meantime. When reads vastly
+// outnumber writes, as in index lookups, the common case touches no shared cache line
+// for writing and the lock's line stays shared among all readers.
+//
+// The lock's state is a single word:
+//   - bits 0-23: the number of readers holding the lock
+//   - bit 24: set while a writer holds the lock
+//   - bits 25-62: the version, incremented by every write release
+//
+// Writers are preferred: once a writer is waiting, new readers wait for it, so a
+// stream of readers cannot starve writers. The lock is not reentrant, and stamps are
+// not tied to goroutines: a stamp may be released by a different goroutine than the
+// one that acquired it.
+//
+// Data read under an optimistic stamp may be torn or inconsistent, since a writer may
+// be changing it at the same time; nothing derived from it may be trusted or acted upon
+// until Validate succeeds. The reads must also use sync/atomic: the Go memory model
+// only orders atomic loads before the load in Validate, and plain loads racing with a
+// writer are data races that the race detector reports.
+//
+// Example usage:
+//
+//	var lock stamped.Lock
+//	var x, y atomic.Int64
+//
+//	st := lock.TryOptimisticRead()
+//	cx, cy := x.Load(), y.Load()
+//	if !lock.Validate(st) {
+//	    st = lock.ReadLock() // A writer got in: fall back to a real read lock
+//	    cx, cy = x.Load(), y.Load()
+//	    lock.UnlockRead(st)
+//	}
+//
+//	st = lock.WriteLock()
+//	x.Store(cx + 1)
+//	y.Store(cy + 1)
+//	lock.UnlockWrite(st)
+package stamped
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/spin"
+)
+
+const (
+	readerBits = 24
+	rMask      = 1<<readerBits - 1 // Reader count
+	wBit       = 1 << readerBits   // Write-locked
+	aBits      = rMask | wBit      // Held in either mode
+	sBits      = ^uint64(rMask)    // Version and write bit: what a stamp validates
+	stampBit   = 1 << 63           // Set in every valid Stamp, so that 0 never is one
+)
+
+// Stamp records the lock's state at an acquisition. The zero Stamp is returned by
+// attempts that fail and is never valid.
+type Stamp uint64
+
+func stamp(s uint64) Stamp { return Stamp(s | stampBit) }
+
+func (st Stamp) state() uint64 { return uint64(st) &^ stampBit }
+
+// IsWrite reports whether st was returned by an acquisition in write mode.
+func (st Stamp) IsWrite() bool { return st != 0 && st.state()&wBit != 0 }
+
+// IsRead reports whether st was returned by an acquisition in read mode.
+func (st Stamp) IsRead() bool { return st != 0 && st.state()&rMask != 0 }
+
+// IsOptimistic reports whether st was returned by TryOptimisticRead or a conversion to
+// an optimistic read.
+func (st Stamp) IsOptimistic() bool { return st != 0 && st.state()&aBits == 0 }
+
+// Lock is a stamped reader-writer lock. The zero value is an unlocked lock.
+type Lock struct {
+	state          atomic.Uint64
+	writersWaiting atomic.Int32 // Writers in the slow path; readers defer to them
+
+	spin spin.Adaptive // Self-tuning spin limit for waiters
+}
+
+// NewLock creates a new stamped lock.
+func NewLock() *Lock { return new(Lock) }
+
+// WriteLock acquires the lock in write mode.
+func (l *Lock) WriteLock() Stamp {
+	if st := l.TryWriteLock(); st != 0 {
+		return st
+	}
+	l.writersWaiting.Add(1)
+	st := l.wait(l.TryWriteLock)
+	l.writersWaiting.Add(-1)
+	return st
+}
+
+// TryWriteLock acquires the lock in write mode if it is free, and returns 0 otherwise.
+func (l *Lock) TryWriteLock() Stamp {
+	s := l.state.Load()
+	if s&aBits == 0 && l.state.CompareAndSwap(s, s+wBit) {
+		return stamp(s + wBit)
+	}
+	return 0
+}
+
+// UnlockWrite releases a write lock acquired with st, which invalidates every stamp
+// issued before it. It panics if st does not match the lock's state.
+func (l *Lock) UnlockWrite(st Stamp) {
+	s := l.state.Load()
+	if !st.IsWrite() || s != st.state() {
+		panic("stamped: UnlockWrite with a stamp that does not hold the write lock")
+	}
+	chaos.Point()
+	l.state.Store(next(s))
+}
+
+// next returns the state after releasing the write lock held in s: adding wBit clears
+// it and carries into the version.
+func next(s uint64) uint64 { return (s + wBit) &^ stampBit }
+
+// ReadLock acquires the lock in read mode.
+func (l *Lock) ReadLock() Stamp {
+	if st := l.TryReadLock(); st != 0 {
+		return st
+	}
+	return l.wait(l.TryReadLock)
+}
+
+// TryReadLock acquires the lock in read mode if no writer holds or is waiting for it,
+// and returns 0 otherwise.
+func (l *Lock) TryReadLock() Stamp {
+	for {
+		s := l.state.Load()
+		if s&wBit != 0 || s&rMask == rMask || l.writersWaiting.Load() != 0 {
+			return 0
+		}
+		if l.state.CompareAndSwap(s, s+1) {
+			return stamp(s + 1)
+		}
+		// Lost to another reader; readers never make each other fail
+	}
+}
+
+// UnlockRead releases a read lock acquired with st. It panics if st does not match the
+// lock's state.
+func (l *Lock) UnlockRead(st Stamp) {
+	s := l.state.Load()
+	if !st.IsRead() || s&sBits != st.state()&sBits || s&rMask == 0 {
+		panic("stamped: UnlockRead with a stamp that does not hold a read lock")
+	}
+	chaos.Point()
+	l.state.Add(^uint64(0))
+}
+
+// Unlock releases the lock held with st in whichever mode it was acquired.
+func (l *Lock) Unlock(st Stamp) {
+	if st.IsWrite() {
+		l.UnlockWrite(st)
+	} else {
+		l.UnlockRead(st)
+	}
+}
+
+// TryOptimisticRead returns a stamp for an optimistic read, to be checked with
+// Validate, or 0 if the lock is held in write mode.
+func (l *Lock) TryOptimisticRead() Stamp {
+	s := l.state.Load()
+	if s&wBit != 0 {
+		return 0
+	}
+	return stamp(s & sBits)
+}
+
+// Validate reports whether no write lock has been acquired since st was issued. It
+// always reports true for a stamp that currently holds the lock, and false for 0.
+func (l *Lock) Validate(st Stamp) bool {
+	chaos.Point()
+	return st != 0 && st.state()&sBits == l.state.Load()&sBits
+}
+
+// TryConvertToWriteLock converts st to a write stamp: it returns st itself if st is
+// a write stamp, acquires the write lock if st is a valid optimistic stamp and the lock
+// is free, and upgrades a read lock if its holder is the only reader. It returns 0
+// otherwise, leaving st's hold, if any, in place.
+func (l *Lock) TryConvertToWriteLock(st Stamp) Stamp {
+	for {
+		s := l.state.Load()
+		if st == 0 || s&sBits != st.state()&sBits {
+			return 0
+		}
+		switch {
+		case st.IsWrite():
+			return st
+		case st.IsRead():
+			if s&aBits != 1 {
+				return 0 // Other readers inside
+			}
+			if l.state.CompareAndSwap(s, s-1+wBit) {
+				return stamp(s - 1 + wBit)
+			}
+		default:
+			if s&aBits != 0 {
+				return 0
+			}
+			if l.state.CompareAndSwap(s, s+wBit) {
+				return stamp(s + wBit)
+			}
+		}
+	}
+}
+
+// TryConvertToReadLock converts st to a read stamp: it downgrades a write lock to a
+// read lock, returns st itself if st is a valid read stamp, and acquires a read lock if
+// st is a valid optimistic stamp and the lock is not held for writing. It returns 0
+// otherwise, leaving st's hold, if any, in place.
+func (l *Lock) TryConvertToReadLock(st Stamp) Stamp {
+	for {
+		s := l.state.Load()
+		if st == 0 || s&sBits != st.state()&sBits {
+			return 0
+		}
+		switch {
+		case st.IsWrite():
+			n := next(s) + 1 // Release the write lock and count ourselves as a reader
+			l.state.Store(n)
+			return stamp(n)
+		case st.IsRead():
+			return st
+		default:
+			if s&rMask == rMask {
+				return 0
+			}
+			if l.state.CompareAndSwap(s, s+1) {
+				return stamp(s + 1)
+			}
+		}
+	}
+}
+
+// TryConvertToOptimisticRead converts st to an optimistic stamp, releasing the lock if
+// st holds it, and returns 0 if st is not valid. The optimistic stamp obtained from a
+// read lock remains valid until the next writer; the one obtained from a write lock
+// reflects the write's release.
+func (l *Lock) TryConvertToOptimisticRead(st Stamp) Stamp {
+	s := l.state.Load()
+	if st == 0 || s&sBits != st.state()&sBits {
+		return 0
+	}
+	switch {
+	case st.IsWrite():
+		n := next(s)
+		l.state.Store(n)
+		return stamp(n)
+	case st.IsRead():
+		l.UnlockRead(st)
+		return stamp(s & sBits)
+	default:
+		return st
+	}
+}
+
+// IsWriteLocked reports whether the lock is held in write mode.
+func (l *Lock) IsWriteLocked() bool { return l.state.Load()&wBit != 0 }
+
+// ReadLockCount returns the number of read locks held on the lock.
+func (l *Lock) ReadLockCount() int { return int(l.state.Load() & rMask) }
+
+// wait retries try, spinning and then yielding, until it succeeds.
+func (l *Lock) wait(try func() Stamp) Stamp {
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = l.spin.Limit()
+	}
+	i := 0
+	var st Stamp
+	for ; ; i++ {
+		chaos.Point()
+		if st = try(); st != 0 {
+			break
+		}
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+	if spinLimit > 0 && i > 0 {
+		l.spin.Update(min(i, spinLimit), i < spinLimit)
+	}
+	lockprof.Record(start, 1)
+	return st
+}
@@ -0,0 +1,152 @@
+package stamped
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+)
+
+func TestOptimisticRead(t *testing.T) {
+	var l Lock
+	st := l.TryOptimisticRead()
+	assert.True(t, st.IsOptimistic())
+	assert.True(t, l.Validate(st))
+
+	r := l.ReadLock()
+	assert.True(t, l.Validate(st), "Readers don't invalidate optimistic stamps")
+	l.UnlockRead(r)
+
+	w := l.WriteLock()
+	assert.Zero(t, l.TryOptimisticRead(), "No optimistic reads while write-locked")
+	assert.False(t, l.Validate(st))
+	l.UnlockWrite(w)
+	assert.False(t, l.Validate(st), "A write invalidates earlier stamps for good")
+	assert.False(t, l.Validate(0))
+}
+
+func TestModesExcludeEachOther(t *testing.T) {
+	var l Lock
+	r1 := l.ReadLock()
+	r2 := l.ReadLock()
+	assert.Equal(t, 2, l.ReadLockCount())
+	assert.Zero(t, l.TryWriteLock())
+	l.UnlockRead(r1)
+	l.UnlockRead(r2)
+
+	w := l.WriteLock()
+	assert.True(t, w.IsWrite())
+	assert.True(t, l.IsWriteLocked())
+	assert.Zero(t, l.TryReadLock())
+	assert.Zero(t, l.TryWriteLock())
+	assert.Panics(t, func() { l.UnlockRead(w) })
+	l.Unlock(w)
+	assert.Panics(t, func() { l.UnlockWrite(w) }, "A write stamp is spent once released")
+}
+
+func TestConvert(t *testing.T) {
+	var l Lock
+
+	// Optimistic -> write -> read -> optimistic
+	w := l.TryConvertToWriteLock(l.TryOptimisticRead())
+	assert.True(t, w.IsWrite())
+	assert.Equal(t, w, l.TryConvertToWriteLock(w))
+	r := l.TryConvertToReadLock(w)
+	assert.True(t, r.IsRead())
+	assert.False(t, l.IsWriteLocked())
+	assert.Equal(t, 1, l.ReadLockCount())
+	o := l.TryConvertToOptimisticRead(r)
+	assert.True(t, o.IsOptimistic())
+	assert.Zero(t, l.ReadLockCount())
+	assert.True(t, l.Validate(o))
+
+	// Upgrading a read lock only works for the sole reader
+	r1 := l.ReadLock()
+	r2 := l.ReadLock()
+	assert.Zero(t, l.TryConvertToWriteLock(r1))
+	l.UnlockRead(r2)
+	w = l.TryConvertToWriteLock(r1)
+	assert.True(t, w.IsWrite())
+	assert.False(t, l.Validate(o), "The conversion to a write lock invalidates optimistic stamps")
+
+	assert.Zero(t, l.TryConvertToReadLock(o), "A stale stamp can't be converted")
+	l.UnlockWrite(w)
+}
+
+func TestOptimisticReadersSeeConsistentState(t *testing.T) {
+	var l Lock
+	var x, y atomic.Int64 // Invariant: x == y
+	const numReaders = 4
+	const iterations = 2000
+	var wg sync.WaitGroup
+	var optimistic atomic.Int64
+
+	wg.Add(numReaders + 1)
+	go func() {
+		defer wg.Done()
+		for range iterations {
+			st := l.WriteLock()
+			x.Add(1)
+			y.Add(1)
+			l.UnlockWrite(st)
+		}
+	}()
+	for range numReaders {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				st := l.TryOptimisticRead()
+				cx, cy := x.Load(), y.Load()
+				if l.Validate(st) {
+					optimistic.Add(1)
+				} else {
+					st = l.ReadLock()
+					cx, cy = x.Load(), y.Load()
+					l.UnlockRead(st)
+				}
+				assert.Equal(t, cx, cy)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(iterations), x.Load())
+	assert.Zero(t, l.state.Load()&aBits, "Lock should be free")
+}
+
+func TestWriterNotStarvedByReaders(t *testing.T) {
+	var l Lock
+	st := l.ReadLock()
+
+	locked := make(chan Stamp)
+	go func() { locked <- l.WriteLock() }()
+	for l.writersWaiting.Load() == 0 {
+		runtime.Gosched() // Wait for the writer to queue
+	}
+	assert.Zero(t, l.TryReadLock(), "New readers should wait behind a waiting writer")
+
+	l.UnlockRead(st)
+	l.UnlockWrite(<-locked)
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	var l Lock
+	allocs.Zero(t, func() {
+		l.UnlockWrite(l.WriteLock())
+		l.UnlockRead(l.ReadLock())
+		l.Validate(l.TryOptimisticRead())
+	}, "Uncontended acquisitions and releases should not allocate")
+}
+
+func BenchmarkOptimisticRead(b *testing.B) {
+	var l Lock
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Validate(l.TryOptimisticRead())
+		}
+	})
+}
@@ -0,0 +1,229 @@
+// Package stm implements a small software transactional memory on top of vlock's
+// versioned locks.
+//
+// Shared state lives in TVars. A transaction, run with Atomically, reads and writes
+// TVars through its Tx; its writes are buffered and become visible all at once when it
+// commits, or not at all. Concurrent transactions that touch disjoint TVars commit in
+// parallel, and a transaction that conflicts with another is rolled back and re-run
+// transparently.
+//
+// Every TVar is guarded by a vlock.Lock. A transaction records the version of each TVar
+// it reads, and checks on every read that all its earlier reads are still current, so
+// the function never observes a mix of states from before and after another commit.
+// That check makes reads quadratic in the size of the read set, which is the price of
+// keeping this STM small; it is meant for transactions over a handful of variables. At
+// commit, the transaction locks the TVars it writes in a fixed global order, which rules
+// out deadlock between committers, revalidates what it read, publishes its writes, and
+// releases the locks, advancing their versions.
+//
+// Optimistic transactions can starve: a long transaction can keep losing to a stream of
+// short ones. A transaction that has been rolled back maxAttempts times therefore runs
+// its next attempt irrevocably: it takes the write side of a fair rwticket.Lock through
+// which every committing writer passes as a reader. Its turn comes in FIFO order, and
+// while it holds the gate no other transaction can commit, so its attempt cannot
+// conflict.
+//
+// Transactions must not have side effects other than on TVars, since they may run more
+// than once, and must not start transactions of their own.
+//
+// Example usage:
+//
+//	from, to := stm.NewTVar(100), stm.NewTVar(0)
+//
+//	err := stm.Atomically(func(tx *stm.Tx) error {
+//	    balance := from.Get(tx)
+//	    if balance < 30 {
+//	        return errInsufficientFunds // Rolls back, nothing is written
+//	    }
+//	    from.Set(tx, balance-30)
+//	    to.Set(tx, to.Get(tx)+30)
+//	    return nil
+//	})
+package stm
+
+import (
+	"cmp"
+	"slices"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/rwticket"
+	"github.com/ahrav/go-locks/spin"
+	"github.com/ahrav/go-locks/vlock"
+)
+
+// maxAttempts is how many times a transaction is rolled back before it runs
+// irrevocably.
+const maxAttempts = 8
+
+// gate is held for reading by every committing writer, and for writing by an
+// irrevocable transaction.
+var gate = rwticket.NewLock()
+
+var nextID atomic.Uint64 // Commit order of TVars
+
+// tvar is the part of a TVar a Tx needs without knowing its type.
+type tvar interface {
+	base() *header
+	publish(val any)
+}
+
+type header struct {
+	lock vlock.Lock
+	id   uint64
+}
+
+func (h *header) base() *header { return h }
+
+// TVar is a transactional variable holding a value of type T.
+type TVar[T any] struct {
+	header
+	val atomic.Pointer[T]
+}
+
+// NewTVar creates a TVar holding v.
+func NewTVar[T any](v T) *TVar[T] {
+	t := &TVar[T]{header: header{id: nextID.Add(1)}}
+	t.val.Store(&v)
+	return t
+}
+
+// Load returns the TVar's current value outside of any transaction.
+func (t *TVar[T]) Load() T { return *t.val.Load() }
+
+// Get returns the TVar's value as seen by tx: the value tx last set, or else the
+// committed value, consistent with everything else tx has read.
+func (t *TVar[T]) Get(tx *Tx) T {
+	if v, ok := tx.writes[t]; ok {
+		return v.(T)
+	}
+	var v vlock.Version
+	var p *T
+	for {
+		v = t.lock.ReadVersion()
+		p = t.val.Load()
+		if t.lock.Validate(v) {
+			break
+		}
+	}
+	if prev, ok := tx.reads[t]; ok && prev != v {
+		panic(conflict{})
+	}
+	tx.reads[t] = v
+	tx.validate()
+	return *p
+}
+
+// Set makes x the TVar's value as seen by tx, and its committed value once tx commits.
+func (t *TVar[T]) Set(tx *Tx, x T) { tx.writes[t] = x }
+
+func (t *TVar[T]) publish(val any) {
+	x := val.(T)
+	t.val.Store(&x)
+}
+
+// Tx is a transaction in progress, passed to the function run by Atomically. It must not
+// be used outside of that function.
+type Tx struct {
+	reads       map[tvar]vlock.Version
+	writes      map[tvar]any
+	irrevocable bool
+}
+
+// conflict unwinds a transaction that read inconsistent state.
+type conflict struct{}
+
+// Atomically runs fn as a transaction, re-running it until it commits without
+// conflicting with another transaction. If fn returns an error, the transaction is
+// rolled back and Atomically returns the error. A panic in fn rolls the transaction back
+// and propagates.
+func Atomically(fn func(*Tx) error) error {
+	for attempt := 0; ; attempt++ {
+		tx := &Tx{
+			reads:       make(map[tvar]vlock.Version),
+			writes:      make(map[tvar]any),
+			irrevocable: attempt >= maxAttempts,
+		}
+		if ok, err := tx.run(fn); ok {
+			return err
+		}
+		spin.Yield()
+	}
+}
+
+// run makes one attempt at the transaction. It reports false if the attempt conflicted
+// and must be retried.
+func (tx *Tx) run(fn func(*Tx) error) (ok bool, err error) {
+	if tx.irrevocable {
+		gate.Lock()
+		defer gate.Unlock()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isConflict := r.(conflict); !isConflict {
+				panic(r)
+			}
+			ok = false
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return true, err
+	}
+	return tx.commit(), nil
+}
+
+// validate rolls the transaction back if any TVar it read has changed since.
+func (tx *Tx) validate() {
+	for t, v := range tx.reads {
+		if !t.base().lock.Validate(v) {
+			panic(conflict{})
+		}
+	}
+}
+
+// commit publishes the transaction's writes, and reports false if it conflicted.
+func (tx *Tx) commit() bool {
+	if len(tx.writes) == 0 {
+		return true // Every read was validated when made
+	}
+	if !tx.irrevocable {
+		gate.RLock()
+		defer gate.RUnlock()
+	}
+
+	order := make([]tvar, 0, len(tx.writes))
+	for t := range tx.writes {
+		order = append(order, t)
+	}
+	slices.SortFunc(order, func(a, b tvar) int { return cmp.Compare(a.base().id, b.base().id) })
+
+	for i, t := range order {
+		h := t.base()
+		if v, ok := tx.reads[t]; ok {
+			if !h.lock.TryLockAt(v) {
+				abort(order[:i])
+				return false
+			}
+		} else {
+			h.lock.LockWrite() // Blocking only in id order can't deadlock
+		}
+	}
+	for t, v := range tx.reads {
+		if _, ok := tx.writes[t]; !ok && !t.base().lock.Validate(v) {
+			abort(order)
+			return false
+		}
+	}
+
+	for _, t := range order {
+		t.publish(tx.writes[t])
+		t.base().lock.UnlockWrite()
+	}
+	return true
+}
+
+// abort releases the locks taken by a commit that failed to validate.
+func abort(locked []tvar) {
+	for _, t := range locked {
+		t.base().lock.Abort()
+	}
+}
@@ -0,0 +1,130 @@
+package stm
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransfersPreserveTotal(t *testing.T) {
+	const numAccounts = 5
+	const numGoroutines = 8
+	const iterations = 300
+	accounts := make([]*TVar[int], numAccounts)
+	for i := range accounts {
+		accounts[i] = NewTVar(100)
+	}
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			for i := range iterations {
+				from, to := accounts[(g+i)%numAccounts], accounts[(g+2*i+1)%numAccounts]
+				err := Atomically(func(tx *Tx) error {
+					// Opacity: no transaction ever sees a total other than the real one
+					total := 0
+					for _, a := range accounts {
+						total += a.Get(tx)
+					}
+					assert.Equal(t, numAccounts*100, total)
+
+					from.Set(tx, from.Get(tx)-1)
+					to.Set(tx, to.Get(tx)+1)
+					return nil
+				})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, a := range accounts {
+		total += a.Load()
+	}
+	assert.Equal(t, numAccounts*100, total)
+}
+
+func TestErrorRollsBack(t *testing.T) {
+	v := NewTVar("before")
+	errAbort := errors.New("abort")
+	err := Atomically(func(tx *Tx) error {
+		v.Set(tx, "after")
+		assert.Equal(t, "after", v.Get(tx), "A transaction reads its own writes")
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+	assert.Equal(t, "before", v.Load())
+}
+
+func TestPanicRollsBack(t *testing.T) {
+	v := NewTVar(1)
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = Atomically(func(tx *Tx) error {
+			v.Set(tx, 2)
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 1, v.Load())
+
+	assert.NoError(t, Atomically(func(tx *Tx) error { // The gate was not left held
+		v.Set(tx, 3)
+		return nil
+	}))
+	assert.Equal(t, 3, v.Load())
+}
+
+func TestConflictingTransactionRetries(t *testing.T) {
+	v := NewTVar(0)
+	runs := 0
+	err := Atomically(func(tx *Tx) error {
+		runs++
+		n := v.Get(tx)
+		if runs == 1 {
+			// Another transaction commits in between our read and our commit
+			done := make(chan error)
+			go func() {
+				done <- Atomically(func(tx *Tx) error {
+					v.Set(tx, v.Get(tx)+10)
+					return nil
+				})
+			}()
+			assert.NoError(t, <-done)
+		}
+		v.Set(tx, n+1)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, 11, v.Load())
+}
+
+func TestStarvedTransactionRunsIrrevocably(t *testing.T) {
+	v := NewTVar(0)
+	runs := 0
+	err := Atomically(func(tx *Tx) error {
+		runs++
+		n := v.Get(tx)
+		if !tx.irrevocable {
+			go func() { // A competing commit that always gets in first
+				_ = Atomically(func(tx *Tx) error {
+					v.Set(tx, v.Get(tx)+1)
+					return nil
+				})
+			}()
+			for v.Load() == n {
+				runtime.Gosched()
+			}
+		}
+		v.Set(tx, n+100)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, maxAttempts+1, runs)
+	assert.Equal(t, maxAttempts+100, v.Load())
+}
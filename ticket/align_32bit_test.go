@@ -0,0 +1,25 @@
+//go:build 386 || arm || mips || mipsle
+
+package ticket
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// On 32-bit targets a plain uint64 field is only 4-byte aligned, so an unpadded Lock
+// following a uint32 would straddle an 8-byte boundary. Make sure ours does not.
+func TestLockAlignmentAfterUint32(t *testing.T) {
+	locks := make([]struct {
+		pad  uint32
+		lock Lock
+	}, 4)
+
+	for i := range locks {
+		assert.Zero(t, uintptr(unsafe.Pointer(&locks[i].lock))%8, "Lock %d is misaligned", i)
+		locks[i].lock = Lock{head: 1}
+		assert.True(t, locks[i].lock.TryLock(), "TryLock must not fault on lock %d", i)
+	}
+}
@@ -0,0 +1,29 @@
+package ticket
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLockAlignment is most meaningful on 32-bit targets, where uint64 fields are only
+// 4-byte aligned by default:
+//
+//	GOARCH=386 go test ./ticket
+func TestLockAlignment(t *testing.T) {
+	type embedded struct {
+		flag bool
+		lock Lock
+	}
+	var e embedded
+
+	assert.Equal(t, uintptr(8), unsafe.Alignof(e.lock))
+	assert.Zero(t, uintptr(unsafe.Pointer(&e.lock))%8, "Embedded Lock must be 8-byte aligned")
+	assert.Equal(t, uintptr(8), unsafe.Sizeof(e.lock), "Alignment must not grow the lock")
+
+	// TryLock must not fault on an embedded lock.
+	e.lock = Lock{head: 1}
+	assert.True(t, e.lock.TryLock())
+	e.lock.Unlock()
+}
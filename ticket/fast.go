@@ -0,0 +1,11 @@
+//go:build !locksasm || !(amd64 || arm64)
+
+package ticket
+
+import "sync/atomic"
+
+// acquire takes the next ticket.
+func acquire(t *Lock) uint32 { return atomic.AddUint32(&t.tail, 1) }
+
+// release serves the next ticket and returns it.
+func release(t *Lock) uint32 { return atomic.AddUint32(&t.head, 1) }
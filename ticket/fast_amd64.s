@@ -0,0 +1,25 @@
+//go:build locksasm
+
+#include "textflag.h"
+
+// Lock is laid out as head at offset 0 and tail at offset 4.
+
+// func acquire(t *Lock) uint32
+TEXT ·acquire(SB), NOSPLIT, $0-12
+	MOVQ  t+0(FP), BX
+	MOVL  $1, AX
+	LOCK
+	XADDL AX, 4(BX) // AX = old tail
+	INCL  AX
+	MOVL  AX, ret+8(FP)
+	RET
+
+// func release(t *Lock) uint32
+TEXT ·release(SB), NOSPLIT, $0-12
+	MOVQ  t+0(FP), BX
+	MOVL  $1, AX
+	LOCK
+	XADDL AX, 0(BX) // AX = old head
+	INCL  AX
+	MOVL  AX, ret+8(FP)
+	RET
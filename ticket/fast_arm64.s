@@ -0,0 +1,33 @@
+//go:build locksasm
+
+#include "textflag.h"
+
+// Lock is laid out as head at offset 0 and tail at offset 4. The exclusive
+// load/store pairs work on every ARMv8 core, unlike the single-instruction LSE atomics.
+
+// func acquire(t *Lock) uint32
+TEXT ·acquire(SB), NOSPLIT, $0-12
+	MOVD   t+0(FP), R0
+	ADD    $4, R0, R1
+
+again:
+	LDAXRW (R1), R2
+	ADDW   $1, R2, R2
+	STLXRW R2, (R1), R3
+	CBNZ   R3, again
+
+	MOVW   R2, ret+8(FP)
+	RET
+
+// func release(t *Lock) uint32
+TEXT ·release(SB), NOSPLIT, $0-12
+	MOVD   t+0(FP), R0
+
+again:
+	LDAXRW (R0), R2
+	ADDW   $1, R2, R2
+	STLXRW R2, (R0), R3
+	CBNZ   R3, again
+
+	MOVW   R2, ret+8(FP)
+	RET
@@ -0,0 +1,22 @@
+//go:build locksasm && (amd64 || arm64)
+
+package ticket
+
+// The locksasm build tag replaces the acquire and release fast paths with a hand-written
+// fetch-and-add: LOCK XADD on amd64, and an exclusive load/store loop on arm64. The
+// atomic intrinsics the portable versions use already compile to the same instructions
+// (or to LSE atomics on arm64 cores that have them) and, unlike assembly, can be
+// inlined, so the tag makes Lock and Unlock calls rather than inlined code. On amd64,
+// BenchmarkTicketLockUncontended measured about 15ns per Lock/Unlock pair without the
+// tag and 18ns with it, against 18ns for sync.Mutex. Keep the tag for comparing code
+// generation, not for speed.
+
+// acquire takes the next ticket.
+//
+//go:noescape
+func acquire(t *Lock) uint32
+
+// release serves the next ticket and returns it.
+//
+//go:noescape
+func release(t *Lock) uint32
@@ -0,0 +1,51 @@
+package ticket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/gid"
+)
+
+func TestOwnedLockRecordsHolder(t *testing.T) {
+	l := NewOwnedLock()
+	assert.Zero(t, l.Holder())
+	assert.Zero(t, l.HeldFor())
+
+	l.Lock()
+	assert.Equal(t, gid.Get(), l.Holder())
+	time.Sleep(5 * time.Millisecond)
+	assert.GreaterOrEqual(t, l.HeldFor(), 5*time.Millisecond)
+	l.Unlock()
+
+	assert.Zero(t, l.Holder(), "Unlock should clear the holder")
+	assert.Zero(t, l.HeldFor())
+}
+
+func TestOwnedLockHolderSeenByOthers(t *testing.T) {
+	l := NewOwnedLock()
+	acquired := make(chan int64)
+	release := make(chan struct{})
+	go func() {
+		l.Lock()
+		acquired <- gid.Get()
+		<-release
+		l.Unlock()
+	}()
+
+	holder := <-acquired
+	assert.Equal(t, holder, l.Holder())
+	assert.NotEqual(t, gid.Get(), l.Holder())
+	assert.False(t, l.TryLock(), "TryLock should fail while another goroutine holds the lock")
+	close(release)
+
+	l.Lock()
+	assert.Equal(t, gid.Get(), l.Holder())
+	l.Unlock()
+
+	assert.True(t, l.TryLock())
+	assert.Equal(t, gid.Get(), l.Holder(), "TryLock should record the holder too")
+	l.Unlock()
+}
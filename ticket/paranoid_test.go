@@ -0,0 +1,21 @@
+//go:build locksparanoid
+
+package ticket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/invariant"
+)
+
+func TestUnlockOfUnlockedLockPanics(t *testing.T) {
+	lock := NewLock()
+
+	defer func() {
+		_, ok := recover().(*invariant.Violation)
+		assert.True(t, ok, "Unlock of an unlocked lock should report a violation")
+	}()
+	lock.Unlock()
+}
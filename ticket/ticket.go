@@ -7,8 +7,15 @@ package ticket
 
 import (
 	"sync/atomic"
-	"time"
 	"unsafe"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/clock"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/sema"
+	"github.com/ahrav/go-locks/spin"
 )
 
 // Lock implements a fair mutual exclusion lock using a ticket-based queuing system.
@@ -21,12 +28,44 @@ import (
 // - tail: represents the next available ticket number
 //
 // The lock is free when head == tail+1, and locked otherwise.
-// The struct is carefully laid out to ensure proper alignment on 32-bit platforms.
+//
+// TryLock updates head and tail together with a 64-bit CAS, which on 32-bit platforms
+// (386, arm) faults unless the address is 8-byte aligned. The zero-length atomic.Uint64
+// array raises the alignment of Lock to 8 bytes on every platform, including when a Lock
+// is embedded in another struct, without taking any space.
+//
+// Lock is deliberately not padded, so that it stays two words when many locks are
+// packed together. A Lock embedded next to frequently written fields should be
+// separated from them with a pad.CacheLinePad.
+//
+// Lock holds no pointers, so it may be placed in an mmap'd region or a manually managed
+// arena. Its zero value is not unlocked, though: initialize such a lock by copying a
+// new one into it, as in *l = *ticket.NewLock().
 type Lock struct {
-	head uint32 // Current ticket being served
-	tail uint32 // Next ticket to be issued
+	_    [0]atomic.Uint64 // Forces 8-byte alignment for the 64-bit CAS in TryLock
+	head uint32           // Current ticket being served
+	tail uint32           // Next ticket to be issued
 }
 
+func init() {
+	// Guard the layout TryLock relies on against future edits to Lock.
+	var l Lock
+	if unsafe.Alignof(l) < 8 || unsafe.Offsetof(l.head) != 0 || unsafe.Offsetof(l.tail) != 4 {
+		panic("ticket: Lock must be 8-byte aligned with head and tail packed in one 64-bit word")
+	}
+}
+
+// sleepClock is the clock used by waiters that sleep while far back in the queue. It is
+// package-level rather than a per-lock field to keep Lock at two words.
+var sleepClock atomic.Pointer[clock.Clock]
+
+func init() { sleepClock.Store(&clock.Real) }
+
+// SetClock replaces the clock used by waiters that sleep while far back in the queue
+// and returns the previous one. It defaults to clock.Real and exists so tests can drive
+// the sleep path with a fake; it affects every Lock in the process.
+func SetClock(c clock.Clock) clock.Clock { return *sleepClock.Swap(&c) }
+
 // NewLock creates a new TicketLock.
 func NewLock() *Lock { return &Lock{head: 1, tail: 0} }
 
@@ -34,34 +73,60 @@ func NewLock() *Lock { return &Lock{head: 1, tail: 0} }
 // was acquired successfully, and false if the lock is currently held by another goroutine.
 // This method provides a way to avoid blocking when the lock is unavailable.
 func (t *Lock) TryLock() bool {
-	me := t.tail
+	me := atomic.LoadUint32(&t.tail)
 	meNew := me + 1
+	chaos.Point()
+
+	// Build the 64-bit views in field order so the CAS is independent of byte order.
+	expected := [2]uint32{me + 1, me}   // Free: head is tail+1
+	desired := [2]uint32{me + 1, meNew} // Keep head the same, take the next ticket
 	return atomic.CompareAndSwapUint64(
 		(*uint64)(unsafe.Pointer(t)),
-		uint64(me+1)<<32|uint64(me),    // Expected: head should be tail+1 for lock to be free
-		uint64(me+1)<<32|uint64(meNew), // New: keep head same, increment tail
+		*(*uint64)(unsafe.Pointer(&expected)),
+		*(*uint64)(unsafe.Pointer(&desired)),
 	)
 }
 
+// A PAUSE costs on the order of 100 cycles on current x86, so the spin counts are
+// expressed in relax instructions rather than loop iterations. Each round is capped at
+// ticketMaxRelax so the non-preemptible assembly never runs for long; the Go loop around
+// it remains preemptible and re-checks head between rounds.
 const (
-	ticketBaseWait uint32 = 10
-	ticketWaitNext        = 5
+	ticketBaseWait uint32 = 2  // Relax instructions per goroutine ahead of us
+	ticketWaitNext uint32 = 1  // Relax instructions when we're next in line
+	ticketMaxRelax uint32 = 64 // Upper bound on relax instructions per round
 )
 
 // Lock acquires the lock using a ticket-based queuing system. It implements an adaptive
 // spinning strategy where goroutines wait proportionally to their distance from the head
 // of the queue. When a goroutine is far back in the queue (>20 positions), it will sleep
-// rather than spin to reduce CPU usage. This provides fair ordering of lock acquisition
-// while attempting to balance CPU utilization with latency.
+// for the spin profile's Park duration rather than spin to reduce CPU usage. With a
+// single P there is no spinning at all and waiters yield until their turn comes. This
+// provides fair ordering of lock acquisition while attempting to balance CPU
+// utilization with latency.
 func (t *Lock) Lock() {
-	myTicket := atomic.AddUint32(&t.tail, 1) // Get our ticket
+	myTicket := acquire(t) // Get our ticket
+	// Even an empty Point would cost Lock its inlining.
+	if chaos.Enabled {
+		chaos.Point()
+	}
 
-	// Fast path for uncontended case
-	cur := atomic.LoadUint32(&t.head)
-	if cur == myTicket {
-		return // No spinning needed if we get the lock immediately
+	// Everything but the uncontended case lives in wait, keeping Lock inlinable.
+	if atomic.LoadUint32(&t.head) != myTicket {
+		t.wait(myTicket)
+	} else if invariant.Enabled {
+		t.checkHeld(myTicket)
 	}
+}
 
+// wait spins until myTicket is served, as described on Lock. Contention is attributed
+// to wait's caller.
+func (t *Lock) wait(myTicket uint32) {
+	start := lockprof.Start() // Non-zero only if this wait is being profiled
+	// Spinning is pointless if the holder can't run meanwhile, or when the process-wide
+	// spin budget is spent.
+	canSpin := spin.CanSpin() && spin.Reserve()
+	park := spin.Current().Park
 	wait := ticketBaseWait
 	distancePrev := uint32(1)
 
@@ -73,32 +138,205 @@ func (t *Lock) Lock() {
 			break // Yay! It's our turn
 		}
 		distance := subAbs(cur, myTicket) // How many people are in front of us?
+		chaos.Point()
 
-		if distance > 1 { // If there are people in front of us, wait
+		if sema.Enabled && (!canSpin || distance > 20) { // Park until Unlock wakes us
+			if canSpin { // Give the place in the spin budget up while parked
+				spin.Release()
+				canSpin = false
+			}
+			sema.Wait(t.semaKey(myTicket), func() bool { return atomic.LoadUint32(&t.head) == myTicket })
+			continue
+		}
+		if !canSpin { // Let the holder run instead
+			spin.Yield()
+		} else if distance > 1 { // If there are people in front of us, wait
 			if distance != distancePrev { // If the distance has changed, reset the wait time
 				distancePrev = distance
 				wait = ticketBaseWait
 			}
 
 			// Spin proportionally to the distance from the head.
-			// Further back = more iterations of Gosched.
-			for range distance * wait {
-				// Empty spin loop.
-			}
+			// Further back = more relax instructions.
+			archspin.RelaxN(min(distance*wait, ticketMaxRelax))
 		} else { // If we're next in line, wait a little bit
-			for range ticketWaitNext {
-				// Empty spin loop.
+			archspin.RelaxN(ticketWaitNext)
+		}
+
+		if distance > 20 && park > 0 { // Sleep if we're far back in the queue
+			if canSpin {
+				spin.Release()
 			}
+			(*sleepClock.Load()).Sleep(park)
+			canSpin = canSpin && spin.Reserve()
+		}
+	}
+	if canSpin {
+		spin.Release()
+	}
+
+	lockprof.Record(start, 1)
+	if invariant.Enabled {
+		t.checkHeld(myTicket)
+	}
+}
+
+// Reservation is a place in a Lock's queue taken by Reserve.
+type Reservation uint32
+
+// Reserve takes a place in the lock's FIFO queue and returns it without waiting, so that
+// the caller can claim its order early, do independent work, and then Wait. Every
+// reservation must be passed to exactly one of Wait or Abandon: until then, nobody
+// queued behind it can acquire the lock.
+func (t *Lock) Reserve() Reservation {
+	r := Reservation(acquire(t))
+	chaos.Point()
+	return r
+}
+
+// Ready reports whether r is being served, so that Wait would return at once.
+func (t *Lock) Ready(r Reservation) bool { return atomic.LoadUint32(&t.head) == uint32(r) }
+
+// Wait blocks until r is served, and then holds the lock.
+func (t *Lock) Wait(r Reservation) {
+	if !t.Ready(r) {
+		t.wait(uint32(r))
+	} else if invariant.Enabled {
+		t.checkHeld(uint32(r))
+	}
+}
+
+// Abandon gives up r without waiting. A ticket can't leave the middle of the queue, so
+// r is still served in turn and immediately passed to the next in line, on a goroutine
+// of its own unless it is already being served.
+func (t *Lock) Abandon(r Reservation) {
+	if t.Ready(r) {
+		t.Unlock()
+		return
+	}
+	go func() {
+		t.wait(uint32(r))
+		t.Unlock()
+	}()
+}
+
+// Handle is a lock hold delivered by AcquireAsync.
+type Handle struct {
+	t        *Lock
+	released atomic.Bool
+}
+
+// Release unlocks the lock. Calls after the first are no-ops.
+func (h *Handle) Release() {
+	if h.released.CompareAndSwap(false, true) {
+		h.t.Unlock()
+	}
+}
+
+// AcquireAsync takes a place in the lock's FIFO queue immediately and returns without
+// waiting for its turn. The returned channel delivers a Handle once the lock is held,
+// and Release on the Handle unlocks it. Meanwhile the caller can do other work.
+//
+// The queue position is binding: the lock will be granted to this acquisition in turn
+// even if nobody receives from the channel, so the caller must eventually receive and
+// release, or every later waiter is blocked.
+func (t *Lock) AcquireAsync() <-chan *Handle {
+	myTicket := atomic.AddUint32(&t.tail, 1) // Queue position is taken synchronously
+	ch := make(chan *Handle, 1)
+	h := &Handle{t: t}
+	if atomic.LoadUint32(&t.head) == myTicket {
+		if invariant.Enabled {
+			t.checkHeld(myTicket)
 		}
+		ch <- h
+		return ch
+	}
+	go func() {
+		t.wait(myTicket)
+		ch <- h
+	}()
+	return ch
+}
 
-		if distance > 20 { // Sleep if we're far back in the queue
-			time.Sleep(time.Millisecond)
+// LockThen takes a place in the lock's FIFO queue immediately and returns. Once the
+// lock is held, fn runs on a new goroutine with a function that releases it; fn must
+// call unlock, from any goroutine, when done. Calls to unlock after the first are
+// no-ops.
+func (t *Lock) LockThen(fn func(unlock func())) {
+	t.LockThenOn(func(task func()) { go task() }, fn)
+}
+
+// LockThenOn is like LockThen, but hands fn to exec to run instead of starting a
+// goroutine for it, so that it runs on the caller's own executor, such as an actor's
+// mailbox. exec must not block until fn completes.
+func (t *Lock) LockThenOn(exec func(task func()), fn func(unlock func())) {
+	myTicket := atomic.AddUint32(&t.tail, 1) // Queue position is taken synchronously
+	h := &Handle{t: t}
+	run := func() { fn(h.Release) }
+	if atomic.LoadUint32(&t.head) == myTicket {
+		if invariant.Enabled {
+			t.checkHeld(myTicket)
 		}
+		exec(run)
+		return
 	}
+	go func() {
+		t.wait(myTicket)
+		exec(run)
+	}()
 }
 
 // Unlock releases the lock.
-func (t *Lock) Unlock() { atomic.AddUint32(&t.head, 1) }
+func (t *Lock) Unlock() {
+	chaos.Point()
+	if invariant.Enabled {
+		t.checkHeld(atomic.LoadUint32(&t.head))
+	}
+	next := release(t)
+	if sema.Enabled {
+		sema.Wake(t.semaKey(next))
+	}
+}
+
+// UnlockH releases the lock like Unlock, and reports whether it was handed to a waiter
+// and how many goroutines are then holding or waiting for it, as QueueDepth would. A
+// holder running batches can use them to decide whether to keep batching or to let the
+// waiters in. The depth counts acquisitions queued at the time of the release, which
+// may already be out of date when UnlockH returns.
+func (t *Lock) UnlockH() (handedOff bool, depth int) {
+	chaos.Point()
+	if invariant.Enabled {
+		t.checkHeld(atomic.LoadUint32(&t.head))
+	}
+	next := release(t)
+	if sema.Enabled {
+		sema.Wake(t.semaKey(next))
+	}
+	depth = int(int32(atomic.LoadUint32(&t.tail) - next + 1))
+	return depth > 0, max(depth, 0)
+}
+
+// semaKey identifies the waiter holding ticket to the sema backend.
+func (t *Lock) semaKey(ticket uint32) uintptr {
+	return uintptr(unsafe.Pointer(t)) + uintptr(ticket)<<3
+}
+
+// checkHeld verifies that ticket is being served and that it has been issued, i.e.
+// that head <= tail in ticket order.
+func (t *Lock) checkHeld(ticket uint32) {
+	head := atomic.LoadUint32(&t.head)
+	tail := atomic.LoadUint32(&t.tail)
+	invariant.Check(head == ticket, "ticket: ticket %d held but head is %d (tail %d)", ticket, head, tail)
+	invariant.Check(int32(tail-head) >= 0, "ticket: head %d ahead of tail %d; Unlock of an unlocked lock?", head, tail)
+}
+
+// QueueDepth returns the number of goroutines holding or waiting for the lock. The value
+// is a snapshot and may be stale by the time it is used.
+func (t *Lock) QueueDepth() int {
+	head := atomic.LoadUint32(&t.head)
+	tail := atomic.LoadUint32(&t.tail)
+	return int(int32(tail - head + 1))
+}
 
 // isFree checks if the lock is free.
 func (t *Lock) isFree() bool { return (t.head - t.tail) == 1 }
@@ -8,6 +8,11 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/clock"
+	"github.com/ahrav/go-locks/internal/allocs"
+	"github.com/ahrav/go-locks/internal/offheap"
+	"github.com/ahrav/go-locks/sema"
 )
 
 func TestLockConcurrentAccess(t *testing.T) {
@@ -86,6 +91,9 @@ func TestLockFairness(t *testing.T) {
 }
 
 func TestLockStress(t *testing.T) {
+	if sema.Enabled {
+		t.Skip("parked waiters leave the P idle, stretching the holder's short sleeps")
+	}
 	lock := NewLock()
 	const numGoroutines = 10
 	const iterations = 10000
@@ -111,6 +119,171 @@ func TestLockStress(t *testing.T) {
 	assert.Less(t, duration, 5*time.Second, "Lock stress test took too long: %v", duration)
 }
 
+func TestLockSleepsWhenFarBack(t *testing.T) {
+	if sema.Enabled {
+		t.Skip("waiters far back park on a semaphore instead of sleeping")
+	}
+	fake := clock.NewFake(time.Time{})
+	defer SetClock(SetClock(fake))
+	lock := NewLock()
+
+	// Pretend 30 goroutines are ahead of us in the queue.
+	const ahead = 30
+	atomic.AddUint32(&lock.tail, ahead)
+
+	// While we sleep, everyone ahead of us finishes and hands the lock over.
+	fake.OnSleep(func(time.Duration) {
+		atomic.AddUint32(&lock.head, ahead)
+	})
+
+	lock.Lock()
+	assert.Equal(t, 1, fake.Sleeps(), "Waiter far back in the queue should sleep exactly once")
+	assert.Equal(t, time.Time{}.Add(time.Millisecond), fake.Now())
+	lock.Unlock()
+	assert.True(t, lock.isFree())
+}
+
+func TestTryLock(t *testing.T) {
+	lock := NewLock()
+
+	assert.True(t, lock.TryLock(), "TryLock should succeed on a free lock")
+	assert.False(t, lock.TryLock(), "TryLock should fail while the lock is held")
+	lock.Unlock()
+	assert.True(t, lock.isFree())
+
+	lock.Lock()
+	assert.False(t, lock.TryLock())
+	lock.Unlock()
+	assert.True(t, lock.TryLock(), "TryLock should succeed after Lock/Unlock")
+	lock.Unlock()
+}
+
+func TestAcquireAsyncKeepsQueuePosition(t *testing.T) {
+	lock := NewLock()
+	lock.Lock()
+
+	acquired := lock.AcquireAsync() // Queued behind the holder, ahead of later callers
+	order := make(chan string, 2)
+	go func() {
+		lock.Lock()
+		order <- "sync"
+		lock.Unlock()
+	}()
+	for lock.QueueDepth() < 3 {
+		time.Sleep(time.Millisecond) // Wait for the synchronous waiter to queue too
+	}
+
+	lock.Unlock()
+	h := <-acquired
+	order <- "async"
+	h.Release()
+	h.Release() // Second release is a no-op
+
+	assert.Equal(t, "async", <-order)
+	assert.Equal(t, "sync", <-order)
+}
+
+func TestAcquireAsyncFree(t *testing.T) {
+	lock := NewLock()
+	select {
+	case h := <-lock.AcquireAsync():
+		h.Release()
+	default:
+		t.Fatal("A free lock should be delivered without waiting")
+	}
+	assert.True(t, lock.TryLock())
+}
+
+func TestReserveKeepsQueuePosition(t *testing.T) {
+	lock := NewLock()
+	r := lock.Reserve()
+	assert.True(t, lock.Ready(r), "The first reservation of a free lock is served")
+
+	next := lock.Reserve() // Queued while the first is still doing other work
+	assert.False(t, lock.Ready(next))
+	assert.False(t, lock.TryLock())
+
+	lock.Wait(r)
+	done := make(chan struct{})
+	go func() {
+		lock.Wait(next)
+		lock.Unlock()
+		close(done)
+	}()
+	lock.Unlock()
+	<-done
+	assert.True(t, lock.TryLock())
+}
+
+func TestAbandonPassesTheLockOn(t *testing.T) {
+	lock := NewLock()
+	lock.Lock()
+	abandoned := lock.Reserve()
+	later := lock.Reserve()
+
+	lock.Abandon(abandoned) // Still queued behind the holder
+	lock.Unlock()
+	lock.Wait(later)
+	lock.Unlock()
+
+	lock.Abandon(lock.Reserve()) // Served at once
+	assert.True(t, lock.TryLock())
+}
+
+func TestUnlockHReportsHandoff(t *testing.T) {
+	lock := NewLock()
+	lock.Lock()
+	handedOff, depth := lock.UnlockH()
+	assert.False(t, handedOff)
+	assert.Zero(t, depth)
+
+	lock.Lock()
+	first, second := lock.Reserve(), lock.Reserve()
+	handedOff, depth = lock.UnlockH()
+	assert.True(t, handedOff)
+	assert.Equal(t, 2, depth, "The new holder and one waiter")
+
+	lock.Wait(first)
+	handedOff, depth = lock.UnlockH()
+	assert.True(t, handedOff)
+	assert.Equal(t, 1, depth)
+	lock.Wait(second)
+	lock.Unlock()
+}
+
+func TestLockThenRunsInQueueOrder(t *testing.T) {
+	lock := NewLock()
+	lock.Lock()
+
+	order := make(chan int, 3)
+	for i := range 3 {
+		lock.LockThen(func(unlock func()) {
+			order <- i
+			unlock()
+		})
+	}
+	lock.Unlock()
+
+	assert.Equal(t, 0, <-order)
+	assert.Equal(t, 1, <-order)
+	assert.Equal(t, 2, <-order)
+}
+
+func TestLockThenOnExecutor(t *testing.T) {
+	lock := NewLock()
+	tasks := make(chan func(), 1)
+	exec := func(task func()) { tasks <- task }
+
+	ran := false
+	lock.LockThenOn(exec, func(unlock func()) {
+		ran = true
+		unlock()
+	})
+	(<-tasks)() // The executor runs the continuation on its own goroutine
+	assert.True(t, ran)
+	assert.True(t, lock.TryLock())
+}
+
 func TestSubAbs(t *testing.T) {
 	tests := []struct {
 		a, b     uint32
@@ -282,3 +455,17 @@ func BenchmarkTicketLockTryLock(b *testing.B) {
 		}
 	})
 }
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	lock := NewLock()
+	allocs.Zero(t, func() {
+		lock.Lock()
+		lock.Unlock()
+		assert.True(t, lock.TryLock())
+		lock.Unlock()
+	}, "Uncontended Lock, TryLock and Unlock should not allocate")
+}
+
+func TestLockIsPointerFree(t *testing.T) {
+	offheap.NoPointers(t, Lock{})
+}
@@ -0,0 +1,78 @@
+package locks
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/gid"
+)
+
+// Adopter is implemented by locks that record which goroutine holds them, such as
+// recursive.RWLock. AdoptLock makes the calling goroutine the holder of a lock that is
+// already held, on behalf of a goroutine that handed it over.
+type Adopter interface {
+	AdoptLock()
+}
+
+// Token is proof that a lock is held, and the only way to release it. Ownership of the
+// lock moves between goroutines by moving the Token: the holder calls TransferTo, and
+// the receiving goroutine calls Adopt before using the protected resource. The lock
+// stays held throughout, so no other goroutine can slip in between the two, unlike a
+// release on one side and a re-acquire on the other:
+//
+//	tok := locks.Acquire(l)
+//	// ... prepare the resource ...
+//	tok.TransferTo(handoff)
+//
+//	// On the receiving goroutine:
+//	tok := <-handoff
+//	tok.Adopt()
+//	defer tok.Release()
+//
+// A Token checks that only its current owner releases or transfers it, and panics
+// otherwise. The checks identify goroutines by ID, which costs about a microsecond per
+// operation.
+type Token struct {
+	l     sync.Locker
+	owner atomic.Int64 // Goroutine ID of the owner, 0 while in transit or released
+	done  atomic.Bool
+}
+
+// Acquire locks l and returns the Token for the hold, owned by the calling goroutine.
+func Acquire(l sync.Locker) *Token {
+	l.Lock()
+	t := &Token{l: l}
+	t.owner.Store(gid.Get())
+	return t
+}
+
+// TransferTo gives up the calling goroutine's ownership of the hold and sends the Token
+// on ch. The lock stays held until the receiver adopts and releases it.
+func (t *Token) TransferTo(ch chan<- *Token) {
+	t.disown("TransferTo")
+	ch <- t
+}
+
+// Adopt makes the calling goroutine the owner of a Token in transit.
+func (t *Token) Adopt() {
+	if !t.owner.CompareAndSwap(0, gid.Get()) || t.done.Load() {
+		panic("locks: Adopt of a Token that is not in transit")
+	}
+	if a, ok := t.l.(Adopter); ok {
+		a.AdoptLock()
+	}
+}
+
+// Release unlocks the lock. It must be called by the Token's owner, once.
+func (t *Token) Release() {
+	t.disown("Release")
+	t.done.Store(true)
+	t.l.Unlock()
+}
+
+// disown clears the owner, panicking if it isn't the calling goroutine.
+func (t *Token) disown(op string) {
+	if t.done.Load() || !t.owner.CompareAndSwap(gid.Get(), 0) {
+		panic("locks: " + op + " of a Token not owned by this goroutine")
+	}
+}
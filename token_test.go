@@ -0,0 +1,81 @@
+package locks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/gid"
+	"github.com/ahrav/go-locks/internal/allocs"
+	"github.com/ahrav/go-locks/recursive"
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestTokenTransfer(t *testing.T) {
+	lock := ticket.NewLock()
+	handoff := make(chan *Token)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		tok := <-handoff
+		assert.False(t, lock.TryLock(), "Lock must stay held while the Token is in transit")
+		tok.Adopt()
+		tok.Release()
+	}()
+
+	tok := Acquire(lock)
+	tok.TransferTo(handoff)
+	<-done
+
+	assert.True(t, lock.TryLock(), "Lock should be free once the adopter releases it")
+	lock.Unlock()
+}
+
+func TestTokenMisuse(t *testing.T) {
+	tok := Acquire(ticket.NewLock())
+	assert.Panics(t, tok.Adopt, "A held Token is not in transit")
+
+	other := make(chan struct{})
+	go func() {
+		defer close(other)
+		assert.Panics(t, tok.Release, "Only the owner may release")
+	}()
+	<-other
+
+	tok.Release()
+	assert.Panics(t, tok.Release, "A Token can only be released once")
+	assert.Panics(t, tok.Adopt, "A released Token cannot be adopted")
+}
+
+func TestTokenAdoptsOwnerTrackingLock(t *testing.T) {
+	rw := recursive.New()
+	handoff := make(chan *Token, 1)
+
+	Acquire(rw).TransferTo(handoff)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tok := <-handoff
+		tok.Adopt()
+		rw.Lock() // Re-entering is only allowed because the adopter now owns the lock
+		rw.Unlock()
+		tok.Release()
+	}()
+	<-done
+}
+
+func TestTokenTransferDoesNotAllocate(t *testing.T) {
+	lock := ticket.NewLock()
+	handoff := make(chan *Token, 1)
+	tok := &Token{l: lock}
+	allocs.Zero(t, func() {
+		lock.Lock() //lockcheck:ignore The Token releases it
+		tok.done.Store(false)
+		tok.owner.Store(gid.Get()) // As Acquire does, reusing the Token
+		tok.TransferTo(handoff)
+		<-handoff
+		tok.Adopt()
+		tok.Release()
+	}, "Transferring, adopting and releasing a Token should not allocate")
+}
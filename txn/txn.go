@@ -0,0 +1,185 @@
+// Package txn provides two-phase locking (2PL) on top of the intent lock manager.
+//
+// A Txn accumulates locks while the transaction runs, its growing phase, and releases
+// all of them at once when it commits or aborts, its shrinking phase. Nothing is
+// released early, which is what makes the schedule of concurrent transactions
+// serializable.
+//
+// Deadlock between transactions is prevented by ordering: every transaction must lock
+// resources in canonical order, which is lexicographic order of their paths. Lock
+// reports an acquisition that goes backwards with ErrLockOrder instead of risking a
+// deadlock, and LockAll sorts a batch of requests into canonical order before acquiring
+// them. Locking a path after its sibling takes the shared ancestors again, possibly in a
+// stronger intention mode; the manager grants those to the transaction already holding
+// them ahead of other waiters, which may be waiting for it.
+//
+// Alternatively, the manager can avoid deadlocks itself with one of the timestamp-based
+// policies of package intent, in which case resources may be locked in any order. A
+// conflicting Lock may then fail with intent.ErrDie or intent.ErrWounded; the caller
+// aborts and starts over with Retry, which keeps the transaction's timestamp so that it
+// eventually wins:
+//
+//	m := intent.NewManager(intent.WithPolicy(intent.WoundWait))
+//
+//	for tx := txn.Begin(m); ; tx = tx.Retry() {
+//	    if err := transfer(ctx, tx); !errors.Is(err, intent.ErrWounded) {
+//	        return err
+//	    }
+//	}
+//
+// Example usage:
+//
+//	tx := txn.Begin(m)
+//	defer tx.Abort() // No-op once committed
+//
+//	err := tx.LockAll(ctx,
+//	    txn.Request{Mode: intent.X, Path: []string{"accounts", "bob"}},
+//	    txn.Request{Mode: intent.X, Path: []string{"accounts", "alice"}},
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	// ... move money between the accounts ...
+//	return tx.Commit()
+package txn
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/ahrav/go-locks/intent"
+)
+
+var (
+	// ErrLockOrder is returned when a transaction requests a resource that sorts before
+	// one it already requested.
+	ErrLockOrder = errors.New("txn: resource requested out of canonical order")
+
+	// ErrUpgrade is returned when a transaction requests a mode that conflicts with a
+	// mode it already holds on the same resource.
+	ErrUpgrade = errors.New("txn: requested mode conflicts with a mode already held")
+
+	// ErrDone is returned when a transaction is used after it committed or aborted.
+	ErrDone = errors.New("txn: transaction already committed or aborted")
+)
+
+// Request asks for the resource at Path to be locked in Mode.
+type Request struct {
+	Mode intent.Mode
+	Path []string
+}
+
+type hold struct {
+	key  string
+	mode intent.Mode
+}
+
+// Txn is a transaction following two-phase locking. A Txn must not be used by multiple
+// goroutines at once.
+type Txn struct {
+	m     *intent.Manager
+	owner *intent.Owner
+	holds []hold                   // In acquisition order
+	modes map[string][]intent.Mode // Modes held per resource key
+	last  []string                 // Path of the last resource requested
+	done  bool
+}
+
+// Begin starts a transaction locking resources of m.
+func Begin(m *intent.Manager) *Txn { return begin(m, intent.NewOwner()) }
+
+func begin(m *intent.Manager, owner *intent.Owner) *Txn {
+	return &Txn{m: m, owner: owner, modes: make(map[string][]intent.Mode)}
+}
+
+// Retry aborts the transaction if it is still running and starts a new one on the same
+// manager with the same timestamp, keeping its seniority under the manager's Policy.
+func (t *Txn) Retry() *Txn {
+	t.Abort()
+	return begin(t.m, intent.NewOwnerAt(t.owner.Timestamp()))
+}
+
+// Lock locks the resource at path in mode, along with the intention locks on its
+// ancestors, unless the transaction already holds a covering mode. If ctx is done
+// first, or the manager's Policy picks the transaction as a victim, Lock returns the
+// error and the transaction keeps only the locks it held before; it should then be
+// aborted.
+func (t *Txn) Lock(ctx context.Context, mode intent.Mode, path ...string) error {
+	if t.done {
+		return ErrDone
+	}
+	if t.m.Policy() == intent.NoPolicy && slices.Compare(path, t.last) < 0 {
+		return ErrLockOrder
+	}
+
+	for i := range path {
+		m := mode
+		if i < len(path)-1 {
+			m = mode.Intent()
+		}
+		if err := t.acquire(ctx, intent.Key(path[:i+1]...), m); err != nil {
+			return err
+		}
+	}
+	t.last = slices.Clone(path)
+	return nil
+}
+
+// LockAll locks every request, in canonical order regardless of the order given.
+func (t *Txn) LockAll(ctx context.Context, reqs ...Request) error {
+	reqs = slices.Clone(reqs)
+	slices.SortStableFunc(reqs, func(a, b Request) int { return slices.Compare(a.Path, b.Path) })
+	for _, r := range reqs {
+		if err := t.Lock(ctx, r.Mode, r.Path...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acquire locks a single resource in mode unless a held mode already covers it.
+func (t *Txn) acquire(ctx context.Context, key string, mode intent.Mode) error {
+	held := t.modes[key]
+	for _, h := range held {
+		if h.Covers(mode) {
+			return nil
+		}
+		if !h.Compatible(mode) {
+			return ErrUpgrade // The manager would make us wait for ourselves
+		}
+	}
+	if err := t.m.AcquireAs(ctx, t.owner, key, mode); err != nil {
+		return err
+	}
+	t.modes[key] = append(held, mode)
+	t.holds = append(t.holds, hold{key: key, mode: mode})
+	return nil
+}
+
+// Commit ends the transaction, releasing all of its locks.
+func (t *Txn) Commit() error {
+	if t.done {
+		return ErrDone
+	}
+	t.release()
+	return nil
+}
+
+// Abort ends the transaction, releasing all of its locks. Aborting a transaction that
+// already ended is a no-op, so Abort can be deferred unconditionally.
+func (t *Txn) Abort() {
+	if !t.done {
+		t.release()
+	}
+}
+
+// release drops every lock, most recent first, and ends the transaction.
+func (t *Txn) release() {
+	for i := len(t.holds) - 1; i >= 0; i-- {
+		t.m.ReleaseAs(t.owner, t.holds[i].key, t.holds[i].mode)
+	}
+	t.holds = nil
+	clear(t.modes)
+	t.done = true
+}
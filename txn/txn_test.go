@@ -0,0 +1,152 @@
+package txn
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/intent"
+)
+
+func TestLockOrder(t *testing.T) {
+	m := intent.NewManager()
+	ctx := context.Background()
+	tx := Begin(m)
+	defer tx.Abort()
+
+	assert.NoError(t, tx.Lock(ctx, intent.X, "accounts", "bob"))
+	assert.ErrorIs(t, tx.Lock(ctx, intent.X, "accounts", "alice"), ErrLockOrder)
+	assert.NoError(t, tx.Lock(ctx, intent.S, "accounts", "bob"), "Re-locking a covered resource is fine")
+	assert.NoError(t, tx.Lock(ctx, intent.X, "accounts", "carol"))
+}
+
+func TestUpgradeConflict(t *testing.T) {
+	m := intent.NewManager()
+	ctx := context.Background()
+	tx := Begin(m)
+	defer tx.Abort()
+
+	assert.NoError(t, tx.Lock(ctx, intent.S, "t"))
+	assert.ErrorIs(t, tx.Lock(ctx, intent.X, "t", "row"), ErrUpgrade, "IX on t conflicts with our own S")
+}
+
+func TestCanonicalOrderExtendingHoldDoesNotDeadlock(t *testing.T) {
+	m := intent.NewManager()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	t1, t2 := Begin(m), Begin(m)
+	defer t1.Abort()
+
+	assert.NoError(t, t1.Lock(ctx, intent.S, "a", "b")) // IS(a), S(a/b)
+	queued := make(chan error)
+	go func() {
+		queued <- t2.Lock(ctx, intent.X, "a") // Waits for t1's IS(a)
+		t2.Abort()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// t1's IX(a) must not queue behind t2, which is waiting for t1.
+	assert.NoError(t, t1.Lock(ctx, intent.X, "a", "c"))
+	assert.NoError(t, t1.Commit())
+	assert.NoError(t, <-queued)
+}
+
+func TestCommitReleasesEverything(t *testing.T) {
+	m := intent.NewManager()
+	ctx := context.Background()
+	tx := Begin(m)
+
+	assert.NoError(t, tx.LockAll(ctx,
+		Request{Mode: intent.X, Path: []string{"t", "b"}},
+		Request{Mode: intent.X, Path: []string{"t", "a"}},
+	))
+	assert.NoError(t, tx.Commit())
+	assert.ErrorIs(t, tx.Commit(), ErrDone)
+	assert.ErrorIs(t, tx.Lock(ctx, intent.S, "t"), ErrDone)
+	tx.Abort() // No-op
+
+	other := Begin(m)
+	assert.NoError(t, other.Lock(ctx, intent.X, "t"), "All locks should have been released")
+	other.Abort()
+}
+
+func TestConcurrentTransfers(t *testing.T) {
+	m := intent.NewManager()
+	a, b, c := 100, 100, 100
+	balances := map[string]*int{"a": &a, "b": &b, "c": &c} // Read-only map; the accounts are locked
+	accounts := []string{"a", "b", "c"}
+	const numGoroutines = 6
+	const iterations = 100
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+	for g := range numGoroutines {
+		go func() {
+			defer wg.Done()
+			from, to := accounts[g%3], accounts[(g+1)%3]
+			for range iterations {
+				tx := Begin(m)
+				// Opposite directions would deadlock without canonical ordering.
+				err := tx.LockAll(context.Background(),
+					Request{Mode: intent.X, Path: []string{"accounts", from}},
+					Request{Mode: intent.X, Path: []string{"accounts", to}},
+				)
+				assert.NoError(t, err)
+				*balances[from]--
+				*balances[to]++
+				assert.NoError(t, tx.Commit())
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 300, a+b+c)
+}
+
+func TestPoliciesAvoidDeadlock(t *testing.T) {
+	for _, policy := range []intent.Policy{intent.WaitDie, intent.WoundWait} {
+		t.Run(policy.String(), func(t *testing.T) {
+			m := intent.NewManager(intent.WithPolicy(policy))
+			a, b := 100, 100
+			const numGoroutines = 4
+			const iterations = 50
+			var wg sync.WaitGroup
+
+			wg.Add(numGoroutines)
+			for g := range numGoroutines {
+				go func() {
+					defer wg.Done()
+					// Half the goroutines lock in the opposite order, which would
+					// deadlock without the policy.
+					first, second := "a", "b"
+					if g%2 == 1 {
+						first, second = second, first
+					}
+					for range iterations {
+						for tx := Begin(m); ; tx = tx.Retry() {
+							err := tx.Lock(context.Background(), intent.X, first)
+							if err == nil {
+								err = tx.Lock(context.Background(), intent.X, second)
+							}
+							if err == nil {
+								a--
+								b++
+								assert.NoError(t, tx.Commit())
+								break
+							}
+							assert.True(t, errors.Is(err, intent.ErrDie) || errors.Is(err, intent.ErrWounded), err)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, 200, a+b)
+			assert.Equal(t, 100-numGoroutines*iterations, a)
+		})
+	}
+}
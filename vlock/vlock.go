@@ -0,0 +1,173 @@
+// Package vlock implements a versioned lock, the building block of optimistic
+// concurrency control.
+//
+// A versioned lock is a write lock and a version counter in one word. Readers take no
+// lock at all: they note the version with ReadVersion before reading, and call Validate
+// afterwards, which reports whether a writer locked the data in between. Writers lock
+// with LockWrite, and releasing the lock with UnlockWrite bumps the version, which
+// invalidates every read that overlapped the write. An OCC layer that read a set of
+// objects under their versions commits by locking the objects it writes with TryLockAt,
+// which succeeds only if an object is still at the version its read saw, validating its
+// other reads, and then releasing every lock.
+//
+// The word holds the version in its upper 63 bits and the lock bit in the lowest bit, so
+// a lock held for writing never validates any version.
+//
+// As with stamped.Lock, data read optimistically must be read with sync/atomic and not
+// trusted until Validate succeeds.
+//
+// Example usage:
+//
+//	var l vlock.Lock
+//	var balance atomic.Int64
+//
+//	for {
+//	    v := l.ReadVersion()
+//	    b := balance.Load()
+//	    if l.Validate(v) {
+//	        return b
+//	    }
+//	    // A writer got in between: retry
+//	}
+//
+//	l.LockWrite()
+//	balance.Add(10)
+//	l.UnlockWrite() // Bumps the version
+package vlock
+
+import (
+	"sync/atomic"
+
+	"github.com/ahrav/go-locks/archspin"
+	"github.com/ahrav/go-locks/chaos"
+	"github.com/ahrav/go-locks/internal/invariant"
+	"github.com/ahrav/go-locks/lockprof"
+	"github.com/ahrav/go-locks/spin"
+)
+
+// Version identifies a state of the data guarded by a Lock. Versions only grow.
+type Version uint64
+
+// Lock is a versioned write lock. The zero value is an unlocked lock at version 0. It
+// contains no pointers, so zeroed memory outside the Go heap is a valid Lock too.
+type Lock struct {
+	word atomic.Uint64 // Version<<1 | locked
+
+	spin spin.Adaptive // Self-tuning spin limit for waiters
+}
+
+// NewLock creates a new versioned lock.
+func NewLock() *Lock { return new(Lock) }
+
+// ReadVersion waits until the lock is not held for writing and returns its version.
+func (l *Lock) ReadVersion() Version {
+	if w := l.word.Load(); w&1 == 0 {
+		return Version(w >> 1)
+	}
+	var v Version
+	l.wait(func() bool {
+		w := l.word.Load()
+		v = Version(w >> 1)
+		return w&1 == 0
+	})
+	return v
+}
+
+// TryReadVersion returns the lock's version, or false if the lock is held for writing.
+func (l *Lock) TryReadVersion() (Version, bool) {
+	w := l.word.Load()
+	return Version(w >> 1), w&1 == 0
+}
+
+// Validate reports whether the lock is still unlocked at version v, i.e. whether no
+// writer has locked it since v was read.
+func (l *Lock) Validate(v Version) bool {
+	chaos.Point()
+	return l.word.Load() == uint64(v)<<1
+}
+
+// Version returns the lock's current version, whether or not it is held.
+func (l *Lock) Version() Version { return Version(l.word.Load() >> 1) }
+
+// Locked reports whether the lock is held for writing.
+func (l *Lock) Locked() bool { return l.word.Load()&1 != 0 }
+
+// LockWrite acquires the lock for writing. It returns the version the lock was at,
+// which UnlockWrite will advance.
+func (l *Lock) LockWrite() Version {
+	w := l.word.Load()
+	if w&1 == 0 && l.word.CompareAndSwap(w, w|1) {
+		return Version(w >> 1)
+	}
+	var v Version
+	l.wait(func() bool {
+		w := l.word.Load()
+		v = Version(w >> 1)
+		return w&1 == 0 && l.word.CompareAndSwap(w, w|1)
+	})
+	return v
+}
+
+// TryLockWrite acquires the lock for writing if it is free, without waiting.
+func (l *Lock) TryLockWrite() (Version, bool) {
+	w := l.word.Load()
+	if w&1 == 0 && l.word.CompareAndSwap(w, w|1) {
+		return Version(w >> 1), true
+	}
+	return 0, false
+}
+
+// TryLockAt acquires the lock for writing only if it is free and still at version v.
+// It is how an optimistic transaction turns a validated read into a write lock.
+func (l *Lock) TryLockAt(v Version) bool {
+	chaos.Point()
+	return l.word.CompareAndSwap(uint64(v)<<1, uint64(v)<<1|1)
+}
+
+// UnlockWrite releases the write lock and advances the version, invalidating every
+// read of an earlier version.
+func (l *Lock) UnlockWrite() {
+	if invariant.Enabled {
+		invariant.Check(l.Locked(), "vlock: UnlockWrite of an unlocked lock")
+	}
+	l.word.Add(1) // Clears the lock bit and carries into the version
+}
+
+// Abort releases the write lock without advancing the version, for a writer that ended
+// up changing nothing. Readers that overlapped the aborted write still validate.
+func (l *Lock) Abort() {
+	if invariant.Enabled {
+		invariant.Check(l.Locked(), "vlock: Abort of an unlocked lock")
+	}
+	l.word.Add(^uint64(0)) // Clears the lock bit only
+}
+
+// wait spins, then yields, until cond reports true.
+func (l *Lock) wait(cond func() bool) {
+	start := lockprof.Start()
+	spinLimit := 0 // No spinning if the holder can't run meanwhile
+	reserved := spin.CanSpin() && spin.Reserve()
+	if reserved {
+		spinLimit = l.spin.Limit()
+	}
+	i := 0
+	for ; !cond(); i++ {
+		chaos.Point()
+		if i < spinLimit {
+			archspin.Relax()
+			continue
+		}
+		if reserved {
+			spin.Release()
+			reserved = false
+		}
+		spin.Wait(i - spinLimit)
+	}
+	if reserved {
+		spin.Release()
+	}
+	if spinLimit > 0 && i > 0 {
+		l.spin.Update(min(i, spinLimit), i < spinLimit)
+	}
+	lockprof.Record(start, 1)
+}
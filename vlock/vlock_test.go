@@ -0,0 +1,105 @@
+package vlock
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/internal/allocs"
+	"github.com/ahrav/go-locks/internal/offheap"
+)
+
+func TestVersionAdvancesOnWrite(t *testing.T) {
+	var l Lock
+	v := l.ReadVersion()
+	assert.True(t, l.Validate(v))
+
+	assert.Equal(t, v, l.LockWrite())
+	assert.False(t, l.Validate(v), "A held lock validates no version")
+	_, ok := l.TryReadVersion()
+	assert.False(t, ok)
+	l.UnlockWrite()
+
+	assert.False(t, l.Validate(v))
+	assert.Equal(t, v+1, l.ReadVersion())
+}
+
+func TestAbortKeepsVersion(t *testing.T) {
+	var l Lock
+	v := l.ReadVersion()
+	l.LockWrite()
+	l.Abort()
+	assert.True(t, l.Validate(v))
+	assert.False(t, l.Locked())
+}
+
+func TestTryLockAt(t *testing.T) {
+	var l Lock
+	v := l.ReadVersion()
+	assert.True(t, l.TryLockAt(v))
+	assert.False(t, l.TryLockAt(v), "Already held")
+	l.UnlockWrite()
+	assert.False(t, l.TryLockAt(v), "Stale version")
+
+	v, ok := l.TryLockWrite()
+	assert.True(t, ok)
+	assert.Equal(t, Version(1), v)
+	_, ok = l.TryLockWrite()
+	assert.False(t, ok)
+	l.UnlockWrite()
+}
+
+func TestOptimisticReadersSeeConsistentState(t *testing.T) {
+	var l Lock
+	var x, y atomic.Int64 // Invariant: x == y
+	const numReaders = 4
+	const iterations = 2000
+	var wg sync.WaitGroup
+
+	wg.Add(numReaders + 2)
+	for range 2 {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				l.LockWrite()
+				x.Add(1)
+				y.Add(1)
+				l.UnlockWrite()
+			}
+		}()
+	}
+	for range numReaders {
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				for {
+					v := l.ReadVersion()
+					cx, cy := x.Load(), y.Load()
+					if l.Validate(v) {
+						assert.Equal(t, cx, cy)
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(2*iterations), x.Load())
+	assert.Equal(t, Version(2*iterations), l.Version())
+}
+
+func TestLockDoesNotAllocate(t *testing.T) {
+	var l Lock
+	allocs.Zero(t, func() {
+		l.LockWrite()
+		l.UnlockWrite()
+		l.Validate(l.ReadVersion())
+	}, "Uncontended operations should not allocate")
+}
+
+func TestLockIsPointerFree(t *testing.T) {
+	offheap.NoPointers(t, Lock{})
+}
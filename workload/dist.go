@@ -0,0 +1,48 @@
+package workload
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Dist is a distribution of durations, used for critical-section lengths, think times
+// and arrival gaps.
+type Dist interface {
+	Sample() time.Duration
+}
+
+// DistFunc adapts a function to a Dist.
+type DistFunc func() time.Duration
+
+// Sample calls f.
+func (f DistFunc) Sample() time.Duration { return f() }
+
+// Fixed always returns d.
+func Fixed(d time.Duration) Dist {
+	return DistFunc(func() time.Duration { return d })
+}
+
+// Uniform draws durations uniformly from [lo, hi].
+func Uniform(lo, hi time.Duration) Dist {
+	if hi <= lo {
+		return Fixed(lo)
+	}
+	return DistFunc(func() time.Duration { return lo + rand.N(hi-lo+1) })
+}
+
+// Exponential draws exponentially distributed durations with the given mean, the gaps
+// between the arrivals of a Poisson process.
+func Exponential(mean time.Duration) Dist {
+	return DistFunc(func() time.Duration { return time.Duration(rand.ExpFloat64() * float64(mean)) })
+}
+
+// Bimodal returns short with probability 1-p and long with probability p, modelling
+// critical sections that occasionally take a slow path.
+func Bimodal(short, long time.Duration, p float64) Dist {
+	return DistFunc(func() time.Duration {
+		if rand.Float64() < p {
+			return long
+		}
+		return short
+	})
+}
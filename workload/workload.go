@@ -0,0 +1,183 @@
+// Package workload drives locks with service-like load and reports throughput and
+// wait-time percentiles.
+//
+// A benchmark that acquires and releases a lock in a tight loop measures a regime real
+// services rarely reach: every goroutine is always contending, and critical sections
+// are empty. Run instead models the arrival of requests and the time they spend inside
+// and outside the critical section:
+//
+//   - Closed loop: a fixed number of workers each acquire the lock, hold it for a time
+//     drawn from Hold, release it and think for a time drawn from Think before
+//     arriving again. Load adapts to the lock: a slow lock slows the arrivals.
+//   - Open loop: requests arrive as a Poisson process at Rate per second, each in its
+//     own goroutine, whether or not earlier ones have finished. Load doesn't adapt, so
+//     a lock that can't keep up builds a queue, as it would behind a network server.
+//
+// Critical sections and think times are spent busy, standing in for CPU work.
+//
+// Example usage:
+//
+//	res, err := workload.Run(ctx, ticket.NewLock(), workload.Config{
+//	    Rate:     50_000,
+//	    Hold:     workload.Exponential(5 * time.Microsecond),
+//	    Duration: time.Second,
+//	})
+//	fmt.Println(res) // ops=49812 throughput=49790/s wait p50=2µs p90=11µs p99=48µs ...
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Config describes a workload.
+type Config struct {
+	Workers  int           // Closed loop: number of workers; ignored in open loop
+	Rate     float64       // Open loop: mean arrivals per second; 0 selects a closed loop
+	Hold     Dist          // Time spent holding the lock; nil for empty critical sections
+	Think    Dist          // Closed loop: time between release and the next arrival
+	Duration time.Duration // How long to generate arrivals
+}
+
+// Percentiles summarizes a distribution of wait times.
+type Percentiles struct {
+	P50, P90, P99, P999, Max time.Duration
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	Ops        int           // Completed critical sections
+	Elapsed    time.Duration // Time from the start of the run until the last release
+	Throughput float64       // Ops per second
+	Wait       Percentiles   // Time from arrival until the lock was acquired
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("ops=%d throughput=%.0f/s wait p50=%v p90=%v p99=%v p99.9=%v max=%v",
+		r.Ops, r.Throughput, r.Wait.P50, r.Wait.P90, r.Wait.P99, r.Wait.P999, r.Wait.Max)
+}
+
+// Run drives l with the workload described by cfg until cfg.Duration has passed or ctx
+// is done, and waits for every arrival to complete.
+//
+// In open loop, an arrival's wait is measured from when it was scheduled to arrive, not
+// from when its goroutine got to call Lock, so a harness that falls behind is charged
+// to the lock instead of hiding the queueing delay.
+func Run(ctx context.Context, l sync.Locker, cfg Config) (Result, error) {
+	if cfg.Duration <= 0 {
+		return Result{}, errors.New("workload: Duration must be positive")
+	}
+	if cfg.Rate < 0 || (cfg.Rate == 0 && cfg.Workers <= 0) {
+		return Result{}, errors.New("workload: need a positive Rate or Workers")
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	start := time.Now()
+	var waits []time.Duration
+	if cfg.Rate > 0 {
+		waits = openLoop(ctx, l, cfg)
+	} else {
+		waits = closedLoop(ctx, l, cfg)
+	}
+	elapsed := time.Since(start)
+
+	return Result{
+		Ops:        len(waits),
+		Elapsed:    elapsed,
+		Throughput: float64(len(waits)) / elapsed.Seconds(),
+		Wait:       Summarize(waits),
+	}, nil
+}
+
+// section performs one critical section arriving at the given time and returns its wait.
+func section(l sync.Locker, arrival time.Time, hold Dist) time.Duration {
+	l.Lock()
+	wait := time.Since(arrival)
+	if hold != nil {
+		Busy(hold.Sample())
+	}
+	l.Unlock()
+	return wait
+}
+
+func closedLoop(ctx context.Context, l sync.Locker, cfg Config) []time.Duration {
+	perWorker := make([][]time.Duration, cfg.Workers)
+	var wg sync.WaitGroup
+	wg.Add(cfg.Workers)
+	for w := range perWorker {
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				perWorker[w] = append(perWorker[w], section(l, time.Now(), cfg.Hold))
+				if cfg.Think != nil {
+					Busy(cfg.Think.Sample())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return slices.Concat(perWorker...)
+}
+
+func openLoop(ctx context.Context, l sync.Locker, cfg Config) []time.Duration {
+	results := make(chan time.Duration, 1024)
+	var waits []time.Duration
+	collected := make(chan struct{})
+	go func() {
+		for w := range results {
+			waits = append(waits, w)
+		}
+		close(collected)
+	}()
+
+	gap := Exponential(time.Duration(float64(time.Second) / cfg.Rate))
+	var wg sync.WaitGroup
+	next := time.Now()
+	for {
+		next = next.Add(gap.Sample())
+		if d := time.Until(next); d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+			case <-t.C:
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(arrival time.Time) {
+			defer wg.Done()
+			results <- section(l, arrival, cfg.Hold)
+		}(next)
+	}
+	wg.Wait()
+	close(results)
+	<-collected
+	return waits
+}
+
+// Busy spins for d, standing in for CPU work.
+func Busy(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
+
+// Summarize returns the percentiles of waits, which it sorts in place.
+func Summarize(waits []time.Duration) Percentiles {
+	if len(waits) == 0 {
+		return Percentiles{}
+	}
+	slices.Sort(waits)
+	at := func(q float64) time.Duration { return waits[int(q*float64(len(waits)-1))] }
+	return Percentiles{P50: at(0.5), P90: at(0.9), P99: at(0.99), P999: at(0.999), Max: waits[len(waits)-1]}
+}
@@ -0,0 +1,76 @@
+package workload
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+func TestClosedLoop(t *testing.T) {
+	counter := 0
+	l := ticket.NewLock()
+	res, err := Run(context.Background(), &countingLocker{l, &counter}, Config{
+		Workers:  4,
+		Hold:     Fixed(time.Microsecond),
+		Duration: 50 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.Positive(t, res.Ops)
+	assert.Equal(t, res.Ops, counter, "Every reported op should have run its critical section")
+	assert.Positive(t, res.Throughput)
+	assert.LessOrEqual(t, res.Wait.P50, res.Wait.P99)
+	assert.LessOrEqual(t, res.Wait.P99, res.Wait.Max)
+}
+
+func TestOpenLoopRate(t *testing.T) {
+	res, err := Run(context.Background(), ticket.NewLock(), Config{
+		Rate:     2000,
+		Duration: 200 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	// About 400 arrivals are expected; allow for a slow, shared test machine.
+	assert.InDelta(t, 400, res.Ops, 200)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := Run(context.Background(), ticket.NewLock(), Config{Workers: 1})
+	assert.Error(t, err, "Duration is required")
+	_, err = Run(context.Background(), ticket.NewLock(), Config{Duration: time.Second})
+	assert.Error(t, err, "Either Rate or Workers is required")
+}
+
+func TestPercentiles(t *testing.T) {
+	waits := make([]time.Duration, 1000)
+	for i := range waits {
+		waits[len(waits)-1-i] = time.Duration(i + 1)
+	}
+	p := Summarize(waits)
+	assert.Equal(t, Percentiles{P50: 500, P90: 900, P99: 990, P999: 999, Max: 1000}, p)
+	assert.Equal(t, Percentiles{}, Summarize(nil))
+}
+
+func TestDists(t *testing.T) {
+	assert.Equal(t, time.Millisecond, Fixed(time.Millisecond).Sample())
+	for range 100 {
+		d := Uniform(10, 20).Sample()
+		assert.True(t, d >= 10 && d <= 20, d)
+		assert.Contains(t, []time.Duration{1, 2}, Bimodal(1, 2, 0.5).Sample())
+		assert.GreaterOrEqual(t, Exponential(time.Millisecond).Sample(), time.Duration(0))
+	}
+}
+
+type countingLocker struct {
+	l *ticket.Lock
+	n *int
+}
+
+func (c *countingLocker) Lock() { c.l.Lock() }
+
+func (c *countingLocker) Unlock() {
+	*c.n++
+	c.l.Unlock()
+}
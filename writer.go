@@ -0,0 +1,74 @@
+package locks
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// WriterOption configures a writer created by SyncWriter.
+type WriterOption func(*syncWriter)
+
+// WithWriteTimeout bounds how long a Write may wait for its turn. A Write that times out
+// returns context.DeadlineExceeded having written nothing, so a stuck destination can't
+// pile up writers indefinitely.
+func WithWriteTimeout(d time.Duration) WriterOption {
+	return func(s *syncWriter) { s.timeout = d }
+}
+
+type syncWriter struct {
+	w       io.Writer
+	l       ContextLocker
+	timeout time.Duration
+}
+
+// SyncWriter returns an io.Writer that serializes calls to w's Write with l, so that
+// each call's bytes reach w whole, never interleaved with another goroutine's, for
+// example one log line per call. A nil l selects a ticket.Lock, which orders concurrent
+// writes by arrival.
+//
+// With WithWriteTimeout, writes wait for l through its LockContext method if it is a
+// ContextLocker, and through a ChanLocker otherwise.
+func SyncWriter(w io.Writer, l sync.Locker, opts ...WriterOption) io.Writer {
+	if l == nil {
+		l = ticket.NewLock()
+	}
+	s := &syncWriter{w: w}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.timeout <= 0 {
+		return &lockedWriter{w: w, l: l}
+	}
+	if cl, ok := l.(ContextLocker); ok {
+		s.l = cl
+	} else {
+		s.l = NewChanLocker(l)
+	}
+	return s
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	if err := s.l.LockContext(ctx); err != nil {
+		return 0, err
+	}
+	defer s.l.Unlock()
+	return s.w.Write(p)
+}
+
+// lockedWriter is the writer for writes without a timeout.
+type lockedWriter struct {
+	w io.Writer
+	l sync.Locker
+}
+
+func (s *lockedWriter) Write(p []byte) (int, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,80 @@
+package locks
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahrav/go-locks/ticket"
+)
+
+// chunkedWriter writes each call one byte at a time, yielding in between, so that
+// unserialized writers would interleave.
+type chunkedWriter struct{ buf bytes.Buffer }
+
+func (c *chunkedWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		c.buf.WriteByte(b)
+		time.Sleep(0)
+	}
+	return len(p), nil
+}
+
+func TestSyncWriterKeepsWritesWhole(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []WriterOption
+	}{
+		{"plain", nil},
+		{"timeout", []WriterOption{WithWriteTimeout(time.Minute)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst chunkedWriter
+			w := SyncWriter(&dst, nil, tt.opts...)
+			const numGoroutines = 4
+			const lines = 50
+			var wg sync.WaitGroup
+
+			wg.Add(numGoroutines)
+			for g := range numGoroutines {
+				go func() {
+					defer wg.Done()
+					line := strings.Repeat(string(rune('a'+g)), 16) + "\n"
+					for range lines {
+						n, err := w.Write([]byte(line))
+						assert.NoError(t, err)
+						assert.Equal(t, len(line), n)
+					}
+				}()
+			}
+			wg.Wait()
+
+			got := strings.Split(strings.TrimSuffix(dst.buf.String(), "\n"), "\n")
+			assert.Len(t, got, numGoroutines*lines)
+			for _, line := range got {
+				assert.Equal(t, strings.Repeat(line[:1], 16), line, "Interleaved write")
+			}
+		})
+	}
+}
+
+func TestSyncWriterTimeout(t *testing.T) {
+	l := ticket.NewLock()
+	var dst bytes.Buffer
+	w := SyncWriter(&dst, l, WithWriteTimeout(10*time.Millisecond))
+
+	l.Lock() // A stuck writer
+	n, err := w.Write([]byte("late"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, n)
+	l.Unlock()
+
+	_, err = w.Write([]byte("on time"))
+	assert.NoError(t, err)
+	assert.Equal(t, "on time", dst.String())
+}